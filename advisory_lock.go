@@ -0,0 +1,166 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// This file exposes the PostgreSQL advisory locks, keyed by strings, in two scopes:
+//
+//   - session-scoped (AdvisoryLock, TryAdvisoryLock and WithAdvisoryLock): the lock is held by a
+//     connection pinned from the pool until it is unlocked, it outlives transactions;
+//   - transaction-scoped (AdvisoryXactLock, TryAdvisoryXactLock and WithAdvisoryXactLock): the lock is
+//     held by the current transaction and it is released when the transaction ends.
+//
+// See Campaign for a leader election built on top of them.
+
+// AdvisoryLockKey returns the 64-bit advisory lock key of the given string key:
+// its FNV-1a hash, stable across processes and restarts.
+func AdvisoryLockKey(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLock is a session-scoped advisory lock held by a connection pinned from the pool,
+// see DB.AdvisoryLock and DB.TryAdvisoryLock.
+type AdvisoryLock struct {
+	key  string
+	conn *pgxpool.Conn
+}
+
+// Key returns the key of the lock.
+func (l *AdvisoryLock) Key() string {
+	return l.key
+}
+
+// Unlock releases the lock and returns its connection to the pool.
+// If the lock cannot be released, e.g. the context is done, the connection is closed instead,
+// which releases the lock too. Calling Unlock more than once is a no-op.
+func (l *AdvisoryLock) Unlock(ctx context.Context) error {
+	conn := l.conn
+	if conn == nil {
+		return nil
+	}
+	l.conn = nil
+
+	var unlocked bool
+	err := conn.QueryRow(ctx, "SELECT pg_advisory_unlock($1)", AdvisoryLockKey(l.key)).Scan(&unlocked)
+	if err != nil || !unlocked {
+		// never return a connection which may still hold the lock to the pool.
+		if closeErr := conn.Hijack().Close(context.WithoutCancel(ctx)); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			return fmt.Errorf("advisory lock: %s: unlock: %w", l.key, err)
+		}
+
+		return nil
+	}
+
+	conn.Release()
+	return nil
+}
+
+// AdvisoryLock waits for the session-scoped advisory lock of the given key (pg_advisory_lock) on a
+// connection pinned from the pool and returns it. The lock is held until its Unlock method is called.
+//
+// Example:
+//
+//	lock, err := db.AdvisoryLock(ctx, "reports")
+//	if err != nil { ... }
+//	defer lock.Unlock(ctx)
+func (db *DB) AdvisoryLock(ctx context.Context, key string) (*AdvisoryLock, error) {
+	lock, _, err := db.advisoryLock(ctx, key, "SELECT true FROM pg_advisory_lock($1)")
+	return lock, err
+}
+
+// TryAdvisoryLock is like AdvisoryLock but it does not wait (pg_try_advisory_lock):
+// it reports false, and returns a nil lock, if the lock is held by another session.
+func (db *DB) TryAdvisoryLock(ctx context.Context, key string) (*AdvisoryLock, bool, error) {
+	return db.advisoryLock(ctx, key, "SELECT pg_try_advisory_lock($1)")
+}
+
+func (db *DB) advisoryLock(ctx context.Context, key, query string) (*AdvisoryLock, bool, error) {
+	conn, err := db.Pool.Acquire(db.poolContext(ctx)) // Always on top.
+	if err != nil {
+		return nil, false, fmt.Errorf("advisory lock: %s: %w", key, err)
+	}
+
+	var locked bool
+	if err = conn.QueryRow(ctx, query, AdvisoryLockKey(key)).Scan(&locked); err != nil {
+		// the lock may have been acquired just before the context was done.
+		_ = conn.Hijack().Close(context.WithoutCancel(ctx))
+		return nil, false, fmt.Errorf("advisory lock: %s: %w", key, err)
+	}
+
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	return &AdvisoryLock{key: key, conn: conn}, true, nil
+}
+
+// WithAdvisoryLock runs fn while holding the session-scoped advisory lock of the given key,
+// waiting for it first, see AdvisoryLock. The lock is released when fn returns.
+func (db *DB) WithAdvisoryLock(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	lock, err := db.AdvisoryLock(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	err = fn(ctx)
+	if unlockErr := lock.Unlock(ctx); unlockErr != nil {
+		err = errors.Join(err, unlockErr)
+	}
+
+	return err
+}
+
+// AdvisoryXactLock waits for the transaction-scoped advisory lock of the given key
+// (pg_advisory_xact_lock). The lock is released when the transaction commits or rolls back.
+// It returns an error if the database is not in a transaction.
+func (db *DB) AdvisoryXactLock(ctx context.Context, key string) error {
+	if !db.IsTransaction() {
+		return fmt.Errorf("advisory lock: %s: the database is not in a transaction", key)
+	}
+
+	if _, err := db.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", AdvisoryLockKey(key)); err != nil {
+		return fmt.Errorf("advisory lock: %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// TryAdvisoryXactLock is like AdvisoryXactLock but it does not wait (pg_try_advisory_xact_lock):
+// it reports false if the lock is held by another session.
+func (db *DB) TryAdvisoryXactLock(ctx context.Context, key string) (bool, error) {
+	if !db.IsTransaction() {
+		return false, fmt.Errorf("advisory lock: %s: the database is not in a transaction", key)
+	}
+
+	var locked bool
+	if err := db.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", AdvisoryLockKey(key)).Scan(&locked); err != nil {
+		return false, fmt.Errorf("advisory lock: %s: %w", key, err)
+	}
+
+	return locked, nil
+}
+
+// WithAdvisoryXactLock runs fn in a transaction, see InTransaction, which holds the transaction-scoped
+// advisory lock of the given key, waiting for it first. The lock is released when the transaction ends.
+func (db *DB) WithAdvisoryXactLock(ctx context.Context, key string, fn func(*DB) error) error {
+	return db.InTransaction(ctx, func(tx *DB) error {
+		if err := tx.AdvisoryXactLock(ctx, key); err != nil {
+			return err
+		}
+
+		return fn(tx)
+	})
+}
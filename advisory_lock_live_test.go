@@ -0,0 +1,143 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestAdvisory|TestLeader' -v .
+
+func TestAdvisoryLock(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	lock, err := db.AdvisoryLock(ctx, "test_advisory_lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, locked, err := db.TryAdvisoryLock(ctx, "test_advisory_lock"); err != nil || locked {
+		t.Fatalf("expected the lock to be held by another session but got: %v: %v", locked, err)
+	}
+
+	err = db.InTransaction(ctx, func(tx *DB) error {
+		locked, err := tx.TryAdvisoryXactLock(ctx, "test_advisory_lock")
+		if err == nil && locked {
+			t.Fatal("expected the lock to be held by another session")
+		}
+
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = lock.Unlock(ctx); err != nil {
+		t.Fatalf("expected a second unlock to be a no-op but got: %v", err)
+	}
+
+	var ran bool
+	err = db.WithAdvisoryLock(ctx, "test_advisory_lock", func(context.Context) error {
+		ran = true
+
+		if _, locked, err := db.TryAdvisoryLock(ctx, "test_advisory_lock"); err != nil || locked {
+			t.Fatalf("expected the lock to be held by another session but got: %v: %v", locked, err)
+		}
+
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("expected the function to run but got: %v: %v", ran, err)
+	}
+
+	// the transaction-scoped lock is released on commit.
+	err = db.WithAdvisoryXactLock(ctx, "test_advisory_lock", func(*DB) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock, locked, err := db.TryAdvisoryLock(ctx, "test_advisory_lock")
+	if err != nil || !locked {
+		t.Fatalf("expected the released lock to be acquired but got: %v: %v", locked, err)
+	}
+
+	if err = lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeader(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	opts := LeaderOptions{HeartbeatInterval: 20 * time.Millisecond, RetryInterval: 20 * time.Millisecond}
+
+	elected := make(chan string, 2)
+	campaign := func(name string) *Leader {
+		opts := opts
+		opts.OnElected = func(context.Context) { elected <- name }
+
+		leader, err := db.Campaign(ctx, "test_leader", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return leader
+	}
+
+	first := campaign("first")
+	defer first.Close(ctx)
+
+	select {
+	case name := <-elected:
+		if name != "first" || !first.IsLeader() {
+			t.Fatalf("expected the first instance to be the leader but got: %s", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no leader was elected")
+	}
+
+	second := campaign("second")
+	defer second.Close(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if second.IsLeader() {
+		t.Fatal("expected a single leader")
+	}
+
+	// the first instance resigns, the second one is elected.
+	if err = first.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.IsLeader() {
+		t.Fatal("expected the closed instance to be demoted")
+	}
+
+	select {
+	case name := <-elected:
+		if name != "second" || !second.IsLeader() {
+			t.Fatalf("expected the second instance to be the leader but got: %s", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the second instance was not elected")
+	}
+}
@@ -0,0 +1,34 @@
+package pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAdvisoryLockKey(t *testing.T) {
+	if AdvisoryLockKey("reports") != AdvisoryLockKey("reports") {
+		t.Fatal("expected the same key for the same string")
+	}
+
+	if AdvisoryLockKey("reports") == AdvisoryLockKey("emails") {
+		t.Fatal("expected different keys for different strings")
+	}
+
+	if expected, got := AdvisoryLockKey("kataras/pg/migrate"), migrateLockKey; got != expected {
+		t.Fatalf("expected the migrate lock key %d but got: %d", expected, got)
+	}
+
+	db := &DB{schema: NewSchema(), searchPath: "public"}
+
+	if err := db.AdvisoryXactLock(context.Background(), "reports"); err == nil {
+		t.Fatal("expected an error for a database which is not in a transaction")
+	}
+
+	if _, err := db.TryAdvisoryXactLock(context.Background(), "reports"); err == nil {
+		t.Fatal("expected an error for a database which is not in a transaction")
+	}
+
+	if _, err := db.Campaign(context.Background(), "", LeaderOptions{}); err == nil {
+		t.Fatal("expected an error for an empty election key")
+	}
+}
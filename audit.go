@@ -0,0 +1,317 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kataras/pg/desc"
+)
+
+// This file adds audited tables. CreateSchema installs a "<table>_history" table for each table
+// registered with the Audited option, plus a trigger which appends a row to it on every INSERT,
+// UPDATE and DELETE of the table, with the old and new row as JSON, the operation, the time
+// and the actor of the change (see WithActor). The trigger runs inside the transaction of the
+// change, so a history row exists if and only if the change was committed.
+//
+// Repository.History returns the revisions of a row and Repository.AsOf reconstructs it at a point in time.
+
+// Audited is a TableFilterFunc which makes CreateSchema record the changes of the rows of the table
+// in its history table, see Repository.History. The table must have a single primary key column.
+//
+// Example:
+//
+//	schema.MustRegister("customers", Customer{}, pg.Audited)
+func Audited(td *desc.Table) bool {
+	td.Audited = true
+	return true
+}
+
+// HistoryTableSuffix is the suffix of the name of the history table of an audited table, see Audited.
+const HistoryTableSuffix = "_history"
+
+// ActorSetting is the name of the run-time parameter which holds the actor of the context
+// (see WithActor), recorded by the history triggers of the audited tables.
+const ActorSetting = "pg.actor"
+
+type actorContextKey struct{}
+
+// WithActor returns a copy of the context which carries the given actor, e.g. the ID or the email of
+// the authenticated user of an HTTP request. The changes of the audited tables made with this context
+// record it as their actor (see Revision.Actor): a transaction sets the ActorSetting run-time parameter
+// (SET LOCAL) when it begins and so does every connection of Open when it is acquired by a query.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor of a context returned by WithActor.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorContextKey{}).(string)
+	return actor, ok && actor != ""
+}
+
+// Revision is a recorded change of a row of an audited table, see Repository.History.
+type Revision[T any] struct {
+	// Revision is the sequential number of the change in the history table.
+	Revision int64
+	// Change is the operation which changed the row: INSERT, UPDATE or DELETE.
+	Change TableChangeType
+	// ChangedAt is the time of the change, the start time of its transaction.
+	ChangedAt time.Time
+	// Actor is the actor of the change, see WithActor. It is empty if the context had none.
+	Actor string
+
+	// Old is the row before the change. It is only populated for UPDATE and
+	// DELETE revisions; it is the zero value of T for INSERT.
+	Old T
+	// New is the row after the change. It is only populated for INSERT and
+	// UPDATE revisions; it is the zero value of T for DELETE.
+	New T
+}
+
+// historyTableName returns the quoted, schema-qualified name of the history table of td.
+func (db *DB) historyTableName(td *desc.Table) string {
+	return QuoteIdentifier(db.searchPath) + "." + QuoteIdentifier(td.Name+HistoryTableSuffix)
+}
+
+// historyKey returns the primary key column of an audited table.
+func historyKey(td *desc.Table) (*desc.Column, error) {
+	primaryKey, ok := td.PrimaryKey()
+	if !ok || td.HasCompositePrimaryKey() {
+		return nil, fmt.Errorf("pg: audited: table: %s: a single primary key column is required", td.Name)
+	}
+
+	return primaryKey, nil
+}
+
+// historyKeyType returns the data type of the row_id column of the history table,
+// the one of the primary key without its sequence.
+func historyKeyType(c *desc.Column) string {
+	switch c.Type {
+	case desc.SmallSerial:
+		return desc.SmallInt.String()
+	case desc.Serial:
+		return desc.Integer.String()
+	case desc.BigSerial:
+		return desc.BigInt.String()
+	}
+
+	if c.TypeArgument != "" {
+		return c.Type.String() + "(" + c.TypeArgument + ")"
+	}
+
+	return c.Type.String()
+}
+
+// auditFunctionName is the name of the trigger function shared by the history triggers.
+const auditFunctionName = "trigger_audit_history"
+
+// createAuditDump creates the history tables and triggers of the audited tables, see Audited.
+// The trigger function receives the name of the primary key column as its argument
+// and writes to the history table of the changed table, in the same schema.
+func (db *DB) createAuditDump(_ context.Context, b *strings.Builder) error {
+	var functionCreated bool
+
+	for _, td := range db.schema.Tables(desc.TableTypeBase) {
+		if !td.Audited || td.IsReadOnly() {
+			continue
+		}
+
+		primaryKey, err := historyKey(td)
+		if err != nil {
+			return err
+		}
+
+		if !functionCreated { // global function, register once for all tables.
+			fmt.Fprintf(b, `CREATE OR REPLACE FUNCTION %s()
+		RETURNS TRIGGER AS $$
+		DECLARE
+		changed record;
+		BEGIN
+		IF TG_OP = 'DELETE' THEN
+			changed := OLD;
+		ELSE
+			changed := NEW;
+		END IF;
+		EXECUTE format('INSERT INTO %%I.%%I (row_id, operation, actor, old_row, new_row) VALUES (($1).%%I, $2, NULLIF(current_setting(%%L, true), ''''), $3, $4)',
+			TG_TABLE_SCHEMA, TG_TABLE_NAME || '%s', TG_ARGV[0], '%s')
+		USING changed, TG_OP, to_jsonb(OLD), to_jsonb(NEW);
+		RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;`, auditFunctionName, HistoryTableSuffix, ActorSetting)
+			functionCreated = true
+		}
+
+		historyTableName := QuoteIdentifier(td.Name + HistoryTableSuffix)
+		fmt.Fprintf(b, `CREATE TABLE IF NOT EXISTS %s (revision bigserial PRIMARY KEY, row_id %s NOT NULL, operation text NOT NULL, changed_at timestamptz NOT NULL DEFAULT now(), actor text, old_row jsonb, new_row jsonb);`,
+			historyTableName, historyKeyType(primaryKey))
+		fmt.Fprintf(b, `CREATE INDEX IF NOT EXISTS %s ON %s (row_id, revision);`,
+			QuoteIdentifier(td.Name+HistoryTableSuffix+"_row_id_idx"), historyTableName)
+		fmt.Fprintf(b, `CREATE OR REPLACE TRIGGER audit_history AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION %s('%s');`,
+			QuoteIdentifier(td.Name), auditFunctionName, primaryKey.Name)
+	}
+
+	return nil
+}
+
+// historyWhere returns the WHERE clause which selects the history rows of the row with the given id,
+// of the tenant of the context for a table with a tenant column (see WithTenant).
+func historyWhere(ctx context.Context, td *desc.Table, id any) (string, []any, error) {
+	if !td.Audited {
+		return "", nil, fmt.Errorf("pg: history: table: %s: is not audited, see Audited", td.Name)
+	}
+
+	if _, err := historyKey(td); err != nil {
+		return "", nil, err
+	}
+
+	where, args := " WHERE row_id = $1", []any{id}
+
+	tenantID, ok, err := tenantOf(ctx, td)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if ok {
+		c, _ := td.TenantColumn()
+		args = append(args, fmt.Sprint(tenantID))
+		where += fmt.Sprintf(` AND COALESCE(new_row, old_row) ->> '%s' = $%d`, c.Name, len(args))
+	}
+
+	return where, args, nil
+}
+
+// History returns the revisions of the row with the given primary key value, oldest first,
+// including the ones before its deletion. The table must be registered with the Audited option.
+//
+// Example:
+//
+//	revisions, err := customers.History(ctx, customerID)
+//	for _, r := range revisions {
+//		fmt.Printf("%s %s by %s: %s -> %s\n", r.ChangedAt, r.Change, r.Actor, r.Old.Email, r.New.Email)
+//	}
+func (repo *Repository[T]) History(ctx context.Context, id any) ([]Revision[T], error) {
+	where, args, err := historyWhere(ctx, repo.td, id)
+	if err != nil {
+		return nil, err
+	}
+
+	historyTableName := repo.db.historyTableName(repo.td)
+
+	// every query runs on the same replica, which holds the revisions the first one returned.
+	db := repo.db.pinRead(ctx)
+
+	query := fmt.Sprintf(`SELECT revision, operation, changed_at, COALESCE(actor, ''), old_row IS NOT NULL, new_row IS NOT NULL FROM %s%s ORDER BY revision;`,
+		historyTableName, where)
+	rows, err := db.queryRead(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		revisions          []Revision[T]
+		revisionNumbers    []int64
+		hasOld, hasNew     []bool
+		oldCount, newCount int
+	)
+	for rows.Next() {
+		var (
+			r                Revision[T]
+			withOld, withNew bool
+		)
+		if err = rows.Scan(&r.Revision, &r.Change, &r.ChangedAt, &r.Actor, &withOld, &withNew); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, r)
+		revisionNumbers = append(revisionNumbers, r.Revision)
+		hasOld, hasNew = append(hasOld, withOld), append(hasNew, withNew)
+		if withOld {
+			oldCount++
+		}
+		if withNew {
+			newCount++
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// the row values are decoded by PostgreSQL to the table's row type, so they are scanned exactly as the rows of a Select.
+	for _, side := range []struct {
+		column string
+		has    []bool
+		count  int
+		field  func(*Revision[T]) *T
+	}{
+		{"old_row", hasOld, oldCount, func(r *Revision[T]) *T { return &r.Old }},
+		{"new_row", hasNew, newCount, func(r *Revision[T]) *T { return &r.New }},
+	} {
+		if side.count == 0 {
+			continue
+		}
+
+		values, err := repo.selectHistoryRows(ctx, db, side.column, historyTableName, " WHERE revision = ANY($1) AND "+side.column+" IS NOT NULL ORDER BY revision", revisionNumbers)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) != side.count {
+			return nil, fmt.Errorf("pg: history: table: %s: expected %d %s values but got %d", repo.td.Name, side.count, side.column, len(values))
+		}
+
+		next := 0
+		for i := range revisions {
+			if side.has[i] {
+				*side.field(&revisions[i]) = values[next]
+				next++
+			}
+		}
+	}
+
+	return revisions, nil
+}
+
+// AsOf returns the row with the given primary key value as it was at the given time, i.e. the row
+// after its last change at or before that time, according to its history (see History).
+// It returns ErrNoRows if the row did not exist at that time: it was not inserted yet or it was deleted.
+func (repo *Repository[T]) AsOf(ctx context.Context, id any, at time.Time) (T, error) {
+	var value T
+
+	where, args, err := historyWhere(ctx, repo.td, id)
+	if err != nil {
+		return value, err
+	}
+
+	args = append(args, at)
+	lastRevision := fmt.Sprintf(`(SELECT new_row FROM %s%s AND changed_at <= $%d ORDER BY revision DESC LIMIT 1)`,
+		repo.db.historyTableName(repo.td), where, len(args))
+
+	values, err := repo.selectHistoryRows(ctx, repo.db.pinRead(ctx), "new_row", lastRevision, " WHERE new_row IS NOT NULL", args...)
+	if err != nil {
+		return value, err
+	}
+
+	if len(values) == 0 {
+		return value, fmt.Errorf("%s: %w", repo.td.GetHumanName(), ErrNoRows)
+	}
+
+	return values[0], nil
+}
+
+// selectHistoryRows decodes the JSON rows of the given column of a history table (or a subquery of it),
+// to the row type of the table and scans them to values of T. The query runs through db, see DB.pinRead.
+func (repo *Repository[T]) selectHistoryRows(ctx context.Context, db *DB, column, from, clause string, args ...any) ([]T, error) {
+	query := fmt.Sprintf(`SELECT (jsonb_populate_record(NULL::%s.%s, %s)).* FROM %s AS history%s;`,
+		QuoteIdentifier(db.searchPath), QuoteIdentifier(repo.td.Name), column, from, clause)
+
+	rows, err := db.queryRead(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.td.RowsToStruct[T](rows)
+}
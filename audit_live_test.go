@@ -0,0 +1,101 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestRepositoryHistory' -v .
+
+type auditLiveCustomer struct {
+	ID    int64  `pg:"type=bigserial,primary"`
+	Email string `pg:"type=varchar(255)"`
+}
+
+const auditScratchTable = "test_audit_customers"
+
+func TestRepositoryHistory(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(auditScratchTable, auditLiveCustomer{}, Audited)
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, auditScratchTable, auditScratchTable+HistoryTableSuffix)
+	defer dropTestTables(ctx, db, auditScratchTable, auditScratchTable+HistoryTableSuffix)
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRepository[auditLiveCustomer](db)
+
+	customer := auditLiveCustomer{Email: "first@example.com"}
+	if err = repo.InsertSingle(WithActor(ctx, "alice"), customer, &customer.ID); err != nil { // outside of a transaction.
+		t.Fatal(err)
+	}
+
+	var beforeUpdate time.Time
+	if err = db.QueryRow(ctx, "SELECT clock_timestamp()").Scan(&beforeUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.InTransaction(WithActor(ctx, "bob"), func(tx *DB) error {
+		customer.Email = "second@example.com"
+		_, err := NewRepository[auditLiveCustomer](tx).Update(ctx, customer)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = repo.Delete(ctx, customer); err != nil { // without an actor.
+		t.Fatal(err)
+	}
+
+	revisions, err := repo.History(ctx, customer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 3 {
+		t.Fatalf("expected 3 revisions but got: %#v", revisions)
+	}
+
+	expected := []struct {
+		change   TableChangeType
+		actor    string
+		old, new string
+	}{
+		{TableChangeTypeInsert, "alice", "", "first@example.com"},
+		{TableChangeTypeUpdate, "bob", "first@example.com", "second@example.com"},
+		{TableChangeTypeDelete, "", "second@example.com", ""},
+	}
+	for i, r := range revisions {
+		if r.Change != expected[i].change || r.Actor != expected[i].actor || r.Old.Email != expected[i].old || r.New.Email != expected[i].new {
+			t.Fatalf("[%d] expected %v but got: %#v", i, expected[i], r)
+		}
+	}
+
+	asOf, err := repo.AsOf(ctx, customer.ID, beforeUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if asOf.Email != "first@example.com" {
+		t.Fatalf("expected the inserted row but got: %#v", asOf)
+	}
+
+	if _, err = repo.AsOf(ctx, customer.ID, time.Now().Add(time.Hour)); !errors.Is(err, ErrNoRows) {
+		t.Fatalf("expected ErrNoRows for a deleted row but got: %v", err)
+	}
+}
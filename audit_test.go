@@ -0,0 +1,105 @@
+package pg
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type auditedCustomer struct {
+	ID    int64  `pg:"type=bigserial,primary"`
+	Email string `pg:"type=varchar(255)"`
+}
+
+func TestCreateAuditDump(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("customers", auditedCustomer{}, Audited)
+	db := &DB{schema: schema, searchPath: "public"}
+
+	var b strings.Builder
+	if err := db.createAuditDump(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	dump := b.String()
+	for _, expected := range []string{
+		`CREATE OR REPLACE FUNCTION trigger_audit_history()`,
+		`VALUES (($1).%I, $2, NULLIF(current_setting(%L, true), ''''), $3, $4)',
+			TG_TABLE_SCHEMA, TG_TABLE_NAME || '_history', TG_ARGV[0], 'pg.actor')`,
+		`CREATE TABLE IF NOT EXISTS "customers_history" (revision bigserial PRIMARY KEY, row_id bigint NOT NULL, operation text NOT NULL, changed_at timestamptz NOT NULL DEFAULT now(), actor text, old_row jsonb, new_row jsonb);`,
+		`CREATE INDEX IF NOT EXISTS "customers_history_row_id_idx" ON "customers_history" (row_id, revision);`,
+		`CREATE OR REPLACE TRIGGER audit_history AFTER INSERT OR UPDATE OR DELETE ON "customers" FOR EACH ROW EXECUTE FUNCTION trigger_audit_history('id');`,
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Fatalf("expected the dump to contain:\n%s\nbut got:\n%s", expected, dump)
+		}
+	}
+
+	// tables which are not audited have no history.
+	plain := NewSchema()
+	plain.MustRegister("customers", auditedCustomer{})
+	b.Reset()
+	if err := (&DB{schema: plain, searchPath: "public"}).createAuditDump(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Len() != 0 {
+		t.Fatalf("expected an empty dump but got: %s", b.String())
+	}
+
+	td, err := plain.GetByTableName("customers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = historyWhere(context.Background(), td, int64(1)); err == nil {
+		t.Fatal("expected an error for a table which is not audited")
+	}
+}
+
+func TestHistoryWhereTenant(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("orders", tenantOrder{}, Audited)
+	td, err := schema.GetByTableName("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = historyWhere(context.Background(), td, int64(1)); err == nil {
+		t.Fatal("expected an error for a context without a tenant")
+	}
+
+	where, args, err := historyWhere(WithTenant(context.Background(), 42), td, int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := ` WHERE row_id = $1 AND COALESCE(new_row, old_row) ->> 'tenant_id' = $2`; where != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, where)
+	}
+
+	if !reflect.DeepEqual(args, []any{int64(1), "42"}) {
+		t.Fatalf("unexpected arguments: %v", args)
+	}
+}
+
+func TestContextSettings(t *testing.T) {
+	db := &DB{schema: NewSchema()}
+	ctx := WithTenant(WithActor(context.Background(), "alice@example.com"), 42)
+
+	names, values := db.contextSettings(ctx)
+	if !reflect.DeepEqual(names, []string{ActorSetting}) || !reflect.DeepEqual(values, []string{"alice@example.com"}) {
+		t.Fatalf("expected the actor setting only but got: %v = %v", names, values)
+	}
+
+	db.schema.TenantRowLevelSecurity = true
+	names, values = db.contextSettings(ctx)
+	if !reflect.DeepEqual(names, []string{TenantSetting, ActorSetting}) || !reflect.DeepEqual(values, []string{"42", "alice@example.com"}) {
+		t.Fatalf("expected the tenant and the actor settings but got: %v = %v", names, values)
+	}
+
+	if names, _ = db.contextSettings(WithActor(context.Background(), "")); len(names) != 0 {
+		t.Fatalf("expected no settings for an empty actor but got: %v", names)
+	}
+}
@@ -0,0 +1,61 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestCommentsSchema' -v .
+
+type commentLiveCustomer struct {
+	ID    string `pg:"type=uuid,primary"`
+	Email string `pg:"type=text,desc=The email address, in lowercase"`
+}
+
+const commentScratchTable = "test_comment_customers"
+
+func TestCommentsSchema(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(commentScratchTable, commentLiveCustomer{}, WithDescription("The customers of the shop"))
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, commentScratchTable)
+	defer dropTestTables(ctx, db, commentScratchTable)
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.CheckSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := db.ListTables(ctx, ListTablesOptions{TableNames: []string{commentScratchTable}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tables) != 1 || tables[0].Description != "The customers of the shop." {
+		t.Fatalf("unexpected tables: %#v", tables)
+	}
+
+	// a description changed on the database side is reported.
+	if _, err = db.Exec(ctx, "COMMENT ON COLUMN test_comment_customers.email IS 'The email address';"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.CheckSchema(ctx)
+	if err == nil || !strings.Contains(err.Error(), `column "email" in table "test_comment_customers" has wrong description`) {
+		t.Fatalf("expected a column description mismatch error but got: %v", err)
+	}
+}
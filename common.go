@@ -3,6 +3,8 @@ package pg
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -17,7 +19,7 @@ import (
 //
 //	names, err := db.QuerySlice[string](ctx, "SELECT name FROM users;")
 func (db *DB) QuerySlice[T any](ctx context.Context, query string, args ...any) ([]T, error) {
-	rows, err := db.Query(ctx, query, args...)
+	rows, err := db.queryRead(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -152,14 +154,27 @@ type ScanFunc[T any] func(rows Rows) (T, error)
 //		return nc, err
 //	}, "SELECT name, COUNT(*) FROM users GROUP BY name;")
 func (db *DB) QueryFunc[T any](ctx context.Context, scan ScanFunc[T], query string, args ...any) ([]T, error) {
-	return db.scanQuery(ctx, scan, query, args...)
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRows(rows, scan)
 }
 
+// scanQuery is QueryFunc for the read queries of this package, e.g. of the catalog:
+// it runs the query on a read replica, if any, see queryRead.
 func (db *DB) scanQuery[T any](ctx context.Context, scanner func(rows Rows) (T, error), query string, args ...any) ([]T, error) {
-	rows, err := db.Query(ctx, query, args...)
+	rows, err := db.queryRead(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+
+	return scanRows(rows, scanner)
+}
+
+// scanRows builds a list by calling scanner once per row and closes the rows.
+func scanRows[T any](rows Rows, scanner func(rows Rows) (T, error)) ([]T, error) {
 	defer rows.Close()
 	var list []T
 
@@ -172,7 +187,7 @@ func (db *DB) scanQuery[T any](ctx context.Context, scanner func(rows Rows) (T,
 		list = append(list, entry)
 	}
 
-	if err = rows.Err(); err != nil && !errors.Is(err, ErrNoRows) {
+	if err := rows.Err(); err != nil && !errors.Is(err, ErrNoRows) {
 		return nil, err
 	}
 
@@ -183,3 +198,20 @@ func (db *DB) scanQuery[T any](ctx context.Context, scanner func(rows Rows) (T,
 func QuoteIdentifier(identifier string) string {
 	return pgx.Identifier{identifier}.Sanitize()
 }
+
+// identifierRegex matches a bare, unquoted PostgreSQL identifier: it must start with a
+// letter or underscore, followed by letters, digits, underscores or dollar signs only. Same
+// character class as desc's (unexported) identifierRegex (see desc/struct_table.go).
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// ValidateIdentifier reports an error if identifier is not a bare, unquoted PostgreSQL identifier
+// (a letter or underscore, followed by letters, digits, underscores or dollar signs).
+// It guards the configurable names which are interpolated into DDL, quoted with QuoteIdentifier,
+// rather than bound as parameters, e.g. MigrateOptions.TableName and the tables of the cron package.
+func ValidateIdentifier(identifier string) error {
+	if !identifierRegex.MatchString(identifier) {
+		return fmt.Errorf("%q: must match %s", identifier, identifierRegex.String())
+	}
+
+	return nil
+}
@@ -2,7 +2,6 @@ package pg
 
 import (
 	"context"
-	"reflect"
 
 	"github.com/kataras/pg/desc"
 )
@@ -37,12 +36,12 @@ func (repo *Repository[T]) InsertOnConflict(ctx context.Context, oc OnConflict,
 			end := min(start+batchSize, len(values))
 			batch := values[start:end]
 
-			structValues := make([]reflect.Value, len(batch))
-			for i := range batch {
-				structValues[i] = desc.IndirectValue(batch[i])
+			structValues, err := bindTenants(ctx, repo.td, batch)
+			if err != nil {
+				return err
 			}
 
-			query, args, err := desc.BuildBulkInsertQueryOnConflict(repo.td, structValues, oc)
+			query, args, err := desc.BuildBulkInsertQueryOnConflict(repo.db.tenantTable(repo.td), structValues, oc)
 			if err != nil {
 				return err
 			}
@@ -67,13 +66,18 @@ func (repo *Repository[T]) InsertSingleOnConflict(ctx context.Context, oc OnConf
 		return ErrIsReadOnly
 	}
 
-	query, args, err := desc.BuildInsertQueryOnConflict(repo.td, desc.IndirectValue(value), idPtr, oc)
+	structValue, err := bindTenant(ctx, repo.td, desc.IndirectValue(value))
+	if err != nil {
+		return err
+	}
+
+	query, args, err := desc.BuildInsertQueryOnConflict(repo.db.tenantTable(repo.td), structValue, idPtr, oc)
 	if err != nil {
 		return err
 	}
 
 	if idPtr != nil {
-		return repo.db.QueryRow(ctx, query, args...).Scan(idPtr)
+		return scanPrimaryKey(repo.db.QueryRow(ctx, query, args...), repo.td, idPtr)
 	}
 
 	_, err = repo.db.Exec(ctx, query, args...)
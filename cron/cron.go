@@ -0,0 +1,688 @@
+// Package cron runs scheduled jobs, defined by cron expressions (see Parse), across the instances of
+// an application, using PostgreSQL as the coordinator: every scheduled run of a job is claimed by a
+// single instance, with SELECT ... FOR UPDATE SKIP LOCKED, and recorded, with its duration and error,
+// in a run-history table. Jobs can be paused, resumed and triggered from any instance.
+//
+// # Usage
+//
+//	scheduler, err := cron.New(db, cron.Options{})
+//	if err != nil { ... }
+//	if err = scheduler.CreateTables(ctx); err != nil { ... }
+//
+//	err = scheduler.Register(ctx, "cleanup", "CRON_TZ=Europe/Athens 0 3 * * *", func(ctx context.Context, run cron.Run) error {
+//		return cleanup(ctx)
+//	}, cron.JobOptions{Jitter: time.Minute})
+//	if err != nil { ... }
+//
+//	runner, err := scheduler.Start(ctx)
+//	if err != nil { ... }
+//	defer runner.Close(ctx)
+//
+// The jobs are stored in the "cron_jobs" table and their runs in the "cron_runs" one (see Options).
+// Each instance runs only the jobs it has registered a handler for. A run is claimed, and the next
+// one is scheduled, in a single transaction, so a scheduled time is claimed by a single instance.
+// The claimed run is leased to that instance, which renews the lease while its handler runs (see
+// Options.LeaseTimeout): if the instance stops before it records the result of the run, e.g. it
+// crashed, the run is claimed again, once its lease expires, by another instance which registered
+// the job. So every scheduled time runs to completion exactly once, provided that the handler can
+// repeat a run which was interrupted half-way (see Run.Attempt), as is the case with any
+// at-least-once delivery.
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/kataras/pg"
+)
+
+// CatchUpPolicy controls the runs of a job which were missed, e.g. while no instance was running.
+type CatchUpPolicy int
+
+const (
+	// CatchUpOnce runs a job which missed any number of runs once and then it follows its schedule.
+	// It is the default policy.
+	CatchUpOnce CatchUpPolicy = iota
+	// CatchUpAll runs every missed run of a job, one after the other.
+	CatchUpAll
+	// CatchUpNone skips a run which is later than the JobOptions.Tolerance of the job.
+	CatchUpNone
+)
+
+// Run statuses, the values of the status column of the runs table.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Options holds the options of a Scheduler, see New.
+type Options struct {
+	// JobsTable is the name of the jobs table, in the search path of the DB. Defaults to "cron_jobs".
+	JobsTable string
+	// RunsTable is the name of the run-history table, in the search path of the DB. Defaults to "cron_runs".
+	RunsTable string
+	// PollInterval is the interval of the scheduler to look for due jobs. Defaults to 1 second.
+	PollInterval time.Duration
+	// LeaseTimeout is the duration a claimed run is leased to the instance which claimed it.
+	// The instance renews the lease every third of it while the handler runs, and once the lease
+	// expires, e.g. the instance crashed, the run is claimed again by another instance. The context
+	// of the handler is canceled if its lease is lost. Defaults to 1 minute.
+	LeaseTimeout time.Duration
+	// Location is the location of the schedules which do not set a CRON_TZ, see Parse.
+	// Defaults to UTC.
+	Location *time.Location
+	// OnError, if not nil, is called with the errors of the database and of the handlers,
+	// which are recorded in the run history too.
+	OnError func(error)
+}
+
+// JobOptions holds the options of a job, see Scheduler.Register.
+type JobOptions struct {
+	// CatchUp is the policy for the missed runs of the job. Defaults to CatchUpOnce.
+	CatchUp CatchUpPolicy
+	// Tolerance is the delay after which a run is considered missed by the CatchUpNone policy.
+	// Defaults to a minute.
+	Tolerance time.Duration
+	// Jitter, if positive, delays each run by a random duration up to it,
+	// e.g. to spread the load of many jobs scheduled at the same time.
+	Jitter time.Duration
+	// Timeout, if positive, is the timeout of the context of the handler.
+	Timeout time.Duration
+}
+
+// Run is a run of a job, passed to its Handler.
+type Run struct {
+	ID          int64     // the ID of the run in the history.
+	Job         string    // the name of the job.
+	ScheduledAt time.Time // the time the run was scheduled at.
+	Attempt     int       // the number of the attempt, starting from 1, greater for a run whose instance stopped.
+}
+
+// Handler runs a job. Its error is recorded in the run history.
+type Handler func(ctx context.Context, run Run) error
+
+// Job is the state of a registered job, see Scheduler.Jobs.
+type Job struct {
+	Name     string
+	Spec     string
+	TimeZone string
+	Paused   bool
+	NextRun  time.Time
+	LastRun  *time.Time
+}
+
+// RunRecord is a run of the history, see Scheduler.Runs.
+type RunRecord struct {
+	ID          int64
+	Job         string
+	ScheduledAt time.Time
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+	Duration    time.Duration
+	Status      string
+	Error       string
+	Attempts    int
+}
+
+type registeredJob struct {
+	handler Handler
+	opts    JobOptions
+}
+
+// Scheduler runs the registered jobs on their schedules, see New.
+type Scheduler struct {
+	db   *pg.DB
+	opts Options
+
+	mu   sync.RWMutex
+	jobs map[string]registeredJob
+}
+
+// New returns a new Scheduler which stores its jobs and their runs through the given database.
+// Create its tables with CreateTables.
+func New(db *pg.DB, opts Options) (*Scheduler, error) {
+	if opts.JobsTable == "" {
+		opts.JobsTable = "cron_jobs"
+	}
+
+	if opts.RunsTable == "" {
+		opts.RunsTable = "cron_runs"
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+
+	if opts.LeaseTimeout <= 0 {
+		opts.LeaseTimeout = time.Minute
+	}
+
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	for _, table := range []string{opts.JobsTable, opts.RunsTable} {
+		if err := pg.ValidateIdentifier(table); err != nil {
+			return nil, fmt.Errorf("cron: invalid table name: %w", err)
+		}
+	}
+
+	return &Scheduler{db: db, opts: opts, jobs: make(map[string]registeredJob)}, nil
+}
+
+func (s *Scheduler) jobsTable() string {
+	return pg.QuoteIdentifier(s.db.SearchPath()) + "." + pg.QuoteIdentifier(s.opts.JobsTable)
+}
+
+func (s *Scheduler) runsTable() string {
+	return pg.QuoteIdentifier(s.db.SearchPath()) + "." + pg.QuoteIdentifier(s.opts.RunsTable)
+}
+
+// CreateTables creates the jobs and the runs tables, if they do not exist.
+func (s *Scheduler) CreateTables(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+		name text PRIMARY KEY,
+		spec text NOT NULL,
+		time_zone text NOT NULL,
+		paused boolean NOT NULL DEFAULT false,
+		next_run timestamptz NOT NULL,
+		last_run timestamptz,
+		created_at timestamptz NOT NULL DEFAULT now(),
+		updated_at timestamptz NOT NULL DEFAULT now()
+	);
+	CREATE TABLE IF NOT EXISTS %[2]s (
+		id bigserial PRIMARY KEY,
+		job text NOT NULL,
+		scheduled_at timestamptz NOT NULL,
+		started_at timestamptz NOT NULL DEFAULT now(),
+		finished_at timestamptz,
+		duration interval,
+		status text NOT NULL DEFAULT 'running',
+		error text,
+		attempts integer NOT NULL DEFAULT 1,
+		locked_until timestamptz
+	);
+	CREATE INDEX IF NOT EXISTS %[3]s ON %[2]s (job, started_at DESC);
+	CREATE INDEX IF NOT EXISTS %[4]s ON %[2]s (locked_until) WHERE status = 'running';`,
+		s.jobsTable(), s.runsTable(), pg.QuoteIdentifier(s.opts.RunsTable+"_job_idx"), pg.QuoteIdentifier(s.opts.RunsTable+"_running_idx"))
+
+	if _, err := s.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("cron: create tables: %w", err)
+	}
+
+	return nil
+}
+
+// Register registers the handler of a job with the given name and cron expression (see Parse) and
+// stores the job, if it is new, or updates its schedule, if it changed. The instances of an
+// application should register the same jobs; only the ones which have registered a job run it.
+func (s *Scheduler) Register(ctx context.Context, name, spec string, handler Handler, opts JobOptions) error {
+	if name == "" {
+		return errors.New("cron: register: empty job name")
+	}
+
+	if handler == nil {
+		return fmt.Errorf("cron: register: %s: handler is nil", name)
+	}
+
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = time.Minute
+	}
+
+	schedule, err := Parse(spec, s.opts.Location)
+	if err != nil {
+		return err
+	}
+
+	next := schedule.Next(time.Now())
+	if next.IsZero() {
+		return fmt.Errorf("cron: register: %s: %q never runs", name, spec)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %[1]s (name, spec, time_zone, next_run) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET spec = EXCLUDED.spec, time_zone = EXCLUDED.time_zone, next_run = EXCLUDED.next_run, updated_at = now()
+		WHERE %[1]s.spec <> EXCLUDED.spec OR %[1]s.time_zone <> EXCLUDED.time_zone;`, s.jobsTable())
+	if _, err = s.db.Exec(ctx, query, name, spec, s.opts.Location.String(), next); err != nil {
+		return fmt.Errorf("cron: register: %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	s.jobs[name] = registeredJob{handler: handler, opts: opts}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Pause pauses a job: it is not run until it is resumed.
+func (s *Scheduler) Pause(ctx context.Context, name string) error {
+	return s.setPaused(ctx, name, true)
+}
+
+// Resume resumes a paused job. It is scheduled from now on: the runs it missed while paused are skipped.
+func (s *Scheduler) Resume(ctx context.Context, name string) error {
+	return s.setPaused(ctx, name, false)
+}
+
+func (s *Scheduler) setPaused(ctx context.Context, name string, paused bool) error {
+	return s.db.InTransaction(ctx, func(tx *pg.DB) error {
+		var spec, timeZone string
+		query := fmt.Sprintf(`SELECT spec, time_zone FROM %s WHERE name = $1 FOR UPDATE;`, s.jobsTable())
+		if err := tx.QueryRow(ctx, query, name).Scan(&spec, &timeZone); err != nil {
+			if errors.Is(err, pg.ErrNoRows) {
+				return fmt.Errorf("cron: %s: job not found", name)
+			}
+
+			return fmt.Errorf("cron: %s: %w", name, err)
+		}
+
+		schedule, err := parseStored(spec, timeZone)
+		if err != nil {
+			return err
+		}
+
+		query = fmt.Sprintf(`UPDATE %s SET paused = $2, next_run = $3, updated_at = now() WHERE name = $1;`, s.jobsTable())
+		if _, err = tx.Exec(ctx, query, name, paused, schedule.Next(time.Now())); err != nil {
+			return fmt.Errorf("cron: %s: %w", name, err)
+		}
+
+		return nil
+	})
+}
+
+// Trigger schedules a run of a job now, on the instances which registered it, unless it is paused.
+// Its schedule continues after the triggered run.
+func (s *Scheduler) Trigger(ctx context.Context, name string) error {
+	query := fmt.Sprintf(`UPDATE %s SET next_run = now(), updated_at = now() WHERE name = $1;`, s.jobsTable())
+	tag, err := s.db.Exec(ctx, query, name)
+	if err != nil {
+		return fmt.Errorf("cron: %s: trigger: %w", name, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("cron: %s: job not found", name)
+	}
+
+	return nil
+}
+
+// Jobs returns the stored jobs, ordered by their name.
+func (s *Scheduler) Jobs(ctx context.Context) ([]Job, error) {
+	query := fmt.Sprintf(`SELECT name, spec, time_zone, paused, next_run, last_run FROM %s ORDER BY name;`, s.jobsTable())
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("cron: jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var job Job
+		if err = rows.Scan(&job.Name, &job.Spec, &job.TimeZone, &job.Paused, &job.NextRun, &job.LastRun); err != nil {
+			return nil, fmt.Errorf("cron: jobs: %w", err)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// Runs returns up to limit of the latest runs of a job, the latest first.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]RunRecord, error) {
+	query := fmt.Sprintf(`SELECT id, job, scheduled_at, started_at, finished_at,
+		COALESCE((EXTRACT(EPOCH FROM duration) * 1000000)::bigint, 0), status, COALESCE(error, ''), attempts
+		FROM %s WHERE job = $1 ORDER BY started_at DESC, id DESC LIMIT $2;`, s.runsTable())
+	rows, err := s.db.Query(ctx, query, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("cron: %s: runs: %w", name, err)
+	}
+	defer rows.Close()
+
+	var runs []RunRecord
+	for rows.Next() {
+		var (
+			run          RunRecord
+			microseconds int64
+		)
+		if err = rows.Scan(&run.ID, &run.Job, &run.ScheduledAt, &run.StartedAt, &run.FinishedAt, &microseconds, &run.Status, &run.Error, &run.Attempts); err != nil {
+			return nil, fmt.Errorf("cron: %s: runs: %w", name, err)
+		}
+
+		run.Duration = time.Duration(microseconds) * time.Microsecond
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// parseStored parses the schedule of a stored job.
+func parseStored(spec, timeZone string) (Schedule, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("cron: %q: time zone: %w", spec, err)
+	}
+
+	return Parse(spec, loc)
+}
+
+// Start starts the scheduler, which claims and runs the due runs of the registered jobs every
+// Options.PollInterval, until the returned Closer is closed or the context is done. Closing it stops
+// claiming runs and waits for the running ones until the context of Close is done, when their context
+// is canceled too.
+func (s *Scheduler) Start(ctx context.Context) (pg.Closer, error) {
+	claimCtx, stopClaiming := context.WithCancel(ctx)
+	runsCtx, cancelRuns := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancelRuns) // the runs are canceled when the parent context is done.
+	r := &runner{stopClaiming: stopClaiming, cancelRuns: cancelRuns, stop: stop}
+
+	r.loop.Go(func() {
+		ticker := time.NewTicker(s.opts.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			runs, err := s.claim(claimCtx)
+			if err != nil {
+				if claimCtx.Err() != nil {
+					return
+				}
+
+				s.reportError(fmt.Errorf("cron: claim: %w", err))
+			}
+
+			for _, run := range runs {
+				r.runs.Go(func() {
+					s.run(runsCtx, run)
+				})
+			}
+
+			select {
+			case <-claimCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+
+	return r, nil
+}
+
+// claimedRun is a run claimed by this instance.
+type claimedRun struct {
+	Run
+	job registeredJob
+}
+
+// claim claims the due runs of the registered jobs and schedules their next runs, in a transaction,
+// along with the runs of the registered jobs whose lease expired.
+func (s *Scheduler) claim(ctx context.Context) ([]claimedRun, error) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var claimed []claimedRun
+	err := s.db.InTransaction(ctx, func(tx *pg.DB) error {
+		claimed = claimed[:0]
+
+		query := fmt.Sprintf(`UPDATE %[1]s AS r SET attempts = r.attempts + 1, started_at = now(), locked_until = now() + $2::interval
+			FROM (
+				SELECT id FROM %[1]s
+				WHERE job = ANY($1) AND status = 'running' AND locked_until < now()
+				FOR UPDATE SKIP LOCKED
+			) AS expired
+			WHERE r.id = expired.id
+			RETURNING r.id, r.job, r.scheduled_at, r.attempts;`, s.runsTable())
+		rows, err := tx.Query(ctx, query, names, s.opts.LeaseTimeout)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var c claimedRun
+			if err = rows.Scan(&c.ID, &c.Job, &c.ScheduledAt, &c.Attempt); err != nil {
+				rows.Close()
+				return err
+			}
+
+			s.mu.RLock()
+			c.job = s.jobs[c.Job]
+			s.mu.RUnlock()
+
+			claimed = append(claimed, c)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		query = fmt.Sprintf(`SELECT name, spec, time_zone, next_run, now() FROM %s
+			WHERE name = ANY($1) AND NOT paused AND next_run <= now()
+			ORDER BY next_run
+			FOR UPDATE SKIP LOCKED;`, s.jobsTable())
+		rows, err = tx.Query(ctx, query, names)
+		if err != nil {
+			return err
+		}
+
+		type due struct {
+			name, spec, timeZone string
+			scheduledAt, now     time.Time
+		}
+
+		var dues []due
+		for rows.Next() {
+			var d due
+			if err = rows.Scan(&d.name, &d.spec, &d.timeZone, &d.scheduledAt, &d.now); err != nil {
+				rows.Close()
+				return err
+			}
+
+			dues = append(dues, d)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, d := range dues {
+			s.mu.RLock()
+			job := s.jobs[d.name]
+			s.mu.RUnlock()
+
+			schedule, err := parseStored(d.spec, d.timeZone)
+			if err != nil {
+				return err
+			}
+
+			run := true
+			next := schedule.Next(d.scheduledAt)
+			switch job.opts.CatchUp {
+			case CatchUpOnce:
+				if !next.After(d.now) {
+					next = schedule.Next(d.now)
+				}
+			case CatchUpAll:
+			case CatchUpNone:
+				if d.now.Sub(d.scheduledAt) > job.opts.Tolerance {
+					run = false
+					next = schedule.Next(d.now)
+				}
+			}
+
+			if next.IsZero() { // no more runs, e.g. the last year of a year field: park it.
+				next = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+			}
+
+			query = fmt.Sprintf(`UPDATE %s SET next_run = $2, last_run = CASE WHEN $3 THEN now() ELSE last_run END WHERE name = $1;`, s.jobsTable())
+			if _, err = tx.Exec(ctx, query, d.name, next, run); err != nil {
+				return err
+			}
+
+			if !run {
+				continue
+			}
+
+			c := claimedRun{Run: Run{Job: d.name, ScheduledAt: d.scheduledAt, Attempt: 1}, job: job}
+			query = fmt.Sprintf(`INSERT INTO %s (job, scheduled_at, locked_until) VALUES ($1, $2, now() + $3::interval) RETURNING id;`, s.runsTable())
+			if err = tx.QueryRow(ctx, query, d.name, d.scheduledAt, s.opts.LeaseTimeout).Scan(&c.ID); err != nil {
+				return err
+			}
+
+			claimed = append(claimed, c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// run runs a claimed run, after its jitter, renewing its lease meanwhile, and records its result,
+// unless the run was claimed again by another instance.
+func (s *Scheduler) run(ctx context.Context, c claimedRun) {
+	leaseCtx, lost := context.WithCancel(ctx)
+	var lease sync.WaitGroup
+	lease.Go(func() {
+		s.keepLease(leaseCtx, c.Run, lost)
+	})
+	defer func() {
+		lost()
+		lease.Wait()
+	}()
+	ctx = leaseCtx
+
+	if c.job.opts.Jitter > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(rand.N(c.job.opts.Jitter)):
+		}
+	}
+
+	runCtx, cancel := ctx, context.CancelFunc(func() {})
+	if c.job.opts.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, c.job.opts.Timeout)
+	}
+
+	started := time.Now()
+	err := runHandler(runCtx, c.job.handler, c.Run)
+	duration := time.Since(started)
+	cancel()
+
+	status, errorText := StatusSucceeded, any(nil)
+	if err != nil {
+		status, errorText = StatusFailed, err.Error()
+		s.reportError(fmt.Errorf("cron: %s: run: %d: %w", c.Job, c.ID, err))
+	}
+
+	// record the result even if the scheduler is being closed.
+	query := fmt.Sprintf(`UPDATE %s SET finished_at = now(), duration = $3, status = $4, error = $5, locked_until = NULL
+		WHERE id = $1 AND attempts = $2 AND status = 'running';`, s.runsTable())
+	if _, err = s.db.Exec(context.WithoutCancel(ctx), query, c.ID, c.Attempt, duration, status, errorText); err != nil {
+		s.reportError(fmt.Errorf("cron: %s: run: %d: record: %w", c.Job, c.ID, err))
+	}
+}
+
+// keepLease renews the lease of a claimed run every third of Options.LeaseTimeout until the context
+// is done. It calls lost, to cancel the handler, when the run was claimed again by another instance
+// or its lease is about to expire because it could not be renewed.
+func (s *Scheduler) keepLease(ctx context.Context, run Run, lost func()) {
+	interval := s.opts.LeaseTimeout / 3
+	expires := time.Now().Add(s.opts.LeaseTimeout)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	query := fmt.Sprintf(`UPDATE %s SET locked_until = now() + $3::interval
+		WHERE id = $1 AND attempts = $2 AND status = 'running';`, s.runsTable())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		tag, err := s.db.Exec(ctx, query, run.ID, run.Attempt, s.opts.LeaseTimeout)
+		switch {
+		case err == nil && tag.RowsAffected() > 0:
+			expires = time.Now().Add(s.opts.LeaseTimeout)
+		case err == nil:
+			lost()
+			return
+		default:
+			if ctx.Err() != nil {
+				return
+			}
+
+			s.reportError(fmt.Errorf("cron: %s: run: %d: renew lease: %w", run.Job, run.ID, err))
+			if time.Until(expires) < interval { // it would expire before the next renewal.
+				lost()
+				return
+			}
+		}
+	}
+}
+
+// runHandler calls the handler and converts its panic to an error.
+func runHandler(ctx context.Context, handler Handler, run Run) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler(ctx, run)
+}
+
+func (s *Scheduler) reportError(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
+
+// runner is the Closer of Scheduler.Start.
+type runner struct {
+	stopClaiming context.CancelFunc
+	cancelRuns   context.CancelFunc
+	stop         func() bool
+	loop         sync.WaitGroup // the claim loop.
+	runs         sync.WaitGroup // the running handlers.
+}
+
+// Close stops claiming runs and waits for the running ones until the context is done,
+// when it cancels their context and waits for them to return.
+func (r *runner) Close(ctx context.Context) error {
+	r.stopClaiming()
+	r.loop.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		r.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		r.cancelRuns()
+		<-done
+	}
+
+	r.cancelRuns()
+	r.stop()
+	return nil
+}
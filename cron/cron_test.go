@@ -0,0 +1,198 @@
+package cron_test
+
+// These tests require a live PostgreSQL server; they skip themselves (via pgtest.ConnString)
+// when the PG_CONNSTRING environment variable is not set. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test ./cron/... -v
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kataras/pg"
+	"github.com/kataras/pg/cron"
+	"github.com/kataras/pg/pgtest"
+)
+
+func TestScheduler(t *testing.T) {
+	connString := pgtest.ConnString(t)
+	ctx := context.Background()
+
+	db := pgtest.New(t, pg.NewSchema(), connString)
+
+	var runs atomic.Int32
+	handler := func(ctx context.Context, run cron.Run) error {
+		if runs.Add(1) > 1 {
+			return errors.New("second run")
+		}
+
+		return nil
+	}
+
+	// two instances of the application: a run is claimed by only one of them.
+	var runners []pg.Closer
+	for i := range 2 {
+		scheduler, err := cron.New(db, cron.Options{PollInterval: 20 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i == 0 {
+			if err = scheduler.CreateTables(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err = scheduler.Register(ctx, "report", "0 0 1 1 *", handler, cron.JobOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		runner, err := scheduler.Start(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runners = append(runners, runner)
+	}
+	defer func() {
+		for _, runner := range runners {
+			runner.Close(ctx)
+		}
+	}()
+
+	scheduler, err := cron.New(db, cron.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = scheduler.Trigger(ctx, "report"); err != nil {
+		t.Fatal(err)
+	}
+
+	var history []cron.RunRecord
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if history, err = scheduler.Runs(ctx, "report", 10); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(history) > 0 && history[0].Status != cron.StatusRunning {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("the triggered run did not finish: %#v", history)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	time.Sleep(100 * time.Millisecond) // give the other instance a chance to run it twice.
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("expected a single run but got: %d", n)
+	}
+
+	if run := history[0]; run.Status != cron.StatusSucceeded || run.FinishedAt == nil || run.Error != "" {
+		t.Fatalf("unexpected run: %#v", run)
+	}
+
+	jobs, err := scheduler.Jobs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) != 1 || jobs[0].LastRun == nil || jobs[0].NextRun.UTC().Month() != time.January {
+		t.Fatalf("expected the next run to follow the schedule but got: %#v", jobs)
+	}
+
+	// a paused job is not run, even if triggered.
+	if err = scheduler.Pause(ctx, "report"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = scheduler.Trigger(ctx, "report"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("expected the paused job not to run but got: %d runs", n)
+	}
+
+	if err = scheduler.Resume(ctx, "unknown"); err == nil {
+		t.Fatal("expected an error for an unknown job")
+	}
+}
+
+func TestSchedulerReclaimsExpiredRun(t *testing.T) {
+	connString := pgtest.ConnString(t)
+	ctx := context.Background()
+
+	db := pgtest.New(t, pg.NewSchema(), connString)
+
+	scheduler, err := cron.New(db, cron.Options{PollInterval: 20 * time.Millisecond, LeaseTimeout: 150 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = scheduler.CreateTables(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := make(chan int, 10)
+	err = scheduler.Register(ctx, "report", "0 0 1 1 *", func(ctx context.Context, run cron.Run) error {
+		// longer than the lease: it is renewed while the handler runs.
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		attempts <- run.Attempt
+		return nil
+	}, cron.JobOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a run claimed by an instance which crashed before it recorded its result.
+	if _, err = db.Exec(ctx, `INSERT INTO cron_runs (job, scheduled_at, locked_until) VALUES ('report', now(), now() - interval '1 second');`); err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := scheduler.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Close(ctx)
+
+	select {
+	case attempt := <-attempts:
+		if attempt != 2 {
+			t.Fatalf("expected the second attempt of the run but got: %d", attempt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the expired run was not claimed again")
+	}
+
+	time.Sleep(200 * time.Millisecond) // give the scheduler a chance to claim it twice.
+
+	select {
+	case attempt := <-attempts:
+		t.Fatalf("expected the run to be claimed once but it ran again: attempt %d", attempt)
+	default:
+	}
+
+	history, err := scheduler.Runs(ctx, "report", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 1 || history[0].Status != cron.StatusSucceeded || history[0].Attempts != 2 {
+		t.Fatalf("expected the reclaimed run to succeed on its second attempt but got: %#v", history)
+	}
+}
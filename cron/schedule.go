@@ -0,0 +1,313 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule reports the activation times of a job.
+type Schedule interface {
+	// Next returns the first activation time after t, in the location of t,
+	// or the zero time if there is none.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a cron expression into a Schedule in the given location (UTC if nil). It accepts:
+//
+//   - the standard 5 fields: minute, hour, day of month, month and day of week, e.g. "30 3 * * MON-FRI";
+//   - 6 fields, with a leading seconds field, e.g. "*/10 * * * * *";
+//   - the descriptors @yearly (or @annually), @monthly, @weekly, @daily (or @midnight) and @hourly;
+//   - "@every <duration>", e.g. "@every 1h30m", for a fixed interval which does not depend on the location.
+//
+// A field is a comma-separated list of values, ranges ("1-5") and steps ("*/15", "0-30/5", "10/20"),
+// where "*" (or "?" for the day fields) matches any value. Months and days of week accept their
+// three-letter English names, and both 0 and 7 are Sunday. As in the standard cron, when both the
+// day of month and the day of week are restricted, a day matching either of them matches.
+// A "CRON_TZ=<zone>" (or "TZ=<zone>") prefix, e.g. "CRON_TZ=Europe/Athens 0 9 * * *",
+// overrides the location.
+func Parse(spec string, loc *time.Location) (Schedule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	spec = strings.TrimSpace(spec)
+	if rest, ok := cutTimeZone(spec); ok {
+		zone, expr, _ := strings.Cut(rest, " ")
+
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return nil, fmt.Errorf("cron: %q: time zone: %w", spec, err)
+		}
+
+		spec = strings.TrimSpace(expr)
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		return parseDescriptor(spec, loc)
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron: %q: expected 5 or 6 fields, got: %d", spec, len(fields))
+	}
+
+	s := &specSchedule{location: loc}
+	for i, dst := range []*uint64{&s.second, &s.minute, &s.hour, &s.dom, &s.month, &s.dow} {
+		bounds := fieldBounds[i]
+
+		bitset, err := parseField(fields[i], bounds)
+		if err != nil {
+			return nil, fmt.Errorf("cron: %q: %s: %w", spec, bounds.name, err)
+		}
+
+		*dst = bitset
+	}
+
+	if s.dow&(1<<7) != 0 { // 7 is Sunday too.
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	return s, nil
+}
+
+// MustParse is like Parse but it panics on error.
+func MustParse(spec string, loc *time.Location) Schedule {
+	s, err := Parse(spec, loc)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func cutTimeZone(spec string) (string, bool) {
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if rest, ok := strings.CutPrefix(spec, prefix); ok {
+			return rest, true
+		}
+	}
+
+	return "", false
+}
+
+func parseDescriptor(spec string, loc *time.Location) (Schedule, error) {
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("cron: %q: %w", spec, err)
+		}
+
+		if d < time.Second {
+			return nil, fmt.Errorf("cron: %q: the interval must be at least a second", spec)
+		}
+
+		return everySchedule(d.Truncate(time.Second)), nil
+	}
+
+	expr, ok := map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}[spec]
+	if !ok {
+		return nil, fmt.Errorf("cron: %q: unknown descriptor", spec)
+	}
+
+	return Parse(expr, loc)
+}
+
+// starBit marks a field which was "*" or "?", see specSchedule.dayMatches.
+const starBit = 1 << 63
+
+type bounds struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var fieldBounds = [...]bounds{
+	{name: "second", min: 0, max: 59},
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// parseField parses a comma-separated list of terms into a bitset of the matching values.
+func parseField(field string, b bounds) (uint64, error) {
+	var bitset uint64
+	for term := range strings.SplitSeq(field, ",") {
+		bits, err := parseTerm(term, b)
+		if err != nil {
+			return 0, err
+		}
+
+		bitset |= bits
+	}
+
+	return bitset, nil
+}
+
+// parseTerm parses a "*", "?", value, range or step term.
+func parseTerm(term string, b bounds) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(term, "/")
+
+	var (
+		start, end = b.min, b.max
+		extra      uint64
+		step       uint = 1
+		err        error
+	)
+
+	switch {
+	case rangeExpr == "*" || (rangeExpr == "?" && (b.name == "day of month" || b.name == "day of week")):
+		if !hasStep {
+			extra = starBit
+		}
+	default:
+		lo, hi, isRange := strings.Cut(rangeExpr, "-")
+		if start, err = parseValue(lo, b); err != nil {
+			return 0, err
+		}
+
+		switch {
+		case isRange:
+			if end, err = parseValue(hi, b); err != nil {
+				return 0, err
+			}
+		case !hasStep:
+			end = start
+		}
+	}
+
+	if hasStep {
+		n, err := strconv.ParseUint(stepExpr, 10, 8)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid step: %q", term)
+		}
+
+		step = uint(n)
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("invalid range: %q", term)
+	}
+
+	var bitset uint64
+	for v := start; v <= end; v += step {
+		bitset |= 1 << v
+	}
+
+	return bitset | extra, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %q", s)
+	}
+
+	if v := uint(n); v >= b.min && v <= b.max {
+		return v, nil
+	}
+
+	return 0, fmt.Errorf("value %s out of range [%d, %d]", s, b.min, b.max)
+}
+
+// specSchedule is the Schedule of a cron expression: a bitset of the matching values per field.
+type specSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	location                              *time.Location
+}
+
+// Next returns the first activation time after t. It searches up to five years ahead, so an
+// expression which never matches, e.g. "0 0 30 2 *", returns the zero time.
+func (s *specSchedule) Next(t time.Time) time.Time {
+	origin := t.Location()
+	t = t.In(s.location).Truncate(time.Second).Add(time.Second)
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		// Add, instead of time.Date with the next hour, moves over the daylight saving time transitions.
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.location).Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, s.location).Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t.In(origin)
+}
+
+// dayMatches reports whether the day of t matches: both the day of month and the day of week
+// if either of them is "*", otherwise any of them.
+func (s *specSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.dom&starBit != 0 || s.dow&starBit != 0 {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// everySchedule is the Schedule of "@every <duration>".
+type everySchedule time.Duration
+
+// Next returns t, truncated to the second, plus the interval.
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(time.Duration(e))
+}
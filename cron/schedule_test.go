@@ -0,0 +1,70 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNext(t *testing.T) {
+	athens, err := time.LoadLocation("Europe/Athens")
+	if err != nil {
+		t.Skip(err) // no tzdata.
+	}
+
+	from := time.Date(2026, time.March, 28, 23, 59, 30, 0, time.UTC) // a Saturday.
+
+	tests := []struct {
+		spec     string
+		loc      *time.Location
+		expected time.Time
+	}{
+		{"* * * * *", nil, time.Date(2026, time.March, 29, 0, 0, 0, 0, time.UTC)},
+		{"*/10 * * * * *", nil, time.Date(2026, time.March, 28, 23, 59, 40, 0, time.UTC)},
+		{"30 3 * * MON-FRI", nil, time.Date(2026, time.March, 30, 3, 30, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", nil, time.Date(2026, time.April, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0", nil, time.Date(2026, time.March, 29, 0, 0, 0, 0, time.UTC)}, // the 1st or a Sunday.
+		{"0 0 * * 7", nil, time.Date(2026, time.March, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 ? JAN,jul *", nil, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", nil, time.Date(2026, time.March, 29, 0, 5, 0, 0, time.UTC)},
+		{"@hourly", nil, time.Date(2026, time.March, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", nil, time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 1h30m", nil, time.Date(2026, time.March, 29, 1, 29, 30, 0, time.UTC)},
+		// 03:00 in Athens is skipped on the 29th of March 2026 (daylight saving time starts).
+		{"0 3 * * *", athens, time.Date(2026, time.March, 30, 0, 0, 0, 0, time.UTC)},
+		{"CRON_TZ=Europe/Athens 0 9 * * *", nil, time.Date(2026, time.March, 29, 6, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", nil, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec, tt.loc)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+
+		if got := schedule.Next(from); !got.Equal(tt.expected) {
+			t.Fatalf("%s: expected next run at %s but got: %s", tt.spec, tt.expected, got)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"? * * * *",
+		"@every 10ms",
+		"@sometimes",
+		"CRON_TZ=Nowhere/Land * * * * *",
+	} {
+		if _, err := Parse(spec, nil); err == nil {
+			t.Fatalf("%q: expected an error", spec)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/kataras/pg/desc"
 
@@ -79,6 +80,28 @@ type (
 	// Constraint) and DO NOTHING/DO UPDATE SET action for Repository.InsertOnConflict and
 	// Repository.InsertSingleOnConflict.
 	OnConflict = desc.OnConflict
+	// Key is a type alias for desc.Key: the primary key value of a row keyed by column name,
+	// e.g. pg.Key{"blog_id": 1, "tag_id": 2}, the ID type of tables with a composite primary key.
+	Key = desc.Key
+	// Enum is a type alias for desc.Enum: a PostgreSQL enum type, see Schema.RegisterEnum.
+	Enum = desc.Enum
+	// TableIndex is a type alias for desc.TableIndex: a table-level (composite, partial, expression, ...) index,
+	// see the TableIndexer interface and the WithIndexes registration option.
+	TableIndex = desc.TableIndex
+	// TableIndexer is a type alias for desc.TableIndexer.
+	TableIndexer = desc.TableIndexer
+	// TableConstraint is a type alias for desc.TableConstraint: a table-level CHECK or EXCLUDE constraint,
+	// see the TableConstrainer interface and the WithConstraints registration option.
+	TableConstraint = desc.TableConstraint
+	// TableConstrainer is a type alias for desc.TableConstrainer.
+	TableConstrainer = desc.TableConstrainer
+	// ExclusionElement is a type alias for desc.ExclusionElement: an element of an EXCLUDE constraint.
+	ExclusionElement = desc.ExclusionElement
+	// PartitionBound is a type alias for desc.PartitionBound: the FOR VALUES clause of a partition,
+	// see RangeBound, ListBound, HashBound and DefaultBound.
+	PartitionBound = desc.PartitionBound
+	// Partition is a type alias for desc.Partition, see DB.ListPartitions.
+	Partition = desc.Partition
 )
 
 // DB represents a database connection that can execute queries and transactions.
@@ -95,6 +118,9 @@ type DB struct {
 
 	tx         pgx.Tx
 	dbTxClosed bool
+	// rollbackHooks holds the functions which undo the changes made to Go values in the
+	// transaction, e.g. a written back version, see onRollback. It is nil outside of a transaction.
+	rollbackHooks *rollbackHooks
 
 	// notifyState holds the mutex-guarded, once-only bookkeeping used by PrepareListenTable
 	// to create the table-change notify function and per-table triggers at most once.
@@ -103,6 +129,20 @@ type DB struct {
 	// nil mutex. See db_table_listener.go for the tableNotifyState type.
 	notifyState *tableNotifyState
 
+	// replicas holds the pools of the read replicas, nil if none, see WithReadReplicas.
+	replicas *replicaSet
+
+	// tenancy reports whether the pools were opened by Open, which installs the search_path hook
+	// the tenants require, see ForTenant.
+	tenancy bool
+	// tenant is the tenant name of a DB returned by ForTenant, empty otherwise.
+	// The searchPath of a tenant is its schema.
+	tenant string
+	// sharedSearchPath is the search path of the DB a tenant was returned from.
+	sharedSearchPath string
+	// tenantSearchPath is the search_path of the connections the queries of a tenant run on.
+	tenantSearchPath string
+
 	schema *Schema
 }
 
@@ -186,15 +226,12 @@ func Open(ctx context.Context, schema *Schema, connString string, opts ...Connec
 		return nil, fmt.Errorf("open: %w", err)
 	}
 
-	for _, opt := range opts {
-		if opt == nil {
-			continue
-		}
-
-		if err = opt(config); err != nil {
-			return nil, err
-		}
+	cfg := new(openConfig)
+	if err = applyConnectionOptions(config, cfg, opts); err != nil {
+		return nil, err
 	}
+	installSearchPathHook(config)
+	installSettingHooks(schema, config)
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
@@ -204,10 +241,19 @@ func Open(ctx context.Context, schema *Schema, connString string, opts ...Connec
 	}
 
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	replicas, err := openReplicas(ctx, schema, cfg, opts)
+	if err != nil {
+		pool.Close()
 		return nil, err
 	}
 
 	db := OpenPool(schema, pool)
+	db.replicas = replicas
+	db.tenancy = true
 	return db, nil
 }
 
@@ -235,9 +281,13 @@ func OpenPool(schema *Schema, pool *pgxpool.Pool) *DB {
 	return db // return the DB instance
 }
 
-// Close closes the database connection pool and its transactions.
+// Close closes the database connection pool, the pools of its read replicas and its transactions.
 func (db *DB) Close() {
 	db.Pool.Close()
+
+	if db.replicas != nil {
+		db.replicas.close()
+	}
 }
 
 // Clone copies all fields from the current "db" instance
@@ -250,11 +300,27 @@ func (db *DB) clone(tx pgx.Tx) *DB {
 		schema:            db.schema,
 		searchPath:        db.searchPath,
 		notifyState:       db.notifyState, // shared pointer: safe to copy as-is, unlike the old split fields.
+		replicas:          db.replicas,
+		tenancy:           db.tenancy,
+		tenant:            db.tenant,
+		sharedSearchPath:  db.sharedSearchPath,
+		tenantSearchPath:  db.tenantSearchPath,
 	}
 
 	return clone
 }
 
+// cloneTx returns a clone of the DB in the transaction of tx, a new one or a savepoint of the
+// transaction of the DB, whose onRollback functions run if it rolls back.
+func (db *DB) cloneTx(tx pgx.Tx) *DB {
+	txDB := db.clone(tx)
+	if db.tx == nil || db.rollbackHooks != nil {
+		txDB.rollbackHooks = &rollbackHooks{parent: db.rollbackHooks}
+	}
+
+	return txDB
+}
+
 // SearchPath returns the search path of the database.
 func (db *DB) SearchPath() string {
 	return db.searchPath
@@ -335,7 +401,7 @@ func (db *DB) Begin(ctx context.Context) (*DB, error) {
 		tx, err = db.tx.Begin(ctx)
 	} else {
 		// Otherwise, start a new transaction using db.Pool.BeginTx with the default options
-		tx, err = db.Pool.BeginTx(ctx, pgx.TxOptions{
+		tx, err = db.Pool.BeginTx(db.poolContext(ctx), pgx.TxOptions{
 			// IsoLevel:       pgx.ReadCommitted,
 			// AccessMode:     pgx.ReadWrite,
 			// DeferrableMode: pgx.Deferrable,
@@ -345,8 +411,15 @@ func (db *DB) Begin(ctx context.Context) (*DB, error) {
 		return nil, err // return nil and the wrapped error if starting the transaction fails
 	}
 
-	txDB := db.clone(tx) // clone the DB instance and assign the transaction instance to its tx field
-	return txDB, nil     // return the cloned DB instance and nil as no error occurred
+	if db.tx == nil {
+		if err = db.setLocalSettings(ctx, tx); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+
+	txDB := db.cloneTx(tx) // clone the DB instance and assign the transaction instance to its tx field
+	return txDB, nil       // return the cloned DB instance and nil as no error occurred
 }
 
 // BeginConcurrent starts a new database transaction and returns a new DB instance that operates within that transaction.
@@ -365,14 +438,21 @@ func (db *DB) BeginConcurrent(ctx context.Context) (*DB, error) {
 		tx, err = db.tx.Begin(ctx)
 	} else {
 		// Otherwise, start a new concurrent transaction using db.Pool with the default transaction options.
-		tx, err = NewConcurrentTx(ctx, db.Pool)
+		tx, err = NewConcurrentTx(db.poolContext(ctx), db.Pool)
 	}
 	if err != nil {
 		return nil, err // return nil and the wrapped error if starting the transaction fails
 	}
 
-	txDB := db.clone(tx) // clone the DB instance and assign the transaction instance to its tx field
-	return txDB, nil     // return the cloned DB instance and nil as no error occurred
+	if db.tx == nil {
+		if err = db.setLocalSettings(ctx, tx); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+
+	txDB := db.cloneTx(tx) // clone the DB instance and assign the transaction instance to its tx field
+	return txDB, nil       // return the cloned DB instance and nil as no error occurred
 }
 
 // Rollback rolls back the current database transaction and returns any error that occurs.
@@ -389,6 +469,9 @@ func (db *DB) Rollback(ctx context.Context) error {
 			db.tx = nil
 			db.dbTxClosed = true
 		}
+
+		// even on an error: the transaction is not committed.
+		db.rolledBack()
 		return err // return the error from db.tx.Rollback (nil or not)
 	}
 
@@ -409,6 +492,12 @@ func (db *DB) Commit(ctx context.Context) error {
 			// If no error occurred, set db.tx to nil and db.dbTxClosed to true
 			db.tx = nil
 			db.dbTxClosed = true
+			if hooks := db.rollbackHooks; hooks != nil {
+				db.rollbackHooks = nil
+				hooks.committed()
+			}
+		} else {
+			db.rolledBack() // a failed commit rolls the transaction back.
 		}
 		return err // return the error from db.tx.Commit (nil or not)
 	}
@@ -417,6 +506,65 @@ func (db *DB) Commit(ctx context.Context) error {
 	return fmt.Errorf("commit outside of a transaction")
 }
 
+// onRollback registers fn to undo a change made to a Go value in the transaction of the DB, e.g. the
+// new version written back to it by Update: fn is called if the transaction, or the savepoint of the
+// DB, rolls back or fails to commit, so the value is left as it was, and dropped once the outermost
+// transaction commits. Outside of a transaction, or in a transaction this package does not commit,
+// e.g. the one of Migrate, fn is never called.
+func (db *DB) onRollback(fn func()) {
+	if db.tx == nil || db.rollbackHooks == nil {
+		return
+	}
+
+	db.rollbackHooks.add(fn)
+}
+
+// rolledBack calls the onRollback functions of the transaction of the DB, latest first.
+func (db *DB) rolledBack() {
+	hooks := db.rollbackHooks
+	if hooks == nil {
+		return
+	}
+
+	db.rollbackHooks = nil
+	funcs := hooks.take()
+	for _, fn := range slices.Backward(funcs) {
+		fn()
+	}
+}
+
+// rollbackHooks holds the functions which undo the changes made to Go values in a transaction,
+// see DB.onRollback.
+type rollbackHooks struct {
+	parent *rollbackHooks // the hooks of the enclosing transaction of a savepoint, nil for the outermost one.
+
+	mu    sync.Mutex // the DB of BeginConcurrent is safe for concurrent use.
+	funcs []func()
+}
+
+func (h *rollbackHooks) add(funcs ...func()) {
+	h.mu.Lock()
+	h.funcs = append(h.funcs, funcs...)
+	h.mu.Unlock()
+}
+
+func (h *rollbackHooks) take() []func() {
+	h.mu.Lock()
+	funcs := h.funcs
+	h.funcs = nil
+	h.mu.Unlock()
+	return funcs
+}
+
+// committed moves the functions of a released savepoint to its enclosing transaction,
+// which may still roll back, and drops the ones of a committed transaction.
+func (h *rollbackHooks) committed() {
+	funcs := h.take()
+	if h.parent != nil {
+		h.parent.add(funcs...)
+	}
+}
+
 // Query executes the given "query" with args.
 // If there is an error the returned Rows will be returned in an error state.
 func (db *DB) Query(ctx context.Context, query string, args ...any) (Rows, error) {
@@ -431,7 +579,7 @@ func (db *DB) Query(ctx context.Context, query string, args ...any) (Rows, error
 		return rows, nil
 	}
 
-	rows, err := db.Pool.Query(ctx, query, args...)
+	rows, err := db.Pool.Query(db.poolContext(ctx), query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -449,7 +597,7 @@ func (db *DB) QueryRow(ctx context.Context, query string, args ...any) Row {
 		return db.tx.QueryRow(ctx, query, args...)
 	}
 
-	return db.Pool.QueryRow(ctx, query, args...)
+	return db.Pool.QueryRow(db.poolContext(ctx), query, args...)
 }
 
 // QueryBoolean executes a query that returns a single boolean value and returns it as a bool and an error.
@@ -462,10 +610,12 @@ func (db *DB) QueryBoolean(ctx context.Context, query string, args ...any) (ok b
 // aggregate, and returns it as an int64. A query that yields no rows (e.g. a COUNT wrapped in
 // a GROUP BY that has nothing to group) counts as zero: ErrNoRows is swallowed and (0, nil) is
 // returned instead of forcing every caller to special-case it.
+//
+// Outside of a transaction it runs on a read replica, if any, see WithReadReplicas.
 func (db *DB) Count(ctx context.Context, query string, args ...any) (int64, error) {
 	var count int64
 
-	err := db.QueryRow(ctx, query, args...).Scan(&count)
+	err := db.queryRowRead(ctx, query, args...).Scan(&count)
 	if err != nil {
 		if IsErrNoRows(err) {
 			return 0, nil
@@ -491,7 +641,7 @@ func (db *DB) Exec(ctx context.Context, query string, args ...any) (pgconn.Comma
 		return tag, nil
 	}
 
-	tag, err := db.Pool.Exec(ctx, query, args...)
+	tag, err := db.Pool.Exec(db.poolContext(ctx), query, args...)
 	if err != nil {
 		return tag, fmt.Errorf("exec: %w", err)
 	}
@@ -643,7 +793,8 @@ func (db *DB) Unlisten(ctx context.Context, channel string) error {
 // an unknown table or column returns a descriptive error instead of reaching SQL, and the table,
 // column and primary key names are all quoted with QuoteIdentifier before being embedded in the
 // generated UPDATE statement.
-func (db *DB) UpdateJSONB(ctx context.Context, tableName, columnName, rowID string, values map[string]any, fieldsToUpdate []string) (int64, error) {
+// The rowID is the primary key value of the row to update, a Key for a table with a composite primary key.
+func (db *DB) UpdateJSONB(ctx context.Context, tableName, columnName string, rowID any, values map[string]any, fieldsToUpdate []string) (int64, error) {
 	td, err := db.schema.GetByTableName(tableName)
 	if err != nil {
 		return 0, err
@@ -654,17 +805,22 @@ func (db *DB) UpdateJSONB(ctx context.Context, tableName, columnName, rowID stri
 		return 0, fmt.Errorf("update jsonb: unknown column %q on table %q", columnName, tableName)
 	}
 
-	primaryKey, ok := td.PrimaryKey()
-	if !ok {
+	if _, ok := td.PrimaryKey(); !ok {
 		return 0, fmt.Errorf("primary key is required in order to perform update jsonb on table: %s", tableName)
 	}
 
+	rowIDValues, err := td.PrimaryKeyValues(rowID)
+	if err != nil {
+		return 0, fmt.Errorf("update jsonb: %w", err)
+	}
+
 	var (
 		tag pgconn.CommandTag
 
-		quotedTable      = QuoteIdentifier(td.Name)
-		quotedColumn     = QuoteIdentifier(col.Name)
-		quotedPrimaryKey = QuoteIdentifier(primaryKey.Name)
+		quotedTable  = QuoteIdentifier(td.Name)
+		quotedColumn = QuoteIdentifier(col.Name)
+		// the row's primary key column(s) = $2 (AND ... = $3).
+		primaryKeyCondition = td.PrimaryKeyCondition(2)
 	)
 
 	// We could extract the id from the column and do a select based on that but let's keep things simple and do it per row id.
@@ -726,12 +882,12 @@ func (db *DB) UpdateJSONB(ctx context.Context, tableName, columnName, rowID stri
 			}
 		}
 
-		query := fmt.Sprintf("UPDATE %s SET %s = %s || $1 WHERE %s = $2;", quotedTable, quotedColumn, quotedColumn, quotedPrimaryKey)
-		tag, err = db.Exec(ctx, query, values, rowID)
+		query := fmt.Sprintf("UPDATE %s SET %s = %s || $1 WHERE %s;", quotedTable, quotedColumn, quotedColumn, primaryKeyCondition)
+		tag, err = db.Exec(ctx, query, append([]any{values}, rowIDValues...)...)
 	} else {
 		// Full Update.
-		query := fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s = $2;", quotedTable, quotedColumn, quotedPrimaryKey)
-		tag, err = db.Exec(ctx, query, values, rowID)
+		query := fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s;", quotedTable, quotedColumn, primaryKeyCondition)
+		tag, err = db.Exec(ctx, query, append([]any{values}, rowIDValues...)...)
 	}
 	if err != nil {
 		return 0, fmt.Errorf("update jsonb: %w", err)
@@ -0,0 +1,326 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/kataras/pg/desc"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Batch queues independent operations and sends them to the database in a single round trip,
+// see DB.Batch. Each queued operation returns a *BatchResult which is filled by Send.
+//
+// Example:
+//
+//	b := db.Batch()
+//	b.Insert(&customer)
+//	updated := b.Update(&order)
+//	b.DeleteByID("carts", cartID)
+//	b.Exec(`UPDATE products SET stock = stock - $1 WHERE id = $2`, quantity, productID)
+//
+//	var total int64
+//	b.QueryRow(func(row pg.Row) error { return row.Scan(&total) }, `SELECT COUNT(*) FROM orders WHERE customer_id = $1`, customerID)
+//
+//	if err := b.Send(ctx); err != nil {
+//		return err
+//	}
+//	// customer.ID holds the generated primary key, updated.RowsAffected() reports the updated rows.
+//
+// Outside of a transaction the queued operations run in a single implicit transaction:
+// if one of them fails, none of them persists. Inside a transaction (see DB.InTransaction)
+// they are part of it. Note that a *StaleEntityError of Update is detected after the batch
+// was executed, so it does not roll back the other operations, unless the batch is sent
+// within a transaction which is then rolled back.
+//
+// A Batch is not safe for concurrent use and it can be sent only once.
+type Batch struct {
+	db    *DB
+	batch pgx.Batch
+	items []*BatchResult
+	sent  bool
+}
+
+// BatchResult is the result of an operation queued to a Batch. It is filled by Batch.Send.
+type BatchResult struct {
+	rowsAffected int64
+	err          error
+
+	// build returns the query of the operation and its arguments, it is called by Send with its context.
+	// It is nil if the operation was not queued.
+	build func(ctx context.Context) (string, []any, error)
+	// read reads the result of the operation from the batch results. It returns the function,
+	// if any, which writes the generated values back to the queued value, called by Send
+	// only if the batch was not rolled back.
+	read func(br pgx.BatchResults) (int64, func(), error)
+}
+
+// RowsAffected returns the number of rows the operation inserted, updated or deleted,
+// it is zero before Send or on failure.
+func (r *BatchResult) RowsAffected() int64 {
+	return r.rowsAffected
+}
+
+// Err returns the error of the operation, if any.
+// Errors found while queueing the operation, e.g. an unregistered value type, are reported before Send;
+// the ones which depend on its context, e.g. ErrMissingTenant, by Send.
+func (r *BatchResult) Err() error {
+	return r.err
+}
+
+// Batch returns a new, empty Batch which sends its queued operations through this DB,
+// within its transaction if it is in one.
+//
+// Independent writes, e.g. the inserts and updates of a single HTTP request, cost one network
+// round trip instead of one per operation. The operations are read in the order they were queued.
+func (db *DB) Batch() *Batch {
+	return &Batch{db: db}
+}
+
+// Len returns the number of the queued operations.
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Insert queues the insertion of a value of a registered struct type. If the value is a pointer
+// and its table has a single primary key column, the generated primary key is written back to it
+// by Send, unless the batch fails. The tenant column, if any, is filled on Send, see WithTenant.
+func (b *Batch) Insert(value any) *BatchResult {
+	structValue := desc.IndirectValue(value)
+	td, err := b.db.schema.Get(structValue.Type())
+	if err != nil {
+		return b.fail(err)
+	}
+
+	if td.IsReadOnly() {
+		return b.fail(ErrIsReadOnly)
+	}
+
+	var (
+		idField reflect.Value // the primary key field the generated value is written back to.
+		idPtr   any
+	)
+	if primaryKey, ok := td.PrimaryKey(); ok && !td.HasCompositePrimaryKey() {
+		if field := structValue.FieldByIndex(primaryKey.FieldIndex); field.CanSet() {
+			idField, idPtr = field, reflect.New(field.Type()).Interface()
+		}
+	}
+
+	build := func(ctx context.Context) (string, []any, error) {
+		structValue, err := bindTenant(ctx, td, structValue)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return desc.BuildInsertQuery(b.db.tenantTable(td), structValue, idPtr, "", false)
+	}
+
+	if idPtr == nil {
+		return b.queue(build, readExec)
+	}
+
+	return b.queue(build, func(br pgx.BatchResults) (int64, func(), error) {
+		if err := br.QueryRow().Scan(idPtr); err != nil {
+			return 0, nil, err
+		}
+
+		return 1, func() { idField.Set(reflect.ValueOf(idPtr).Elem()) }, nil
+	})
+}
+
+// Update queues the update of a value of a registered struct type by its primary key value.
+// The `version` column, if any, is handled exactly as in DB.Update: on a version mismatch
+// the result's error is a *StaleEntityError, and the new version is written back by Send,
+// unless the batch fails.
+func (b *Batch) Update(value any) *BatchResult {
+	td, err := b.db.schema.Get(desc.IndirectType(desc.IndirectValue(value).Type()))
+	if err != nil {
+		return b.fail(err)
+	}
+
+	if td.IsReadOnly() {
+		return b.fail(ErrIsReadOnly)
+	}
+
+	primaryKey, ok := td.PrimaryKey()
+	if !ok {
+		return b.fail(fmt.Errorf("no primary key found in table definition: %s", td.Name))
+	}
+
+	var args []any
+	build := func(ctx context.Context) (query string, _ []any, err error) {
+		if value, err = bindTenantValue(ctx, td, value); err != nil {
+			return "", nil, err
+		}
+
+		query, args, err = desc.BuildUpdateQuery(value, nil, false, primaryKey)
+		return query, args, err
+	}
+
+	versionColumn, ok := td.VersionColumn()
+	if !ok {
+		return b.queue(build, readExec)
+	}
+
+	return b.queue(build, func(br pgx.BatchResults) (int64, func(), error) {
+		var newVersion int64
+		if err := br.QueryRow().Scan(&newVersion); err != nil {
+			if errors.Is(err, ErrNoRows) {
+				return 0, nil, staleEntityError(args, versionColumn)
+			}
+
+			return 0, nil, err
+		}
+
+		return 1, func() { b.db.writeVersion(value, versionColumn, newVersion) }, nil
+	})
+}
+
+// DeleteByID queues the deletion of a row of the registered table by its primary key,
+// see DB.DeleteByID. If the table has a soft_delete column the row is marked as deleted instead.
+func (b *Batch) DeleteByID(tableName string, id any) *BatchResult {
+	td, err := b.db.schema.GetByTableName(tableName)
+	if err != nil {
+		return b.fail(err)
+	}
+
+	if td.IsReadOnly() {
+		return b.fail(ErrIsReadOnly)
+	}
+
+	return b.queue(func(ctx context.Context) (string, []any, error) {
+		return b.db.deleteByIDQuery(ctx, td, id)
+	}, readExec)
+}
+
+// Exec queues a query which modifies the database, its result reports the number of rows affected.
+func (b *Batch) Exec(query string, args ...any) *BatchResult {
+	return b.queue(queryBuilder(query, args), readExec)
+}
+
+// readExec reads the result of a query which does not return rows.
+func readExec(br pgx.BatchResults) (int64, func(), error) {
+	tag, err := br.Exec()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return tag.RowsAffected(), nil, nil
+}
+
+// QueryRow queues a query which returns at most one row, which is passed to the scanner function
+// on Send, e.g. func(row pg.Row) error { return row.Scan(&total) }. If the query returns no rows
+// the scanner function receives a row whose Scan method returns ErrNoRows.
+func (b *Batch) QueryRow(scannerFunc func(Row) error, query string, args ...any) *BatchResult {
+	if scannerFunc == nil {
+		return b.fail(fmt.Errorf("scannerFunc is nil"))
+	}
+
+	return b.queue(queryBuilder(query, args), func(br pgx.BatchResults) (int64, func(), error) {
+		return 0, nil, scannerFunc(br.QueryRow())
+	})
+}
+
+// queryBuilder returns the build function of an operation of a ready query.
+func queryBuilder(query string, args []any) func(context.Context) (string, []any, error) {
+	return func(context.Context) (string, []any, error) {
+		return query, args, nil
+	}
+}
+
+func (b *Batch) queue(build func(ctx context.Context) (string, []any, error), read func(br pgx.BatchResults) (int64, func(), error)) *BatchResult {
+	result := &BatchResult{build: build, read: read}
+	b.items = append(b.items, result)
+	return result
+}
+
+// fail adds a result which failed to be queued.
+func (b *Batch) fail(err error) *BatchResult {
+	result := &BatchResult{err: err}
+	b.items = append(b.items, result)
+	return result
+}
+
+// Send sends the queued operations in a single round trip and fills their results.
+// It returns the first error of the operations, prefixed with its position in the batch.
+//
+// If an operation failed to be queued or its query can not be built, e.g. its value type is not
+// registered or the context has no tenant for a table with a tenant column, nothing is sent.
+// Outside of a transaction, the failure of one operation aborts the rest of them too, and the
+// generated primary keys and new versions are written back to the queued values only if none failed.
+func (b *Batch) Send(ctx context.Context) error {
+	if b.sent {
+		return fmt.Errorf("pg: batch: already sent")
+	}
+	b.sent = true
+
+	for i, item := range b.items {
+		if item.err != nil {
+			return fmt.Errorf("pg: batch: %d: %w", i, item.err)
+		}
+	}
+
+	if len(b.items) == 0 {
+		return nil
+	}
+
+	for i, item := range b.items {
+		query, args, err := item.build(ctx)
+		if err != nil {
+			item.err = err
+			return fmt.Errorf("pg: batch: %d: %w", i, err)
+		}
+
+		b.batch.Queue(query, args...)
+	}
+
+	var br pgx.BatchResults
+	if b.db.tx != nil {
+		br = b.db.tx.SendBatch(ctx, &b.batch)
+	} else {
+		br = b.db.Pool.SendBatch(b.db.poolContext(ctx), &b.batch)
+	}
+
+	var (
+		firstErr   error
+		rolledBack bool // a query failed, a *StaleEntityError is detected after the query succeeded.
+		writeBacks []func()
+	)
+	for i, item := range b.items {
+		var writeBack func()
+		item.rowsAffected, writeBack, item.err = item.read(br)
+		if item.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("pg: batch: %d: %w", i, item.err)
+			}
+
+			rolledBack = rolledBack || !errors.Is(item.err, ErrStaleEntity)
+			continue
+		}
+
+		if writeBack != nil {
+			writeBacks = append(writeBacks, writeBack)
+		}
+	}
+
+	if err := br.Close(); err != nil {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("pg: batch: %w", err)
+		}
+
+		rolledBack = true
+	}
+
+	// the generated primary keys and the new versions are written back to the values only
+	// if their rows were stored, so a failed batch leaves them as they were.
+	if !rolledBack {
+		for _, writeBack := range writeBacks {
+			writeBack()
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,117 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestDBBatch' -v .
+
+type batchLiveCustomer struct {
+	ID      int64  `pg:"type=bigserial,primary"`
+	Name    string `pg:"type=varchar(255)"`
+	Version int64  `pg:"type=bigint,default=1,version"`
+}
+
+const batchScratchTable = "test_batch_customers"
+
+func TestDBBatch(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(batchScratchTable, batchLiveCustomer{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, batchScratchTable)
+	defer dropTestTables(ctx, db, batchScratchTable)
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	alice := batchLiveCustomer{Name: "alice"}
+	bob := batchLiveCustomer{Name: "bob"}
+
+	b := db.Batch()
+	b.Insert(&alice)
+	b.Insert(&bob)
+	if err = b.Send(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if alice.ID == 0 || bob.ID == 0 || alice.ID == bob.ID {
+		t.Fatalf("expected the generated primary keys to be written back but got: %d and %d", alice.ID, bob.ID)
+	}
+
+	alice.Name = "alice2"
+	stale := bob
+	stale.Version = 42
+
+	var count int64
+	b = db.Batch()
+	updated := b.Update(&alice)
+	renamed := b.Exec(`UPDATE `+batchScratchTable+` SET name = $1 WHERE id = $2`, "bob2", bob.ID)
+	counted := b.QueryRow(func(row Row) error { return row.Scan(&count) }, `SELECT COUNT(*) FROM `+batchScratchTable)
+	if err = b.Send(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.RowsAffected() != 1 || alice.Version != 2 {
+		t.Fatalf("expected alice to be updated to version 2 but got: %d rows, version %d", updated.RowsAffected(), alice.Version)
+	}
+
+	if renamed.RowsAffected() != 1 || counted.Err() != nil || count != 2 {
+		t.Fatalf("expected one renamed row and a count of 2 but got: %d, %v, %d", renamed.RowsAffected(), counted.Err(), count)
+	}
+
+	b = db.Batch()
+	staleUpdate := b.Update(&stale)
+	if err = b.Send(ctx); !errors.Is(err, ErrStaleEntity) || !errors.Is(staleUpdate.Err(), ErrStaleEntity) {
+		t.Fatalf("expected a stale entity error but got: %v", err)
+	}
+
+	// a failed statement aborts the whole batch outside of a transaction.
+	b = db.Batch()
+	deleted := b.DeleteByID(batchScratchTable, alice.ID)
+	duplicate := b.Exec(`INSERT INTO `+batchScratchTable+` (id, name) VALUES ($1, $2)`, bob.ID, "duplicate")
+	if err = b.Send(ctx); err == nil || duplicate.Err() == nil {
+		t.Fatal("expected a unique violation error")
+	}
+
+	if deleted.Err() != nil || deleted.RowsAffected() != 1 {
+		t.Fatalf("expected the delete to be executed but got: %d rows, %v", deleted.RowsAffected(), deleted.Err())
+	}
+
+	exists, err := db.ExistsBy(ctx, batchScratchTable, "id", alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exists {
+		t.Fatal("expected the delete to be rolled back with the failed batch")
+	}
+
+	// and the generated primary keys and new versions of a failed batch are not written back.
+	carol := batchLiveCustomer{Name: "carol"}
+	aliceVersion := alice.Version
+
+	b = db.Batch()
+	b.Insert(&carol)
+	b.Update(&alice)
+	b.Exec(`INSERT INTO `+batchScratchTable+` (id, name) VALUES ($1, $2)`, bob.ID, "duplicate")
+	if err = b.Send(ctx); err == nil {
+		t.Fatal("expected a unique violation error")
+	}
+
+	if carol.ID != 0 || alice.Version != aliceVersion {
+		t.Fatalf("expected the values of the failed batch to be kept but got: id %d, version %d", carol.ID, alice.Version)
+	}
+}
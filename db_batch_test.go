@@ -0,0 +1,58 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type batchTestCustomer struct {
+	ID   int64  `pg:"type=bigserial,primary"`
+	Name string `pg:"type=varchar(255)"`
+}
+
+type batchTestUnregistered struct {
+	ID int64
+}
+
+// TestBatchQueueErrors verifies that an operation which fails to be queued is reported by its
+// result and makes Send fail before anything reaches the database (the DB has no pool).
+func TestBatchQueueErrors(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("customers", batchTestCustomer{})
+	db := &DB{schema: schema, searchPath: "public"}
+
+	b := db.Batch()
+	b.Insert(&batchTestCustomer{Name: "alice"})
+	unregistered := b.Update(batchTestUnregistered{ID: 1})
+	unknownTable := b.DeleteByID("orders", 1)
+	nilScanner := b.QueryRow(nil, "SELECT 1")
+
+	if b.Len() != 4 {
+		t.Fatalf("expected 4 queued operations but got: %d", b.Len())
+	}
+
+	for i, result := range []*BatchResult{unregistered, unknownTable, nilScanner} {
+		if result.Err() == nil {
+			t.Fatalf("[%d] expected a queue error", i)
+		}
+	}
+
+	err := b.Send(context.Background())
+	if !errors.Is(err, unregistered.Err()) {
+		t.Fatalf("expected the error of the first failed operation but got: %v", err)
+	}
+
+	if err = b.Send(context.Background()); err == nil {
+		t.Fatal("expected an error when sending a batch twice")
+	}
+}
+
+// TestBatchSendEmpty verifies that an empty batch is a no-op.
+func TestBatchSendEmpty(t *testing.T) {
+	db := &DB{schema: NewSchema(), searchPath: "public"}
+
+	if err := db.Batch().Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
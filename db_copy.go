@@ -48,7 +48,7 @@ func (db *DB) CopyFrom(ctx context.Context, tableName Identifier, columnNames []
 		return n, nil
 	}
 
-	n, err := db.Pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	n, err := db.Pool.CopyFrom(db.poolContext(ctx), tableName, columnNames, rowSrc)
 	if err != nil {
 		return n, fmt.Errorf("copy from: %w", err)
 	}
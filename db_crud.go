@@ -100,6 +100,7 @@ func resolveWhere(td *desc.Table, colValPairs []any) (clause string, args []any,
 
 // DeleteByID deletes one row of the registered table by its primary key and reports
 // whether a row was removed; the primary-key column comes from the table's descriptor.
+// If the table has a soft_delete column the row is marked as deleted instead.
 //
 // tableName is resolved through the Schema (Schema.GetByTableName): an unknown table name
 // returns its descriptive error instead of reaching SQL. If the table is read-only (a view,
@@ -134,6 +135,9 @@ func (db *DB) DeleteByID(ctx context.Context, tableName string, id any) (bool, e
 // If the table is read-only (a view, materialized view or presenter: see
 // desc.TableType.IsReadOnly), it returns ErrIsReadOnly, matching Repository.Delete's behavior
 // for read-only tables.
+//
+// If the table has a soft_delete column, the matching rows that are not already soft-deleted
+// are marked as deleted (`UPDATE ... SET "deleted_at" = now() ...`) instead of removed.
 func (db *DB) DeleteBy(ctx context.Context, tableName string, colValPairs ...any) (int64, error) {
 	td, err := db.schema.GetByTableName(tableName)
 	if err != nil {
@@ -149,8 +153,18 @@ func (db *DB) DeleteBy(ctx context.Context, tableName string, colValPairs ...any
 		return 0, err
 	}
 
+	where, args, err = andTenant(ctx, td, where, args)
+	if err != nil {
+		return 0, err
+	}
+
 	query := fmt.Sprintf(`DELETE FROM %s.%s%s;`,
 		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), where)
+	if softDeleteCol, ok := td.SoftDeleteColumn(); ok {
+		query = fmt.Sprintf(`UPDATE %s.%s SET %s = now()%s;`,
+			QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), QuoteIdentifier(softDeleteCol.Name),
+			andCondition(where, td.SoftDeleteCondition(desc.SoftDeleteScopeActive)))
+	}
 
 	tag, err := db.Exec(ctx, query, args...)
 	if err != nil {
@@ -161,13 +175,15 @@ func (db *DB) DeleteBy(ctx context.Context, tableName string, colValPairs ...any
 }
 
 // ExistsBy reports whether any row of the registered table matches the given
-// column/value pairs (no pairs: whether the table has any row).
+// column/value pairs (no pairs: whether the table has any row). Soft-deleted rows are not taken
+// into account.
 //
 // tableName is resolved through the Schema (Schema.GetByTableName) and every column name in
 // colValPairs is resolved through the table's descriptor; an unknown table or an unknown column
 // returns a descriptive error instead of reaching SQL, and resolved names are quoted with
 // QuoteIdentifier before being embedded in the generated
-// `SELECT EXISTS (SELECT 1 FROM ... [WHERE ...])` query, executed through DB.QueryBoolean.
+// `SELECT EXISTS (SELECT 1 FROM ... [WHERE ...])` query, executed on a read replica, if any
+// (see WithReadReplicas).
 // colValPairs must have an even length with string keys, or it returns a descriptive error (see
 // parseColValPairs).
 func (db *DB) ExistsBy(ctx context.Context, tableName string, colValPairs ...any) (bool, error) {
@@ -181,14 +197,21 @@ func (db *DB) ExistsBy(ctx context.Context, tableName string, colValPairs ...any
 		return false, err
 	}
 
+	where, args, err = andTenant(ctx, td, where, args)
+	if err != nil {
+		return false, err
+	}
+
 	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s.%s%s);`,
-		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), where)
+		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), andCondition(where, td.SoftDeleteCondition(desc.SoftDeleteScopeActive)))
 
-	return db.QueryBoolean(ctx, query, args...)
+	var exists bool
+	err = db.queryRowRead(ctx, query, args...).Scan(&exists)
+	return exists, err
 }
 
 // CountBy returns the number of rows of the registered table matching the given
-// column/value pairs; no pairs count the whole table.
+// column/value pairs; no pairs count the whole table. Soft-deleted rows are not counted.
 //
 // tableName is resolved through the Schema (Schema.GetByTableName) and every column name in
 // colValPairs is resolved through the table's descriptor; an unknown table or an unknown column
@@ -208,8 +231,13 @@ func (db *DB) CountBy(ctx context.Context, tableName string, colValPairs ...any)
 		return 0, err
 	}
 
+	where, args, err = andTenant(ctx, td, where, args)
+	if err != nil {
+		return 0, err
+	}
+
 	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s.%s%s;`,
-		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), where)
+		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), andCondition(where, td.SoftDeleteCondition(desc.SoftDeleteScopeActive)))
 
 	return db.Count(ctx, query, args...)
 }
@@ -1,6 +1,9 @@
 package pg
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 // Ping verifies the database connection pool is alive, acquiring a connection if
 // necessary. It is intended for readiness/liveness handlers.
@@ -18,12 +21,13 @@ func (db *DB) Ping(ctx context.Context) error {
 type Health struct {
 	// ServerVersion is the PostgreSQL server version number, as returned by DB.GetVersion.
 	ServerVersion string `json:"server_version"`
-	// Pool is a snapshot of the connection pool statistics, as returned by DB.PoolStat.
+	// Pool is a snapshot of the connection pool statistics, as returned by DB.PoolStat,
+	// including the ones of the read replicas.
 	Pool PoolStat `json:"pool"`
 }
 
 // Health pings the database and returns its server version together with pool
-// statistics; it fails when the database is unreachable.
+// statistics; it fails when the database or one of its read replicas is unreachable.
 //
 // Like Ping, Health always checks db.Pool, even when db.IsTransaction reports true: pgx.Tx has
 // no Ping method of its own, and a liveness/readiness check is about whether the database is
@@ -34,6 +38,14 @@ func (db *DB) Health(ctx context.Context) (Health, error) {
 		return Health{}, err
 	}
 
+	if db.replicas != nil {
+		for i, pool := range db.replicas.pools {
+			if err := pool.Ping(ctx); err != nil {
+				return Health{}, fmt.Errorf("replica %d: %w", i, err)
+			}
+		}
+	}
+
 	version, err := db.GetVersion(ctx)
 	if err != nil {
 		return Health{}, err
@@ -36,8 +36,11 @@ func (db *DB) CreateSchemaDumpSQL(ctx context.Context) (string, error) {
 	var dumpers = []sqlDumperFunc{
 		db.createDatabaseSchemaDump,
 		db.createExtensionsDump,
+		db.createEnumsDump,
 		db.createTablesDump,
 		db.createFunctionsAndTriggersDump,
+		db.createAuditDump,
+		db.createTenantPoliciesDump,
 	}
 
 	b := new(strings.Builder)
@@ -81,22 +84,42 @@ func (db *DB) createDatabaseSchemaDump(_ context.Context, b *strings.Builder) er
 }
 
 // createExtensions creates the necessary PostgreSQL extensions for the database schema.
+// The extensions of a tenant are created in the shared schema, an extension can only be installed once per database.
 func (db *DB) createExtensionsDump(_ context.Context, b *strings.Builder) error {
+	var schemaClause string
+	if db.tenant != "" {
+		schemaClause = ` SCHEMA ` + QuoteIdentifier(db.sharedSearchPath)
+	}
+
 	if db.schema.HasColumnType(desc.UUID) || db.schema.HasPassword() {
-		query := `CREATE EXTENSION IF NOT EXISTS pgcrypto;`
+		query := `CREATE EXTENSION IF NOT EXISTS pgcrypto` + schemaClause + `;`
 		b.WriteString(query)
 	}
 
 	if db.schema.HasColumnType(desc.CIText) {
-		query := `CREATE EXTENSION IF NOT EXISTS citext;`
+		query := `CREATE EXTENSION IF NOT EXISTS citext` + schemaClause + `;`
 		b.WriteString(query)
 	}
 
 	if db.schema.HasColumnType(desc.HStore) {
-		query := `CREATE EXTENSION IF NOT EXISTS hstore;`
+		query := `CREATE EXTENSION IF NOT EXISTS hstore` + schemaClause + `;`
 		b.WriteString(query)
 	}
 
+	if slices.ContainsFunc(db.schema.Tables(desc.TableTypeBase), (*desc.Table).RequiresBtreeGist) {
+		query := `CREATE EXTENSION IF NOT EXISTS btree_gist` + schemaClause + `;`
+		b.WriteString(query)
+	}
+
+	return nil
+}
+
+// createEnumsDump dumps the enum types of the database schema, before the tables which use them.
+func (db *DB) createEnumsDump(_ context.Context, b *strings.Builder) error {
+	for _, e := range db.schema.Enums() {
+		b.WriteString(desc.BuildCreateEnumQuery(e))
+	}
+
 	return nil
 }
 
@@ -131,6 +154,11 @@ func (db *DB) createTableDump(_ context.Context, b *strings.Builder, td *desc.Ta
 
 	query := desc.BuildCreateTableQuery(td)
 	b.WriteString(query)
+
+	for _, commentQuery := range desc.BuildCommentQueries(td) {
+		b.WriteString(commentQuery)
+	}
+
 	return nil
 }
 
@@ -192,7 +220,8 @@ tablesLoop:
 		var setTimestampTriggerCreated bool
 
 		for _, trigger := range triggers {
-			if trigger.Name == db.schema.SetTimestampTriggerName && trigger.TableName == td.Name {
+			// a tenant's tables have the same names as the ones of the other tenants.
+			if trigger.Name == db.schema.SetTimestampTriggerName && trigger.TableName == td.Name && (db.tenant == "" || trigger.SearchPath == db.searchPath) {
 				setTimestampTriggerCreated = true
 				continue tablesLoop
 			}
@@ -224,6 +253,10 @@ tablesLoop:
 // CheckSchema checks if the database schema matches the expected table definitions by querying the information schema and
 // comparing the results.
 func (db *DB) CheckSchema(ctx context.Context) error {
+	if err := db.checkEnums(ctx); err != nil {
+		return err
+	}
+
 	tableNames := db.schema.TableNames(desc.DatabaseTableTypes...)
 	if len(tableNames) == 0 {
 		return nil // if no tables are defined, there is nothing to check.
@@ -252,6 +285,12 @@ func (db *DB) CheckSchema(ctx context.Context) error {
 
 		if td.Description == "" {
 			td.Description = table.Description
+		} else if !descriptionsEqual(table.Description, td.Description) {
+			return fmt.Errorf("table %q has wrong description: db:\n%s\nvs code:\n%s", tableName, table.Description, td.Description)
+		}
+
+		if !td.PartitionBy.Equal(table.PartitionBy) {
+			return fmt.Errorf("table %q has wrong partitioning: db:\n%s\nvs code:\n%s", tableName, partitionByString(table.PartitionBy), partitionByString(td.PartitionBy))
 		}
 
 		for _, col := range table.Columns {
@@ -280,6 +319,8 @@ func (db *DB) CheckSchema(ctx context.Context) error {
 
 			if column.Description == "" {
 				column.Description = col.Description
+			} else if !descriptionsEqual(col.Description, column.Description) {
+				return fmt.Errorf("column %q in table %q has wrong description: db:\n%s\nvs code:\n%s", col.Name, tableName, col.Description, column.Description)
 			}
 		}
 	}
@@ -299,9 +340,115 @@ func (db *DB) CheckSchema(ctx context.Context) error {
 	// 	})
 	// }
 
+	if err = db.checkTableIndexes(ctx, tableNames); err != nil {
+		return err
+	}
+
+	if err = db.checkTableConstraints(ctx, tableNames); err != nil {
+		return err
+	}
+
 	return nil // return nil if no mismatch is found
 }
 
+// descriptionsEqual reports whether the database and the code descriptions are the same.
+// A trailing period is ignored, as ListColumnsInformationSchema adds one to the database descriptions.
+func descriptionsEqual(dbDescription, codeDescription string) bool {
+	return strings.TrimSuffix(dbDescription, ".") == strings.TrimSuffix(strings.TrimSpace(codeDescription), ".")
+}
+
+// partitionByString returns the text of the given partitioning or "none" for a non-partitioned table.
+func partitionByString(p *desc.PartitionBy) string {
+	if p == nil {
+		return "none"
+	}
+
+	return p.String()
+}
+
+// checkTableIndexes checks if the table-level indexes of the given tables exist in the database with the same definition.
+func (db *DB) checkTableIndexes(ctx context.Context, tableNames []string) error {
+	var indexes []*desc.TableIndex
+	for _, tableName := range tableNames {
+		td, err := db.schema.GetByTableName(tableName)
+		if err != nil {
+			return err
+		}
+
+		indexes = append(indexes, td.TableIndexes...)
+	}
+
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	dbIndexes, err := db.ListIndexes(ctx, tableNames...)
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		i := slices.IndexFunc(dbIndexes, func(dbIndex *desc.TableIndex) bool {
+			return dbIndex.TableName == idx.TableName && dbIndex.Name == idx.Name
+		})
+		if i == -1 {
+			return fmt.Errorf("index %q in table %q not found in database", idx.Name, idx.TableName)
+		}
+
+		if dbIndex := dbIndexes[i]; !dbIndex.Equal(idx) {
+			return fmt.Errorf("index %q in table %q has wrong definition: db:\n%s\nvs code:\n%s", idx.Name, idx.TableName, dbIndex, idx)
+		}
+	}
+
+	return nil
+}
+
+// checkEnums checks if the schema's enum types exist in the database with the same values, in the same order.
+func (db *DB) checkEnums(ctx context.Context) error {
+	enums := db.schema.Enums()
+	if len(enums) == 0 {
+		return nil
+	}
+
+	dbEnums, err := db.ListEnums(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range enums {
+		idx := slices.IndexFunc(dbEnums, func(dbEnum *desc.Enum) bool { return dbEnum.Name == e.Name })
+		if idx == -1 {
+			return fmt.Errorf("enum %q not found in database", e.Name)
+		}
+
+		if dbEnum := dbEnums[idx]; !dbEnum.Equal(e) {
+			return fmt.Errorf("enum %q has wrong values: db:\n%s\nvs code:\n%s", e.Name, strings.Join(dbEnum.Values, ", "), strings.Join(e.Values, ", "))
+		}
+	}
+
+	return nil
+}
+
+// ListEnums returns the enum types of the database schema (search path) with their values,
+// in their sort order, by querying the pg_enum table.
+func (db *DB) ListEnums(ctx context.Context) ([]*desc.Enum, error) {
+	query := `SELECT
+	t.typname AS enum_name,
+	array_agg(e.enumlabel ORDER BY e.enumsortorder)::text[] AS enum_values
+FROM pg_catalog.pg_type t
+	JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid
+	JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = $1
+GROUP BY t.typname
+ORDER BY t.typname;`
+
+	return db.scanQuery(ctx, func(rows Rows) (*desc.Enum, error) {
+		var e desc.Enum
+		err := rows.Scan(&e.Name, &e.Values)
+		return &e, err
+	}, query, db.searchPath)
+}
+
 // tolerateUndeclaredUniqueMemberIndex clears the database-reported single-column
 // index of a column that participates in a composite/named unique index when the
 // code does not explicitly declare one. Such indexes are usually legacy or
@@ -544,6 +691,10 @@ func (db *DB) ListTables(ctx context.Context, opts ListTablesOptions) ([]*desc.T
 		tables = append(tables, table)
 	}
 
+	if err = db.setTablesPartitioning(ctx, tables); err != nil {
+		return nil, err
+	}
+
 	// Sort so "parent" tables are going first to the list.
 	slices.SortStableFunc(tables, func(a, b *desc.Table) int {
 		switch {
@@ -602,6 +753,10 @@ func (db *DB) ListColumns(ctx context.Context, tableNames ...string) ([]*desc.Co
 
 		for _, constraint := range constraints {
 			if constraint.TableName == column.TableName && constraint.ColumnName == column.Name {
+				if db.isTableLevel(constraint.TableName, constraint.ConstraintName) {
+					continue // a registered table-level index or constraint, see ListIndexes and ListConstraints.
+				}
+
 				if err := constraint.BuildColumn(&column); err != nil {
 					// e.g. a composite foreign key or multiline CHECK expression that
 					// desc.Constraint's regex-based parser couldn't understand. Surface it
@@ -613,7 +768,7 @@ func (db *DB) ListColumns(ctx context.Context, tableNames ...string) ([]*desc.Co
 
 	uniqueIndexLoop:
 		for _, uniqueIndex := range uniqueIndexes {
-			if uniqueIndex.TableName == column.TableName {
+			if uniqueIndex.TableName == column.TableName && !db.isTableLevel(uniqueIndex.TableName, uniqueIndex.IndexName) {
 				for _, columnName := range uniqueIndex.Columns {
 					if columnName == column.Name {
 						column.Unique = false
@@ -637,6 +792,14 @@ func (db *DB) ListColumns(ctx context.Context, tableNames ...string) ([]*desc.Co
 	return columns, nil
 }
 
+// isTableLevel reports whether the given index or constraint name is one of the registered table-level indexes
+// or constraints of the table (an exclusion constraint's index has the constraint's name).
+// ListColumns does not report them as column indexes or constraints.
+func (db *DB) isTableLevel(tableName, name string) bool {
+	td, err := db.schema.GetByTableName(tableName)
+	return err == nil && (td.GetTableIndex(name) != nil || td.GetTableConstraint(name) != nil)
+}
+
 // ListConstraints returns a list of constraint definitions in the database schema by querying the pg_constraint table and.
 func (db *DB) ListConstraints(ctx context.Context, tableNames ...string) ([]*desc.Constraint, error) {
 	if tableNames == nil {
@@ -836,6 +999,93 @@ func (db *DB) ListUniqueIndexes(ctx context.Context, tableNames ...string) ([]*d
 	return cs, nil
 }
 
+// checkTableConstraints checks if the table-level CHECK and EXCLUDE constraints of the given tables exist in the database
+// with the same definition.
+func (db *DB) checkTableConstraints(ctx context.Context, tableNames []string) error {
+	var constraints []*desc.TableConstraint
+	for _, tableName := range tableNames {
+		td, err := db.schema.GetByTableName(tableName)
+		if err != nil {
+			return err
+		}
+
+		constraints = append(constraints, td.TableConstraints...)
+	}
+
+	if len(constraints) == 0 {
+		return nil
+	}
+
+	dbConstraints, err := db.ListConstraints(ctx, tableNames...)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range constraints {
+		i := slices.IndexFunc(dbConstraints, func(dbConstraint *desc.Constraint) bool {
+			return dbConstraint.TableName == c.TableName && dbConstraint.ConstraintName == c.Name &&
+				(dbConstraint.ConstraintType == desc.CheckConstraintType || dbConstraint.ConstraintType == desc.ExclusionConstraintType)
+		})
+		if i == -1 {
+			return fmt.Errorf("constraint %q in table %q not found in database", c.Name, c.TableName)
+		}
+
+		if dbConstraint := dbConstraints[i]; !c.EqualDefinition(dbConstraint.Definition()) {
+			return fmt.Errorf("constraint %q in table %q has wrong definition: db:\n%s\nvs code:\n%s", c.Name, c.TableName, dbConstraint.Definition(), c.Definition())
+		}
+	}
+
+	return nil
+}
+
+// ListIndexes returns the indexes of the database schema (search path) which are not created by a constraint
+// (primary key, unique or exclusion one), by querying the pg_index table. Each index describes its
+// key columns or expressions, included columns, method, uniqueness and predicate.
+// The optional tableNames limits the results to the indexes of the given tables.
+func (db *DB) ListIndexes(ctx context.Context, tableNames ...string) ([]*desc.TableIndex, error) {
+	if tableNames == nil {
+		tableNames = make([]string, 0)
+	}
+
+	query := `SELECT
+	t.relname AS table_name,
+	i.relname AS index_name,
+	ARRAY(
+		SELECT pg_get_indexdef(p.indexrelid, k, true) ||
+			CASE WHEN p.indoption[k - 1] & 1 = 1 THEN ' DESC' ELSE '' END ||
+			CASE p.indoption[k - 1] & 3 WHEN 2 THEN ' NULLS FIRST' WHEN 1 THEN ' NULLS LAST' ELSE '' END
+		FROM generate_series(1, p.indnkeyatts) AS k
+		ORDER BY k
+	)::text[] AS index_columns,
+	ARRAY(
+		SELECT pg_get_indexdef(p.indexrelid, k, true)
+		FROM generate_series(p.indnkeyatts + 1, p.indnatts) AS k
+		ORDER BY k
+	)::text[] AS include_columns,
+	p.indisunique AS is_unique,
+	am.amname AS index_type,
+	COALESCE(pg_get_expr(p.indpred, p.indrelid, true), '') AS predicate
+FROM pg_index p
+JOIN pg_class t ON t.oid = p.indrelid -- the table
+JOIN pg_class i ON i.oid = p.indexrelid -- the index
+JOIN pg_am am ON am.oid = i.relam -- the access method
+JOIN pg_namespace n ON n.oid = t.relnamespace -- the schema
+WHERE n.nspname = $1
+AND ( CARDINALITY($2::varchar[]) = 0 OR t.relname = ANY($2::varchar[]) )
+AND NOT p.indisprimary -- not primary keys
+AND NOT EXISTS ( -- not created by a constraint
+	SELECT 1 FROM pg_constraint c
+	WHERE c.conindid = p.indexrelid
+)
+ORDER BY t.relname, i.relname;`
+
+	return db.scanQuery(ctx, func(rows Rows) (*desc.TableIndex, error) {
+		var idx desc.TableIndex
+		err := rows.Scan(&idx.TableName, &idx.Name, &idx.Columns, &idx.Include, &idx.Unique, &idx.Method, &idx.Where)
+		return &idx, err
+	}, query, db.searchPath, tableNames)
+}
+
 // ListTriggers returns a list of triggers in the database for a given set of tables
 // The method takes a context and returns a slice of Trigger pointers, and an error if any.
 func (db *DB) ListTriggers(ctx context.Context) ([]*desc.Trigger, error) {
@@ -901,6 +1151,7 @@ func (db *DB) ListColumnsInformationSchema(ctx context.Context, tableNames ...st
 	col_description(p.attrelid::regclass, p.attnum) as column_description,
 	c.column_default,
 	pg_catalog.format_type(p.atttypid, p.atttypmod) AS data_type,
+	CASE WHEN ty.typtype = 'e' THEN ty.typname ELSE '' END AS enum_name,
 	CASE WHEN c.is_nullable = 'YES' THEN true ELSE false END AS is_nullable,
 	CASE WHEN c.is_identity = 'YES' THEN true ELSE false END AS is_identity,
 	CASE WHEN c.is_generated = 'ALWAYS' THEN true ELSE false END AS is_generated,
@@ -908,6 +1159,7 @@ func (db *DB) ListColumnsInformationSchema(ctx context.Context, tableNames ...st
 	FROM information_schema.columns c
 		JOIN information_schema.tables t ON t.table_catalog = c.table_catalog AND t.table_schema = c.table_schema AND t.table_name = c.table_name
 		JOIN pg_catalog.pg_attribute p ON p.attrelid = (c.table_schema || '.' || c.table_name)::regclass AND p.attname = c.column_name
+		JOIN pg_catalog.pg_type ty ON ty.oid = p.atttypid
    WHERE
    	    c.table_catalog = $1 AND
    	    c.table_schema = $2 AND
@@ -929,6 +1181,7 @@ func (db *DB) ListColumnsInformationSchema(ctx context.Context, tableNames ...st
 			columnDescription    sql.NullString
 			columnDefault        sql.NullString
 			fullDataType         string
+			enumName             string
 			isNullable           bool
 			isIdentity           bool
 			isGenerated          bool
@@ -944,6 +1197,7 @@ func (db *DB) ListColumnsInformationSchema(ctx context.Context, tableNames ...st
 			&columnDescription,
 			&columnDefault,
 			&fullDataType,
+			&enumName,
 			&isNullable,
 			&isIdentity,
 			&isGenerated,
@@ -968,6 +1222,9 @@ func (db *DB) ListColumnsInformationSchema(ctx context.Context, tableNames ...st
 
 		tableType := desc.ParseTableType(fullTableType)
 		dataType, dataTypeArgument := desc.ParseDataType(fullDataType)
+		if enumName != "" {
+			dataType, dataTypeArgument = desc.Enumerated, ""
+		}
 		column := &desc.ColumnBasicInfo{
 			TableName:            tableName,
 			TableDescription:     tableDesc,
@@ -978,6 +1235,7 @@ func (db *DB) ListColumnsInformationSchema(ctx context.Context, tableNames ...st
 			Default:              columnDefault.String,
 			DataType:             dataType,
 			DataTypeArgument:     dataTypeArgument,
+			EnumName:             enumName,
 			IsNullable:           isNullable,
 			IsIdentity:           isIdentity,
 			IsGenerated:          isGenerated,
@@ -31,6 +31,30 @@ func TestValidateSearchPath(t *testing.T) {
 	}
 }
 
+// TestValidateIdentifier covers the identifier check of the names Migrate and the cron package
+// interpolate into DDL.
+func TestValidateIdentifier(t *testing.T) {
+	valid := []string{"schema_migrations", "_jobs", "runs$2"}
+	for _, v := range valid {
+		if err := ValidateIdentifier(v); err != nil {
+			t.Errorf("expected %q to be accepted as a valid identifier, got error: %v", v, err)
+		}
+	}
+
+	invalid := []string{
+		"cron jobs",              // space is not a valid identifier character.
+		`jobs"; DROP TABLE x;--`, // SQL injection attempt.
+		"public.jobs",            // a qualified name is not a bare identifier.
+		"1jobs",                  // starts with a digit.
+		"",                       // empty.
+	}
+	for _, v := range invalid {
+		if err := ValidateIdentifier(v); err == nil {
+			t.Errorf("expected %q to be rejected as an invalid identifier", v)
+		}
+	}
+}
+
 // pwdCustomColumnUser is a scratch table used only by TestSelectByUsernameAndPasswordCustomColumn.
 // Its password column is deliberately renamed away from the field name via the "name=pwd" tag
 // option, so that its actual column name ("pwd") differs from the literal "password" that the
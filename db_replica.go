@@ -0,0 +1,231 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReplicaBalancing is the way the read queries are spread over the read replicas, see WithReadReplicas.
+type ReplicaBalancing uint8
+
+// These are the possible values for ReplicaBalancing.
+const (
+	RoundRobin       ReplicaBalancing = iota // RoundRobin picks the replicas in turn, it is the default.
+	LeastConnections                         // LeastConnections picks the replica with the fewest acquired connections.
+)
+
+// openConfig holds the settings of the ConnectionOptions which are not part of a pgxpool.Config
+// and can be applied only by Open, e.g. WithReadReplicas.
+type openConfig struct {
+	replicaConnStrings []string
+	balancing          ReplicaBalancing
+	replica            bool // true when the options are applied to the config of a read replica.
+}
+
+// openConfigs maps the *pgxpool.Config that Open applies its ConnectionOptions to, to its *openConfig.
+var openConfigs sync.Map
+
+// applyConnectionOptions applies the options to the config, the Open-only ones to cfg.
+func applyConnectionOptions(config *pgxpool.Config, cfg *openConfig, opts []ConnectionOption) error {
+	openConfigs.Store(config, cfg)
+	defer openConfigs.Delete(config)
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if err := opt(config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withOpenConfig returns a ConnectionOption which calls fn with the *openConfig of Open.
+// It fails outside of Open and it is ignored while the options are applied to a read replica.
+func withOpenConfig(name string, fn func(*openConfig)) ConnectionOption {
+	return func(poolConfig *pgxpool.Config) error {
+		v, ok := openConfigs.Load(poolConfig)
+		if !ok {
+			return fmt.Errorf("pg: %s: the option can only be passed to Open", name)
+		}
+
+		if cfg := v.(*openConfig); !cfg.replica {
+			fn(cfg)
+		}
+
+		return nil
+	}
+}
+
+// WithReadReplicas is a ConnectionOption. It makes Open open one more connection pool for each
+// of the given connection strings, with the same ConnectionOptions as the primary one, and route
+// the read queries to them, see WithReplicaBalancing for the way they are spread:
+//   - Select, SelectSingle, SelectByID, SelectByUsernameAndPassword, Exists, ExistsBy, Count and CountBy,
+//   - QuerySlice and QueryIter,
+//   - the read methods of a Repository (Select*, Exists, Count, Find and the Preload queries)
+//     and the Query and QueryRow methods of a Repository whose table is read-only (see desc.TableType.IsReadOnly).
+//
+// Everything else, e.g. Query, QueryRow, Exec, the write methods and the helpers which may run
+// a write with a RETURNING clause (QuerySingle, QueryTwoSlices, QueryMap, QueryFunc, QueryStructs
+// and QueryStruct), and every query of a transaction runs on the primary. A context returned by WithPrimary forces the reads to the primary too,
+// e.g. to read a row right after writing it, as the replicas may lag behind.
+//
+// Example:
+//
+//	db, err := pg.Open(ctx, schema, primaryConnString, pg.WithReadReplicas(replica1ConnString, replica2ConnString))
+func WithReadReplicas(connStrings ...string) ConnectionOption {
+	return withOpenConfig("WithReadReplicas", func(cfg *openConfig) {
+		cfg.replicaConnStrings = append(cfg.replicaConnStrings, connStrings...)
+	})
+}
+
+// WithReplicaBalancing is a ConnectionOption. It sets the way the read queries are spread over
+// the read replicas of WithReadReplicas, defaults to RoundRobin.
+func WithReplicaBalancing(balancing ReplicaBalancing) ConnectionOption {
+	return withOpenConfig("WithReplicaBalancing", func(cfg *openConfig) {
+		cfg.balancing = balancing
+	})
+}
+
+// openReplicas opens and pings the pools of the read replicas of cfg, applying the given options to each one of them.
+func openReplicas(ctx context.Context, schema *Schema, cfg *openConfig, opts []ConnectionOption) (*replicaSet, error) {
+	if len(cfg.replicaConnStrings) == 0 {
+		return nil, nil
+	}
+
+	replicas := &replicaSet{balancing: cfg.balancing}
+	for i, connString := range cfg.replicaConnStrings {
+		config, err := pgxpool.ParseConfig(connString)
+		if err != nil {
+			replicas.close()
+			return nil, fmt.Errorf("open: replica %d: %w", i, err)
+		}
+
+		if err = applyConnectionOptions(config, &openConfig{replica: true}, opts); err != nil {
+			replicas.close()
+			return nil, err
+		}
+		installSearchPathHook(config)
+		installSettingHooks(schema, config)
+
+		pool, err := pgxpool.NewWithConfig(ctx, config)
+		if err != nil {
+			replicas.close()
+			// Same as Open: never include the connection string, it may hold the plaintext password.
+			return nil, fmt.Errorf("open: replica %d: host=%s dbname=%s: %w", i, config.ConnConfig.Host, config.ConnConfig.Database, err)
+		}
+
+		replicas.pools = append(replicas.pools, pool)
+
+		if err = pool.Ping(ctx); err != nil {
+			replicas.close()
+			return nil, fmt.Errorf("open: replica %d: %w", i, err)
+		}
+	}
+
+	return replicas, nil
+}
+
+// replicaSet holds the pools of the read replicas of a DB.
+type replicaSet struct {
+	pools     []*pgxpool.Pool
+	balancing ReplicaBalancing
+	next      atomic.Uint64 // the round-robin counter.
+}
+
+// pick returns the pool of the replica the next read query runs on.
+func (r *replicaSet) pick() *pgxpool.Pool {
+	if r.balancing == LeastConnections {
+		picked := r.pools[0]
+		for _, pool := range r.pools[1:] {
+			if pool.Stat().AcquiredConns() < picked.Stat().AcquiredConns() {
+				picked = pool
+			}
+		}
+
+		return picked
+	}
+
+	return r.pools[(r.next.Add(1)-1)%uint64(len(r.pools))]
+}
+
+func (r *replicaSet) close() {
+	for _, pool := range r.pools {
+		pool.Close()
+	}
+}
+
+type primaryContextKey struct{}
+
+// WithPrimary returns a copy of the context which routes the read queries to the primary
+// instead of a read replica, see WithReadReplicas. Use it to read your own writes:
+//
+//	if err = repo.InsertSingle(ctx, order, &order.ID); err != nil { ... }
+//	order, err = repo.SelectByID(pg.WithPrimary(ctx), order.ID)
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// isPrimary reports whether the context was returned by WithPrimary.
+func isPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryContextKey{}).(bool)
+	return primary
+}
+
+// HasReadReplicas reports whether the database routes its read queries to read replicas, see WithReadReplicas.
+func (db *DB) HasReadReplicas() bool {
+	return db.replicas != nil
+}
+
+// readPool returns the pool a read query runs on outside of a transaction:
+// a read replica, if any, unless the context was returned by WithPrimary.
+func (db *DB) readPool(ctx context.Context) *pgxpool.Pool {
+	if db.replicas == nil || isPrimary(ctx) {
+		return db.Pool
+	}
+
+	return db.replicas.pick()
+}
+
+// pinRead returns a copy of the DB whose read queries all run on the same pool, the one readPool
+// picks, e.g. for the queries of a single read which must see the same replica. It returns the DB
+// itself when its read queries already run on a single pool.
+func (db *DB) pinRead(ctx context.Context) *DB {
+	if db.tx != nil || db.replicas == nil || isPrimary(ctx) {
+		return db
+	}
+
+	pinned := db.clone(nil)
+	pinned.replicas = &replicaSet{pools: []*pgxpool.Pool{db.replicas.pick()}}
+	return pinned
+}
+
+// queryRead is like Query but, outside of a transaction, it runs the query on a read replica, see readPool.
+func (db *DB) queryRead(ctx context.Context, query string, args ...any) (Rows, error) {
+	if db.tx != nil {
+		return db.Query(ctx, query, args...)
+	}
+
+	rows, err := db.readPool(ctx).Query(db.poolContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return rows, nil
+}
+
+// queryRowRead is like QueryRow but, outside of a transaction, it runs the query on a read replica, see readPool.
+func (db *DB) queryRowRead(ctx context.Context, query string, args ...any) Row {
+	if db.tx != nil {
+		return db.QueryRow(ctx, query, args...)
+	}
+
+	return db.readPool(ctx).QueryRow(db.poolContext(ctx), query, args...)
+}
@@ -0,0 +1,57 @@
+package pg
+
+import (
+	"context"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestDBReadReplicas' -v .
+
+// TestDBReadReplicas uses the test database as its own two "replicas".
+func TestDBReadReplicas(t *testing.T) {
+	connString := getTestConnString()
+
+	db, err := Open(context.Background(), NewSchema(), connString, WithReadReplicas(connString, connString))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if !db.HasReadReplicas() {
+		t.Fatal("expected read replicas")
+	}
+
+	for range 2 {
+		count, err := db.Count(ctx, "SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if count != 1 {
+			t.Fatalf("expected 1 but got: %d", count)
+		}
+	}
+
+	if _, err = db.Count(WithPrimary(ctx), "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	health, err := db.Health(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(health.Pool.Replicas) != 2 {
+		t.Fatalf("expected the stats of 2 replicas but got: %d", len(health.Pool.Replicas))
+	}
+
+	for i, stat := range health.Pool.Replicas {
+		if stat.TotalConns <= 0 {
+			t.Fatalf("[%d] expected the replica to have connected", i)
+		}
+	}
+}
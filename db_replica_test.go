@@ -0,0 +1,172 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newLazyTestPool returns a pool which never connects: pgxpool connects on the first acquire.
+func newLazyTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	return newLazyTestPoolAt(t, "127.0.0.1:1")
+}
+
+// newLazyTestPoolAt is newLazyTestPool with a given address, e.g. to tell apart the pool
+// a query was sent to by the address in its connection error.
+func newLazyTestPoolAt(t *testing.T, addr string) *pgxpool.Pool {
+	t.Helper()
+
+	pool, err := pgxpool.New(context.Background(), "postgres://postgres@"+addr+"/test_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// TestReadPool verifies the routing of the read queries: round-robin over the replicas,
+// the primary for a WithPrimary context or when there are no replicas.
+func TestReadPool(t *testing.T) {
+	primary, replica1, replica2 := newLazyTestPool(t), newLazyTestPool(t), newLazyTestPool(t)
+
+	db := &DB{Pool: primary, schema: NewSchema(), searchPath: "public"}
+	ctx := context.Background()
+
+	if got := db.readPool(ctx); got != primary {
+		t.Fatal("expected the primary without replicas")
+	}
+
+	db.replicas = &replicaSet{pools: []*pgxpool.Pool{replica1, replica2}}
+	for i, want := range []*pgxpool.Pool{replica1, replica2, replica1} {
+		if got := db.readPool(ctx); got != want {
+			t.Fatalf("[%d] unexpected round-robin replica", i)
+		}
+	}
+
+	if got := db.readPool(WithPrimary(ctx)); got != primary {
+		t.Fatal("expected the primary for a WithPrimary context")
+	}
+
+	db.replicas.balancing = LeastConnections
+	if got := db.readPool(ctx); got != replica1 {
+		t.Fatal("expected the first replica when they have the same acquired connections")
+	}
+
+	if !db.clone(nil).HasReadReplicas() {
+		t.Fatal("expected the replicas to be cloned")
+	}
+
+	if stat := db.PoolStat(); len(stat.Replicas) != 2 {
+		t.Fatalf("expected the stats of 2 replicas but got: %d", len(stat.Replicas))
+	}
+}
+
+// TestPinRead verifies that the read queries of a pinned DB stay on the replica it picked.
+func TestPinRead(t *testing.T) {
+	primary, replica1, replica2 := newLazyTestPool(t), newLazyTestPool(t), newLazyTestPool(t)
+
+	db := &DB{Pool: primary, schema: NewSchema(), searchPath: "public"}
+	ctx := context.Background()
+
+	if db.pinRead(ctx) != db {
+		t.Fatal("expected the same DB without replicas")
+	}
+
+	db.replicas = &replicaSet{pools: []*pgxpool.Pool{replica1, replica2}}
+	if db.pinRead(WithPrimary(ctx)) != db {
+		t.Fatal("expected the same DB for a WithPrimary context")
+	}
+
+	pinned := db.pinRead(ctx)
+	for i := 0; i < 3; i++ {
+		if got := pinned.readPool(ctx); got != replica1 {
+			t.Fatalf("[%d] expected the pinned replica", i)
+		}
+	}
+
+	if got := db.readPool(ctx); got != replica2 {
+		t.Fatal("expected the DB to keep its round-robin")
+	}
+}
+
+// TestWithReadReplicasOutsideOpen verifies that the Open-only options fail when applied to
+// a pool config by anything but Open.
+func TestWithReadReplicasOutsideOpen(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://postgres@127.0.0.1:1/test_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = WithReadReplicas("postgres://postgres@127.0.0.1:2/test_db")(config); err == nil {
+		t.Fatal("expected an error outside of Open")
+	}
+
+	cfg := new(openConfig)
+	opts := []ConnectionOption{WithReadReplicas("a", "b"), WithReplicaBalancing(LeastConnections)}
+	if err = applyConnectionOptions(config, cfg, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.replicaConnStrings) != 2 || cfg.balancing != LeastConnections {
+		t.Fatalf("unexpected open config: %#v", cfg)
+	}
+
+	replicaCfg := &openConfig{replica: true}
+	if err = applyConnectionOptions(config, replicaCfg, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replicaCfg.replicaConnStrings) != 0 {
+		t.Fatal("expected the options to be ignored for a replica")
+	}
+}
+
+// TestReadHelpersRouting verifies that only the read helpers named by WithReadReplicas run on a
+// replica: the ones which take arbitrary SQL, e.g. an INSERT ... RETURNING, stay on the primary.
+func TestReadHelpersRouting(t *testing.T) {
+	const primaryAddr, replicaAddr = "127.0.0.1:1", "127.0.0.1:2"
+
+	db := &DB{Pool: newLazyTestPoolAt(t, primaryAddr), schema: NewSchema(), searchPath: "public"}
+	db.replicas = &replicaSet{pools: []*pgxpool.Pool{newLazyTestPoolAt(t, replicaAddr)}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type row struct {
+		ID int64 `pg:"id"`
+	}
+
+	tests := []struct {
+		name string
+		addr string
+		run  func() error
+	}{
+		{"QuerySlice", replicaAddr, func() error { _, err := db.QuerySlice[int64](ctx, "SELECT 1"); return err }},
+		{"Count", replicaAddr, func() error { _, err := db.Count(ctx, "SELECT 1"); return err }},
+		{"QuerySingle", primaryAddr, func() error {
+			_, err := db.QuerySingle[int64](ctx, "INSERT INTO t DEFAULT VALUES RETURNING id")
+			return err
+		}},
+		{"QueryTwoSlices", primaryAddr, func() error { _, _, err := db.QueryTwoSlices[int64, int64](ctx, "SELECT 1, 2"); return err }},
+		{"QueryMap", primaryAddr, func() error { _, err := db.QueryMap[int64, int64](ctx, "SELECT 1, 2"); return err }},
+		{"QueryFunc", primaryAddr, func() error {
+			_, err := db.QueryFunc(ctx, func(rows Rows) (int64, error) { return 0, nil }, "SELECT 1")
+			return err
+		}},
+		{"QueryStructs", primaryAddr, func() error { _, err := db.QueryStructs[row](ctx, "SELECT 1 AS id"); return err }},
+		{"QueryStruct", primaryAddr, func() error { _, err := db.QueryStruct[row](ctx, "SELECT 1 AS id"); return err }},
+	}
+
+	for _, tt := range tests {
+		err := tt.run()
+		if err == nil || !strings.Contains(err.Error(), tt.addr) {
+			t.Fatalf("%s: expected a connection error of %s but got: %v", tt.name, tt.addr, err)
+		}
+	}
+}
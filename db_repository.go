@@ -2,8 +2,10 @@ package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/kataras/pg/desc"
 )
@@ -16,7 +18,7 @@ func (db *DB) Select(ctx context.Context, scannerFunc func(Rows) error, query st
 		return fmt.Errorf("scannerFunc is nil") // check if the scanner function is nil and return an error if so
 	}
 
-	rows, err := db.Query(ctx, query, args...) // execute the query using repo.db.Query and pass in the arguments
+	rows, err := db.queryRead(ctx, query, args...) // execute the query using db.queryRead (a read replica, if any) and pass in the arguments
 	if err != nil {
 		return err // return nil and the error if the query fails
 	}
@@ -30,38 +32,57 @@ func (db *DB) Select(ctx context.Context, scannerFunc func(Rows) error, query st
 }
 
 // SelectByID selects a row from a table by matching the primary key column with the given argument.
+// For a table with a composite primary key the id is a Key (or a struct value holding the primary key fields).
+// Soft-deleted rows are not selected.
 func (db *DB) SelectByID(ctx context.Context, destPtr any, id any) error {
 	td, err := db.schema.Get(reflect.TypeOf(destPtr)) // get the table definition from the schema by using the type of the result variable
 	if err != nil {
 		return err // return an error if getting the table definition failed.
 	}
 
-	return db.selectTableRecordByID(ctx, td, destPtr, id)
+	return db.selectTableRecordByID(ctx, td, desc.SoftDeleteScopeActive, destPtr, id)
 }
 
-func (db *DB) selectTableRecordByID(ctx context.Context, td *desc.Table, destPtr any, id any) error {
-	primaryCol, ok := td.PrimaryKey()
-	if !ok {
-		return fmt.Errorf("no primary key found in table definition: %s", td.Name) // return an error if the table definition does not have a primary key
+func (db *DB) selectTableRecordByID(ctx context.Context, td *desc.Table, scope desc.SoftDeleteScope, destPtr any, id any) error {
+	where, args, err := primaryKeyWhere(td, id)
+	if err != nil {
+		return err // return an error if the table definition does not have a primary key or the id does not match it
+	}
+
+	where = andCondition(where, td.SoftDeleteCondition(scope))
+	if where, args, err = andTenant(ctx, td, where, args); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`SELECT * FROM %s.%s%s LIMIT 1;`,
+		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), where)
+	return db.selectSingleTable(ctx, td, destPtr, query, args...)
+}
+
+// primaryKeyWhere resolves the given id (a plain value, a Key or a struct value, see
+// desc.Table.PrimaryKeyValues) against the table's primary key and returns the
+// ` WHERE "a" = $1 AND "b" = $2` clause which matches its row, together with its arguments.
+func primaryKeyWhere(td *desc.Table, id any) (string, []any, error) {
+	args, err := td.PrimaryKeyValues(id)
+	if err != nil {
+		return "", nil, err
 	}
 
-	query := fmt.Sprintf(`SELECT * FROM %s.%s WHERE %s = $1 LIMIT 1;`,
-		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), QuoteIdentifier(primaryCol.Name))
-	return db.selectSingleTable(ctx, td, destPtr, query, id)
+	return " WHERE " + td.PrimaryKeyCondition(1), args, nil
 }
 
 // SelectByUsernameAndPassword selects a row from a table by matching the username and password columns with the given arguments
-// and scans the row into the destPtr variable.
+// and scans the row into the destPtr variable. Soft-deleted rows are not selected.
 func (db *DB) SelectByUsernameAndPassword(ctx context.Context, destPtr any, username, plainPassword string) error {
 	td, err := db.schema.Get(reflect.TypeOf(destPtr)) // get the table definition from the schema by using the type of the result variable
 	if err != nil {
 		return err // return an error if getting the table definition failed.
 	}
 
-	return db.selectTableRecordByUsernameAndPassword(ctx, td, destPtr, username, plainPassword)
+	return db.selectTableRecordByUsernameAndPassword(ctx, td, desc.SoftDeleteScopeActive, destPtr, username, plainPassword)
 }
 
-func (db *DB) selectTableRecordByUsernameAndPassword(ctx context.Context, td *desc.Table, destPtr any, username, plainPassword string) error {
+func (db *DB) selectTableRecordByUsernameAndPassword(ctx context.Context, td *desc.Table, scope desc.SoftDeleteScope, destPtr any, username, plainPassword string) error {
 	usernameCol := td.GetUsernameColumn() // get the username column from the table definition
 	passwordCol := td.GetPasswordColumn() // get the password column from the table definition
 
@@ -74,14 +95,21 @@ func (db *DB) selectTableRecordByUsernameAndPassword(ctx context.Context, td *de
 	// so it must resolve to the actual, possibly renamed, password column (not the literal
 	// "password") on both sides of the AND.
 	quotedPasswordCol := QuoteIdentifier(passwordCol.Name)
-	query := fmt.Sprintf(`SELECT * FROM %s.%s WHERE %s = $1 AND %s = crypt($2, %s) LIMIT 1;`,
-		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), QuoteIdentifier(usernameCol.Name), quotedPasswordCol, quotedPasswordCol)
+	where := fmt.Sprintf(` WHERE %s = $1 AND %s = crypt($2, %s)`, QuoteIdentifier(usernameCol.Name), quotedPasswordCol, quotedPasswordCol)
+	where = andCondition(where, td.SoftDeleteCondition(scope))
+	where, args, err := andTenant(ctx, td, where, []any{username, plainPassword})
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`SELECT * FROM %s.%s%s LIMIT 1;`,
+		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), where)
 
-	return db.selectSingleTable(ctx, td, destPtr, query, username, plainPassword)
+	return db.selectSingleTable(ctx, td, destPtr, query, args...)
 }
 
 func (db *DB) selectSingleTable(ctx context.Context, td *desc.Table, destPtr any, query string, args ...any) error {
-	rows, err := db.Query(ctx, query, args...) // execute the query with the given arguments and get the rows
+	rows, err := db.queryRead(ctx, query, args...) // execute the query with the given arguments and get the rows
 	if err != nil {
 		return err // return an error if executing the query failed
 	}
@@ -100,23 +128,29 @@ func (db *DB) selectSingleTable(ctx context.Context, td *desc.Table, destPtr any
 }
 
 // Exists returns true if a row exists in the table that matches the given value's non-zero fields or false otherwise.
+// Soft-deleted rows are not taken into account.
 func (db *DB) Exists(ctx context.Context, value any) (bool, error) {
 	structValue := desc.IndirectValue(value)     // get the reflect.Value of the value and dereference it if it is a pointe
 	td, err := db.schema.Get(structValue.Type()) // get the table definition from the schema by using the type of the result variable
 	if err != nil {
 		return false, err // return an error if getting the table definition failed.
 	}
-	return db.tableRecordExists(ctx, td, structValue)
+	return db.tableRecordExists(ctx, td, desc.SoftDeleteScopeActive, structValue)
 }
 
-func (db *DB) tableRecordExists(ctx context.Context, td *desc.Table, structValue reflect.Value) (bool, error) {
-	query, args, err := desc.BuildExistsQuery(td, structValue)
+func (db *DB) tableRecordExists(ctx context.Context, td *desc.Table, scope desc.SoftDeleteScope, structValue reflect.Value) (bool, error) {
+	structValue, err := bindTenant(ctx, td, structValue) // match the tenant's rows only.
+	if err != nil {
+		return false, err
+	}
+
+	query, args, err := desc.BuildScopedExistsQuery(td, structValue, scope)
 	if err != nil {
 		return false, err // return the error if finding arguments fails
 	}
 
 	var exists bool
-	err = db.QueryRow(ctx, query, args...).Scan(&exists)
+	err = db.queryRowRead(ctx, query, args...).Scan(&exists)
 	return exists, err
 }
 
@@ -147,6 +181,9 @@ func (db *DB) Insert(ctx context.Context, values ...any) error {
 
 // InsertSingle inserts a single value into the database by building and
 // executing an SQL query based on the value and the table definition.
+// The idPtr parameter can be used to get the primary key value of the inserted row.
+// If idPtr is nil, the primary key value is not returned. For a table with a composite
+// primary key idPtr should be a *Key, and the key needs a generated column, see desc.BuildDuplicateQuery.
 func (db *DB) InsertSingle(ctx context.Context, value any, idPtr any) error {
 	structValue := desc.IndirectValue(value)     // get the reflect.Value of the value and dereference it if it is a pointer
 	td, err := db.schema.Get(structValue.Type()) // get the table definition from the schema based on the type of the value
@@ -202,19 +239,19 @@ func (db *DB) UpsertSingle(ctx context.Context, forceOnConflictExpr string, valu
 }
 
 func (db *DB) insertTableRecord(ctx context.Context, td *desc.Table, structValue reflect.Value, idPtr any, forceOnConflictExpr string, upsert bool) error {
-	query, args, err := desc.BuildInsertQuery(td, structValue, idPtr, forceOnConflictExpr, upsert)
+	structValue, err := bindTenant(ctx, td, structValue)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := desc.BuildInsertQuery(db.tenantTable(td), structValue, idPtr, forceOnConflictExpr, upsert)
 	if err != nil {
 		return err // return the error if building the query fails
 	}
 
 	if idPtr != nil {
 		// if returningColumn is not empty, use db.QueryRow to execute the query and scan the returned value into idPtr
-		err = db.QueryRow(ctx, query, args...).Scan(idPtr)
-		if err != nil {
-			return err
-		}
-
-		return nil
+		return scanPrimaryKey(db.QueryRow(ctx, query, args...), td, idPtr)
 	}
 
 	// otherwise, use db.Exec to execute the query without scanning any result
@@ -244,6 +281,7 @@ func (db *DB) MutateSingle(ctx context.Context, query string, args ...any) (bool
 
 // Delete deletes one or more values from the database by building and executing an
 // SQL query based on the values and the table definition.
+// If the table has a soft_delete column the rows are marked as deleted instead.
 func (db *DB) Delete(ctx context.Context, values ...any) (int64, error) {
 	if len(values) == 0 {
 		return 0, nil // return false and nil if no values are given
@@ -265,8 +303,23 @@ func (db *DB) deleteTableRecords(ctx context.Context, td *desc.Table, values []a
 		return 0, err
 	}
 
-	// execute the query using db.Exec and pass in the primary key values as a single input parameter
-	tag, err := db.Exec(ctx, query, ids)
+	args := ids // one input parameter per primary key value.
+	if !td.HasCompositePrimaryKey() {
+		// pass in the primary key values as a single input parameter.
+		args = []any{ids}
+	}
+
+	tenantCondition, args, err := tenantCondition(ctx, td, args)
+	if err != nil {
+		return 0, err
+	}
+
+	if tenantCondition != "" {
+		// the WHERE clause is the last one of the query.
+		query = strings.TrimSuffix(query, ";") + " AND " + tenantCondition + ";"
+	}
+
+	tag, err := db.Exec(ctx, query, args...)
 	if err != nil {
 		return 0, err // return false and the wrapped error if executing fails
 	}
@@ -275,14 +328,12 @@ func (db *DB) deleteTableRecords(ctx context.Context, td *desc.Table, values []a
 }
 
 func (db *DB) deleteByID(ctx context.Context, td *desc.Table, id any) (bool, error) {
-	primaryKey, ok := td.PrimaryKey()
-	if !ok {
-		return false, fmt.Errorf("no primary key found in table definition: %s", td.Name)
+	query, args, err := db.deleteByIDQuery(ctx, td, id)
+	if err != nil {
+		return false, err
 	}
 
-	query := fmt.Sprintf(`DELETE FROM %s.%s WHERE %s = $1;`,
-		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), QuoteIdentifier(primaryKey.Name))
-	tag, err := db.Exec(ctx, query, id)
+	tag, err := db.Exec(ctx, query, args...)
 	if err != nil {
 		return false, err
 	}
@@ -290,8 +341,38 @@ func (db *DB) deleteByID(ctx context.Context, td *desc.Table, id any) (bool, err
 	return tag.RowsAffected() > 0, nil
 }
 
+// deleteByIDQuery returns the query which deletes the row of the given primary key value and its arguments:
+// a DELETE or, if the table has a soft_delete column, an UPDATE which marks the row as deleted.
+func (db *DB) deleteByIDQuery(ctx context.Context, td *desc.Table, id any) (string, []any, error) {
+	where, args, err := primaryKeyWhere(td, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if where, args, err = andTenant(ctx, td, where, args); err != nil {
+		return "", nil, err
+	}
+
+	softDeleteCol, ok := td.SoftDeleteColumn()
+	if !ok {
+		query := fmt.Sprintf(`DELETE FROM %s.%s%s;`,
+			QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), where)
+		return query, args, nil
+	}
+
+	query := fmt.Sprintf(`UPDATE %s.%s SET %s = now()%s;`,
+		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), QuoteIdentifier(softDeleteCol.Name),
+		andCondition(where, td.SoftDeleteCondition(desc.SoftDeleteScopeActive)))
+	return query, args, nil
+}
+
 // Update updates one or more values in the database by building and executing an
 // SQL query based on the values and the table definition.
+//
+// If the table has a `version` column the update only succeeds when the row still has the
+// value's version; the column is incremented and, when the value is a pointer, the new version
+// is written back to it, and the previous one if the transaction of the DB, if any, rolls back.
+// Otherwise a *StaleEntityError (matching ErrStaleEntity) is returned.
 func (db *DB) Update(ctx context.Context, values ...any) (int64, error) {
 	return db.UpdateOnlyColumns(ctx, nil, values...)
 }
@@ -338,20 +419,26 @@ func (db *DB) updateTableRecords(ctx context.Context, td *desc.Table, columnsToU
 	}
 
 	if len(values) == 1 {
-		return db.updateTableRecord(ctx, values[0], columnsToUpdate, reportNotFound, primaryKey)
+		rowsAffected, writeBack, err := db.updateTableRecord(ctx, values[0], columnsToUpdate, reportNotFound, primaryKey)
+		if err != nil {
+			return 0, err
+		}
+
+		writeBack()
+		return rowsAffected, nil
 	}
 
 	// if more than one: update each value inside a transaction.
 	var totalRowsAffected int64
-
 	err := db.InTransaction(ctx, func(db *DB) error {
 		for _, value := range values {
-			rowsAffected, err := db.updateTableRecord(ctx, value, columnsToUpdate, reportNotFound, primaryKey)
+			rowsAffected, writeBack, err := db.updateTableRecord(ctx, value, columnsToUpdate, reportNotFound, primaryKey)
 			if err != nil {
 				return err
 			}
 
 			totalRowsAffected += rowsAffected
+			writeBack() // undone if the transaction rolls back, see writeVersion.
 		}
 
 		return nil
@@ -363,35 +450,118 @@ func (db *DB) updateTableRecords(ctx context.Context, td *desc.Table, columnsToU
 	return totalRowsAffected, nil
 }
 
-func (db *DB) updateTableRecord(ctx context.Context, value any, columnsToUpdate []string, reportNotFound bool, primaryKey *desc.Column) (int64, error) {
+// updateTableRecord updates a single value. It returns the function which writes
+// the new version, if any, back to the value, to be called once the update succeeded.
+func (db *DB) updateTableRecord(ctx context.Context, value any, columnsToUpdate []string, reportNotFound bool, primaryKey *desc.Column) (int64, func(), error) {
+	value, err := bindTenantValue(ctx, primaryKey.Table, value) // match the tenant's row only.
+	if err != nil {
+		return 0, nil, err
+	}
+
 	// build the SQL query and arguments using the table definition and its primary key.
 	query, args, err := desc.BuildUpdateQuery(value, columnsToUpdate, reportNotFound, primaryKey)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
+	}
+
+	if versionColumn, ok := primaryKey.Table.VersionColumn(); ok {
+		return db.updateVersionedTableRecord(ctx, value, query, args, versionColumn)
 	}
 
 	if reportNotFound {
 		scanErr := db.QueryRow(ctx, query, args...).Scan(nil)
 		if scanErr != nil {
-			return 0, scanErr
+			return 0, nil, scanErr
 		}
 
-		return 1, nil
+		return 1, noWriteBack, nil
 	}
 
 	// execute the query using db.Exec and pass in the primary key values as a parameter
 	tag, err := db.Exec(ctx, query, args...)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
-	return tag.RowsAffected(), nil
+	return tag.RowsAffected(), noWriteBack, nil
+}
+
+// noWriteBack is the write-back function of an update which has nothing to write back.
+func noWriteBack() {}
+
+// updateVersionedTableRecord executes an update query built by desc.BuildUpdateQuery for a table
+// with a version column. The query returns the new version, which the returned function writes back
+// to the value when it's addressable (a pointer), or a *StaleEntityError if no row matched.
+func (db *DB) updateVersionedTableRecord(ctx context.Context, value any, query string, args []any, versionColumn *desc.Column) (int64, func(), error) {
+	var newVersion int64
+	err := db.QueryRow(ctx, query, args...).Scan(&newVersion)
+	if err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return 0, nil, staleEntityError(args, versionColumn)
+		}
+
+		return 0, nil, err
+	}
+
+	return 1, func() { db.writeVersion(value, versionColumn, newVersion) }, nil
+}
+
+// staleEntityError returns the *StaleEntityError of a versioned update query, built by
+// desc.BuildUpdateQuery, which matched no row.
+func staleEntityError(args []any, versionColumn *desc.Column) *StaleEntityError {
+	// the last arguments are the primary key values and the expected version value.
+	primaryKeys := versionColumn.Table.PrimaryKeys()
+	primaryKeyValues := args[len(args)-1-len(primaryKeys) : len(args)-1]
+
+	var id any = primaryKeyValues[0]
+	if len(primaryKeys) > 1 {
+		key := make(Key, len(primaryKeys))
+		for i, c := range primaryKeys {
+			key[c.Name] = primaryKeyValues[i]
+		}
+		id = key
+	}
+
+	return &StaleEntityError{
+		TableName: versionColumn.TableName,
+		ID:        id,
+		Version:   args[len(args)-1],
+	}
+}
+
+// writeVersion writes the new version back to the value, if it's addressable (a pointer).
+// In a transaction the previous version is written back if the transaction rolls back, so
+// the value keeps matching its committed row, see DB.onRollback.
+func (db *DB) writeVersion(value any, versionColumn *desc.Column, newVersion int64) {
+	field := desc.IndirectValue(value).FieldByIndex(versionColumn.FieldIndex)
+	if !field.CanSet() {
+		return
+	}
+
+	previous := reflect.New(field.Type()).Elem()
+	previous.Set(field)
+
+	setVersion(value, versionColumn, newVersion)
+	db.onRollback(func() { field.Set(previous) })
+}
+
+// setVersion writes the new version back to the value, if it's addressable (a pointer).
+func setVersion(value any, versionColumn *desc.Column, newVersion int64) {
+	if field := desc.IndirectValue(value).FieldByIndex(versionColumn.FieldIndex); field.CanSet() {
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			field.SetInt(newVersion)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			field.SetUint(uint64(newVersion))
+		}
+	}
 }
 
 // Duplicate duplicates a row in the database by building and executing an
 // SQL query based on the value's primary key (uses SELECT for insert column values).
 // The idPtr parameter can be used to get the primary key value of the inserted row.
-// If idPtr is nil, the primary key value is not returned.
+// If idPtr is nil, the primary key value is not returned. For a table with a composite
+// primary key idPtr should be a *Key, and the key needs a generated column, see desc.BuildDuplicateQuery.
 // If the value is nil, the method returns nil.
 func (db *DB) Duplicate(ctx context.Context, value any, idPtr any) error {
 	if value == nil { // return false and nil if no values are given
@@ -404,12 +574,11 @@ func (db *DB) Duplicate(ctx context.Context, value any, idPtr any) error {
 		return err // return the error if the table definition is not found
 	}
 
-	primaryKey, ok := td.PrimaryKey()
-	if !ok {
+	if _, ok := td.PrimaryKey(); !ok {
 		return fmt.Errorf("duplicate: primary key is required")
 	}
 
-	idValue, err := desc.ExtractPrimaryKeyValue(primaryKey, val)
+	idValue, err := desc.ExtractPrimaryKey(td, val)
 	if err != nil {
 		return err
 	}
@@ -422,18 +591,58 @@ func (db *DB) duplicateTableRecord(ctx context.Context, td *desc.Table, id any,
 		return fmt.Errorf("duplicate: id is required")
 	}
 
-	query, err := desc.BuildDuplicateQuery(td, newIDPtr)
+	args, err := td.PrimaryKeyValues(id)
+	if err != nil {
+		return err
+	}
+
+	if tenant, ok, err := tenantOf(ctx, td); err != nil {
+		return err
+	} else if ok {
+		args = append(args, tenant) // see desc.BuildDuplicateQuery.
+	}
+
+	query, err := desc.BuildDuplicateQuery(db.tenantTable(td), newIDPtr)
 	if err != nil {
 		return err
 	}
 
-	if newIDPtr != nil {
-		// Bind returning id.
-		err = db.QueryRow(ctx, query, id).Scan(newIDPtr)
-	} else {
-		// Otherwise just execution the insert command.
-		_, err = db.Exec(ctx, query, id)
+	if newIDPtr == nil {
+		// Just execute the insert command.
+		_, err = db.Exec(ctx, query, args...)
+		return err
 	}
 
-	return err
+	// Bind returning id.
+	return scanPrimaryKey(db.QueryRow(ctx, query, args...), td, newIDPtr)
+}
+
+// scanPrimaryKey scans the primary key value(s) returned by an insert query into idPtr.
+// A *Key receives all the primary key column values, any other idPtr the value of a
+// single-column primary key (the query builders reject it for a composite one).
+func scanPrimaryKey(row Row, td *desc.Table, idPtr any) error {
+	keyPtr, ok := idPtr.(*Key)
+	if !ok {
+		return row.Scan(idPtr)
+	}
+
+	// Bind all returning primary key values.
+	primaryKeys := td.PrimaryKeys()
+	values := make([]any, len(primaryKeys))
+	dest := make([]any, len(primaryKeys))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+
+	key := make(Key, len(primaryKeys))
+	for i, c := range primaryKeys {
+		key[c.Name] = values[i]
+	}
+	*keyPtr = key
+
+	return nil
 }
@@ -0,0 +1,440 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kataras/pg/desc"
+)
+
+// SchemaChangeKind is the kind of a SchemaChange.
+type SchemaChangeKind uint8
+
+// The kinds of SchemaChange, in the order DiffSchema returns them: a foreign key or an index is
+// dropped before the column it depends on is altered, a table is created before its columns are
+// referenced and the columns are dropped last.
+const (
+	// DropForeignKeyChange drops a foreign key constraint which the code no longer declares,
+	// or which it declares differently.
+	DropForeignKeyChange SchemaChangeKind = iota + 1
+	// DropIndexChange drops an index which the code no longer declares, or which it declares differently.
+	DropIndexChange
+	// CreateTableChange creates a registered table which the database does not have.
+	CreateTableChange
+	// AddColumnChange adds a column which the database table does not have.
+	AddColumnChange
+	// AlterColumnTypeChange changes the data type of a column.
+	AlterColumnTypeChange
+	// AlterColumnNullableChange sets or drops the NOT NULL constraint of a column.
+	AlterColumnNullableChange
+	// AlterColumnDefaultChange sets or drops the default value of a column.
+	AlterColumnDefaultChange
+	// CreateIndexChange creates an index which the database does not have.
+	CreateIndexChange
+	// AddForeignKeyChange adds a foreign key constraint which the database does not have.
+	AddForeignKeyChange
+	// DropColumnChange drops a column which the code no longer declares.
+	DropColumnChange
+)
+
+var schemaChangeKindText = map[SchemaChangeKind]string{
+	DropForeignKeyChange:      "drop foreign key",
+	DropIndexChange:           "drop index",
+	CreateTableChange:         "create table",
+	AddColumnChange:           "add column",
+	AlterColumnTypeChange:     "alter column type",
+	AlterColumnNullableChange: "alter column nullability",
+	AlterColumnDefaultChange:  "alter column default",
+	CreateIndexChange:         "create index",
+	AddForeignKeyChange:       "add foreign key",
+	DropColumnChange:          "drop column",
+}
+
+// String returns the text representation of the kind, e.g. "add column".
+func (k SchemaChangeKind) String() string {
+	return schemaChangeKindText[k]
+}
+
+// SchemaChange is a difference between the registered schema and the database, see DiffSchema.
+type SchemaChange struct {
+	// Kind is the kind of the change.
+	Kind SchemaChangeKind
+	// Table is the name of the table.
+	Table string
+	// Name is the name of the column, index or foreign key constraint, empty for a CreateTableChange.
+	Name string
+	// From is the definition of the database, empty for an addition.
+	From string
+	// To is the definition of the code, empty for a removal.
+	To string
+	// SQL is the ';'-terminated statement, or statements, which applies the change.
+	SQL string
+}
+
+// Destructive reports whether the change may lose data: a dropped column or a changed data type.
+func (c SchemaChange) Destructive() bool {
+	return c.Kind == DropColumnChange || c.Kind == AlterColumnTypeChange
+}
+
+// String returns the text representation of the change, e.g. "add column users.email: varchar(255)"
+// or "alter column type users.email: text -> varchar(255)".
+func (c SchemaChange) String() string {
+	s := c.Kind.String() + " " + c.Table
+	if c.Name != "" {
+		s += "." + c.Name
+	}
+
+	switch {
+	case c.From != "" && c.To != "":
+		s += ": " + c.From + " -> " + c.To
+	case c.To != "":
+		s += ": " + c.To
+	case c.From != "":
+		s += ": " + c.From
+	}
+
+	return s
+}
+
+// SchemaChanges is the list of changes DiffSchema returns.
+type SchemaChanges []SchemaChange
+
+// SQL returns the statements of the changes, in order, each one preceded by a comment which describes it.
+// The statements of the destructive changes (see SchemaChange.Destructive) are marked for review.
+func (changes SchemaChanges) SQL() string {
+	var b strings.Builder
+	for i, change := range changes {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		b.WriteString("-- " + change.String() + "\n")
+		if change.Destructive() {
+			b.WriteString("-- WARNING: this change may lose data, review it before applying.\n")
+		}
+		b.WriteString(change.SQL + "\n")
+	}
+
+	return b.String()
+}
+
+// WriteMigration writes the SQL of the changes into a new migration file in dir, named
+// "<UTC timestamp>_<name>.sql", e.g. "20261016150405_add_users_email.sql", which sorts after the
+// existing migrations and which Migrate can apply. It returns the path of the file, or an empty one,
+// without writing anything, if there are no changes. It never overwrites an existing file.
+//
+// Example:
+//
+//	changes, err := db.DiffSchema(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	filename, err := changes.WriteMigration("migrations", "sync_schema")
+func (changes SchemaChanges) WriteMigration(dir, name string) (string, error) {
+	if len(changes) == 0 {
+		return "", nil
+	}
+
+	if name == "" || filepath.Base(name) != name || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("schema diff: invalid migration name: %q", name)
+	}
+
+	filename := filepath.Join(dir, time.Now().UTC().Format("20060102150405")+"_"+name+".sql")
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("schema diff: %w", err)
+	}
+
+	_, err = f.WriteString("-- Generated by DiffSchema: review it before applying.\n\n" + changes.SQL())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Join(fmt.Errorf("schema diff: %w", err), os.Remove(filename))
+	}
+
+	return filename, nil
+}
+
+// DiffSchema compares the registered tables with the database and returns the changes which make
+// the database match the code, the Go structs being the source of truth, in the order they should
+// be applied: missing tables, added, removed and altered columns (data type, nullability and default
+// value), indexes and foreign keys. Unlike CheckSchema, it does not stop at the first difference.
+// See SchemaChanges.WriteMigration to write them into a migration file.
+//
+// The tables of the database which are not registered are not reported, neither are the views,
+// enum types, descriptions, triggers and table-level CHECK and EXCLUDE constraints. The changes are
+// a starting point to review: e.g. a NOT NULL column added to a table with rows requires a default
+// value and a renamed column is reported as a dropped and an added one.
+func (db *DB) DiffSchema(ctx context.Context) (SchemaChanges, error) {
+	tableNames := db.schema.TableNames(desc.TableTypeBase)
+	if len(tableNames) == 0 {
+		return nil, nil // if no tables are defined, there is nothing to compare.
+	}
+
+	tables, err := db.ListTables(ctx, ListTablesOptions{TableNames: tableNames})
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := db.ListIndexes(ctx, tableNames...)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes SchemaChanges
+	for _, tableName := range tableNames {
+		td, err := db.schema.GetByTableName(tableName)
+		if err != nil {
+			return nil, err // this should never happen as we get the table names from the schema.
+		}
+
+		i := slices.IndexFunc(tables, func(table *desc.Table) bool { return table.Name == tableName })
+		if i == -1 {
+			changes = append(changes, SchemaChange{Kind: CreateTableChange, Table: tableName, SQL: desc.BuildCreateTableQuery(td)})
+			for _, fk := range td.ForeignKeys() {
+				changes = append(changes, addForeignKeyChange(tableName, fk))
+			}
+
+			continue
+		}
+
+		changes = append(changes, diffColumns(td, tables[i])...)
+		changes = append(changes, diffIndexes(td, indexes)...)
+	}
+
+	// The kinds are in the order the changes should be applied.
+	slices.SortStableFunc(changes, func(a, b SchemaChange) int { return int(a.Kind) - int(b.Kind) })
+	return changes, nil
+}
+
+// diffColumns returns the column and foreign key changes of a registered table which exists in the database.
+func diffColumns(td, table *desc.Table) SchemaChanges {
+	var (
+		changes    SchemaChanges
+		quotedName = QuoteIdentifier(td.Name)
+	)
+	for _, column := range td.ListColumnsWithoutPresenter() {
+		quotedColumn := QuoteIdentifier(column.Name)
+
+		col := table.GetColumnByName(column.Name)
+		if col == nil {
+			changes = append(changes, SchemaChange{
+				Kind:  AddColumnChange,
+				Table: td.Name,
+				Name:  column.Name,
+				To:    desc.BuildColumnType(column),
+				SQL:   fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", quotedName, desc.BuildColumnDefinition(column)),
+			})
+		} else {
+			if from, to := desc.BuildColumnType(col), desc.BuildColumnType(column); !columnTypesEqual(col, column) {
+				changes = append(changes, SchemaChange{
+					Kind:  AlterColumnTypeChange,
+					Table: td.Name,
+					Name:  column.Name,
+					From:  from,
+					To:    to,
+					SQL:   fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;", quotedName, quotedColumn, to, quotedColumn, to),
+				})
+			}
+
+			if col.Nullable != column.Nullable {
+				change := SchemaChange{Kind: AlterColumnNullableChange, Table: td.Name, Name: column.Name, From: "NOT NULL", To: "NULL"}
+				change.SQL = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;", quotedName, quotedColumn)
+				if !column.Nullable {
+					change.From, change.To = change.To, change.From
+					change.SQL = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", quotedName, quotedColumn)
+				}
+
+				changes = append(changes, change)
+			}
+
+			if from, to := columnDefault(col), columnDefault(column); from != to && comparableDefaults(col, column) {
+				change := SchemaChange{Kind: AlterColumnDefaultChange, Table: td.Name, Name: column.Name, From: from, To: to}
+				if to == "" {
+					change.SQL = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", quotedName, quotedColumn)
+				} else {
+					change.SQL = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s;", quotedName, quotedColumn, column.Default)
+				}
+
+				changes = append(changes, change)
+			}
+		}
+
+		changes = append(changes, diffForeignKey(td.Name, col, column)...)
+	}
+
+	for _, col := range table.Columns {
+		if column := td.GetColumnByName(col.Name); column == nil || column.Presenter {
+			changes = append(changes, SchemaChange{
+				Kind:  DropColumnChange,
+				Table: td.Name,
+				Name:  col.Name,
+				From:  desc.BuildColumnType(col),
+				SQL:   fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", quotedName, QuoteIdentifier(col.Name)),
+			})
+		}
+	}
+
+	return changes
+}
+
+// columnTypesEqual reports whether the database and the code columns have the same data type.
+// A serial column is an integer one with a sequence, and the type argument of the code column,
+// e.g. 255 of varchar(255), is compared only if it is declared.
+func columnTypesEqual(dbColumn, codeColumn *desc.Column) bool {
+	if !strings.EqualFold(desc.BuildColumnType(&desc.Column{Type: serialType(dbColumn.Type), Enum: dbColumn.Enum}),
+		desc.BuildColumnType(&desc.Column{Type: serialType(codeColumn.Type), Enum: codeColumn.Enum})) {
+		return false
+	}
+
+	return codeColumn.TypeArgument == "" || strings.EqualFold(dbColumn.TypeArgument, codeColumn.TypeArgument)
+}
+
+// serialType returns the integer type of a serial one, e.g. integer for serial.
+func serialType(t desc.DataType) desc.DataType {
+	switch t {
+	case desc.SmallSerial:
+		return desc.SmallInt
+	case desc.Serial:
+		return desc.Integer
+	case desc.BigSerial:
+		return desc.BigInt
+	default:
+		return t
+	}
+}
+
+// columnDefault returns the default value of the column to compare, see desc.Column.DefaultWithoutCast.
+func columnDefault(c *desc.Column) string {
+	defaultValue := strings.TrimSpace(c.DefaultWithoutCast())
+	if strings.EqualFold(defaultValue, "null") {
+		return ""
+	}
+
+	return strings.ToLower(defaultValue)
+}
+
+// comparableDefaults reports whether the default values of the columns are declared by them:
+// the identity, generated and serial columns have a default value of the database.
+func comparableDefaults(dbColumn, codeColumn *desc.Column) bool {
+	return !dbColumn.Identity && !codeColumn.Identity &&
+		dbColumn.GeneratedExpression == "" && codeColumn.GeneratedExpression == "" &&
+		!strings.HasPrefix(dbColumn.Default, "nextval(")
+}
+
+// diffForeignKey returns the foreign key changes of a column, dbColumn is nil if the column is added.
+func diffForeignKey(tableName string, dbColumn, codeColumn *desc.Column) SchemaChanges {
+	var from, to string
+	if dbColumn != nil && dbColumn.ReferenceTableName != "" {
+		from = foreignKeyString(dbColumn)
+	}
+	if codeColumn.ReferenceTableName != "" {
+		to = foreignKeyString(codeColumn)
+	}
+
+	if strings.EqualFold(from, to) {
+		return nil
+	}
+
+	var changes SchemaChanges
+	if from != "" {
+		name := desc.ForeignKeyConstraintName(tableName, codeColumn.Name)
+		changes = append(changes, SchemaChange{
+			Kind:  DropForeignKeyChange,
+			Table: tableName,
+			Name:  name,
+			From:  from,
+			SQL:   fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", QuoteIdentifier(tableName), QuoteIdentifier(name)),
+		})
+	}
+
+	if to != "" {
+		changes = append(changes, addForeignKeyChange(tableName, desc.ForeignKeyConstraint{
+			ColumnName:          codeColumn.Name,
+			ReferenceTableName:  codeColumn.ReferenceTableName,
+			ReferenceColumnName: codeColumn.ReferenceColumnName,
+			OnDelete:            codeColumn.ReferenceOnDelete,
+			Deferrable:          codeColumn.DeferrableReference,
+		}))
+	}
+
+	return changes
+}
+
+// foreignKeyString returns the text representation of the foreign key of a column,
+// e.g. "REFERENCES blogs (id) ON DELETE CASCADE".
+func foreignKeyString(c *desc.Column) string {
+	s := fmt.Sprintf("REFERENCES %s (%s) ON DELETE %s", c.ReferenceTableName, c.ReferenceColumnName, c.ReferenceOnDelete)
+	if c.DeferrableReference {
+		s += " DEFERRABLE"
+	}
+
+	return s
+}
+
+// addForeignKeyChange returns the change which adds a foreign key constraint.
+func addForeignKeyChange(tableName string, fk desc.ForeignKeyConstraint) SchemaChange {
+	return SchemaChange{
+		Kind:  AddForeignKeyChange,
+		Table: tableName,
+		Name:  desc.ForeignKeyConstraintName(tableName, fk.ColumnName),
+		To:    fmt.Sprintf("REFERENCES %s (%s) ON DELETE %s", fk.ReferenceTableName, fk.ReferenceColumnName, fk.OnDelete),
+		SQL:   desc.BuildAddForeignKeyQuery(tableName, fk),
+	}
+}
+
+// diffIndexes returns the index changes of a registered table which exists in the database: the column-level
+// indexes (the index struct tag option) and the table-level ones, see ListIndexes.
+func diffIndexes(td *desc.Table, dbIndexes []*desc.TableIndex) SchemaChanges {
+	var codeIndexes []*desc.TableIndex
+	for _, idx := range td.Indexes() {
+		if column := td.GetColumnByName(idx.ColumnName); column == nil || column.PrimaryKey || column.Unique {
+			continue // postgres manages these, see ListColumns.
+		}
+
+		codeIndexes = append(codeIndexes, &desc.TableIndex{TableName: td.Name, Name: idx.Name, Columns: []string{idx.ColumnName}, Method: idx.Type})
+	}
+	codeIndexes = append(codeIndexes, td.TableIndexes...)
+
+	var changes SchemaChanges
+	dropIndex := func(idx *desc.TableIndex) {
+		changes = append(changes, SchemaChange{
+			Kind:  DropIndexChange,
+			Table: td.Name,
+			Name:  idx.Name,
+			From:  idx.String(),
+			SQL:   "DROP INDEX IF EXISTS " + QuoteIdentifier(idx.Name) + ";",
+		})
+	}
+
+	for _, idx := range codeIndexes {
+		i := slices.IndexFunc(dbIndexes, func(dbIndex *desc.TableIndex) bool {
+			return dbIndex.TableName == idx.TableName && dbIndex.Name == idx.Name
+		})
+		if i != -1 && dbIndexes[i].Equal(idx) {
+			continue
+		}
+
+		if i != -1 {
+			dropIndex(dbIndexes[i])
+		}
+
+		changes = append(changes, SchemaChange{Kind: CreateIndexChange, Table: td.Name, Name: idx.Name, To: idx.String(), SQL: desc.BuildCreateTableIndexQuery(idx)})
+	}
+
+	for _, dbIndex := range dbIndexes {
+		if dbIndex.TableName == td.Name && !slices.ContainsFunc(codeIndexes, func(idx *desc.TableIndex) bool { return idx.Name == dbIndex.Name }) {
+			dropIndex(dbIndex)
+		}
+	}
+
+	return changes
+}
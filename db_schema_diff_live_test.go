@@ -0,0 +1,89 @@
+package pg
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestDiffSchema' -v .
+
+type schemaDiffLiveBlog struct {
+	ID    int64  `pg:"type=bigserial,primary"`
+	Title string `pg:"type=text"`
+}
+
+type schemaDiffLivePost struct {
+	ID     int64  `pg:"type=bigserial,primary"`
+	BlogID int64  `pg:"type=bigint,ref=test_diff_blogs(id CASCADE)"`
+	Title  string `pg:"type=varchar(255),index=btree"`
+	Status string `pg:"type=text,default='draft'"`
+	Body   string `pg:"type=text,nullable"`
+}
+
+func TestDiffSchema(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("test_diff_blogs", schemaDiffLiveBlog{})
+	schema.MustRegister("test_diff_posts", schemaDiffLivePost{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tables := []string{"test_diff_posts", "test_diff_blogs", "test_diff_migrations"}
+	dropTestTables(ctx, db, tables...)
+	defer dropTestTables(ctx, db, tables...)
+
+	// an earlier version of the posts table, the blogs table does not exist yet.
+	if _, err = db.Exec(ctx, `CREATE TABLE test_diff_posts (id bigserial PRIMARY KEY, blog_id bigint NOT NULL, title text NOT NULL,
+status text NOT NULL DEFAULT 'published', body text NOT NULL, legacy text);`); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := db.DiffSchema(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.Kind.String()+" "+change.Table+"."+change.Name)
+	}
+
+	expected := []string{
+		"create table test_diff_blogs.",
+		"alter column type test_diff_posts.title",
+		"alter column nullability test_diff_posts.body",
+		"alter column default test_diff_posts.status",
+		"create index test_diff_posts.test_diff_posts_title_idx",
+		"add foreign key test_diff_posts.test_diff_posts_blog_id_fkey",
+		"drop column test_diff_posts.legacy",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected changes:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	dir := t.TempDir()
+	if _, err = changes.WriteMigration(dir, "sync_schema"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Migrate(ctx, os.DirFS(dir), &MigrateOptions{TableName: "test_diff_migrations"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes, err = db.DiffSchema(ctx); err != nil || len(changes) != 0 {
+		t.Fatalf("expected no changes after the migration but got: %v: %v", changes, err)
+	}
+
+	if err = db.CheckSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
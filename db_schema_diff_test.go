@@ -0,0 +1,107 @@
+package pg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kataras/pg/desc"
+)
+
+func TestDiffColumnsAndIndexes(t *testing.T) {
+	td := &desc.Table{Name: "users"}
+	td.AddColumns(
+		&desc.Column{Name: "id", Type: desc.BigSerial, PrimaryKey: true},
+		&desc.Column{Name: "email", Type: desc.CharacterVarying, TypeArgument: "255", Index: desc.Btree},
+		&desc.Column{Name: "name", Type: desc.Text, Nullable: true},
+		&desc.Column{Name: "status", Type: desc.Text, Default: "'active'"},
+		&desc.Column{Name: "blog_id", Type: desc.BigInt, ReferenceTableName: "blogs", ReferenceColumnName: "id", ReferenceOnDelete: "CASCADE"},
+	)
+
+	table := &desc.Table{Name: "users"}
+	table.AddColumns(
+		&desc.Column{Name: "id", Type: desc.BigInt, PrimaryKey: true, Default: "nextval('users_id_seq'::regclass)"},
+		&desc.Column{Name: "email", Type: desc.Text},
+		&desc.Column{Name: "name", Type: desc.Text},
+		&desc.Column{Name: "status", Type: desc.Text, Default: "'pending'::text"},
+		&desc.Column{Name: "legacy", Type: desc.Text, Nullable: true},
+	)
+
+	dbIndexes := []*desc.TableIndex{
+		{TableName: "users", Name: "users_legacy_idx", Columns: []string{"legacy"}, Method: desc.Btree},
+		{TableName: "blogs", Name: "blogs_title_idx", Columns: []string{"title"}, Method: desc.Btree},
+	}
+
+	changes := append(diffColumns(td, table), diffIndexes(td, dbIndexes)...)
+
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+
+	expected := []string{
+		"alter column type users.email: text -> varchar(255)",
+		"alter column nullability users.name: NOT NULL -> NULL",
+		"alter column default users.status: 'pending' -> 'active'",
+		"add column users.blog_id: bigint",
+		"add foreign key users.users_blog_id_fkey: REFERENCES blogs (id) ON DELETE CASCADE",
+		"drop column users.legacy: text",
+		"create index users.users_email_idx: CREATE INDEX IF NOT EXISTS users_email_idx ON users USING btree (\"email\");",
+		"drop index users.users_legacy_idx: CREATE INDEX IF NOT EXISTS users_legacy_idx ON users USING btree (\"legacy\");",
+	}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("expected changes:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	if expected, got := `ALTER TABLE "users" ALTER COLUMN "status" SET DEFAULT 'active';`, changes[2].SQL; got != expected {
+		t.Fatalf("expected SQL %q but got: %q", expected, got)
+	}
+
+	if expected, got := `ALTER TABLE "users" ADD COLUMN "blog_id" bigint NOT NULL;`, changes[3].SQL; got != expected {
+		t.Fatalf("expected SQL %q but got: %q", expected, got)
+	}
+}
+
+func TestSchemaChangesWriteMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	if filename, err := SchemaChanges(nil).WriteMigration(dir, "empty"); err != nil || filename != "" {
+		t.Fatalf("expected no file for no changes but got: %q: %v", filename, err)
+	}
+
+	changes := SchemaChanges{
+		{Kind: DropColumnChange, Table: "users", Name: "legacy", From: "text", SQL: `ALTER TABLE "users" DROP COLUMN "legacy";`},
+		{Kind: AddColumnChange, Table: "users", Name: "email", To: "text", SQL: `ALTER TABLE "users" ADD COLUMN "email" text NOT NULL;`},
+	}
+
+	if _, err := changes.WriteMigration(dir, "../escape"); err == nil {
+		t.Fatal("expected an error for a migration name with a path separator")
+	}
+
+	filename, err := changes.WriteMigration(dir, "sync_users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base := filepath.Base(filename); len(base) != len("20060102150405_sync_users.sql") || !strings.HasSuffix(base, "_sync_users.sql") {
+		t.Fatalf("unexpected migration filename: %s", base)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "-- Generated by DiffSchema: review it before applying.\n\n" +
+		"-- drop column users.legacy: text\n" +
+		"-- WARNING: this change may lose data, review it before applying.\n" +
+		"ALTER TABLE \"users\" DROP COLUMN \"legacy\";\n" +
+		"\n" +
+		"-- add column users.email: text\n" +
+		"ALTER TABLE \"users\" ADD COLUMN \"email\" text NOT NULL;\n"
+	if got := string(data); got != expected {
+		t.Fatalf("expected file contents:\n%s\nbut got:\n%s", expected, got)
+	}
+}
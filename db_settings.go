@@ -0,0 +1,109 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// This file sets the run-time parameters which carry values of the context to the database,
+// for the SQL which reads them with current_setting, e.g. the row-level security policies of
+// Schema.TenantRowLevelSecurity (TenantSetting) and the history triggers of Audited (ActorSetting).
+// A transaction sets them with SET LOCAL semantics when it begins (see setLocalSettings),
+// the queries outside of a transaction by the PrepareConn hooks which Open installs (see installSettingHook).
+
+// contextSettings returns the names and the values of the run-time parameters of the context.
+func (db *DB) contextSettings(ctx context.Context) (names, values []string) {
+	if db.schema.TenantRowLevelSecurity {
+		if id, ok := TenantFromContext(ctx); ok {
+			names, values = append(names, TenantSetting), append(values, fmt.Sprint(id))
+		}
+	}
+
+	if actor, ok := ActorFromContext(ctx); ok {
+		names, values = append(names, ActorSetting), append(values, actor)
+	}
+
+	return
+}
+
+// setLocalSettings sets the run-time parameters of the context (see contextSettings)
+// for the rest of a new transaction, in a single round trip.
+func (db *DB) setLocalSettings(ctx context.Context, tx pgx.Tx) error {
+	names, values := db.contextSettings(ctx)
+	if len(names) == 0 {
+		return nil
+	}
+
+	calls := make([]string, len(names))
+	args := make([]any, 0, 2*len(names))
+	for i := range names {
+		calls[i] = fmt.Sprintf("set_config($%d, $%d, true)", 2*i+1, 2*i+2)
+		args = append(args, names[i], values[i])
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT "+strings.Join(calls, ", ")+";", args...); err != nil {
+		return fmt.Errorf("pg: set %s: %w", strings.Join(names, ", "), err)
+	}
+
+	return nil
+}
+
+// installSettingHook installs the PrepareConn hook of the pool which sets the given run-time parameter
+// of a connection to the value the valueOf function returns for the context of the query when it is
+// acquired, or clears it when that is empty. The value a connection was set to is kept under
+// customDataKey in its pgconn.PgConn.CustomData map, so the parameter is set only when it changes.
+// The previous PrepareConn hook is called first.
+func installSettingHook(config *pgxpool.Config, name, customDataKey string, valueOf func(context.Context) string) {
+	prepareConn := config.PrepareConn
+
+	config.PrepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+		if prepareConn != nil {
+			if ok, err := prepareConn(ctx, conn); !ok || err != nil {
+				return ok, err
+			}
+		}
+
+		value := valueOf(ctx)
+
+		data := conn.PgConn().CustomData()
+		if current, _ := data[customDataKey].(string); current == value {
+			return true, nil
+		}
+
+		if _, err := conn.Exec(ctx, `SELECT set_config($1, $2, false);`, name, value); err != nil {
+			// destroy the connection, its setting is unknown.
+			return false, fmt.Errorf("pg: set %s: %w", name, err)
+		}
+
+		if value == "" {
+			delete(data, customDataKey)
+		} else {
+			data[customDataKey] = value
+		}
+
+		return true, nil
+	}
+}
+
+// installSettingHooks installs the setting hooks (see installSettingHook) of the run-time parameters
+// of contextSettings to the config of a pool opened by Open.
+func installSettingHooks(schema *Schema, config *pgxpool.Config) {
+	if schema.TenantRowLevelSecurity {
+		installSettingHook(config, TenantSetting, "pg.tenant_id", func(ctx context.Context) string {
+			if id, ok := TenantFromContext(ctx); ok {
+				return fmt.Sprint(id)
+			}
+
+			return ""
+		})
+	}
+
+	installSettingHook(config, ActorSetting, "pg.actor", func(ctx context.Context) string {
+		actor, _ := ActorFromContext(ctx)
+		return actor
+	})
+}
@@ -1,6 +1,10 @@
 package pg
 
-import "time"
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
 
 // PoolStat holds the database pool's statistics.
 type PoolStat struct {
@@ -29,12 +33,29 @@ type PoolStat struct {
 	// The value is the sum of ConstructingConns, AcquiredConns, and
 	// IdleConns.
 	TotalConns int32 `json:"total_conns"`
+
+	// Replicas are the statistics of the pools of the read replicas, in the order
+	// of their WithReadReplicas connection strings.
+	Replicas []PoolStat `json:"replicas,omitempty"`
 }
 
 // PoolStat returns a snapshot of the database pool statistics.
 // The returned structure can be represented through JSON.
 func (db *DB) PoolStat() PoolStat {
-	stats := db.Pool.Stat()
+	stat := poolStat(db.Pool)
+
+	if db.replicas != nil {
+		stat.Replicas = make([]PoolStat, 0, len(db.replicas.pools))
+		for _, pool := range db.replicas.pools {
+			stat.Replicas = append(stat.Replicas, poolStat(pool))
+		}
+	}
+
+	return stat
+}
+
+func poolStat(pool *pgxpool.Pool) PoolStat {
+	stats := pool.Stat()
 	return PoolStat{
 		AcquireCount:         stats.AcquireCount(),
 		AcquireDuration:      stats.AcquireDuration(),
@@ -0,0 +1,204 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kataras/pg/desc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// This file adds schema-per-tenant multitenancy. Every tenant has its own PostgreSQL schema,
+// named TenantSchemaPrefix + the tenant name (e.g. "tenant_acme"), holding its own copy of the
+// registered tables, and all tenants share the connection pool(s) of the DB returned by Open.
+//
+// DB.ForTenant returns a *DB whose queries see the tenant's schema: the queries it builds itself
+// are qualified with the tenant's schema and every connection it acquires from the pool has its
+// search_path set to the tenant's schema (followed by the DB's own one) for the duration of the
+// query, so the raw SQL of the caller resolves unqualified table names to the tenant's tables too.
+// The search_path is set by a pool hook which Open installs, only when a connection switches
+// between tenants; a DB created by OpenPool can not be used for tenants.
+
+// TenantSchemaPrefix is the prefix of the names of the tenant schemas, see DB.ForTenant and DB.ListTenants.
+// It must be set once, before the first call of ForTenant.
+var TenantSchemaPrefix = "tenant_"
+
+// tenantNameRegex matches a valid tenant name. Tenant schema names are embedded unquoted in
+// CREATE SCHEMA (see validateSearchPath), so upper-case letters, which Postgres would fold, are rejected.
+var tenantNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// maxIdentifierLength is the maximum length, in bytes, of a PostgreSQL identifier.
+const maxIdentifierLength = 63
+
+// ForTenant returns a shallow copy of the database bound to the schema of the given tenant,
+// e.g. "tenant_acme" for "acme" (see TenantSchemaPrefix). The name must consist of lower-case
+// letters, digits and underscores.
+//
+// The returned DB shares the pool(s) and the Schema of db: the registered tables are not
+// modified, so any number of tenants can be used concurrently. Its queries run on connections
+// whose search_path is the tenant's schema followed by the search path of db, so
+// shared tables (and extensions) which are not in the tenant's schema are still visible.
+//
+// Provision a new tenant with CreateSchema (or Migrate) on the returned DB and upgrade all of them
+// with ForEachTenant. DeleteSchema drops the tenant's schema.
+//
+// It returns an error if db was not created by Open or it is in a transaction.
+func (db *DB) ForTenant(name string) (*DB, error) {
+	if !db.tenancy {
+		return nil, fmt.Errorf("pg: for tenant: %s: the database was not opened by Open", name)
+	}
+
+	if db.IsTransaction() {
+		return nil, fmt.Errorf("pg: for tenant: %s: the database is in a transaction", name)
+	}
+
+	if !tenantNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("pg: for tenant: invalid name: %q: must match %s", name, tenantNameRegex.String())
+	}
+
+	schemaName := TenantSchemaPrefix + name
+	if err := validateSearchPath(schemaName); err != nil || len(schemaName) > maxIdentifierLength {
+		return nil, fmt.Errorf("pg: for tenant: invalid schema name: %q", schemaName)
+	}
+
+	sharedSearchPath := db.searchPath
+	if db.tenant != "" {
+		sharedSearchPath = db.sharedSearchPath
+	}
+
+	tenantDB := db.clone(nil)
+	tenantDB.searchPath = schemaName
+	tenantDB.tenant = name
+	tenantDB.sharedSearchPath = sharedSearchPath
+	tenantDB.tenantSearchPath = QuoteIdentifier(schemaName) + ", " + QuoteIdentifier(sharedSearchPath)
+	return tenantDB, nil
+}
+
+// Tenant returns the tenant name of a DB returned by ForTenant, or an empty string.
+func (db *DB) Tenant() string {
+	return db.tenant
+}
+
+// ListTenants returns the names of the tenants whose schema exists, i.e. the names of the schemas
+// which start with TenantSchemaPrefix without it, sorted by name.
+func (db *DB) ListTenants(ctx context.Context) ([]string, error) {
+	query := `SELECT nspname FROM pg_catalog.pg_namespace WHERE starts_with(nspname, $1) ORDER BY nspname;`
+	schemaNames, err := db.QuerySlice[string](ctx, query, TenantSchemaPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("pg: list tenants: %w", err)
+	}
+
+	names := make([]string, 0, len(schemaNames))
+	for _, schemaName := range schemaNames {
+		names = append(names, strings.TrimPrefix(schemaName, TenantSchemaPrefix))
+	}
+
+	return names, nil
+}
+
+// ForEachTenant calls fn with the DB of each tenant of ListTenants, one after the other,
+// and stops at the first error. It is the way to upgrade every tenant schema, e.g.
+//
+//	err := db.ForEachTenant(ctx, func(tenant *pg.DB) error {
+//		_, err := tenant.Migrate(ctx, migrations, nil)
+//		return err
+//	})
+func (db *DB) ForEachTenant(ctx context.Context, fn func(tenant *DB) error) error {
+	names, err := db.ListTenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		tenantDB, err := db.ForTenant(name)
+		if err != nil {
+			return err
+		}
+
+		if err = fn(tenantDB); err != nil {
+			return fmt.Errorf("tenant: %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// tenantTable returns the table definition the query builders of the desc package, which qualify
+// the table name with its SearchPath field, should use: a copy of td bound to the tenant's schema
+// for a tenant, td itself otherwise.
+func (db *DB) tenantTable(td *desc.Table) *desc.Table {
+	if db.tenant == "" {
+		return td
+	}
+
+	tenantTd := *td
+	tenantTd.SearchPath = db.searchPath
+	return &tenantTd
+}
+
+type searchPathContextKey struct{}
+
+// poolContext returns the context of a query which acquires a connection from a pool:
+// for a tenant, a context which makes the search_path hook (see installSearchPathHook)
+// set the connection's search_path to the tenant's one.
+func (db *DB) poolContext(ctx context.Context) context.Context {
+	if db.tenant == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, searchPathContextKey{}, db.tenantSearchPath)
+}
+
+// searchPathCustomDataKey is the key of the search_path a connection was set to
+// in its pgconn.PgConn.CustomData map, absent for its default one.
+const searchPathCustomDataKey = "pg.search_path"
+
+// installSearchPathHook installs the PrepareConn hook of the pool which sets the search_path of
+// a connection to the one of the context (see poolContext) when it is acquired, or resets it to
+// its default one when the context has none. The previous PrepareConn (or BeforeAcquire) hook is called first.
+func installSearchPathHook(config *pgxpool.Config) {
+	prepareConn := config.PrepareConn
+	if prepareConn == nil && config.BeforeAcquire != nil {
+		beforeAcquire := config.BeforeAcquire
+		prepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+			return beforeAcquire(ctx, conn), nil
+		}
+	}
+
+	config.PrepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+		if prepareConn != nil {
+			if ok, err := prepareConn(ctx, conn); !ok || err != nil {
+				return ok, err
+			}
+		}
+
+		searchPath, _ := ctx.Value(searchPathContextKey{}).(string)
+
+		data := conn.PgConn().CustomData()
+		if current, _ := data[searchPathCustomDataKey].(string); current == searchPath {
+			return true, nil
+		}
+
+		query := "RESET search_path"
+		if searchPath != "" {
+			query = "SET search_path TO " + searchPath
+		}
+
+		if _, err := conn.Exec(ctx, query); err != nil {
+			// destroy the connection, its search_path is unknown.
+			return false, fmt.Errorf("pg: set search_path: %w", err)
+		}
+
+		if searchPath == "" {
+			delete(data, searchPathCustomDataKey)
+		} else {
+			data[searchPathCustomDataKey] = searchPath
+		}
+
+		return true, nil
+	}
+}
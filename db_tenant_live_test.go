@@ -0,0 +1,101 @@
+package pg
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestDBForTenant' -v .
+
+type tenantLiveItem struct {
+	ID   int64  `pg:"type=bigserial,primary"`
+	Name string `pg:"type=varchar(255)"`
+}
+
+const tenantScratchTable = "test_tenant_items"
+
+func TestDBForTenant(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(tenantScratchTable, tenantLiveItem{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	tenants := make(map[string]*DB)
+	for _, name := range []string{"test_acme", "test_globex"} {
+		tenant, err := db.ForTenant(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = tenant.DeleteSchema(ctx)
+		defer tenant.DeleteSchema(ctx)
+
+		if err = tenant.CreateSchema(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		tenants[name] = tenant
+	}
+
+	for name, tenant := range tenants {
+		repo := NewRepository[tenantLiveItem](tenant)
+		item := tenantLiveItem{Name: name}
+		if err = repo.InsertSingle(ctx, item, &item.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the raw SQL of a tenant resolves unqualified table names to the tenant's tables.
+	for name, tenant := range tenants {
+		names, err := tenant.QuerySlice[string](ctx, "SELECT name FROM "+tenantScratchTable)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(names, []string{name}) {
+			t.Fatalf("[%s] expected the tenant's row only but got: %v", name, names)
+		}
+	}
+
+	// the shared pool's connections are reset to the default search_path.
+	if _, err = db.QuerySlice[string](ctx, "SELECT name FROM "+tenantScratchTable); err == nil {
+		t.Fatal("expected the table to be missing from the shared schema")
+	}
+
+	names, err := db.ListTenants(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range tenants {
+		if !slices.Contains(names, name) {
+			t.Fatalf("expected tenant %s in: %v", name, names)
+		}
+	}
+
+	migrations := fstest.MapFS{
+		"0001_add_column.sql": {Data: []byte("ALTER TABLE " + tenantScratchTable + " ADD COLUMN note text;")},
+	}
+
+	for name, tenant := range tenants {
+		applied, err := tenant.Migrate(ctx, migrations, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(applied) != 1 {
+			t.Fatalf("[%s] expected the migration to be applied once per tenant but got: %v", name, applied)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestForTenant(t *testing.T) {
+	type tenantItem struct {
+		ID   string `pg:"type=uuid,primary,default=gen_random_uuid()"`
+		Name string `pg:"type=varchar(255)"`
+	}
+
+	schema := NewSchema()
+	schema.MustRegister("items", tenantItem{})
+	td, err := schema.GetByTableName("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = (&DB{schema: schema, searchPath: "public"}).ForTenant("acme"); err == nil {
+		t.Fatal("expected an error for a database not opened by Open")
+	}
+
+	db := &DB{schema: schema, searchPath: "public", tenancy: true}
+	for _, name := range []string{"", "Acme", "acme-corp", "acme;drop", `acme"`, string(make([]byte, 64))} {
+		if _, err = db.ForTenant(name); err == nil {
+			t.Fatalf("expected an error for the tenant name %q", name)
+		}
+	}
+
+	acme, err := db.ForTenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := acme.Tenant(); got != "acme" {
+		t.Fatalf("expected tenant acme but got: %q", got)
+	}
+
+	if got := acme.SearchPath(); got != "tenant_acme" {
+		t.Fatalf("expected search path tenant_acme but got: %q", got)
+	}
+
+	if expected, got := `"tenant_acme", "public"`, acme.tenantSearchPath; got != expected {
+		t.Fatalf("expected connection search_path %s but got: %s", expected, got)
+	}
+
+	// a tenant of a tenant falls back to the shared schema of the root database.
+	globex, err := acme.ForTenant("globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := `"tenant_globex", "public"`, globex.tenantSearchPath; got != expected {
+		t.Fatalf("expected connection search_path %s but got: %s", expected, got)
+	}
+
+	if got := db.tenantTable(td); got != td {
+		t.Fatal("expected the registered table for the root database")
+	}
+
+	tenantTd := acme.tenantTable(td)
+	if tenantTd == td || tenantTd.SearchPath != "tenant_acme" {
+		t.Fatalf("expected a copy of the table bound to tenant_acme but got: %q", tenantTd.SearchPath)
+	}
+
+	if td.SearchPath != "public" {
+		t.Fatalf("the registered table was modified: %q", td.SearchPath)
+	}
+
+	ctx := context.Background()
+	if searchPath, _ := acme.poolContext(ctx).Value(searchPathContextKey{}).(string); searchPath != acme.tenantSearchPath {
+		t.Fatalf("expected the tenant's search_path in the pool context but got: %q", searchPath)
+	}
+
+	if db.poolContext(ctx) != ctx {
+		t.Fatal("expected the same context for the root database")
+	}
+
+	var b strings.Builder
+	if err = acme.createExtensionsDump(ctx, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := `CREATE EXTENSION IF NOT EXISTS pgcrypto SCHEMA "public";`, b.String(); got != expected {
+		t.Fatalf("expected the extensions in the shared schema:\n%s\nbut got:\n%s", expected, got)
+	}
+}
@@ -10,22 +10,32 @@ func BuildAlterTableForeignKeysQueries(td *Table) []string {
 	queries := make([]string, 0, len(foreignKeys))
 
 	for _, fk := range foreignKeys {
-		constraintName := fmt.Sprintf("%s_%s_fkey", td.Name, fk.ColumnName)
+		constraintName := ForeignKeyConstraintName(td.Name, fk.ColumnName)
 
 		dropQuery := fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;`, td.Name, constraintName)
 		queries = append(queries, dropQuery)
+		queries = append(queries, BuildAddForeignKeyQuery(td.Name, fk))
+	}
+
+	return queries
+}
 
-		q := fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s`,
-			td.Name, constraintName, fk.ColumnName, fk.ReferenceTableName, fk.ReferenceColumnName, fk.OnDelete)
+// ForeignKeyConstraintName returns the name of the foreign key constraint of the given column,
+// "<table>_<column>_fkey", the one PostgreSQL would generate.
+func ForeignKeyConstraintName(tableName, columnName string) string {
+	return fmt.Sprintf("%s_%s_fkey", tableName, columnName)
+}
 
-		// Add the DEFERRABLE option if applicable
-		if fk.Deferrable {
-			q += " DEFERRABLE"
-		}
+// BuildAddForeignKeyQuery creates the ALTER TABLE query which adds the given foreign key constraint
+// to the table, named by ForeignKeyConstraintName.
+func BuildAddForeignKeyQuery(tableName string, fk ForeignKeyConstraint) string {
+	q := fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s`,
+		tableName, ForeignKeyConstraintName(tableName, fk.ColumnName), fk.ColumnName, fk.ReferenceTableName, fk.ReferenceColumnName, fk.OnDelete)
 
-		q += ";"
-		queries = append(queries, q)
+	// Add the DEFERRABLE option if applicable
+	if fk.Deferrable {
+		q += " DEFERRABLE"
 	}
 
-	return queries
+	return q + ";"
 }
@@ -22,7 +22,7 @@ type (
 
 		Name            string       // the name of the column
 		Type            DataType     // the data type of the column
-		Description     string       // the description of the column
+		Description     string       // the description (comment) of the column, e.g. from the `desc` tag option
 		OrdinalPosition int          // the position (starting from 1) of the corresponding column in the table.
 		FieldIndex      []int        // the index of the corresponding struct field
 		FieldType       reflect.Type // the reflect.Type of the corresponding struct field
@@ -69,6 +69,21 @@ type (
 		GeneratedExpression string
 		// If true then this column->struct value is skipped from the Select queries
 		Unscannable bool // a flag that indicates if the column is unscannable
+		// If true then delete queries set this (nullable, time) column to now() instead of removing the row,
+		// and the repository's select queries hide rows where this column is not null.
+		// E.g. pg:"type=timestamp,soft_delete"
+		SoftDelete bool
+		// If true then this (integer) column is used for optimistic locking: update queries
+		// match the row by its current value too and increment it by one.
+		// E.g. pg:"type=int,version"
+		Version bool
+		// If true then this column holds the tenant of the row for row-level multitenancy:
+		// update queries match the row by its value too and never update it.
+		// E.g. pg:"type=bigint,tenant"
+		Tenant bool
+		// The enum type of the column, if Type is Enumerated.
+		// E.g. pg:"type=status", where status is declared by a Go type's Values method or by Schema.RegisterEnum.
+		Enum *Enum
 
 		// If true  then this column-> struct field type is already implements a scanner interface for the table.
 		isScanner bool
@@ -128,6 +143,27 @@ func writeTagProp(w io.StringWriter, key string, value any) {
 	_, _ = w.WriteString(fmt.Sprintf(key, value))
 }
 
+// DefaultWithoutCast returns the default value of the column without the type cast
+// the database reports it with, e.g. "'{}'" for "'{}'::integer[]" or "'draft'" for "'draft'::status".
+func (c *Column) DefaultWithoutCast() string {
+	defaultValue := c.Default
+
+	// E.g. {}::integer[], we need to cut the ::integer[] part as it's so strict.
+	// Cut {}::integer[] the :: part.
+	if names, ok := dataTypeText[c.Type]; ok {
+		for _, name := range names {
+			defaultValue = strings.TrimSuffix(defaultValue, "::"+name)
+		}
+	}
+
+	if c.Type == Enumerated && c.Enum != nil {
+		// E.g. 'draft'::status.
+		defaultValue = strings.TrimSuffix(defaultValue, "::"+c.Enum.Name)
+	}
+
+	return defaultValue
+}
+
 // FieldTagString returns a string representation of the struct field tag for the column.
 func (c *Column) FieldTagString(strict bool) string {
 	b := new(strings.Builder)
@@ -135,7 +171,7 @@ func (c *Column) FieldTagString(strict bool) string {
 	b.WriteString(`:"`)
 
 	writeTagProp(b, "name=%s", c.Name)
-	writeTagProp(b, ",type=%s", c.Type.String())
+	writeTagProp(b, ",type=%s", c.typeName())
 	if (c.Table != nil && c.Table.Type.IsReadOnly()) || c.TableType.IsReadOnly() {
 		// If it's a view then don't write the rest of the tags, we only care for name and type.
 		b.WriteString(`"`)
@@ -155,13 +191,7 @@ func (c *Column) FieldTagString(strict bool) string {
 	} else {
 		defaultValue := c.Default
 		if !strict {
-			// E.g. {}::integer[], we need to cut the ::integer[] part as it's so strict.
-			// Cut {}::integer[] the :: part.
-			if names, ok := dataTypeText[c.Type]; ok {
-				for _, name := range names {
-					defaultValue = strings.TrimSuffix(defaultValue, "::"+name)
-				}
-			}
+			defaultValue = c.DefaultWithoutCast()
 		}
 
 		writeTagProp(b, ",default=%s", defaultValue)
@@ -205,6 +235,9 @@ func (c *Column) FieldTagString(strict bool) string {
 		writeTagProp(b, ",auto", c.AutoGenerated)
 		writeTagProp(b, ",presenter", c.Presenter)
 		writeTagProp(b, ",unscannable", c.Unscannable)
+		writeTagProp(b, ",soft_delete", c.SoftDelete)
+		writeTagProp(b, ",version", c.Version)
+		writeTagProp(b, ",tenant", c.Tenant)
 	}
 
 	b.WriteString(`"`)
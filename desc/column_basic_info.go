@@ -24,6 +24,8 @@ type ColumnBasicInfo struct {
 	DataType DataType
 	// DataTypeArgument is an optional argument for DataType, e.g. "255" for a varchar(255).
 	DataTypeArgument string
+	// EnumName is the name of the column's enum type, when DataType is Enumerated.
+	EnumName string
 	// IsNullable reports whether the column accepts NULL values.
 	IsNullable bool
 	// IsIdentity reports whether the column is an identity column (GENERATED ALWAYS/BY
@@ -52,6 +54,10 @@ func (c *ColumnBasicInfo) BuildColumn(column *Column) error {
 	column.Default = c.Default
 	column.Type = c.DataType
 	column.TypeArgument = c.DataTypeArgument
+	if c.DataType == Enumerated {
+		// the values are not part of the column information, see DB.ListEnums.
+		column.Enum = &Enum{Name: c.EnumName}
+	}
 	column.Nullable = c.IsNullable
 	// column.Required = !c.IsNullable
 	column.Identity = c.IsIdentity
@@ -0,0 +1,27 @@
+package desc
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BuildCommentQueries creates COMMENT ON TABLE and COMMENT ON COLUMN queries for the table's
+// and its columns' descriptions. Tables and columns without a description are skipped.
+func BuildCommentQueries(td *Table) []string {
+	var queries []string
+
+	if td.Description != "" {
+		queries = append(queries, fmt.Sprintf(`COMMENT ON TABLE %s IS %s;`, td.Name, quoteLiteral(td.Description)))
+	}
+
+	for _, c := range td.ListColumnsWithoutPresenter() {
+		if c.Description == "" {
+			continue
+		}
+
+		queries = append(queries, fmt.Sprintf(`COMMENT ON COLUMN %s.%s IS %s;`, td.Name, pgx.Identifier{c.Name}.Sanitize(), quoteLiteral(c.Description)))
+	}
+
+	return queries
+}
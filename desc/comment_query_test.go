@@ -0,0 +1,35 @@
+package desc
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestBuildCommentQueries(t *testing.T) {
+	type customer struct {
+		ID    int64  `pg:"type=bigserial,primary,desc=The customer's ID."`
+		Email string `pg:"type=varchar(255),unique,comment=The email address, in lowercase"`
+		Name  string `pg:"type=text"`
+	}
+
+	td, err := ConvertStructToTable("customers", reflect.TypeFor[customer]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email := td.GetColumnByName("email"); !email.Unique || email.Description != "The email address, in lowercase" {
+		t.Fatalf("unexpected email column: %#v", email)
+	}
+
+	td.Description = "The customers of the shop"
+
+	expected := []string{
+		`COMMENT ON TABLE customers IS 'The customers of the shop';`,
+		`COMMENT ON COLUMN customers."id" IS 'The customer''s ID.';`,
+		`COMMENT ON COLUMN customers."email" IS 'The email address, in lowercase';`,
+	}
+	if got := BuildCommentQueries(td); !slices.Equal(got, expected) {
+		t.Fatalf("expected comment queries:\n%q\nbut got:\n%q", expected, got)
+	}
+}
@@ -22,6 +22,8 @@ const (
 	CheckConstraintType
 	// IndexConstraintType is a constraint type that represents a simple index constraint.
 	IndexConstraintType // A custom type to represent a simple index, see ListConstraints.
+	// ExclusionConstraintType is a constraint type that represents an exclusion (EXCLUDE) constraint.
+	ExclusionConstraintType
 )
 
 var textToConstraintType = map[string]ConstraintType{
@@ -31,6 +33,7 @@ var textToConstraintType = map[string]ConstraintType{
 	"CHECK":       CheckConstraintType,
 	"FOREIGN KEY": ForeignKeyConstraintType,
 	"INDEX":       IndexConstraintType,
+	"EXCLUDE":     ExclusionConstraintType,
 
 	// contype
 	"p": PrimaryKeyConstraintType,
@@ -38,6 +41,7 @@ var textToConstraintType = map[string]ConstraintType{
 	"c": CheckConstraintType,
 	"f": ForeignKeyConstraintType,
 	"i": IndexConstraintType,
+	"x": ExclusionConstraintType,
 }
 
 // Scan implements the sql.Scanner interface.
@@ -88,6 +92,8 @@ type Constraint struct {
 	Check *CheckConstraint
 	// ForeignKey holds the parsed definition when ConstraintType is ForeignKeyConstraintType.
 	ForeignKey *ForeignKeyConstraint
+	// Exclusion holds the parsed definition when ConstraintType is ExclusionConstraintType.
+	Exclusion *ExclusionConstraint
 	// Primary does not need it, as it's already described by table name and column name fields.
 
 	// rawDefinition holds the original pg_get_constraintdef() output this Constraint was
@@ -127,11 +133,19 @@ func (c *Constraint) String() string {
 		return fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", c.ColumnName, c.ForeignKey.ReferenceTableName, c.ForeignKey.ReferenceColumnName)
 	case IndexConstraintType:
 		return fmt.Sprintf("INDEX (%s)", c.ColumnName)
+	case ExclusionConstraintType:
+		return c.rawDefinition
 	}
 
 	return ""
 }
 
+// Definition returns the original definition the constraint was built from,
+// e.g. the output of the pg_get_constraintdef function.
+func (c *Constraint) Definition() string {
+	return c.rawDefinition
+}
+
 // Build implements the ColumnBuilder interface.
 func (c *Constraint) Build(constraintDefinition string) {
 	c.rawDefinition = constraintDefinition
@@ -147,6 +161,8 @@ func (c *Constraint) Build(constraintDefinition string) {
 		_, _, columnName, indexType := parseSimpleIndexConstraint(constraintDefinition)
 		c.ColumnName = columnName
 		c.IndexType = indexType
+	case ExclusionConstraintType:
+		c.Exclusion = parseExclusionConstraint(constraintDefinition)
 	}
 }
 
@@ -199,6 +215,8 @@ func (c *Constraint) BuildColumn(column *Column) error {
 		column.DeferrableReference = c.ForeignKey.Deferrable
 	case IndexConstraintType:
 		column.Index = c.IndexType
+	case ExclusionConstraintType:
+		// a table-level constraint, see Table.TableConstraints.
 	}
 
 	return nil
@@ -337,3 +355,102 @@ func parseForeignKeyConstraint(constraintDefinition string) *ForeignKeyConstrain
 		Deferrable:          deferrable,
 	}
 }
+
+// ExclusionConstraint is a type that represents an exclusion constraint,
+// e.g. EXCLUDE USING gist (room_id WITH =, during WITH &&) WHERE (NOT cancelled).
+type ExclusionConstraint struct {
+	Method   IndexType          // the index access method, e.g. gist.
+	Elements []ExclusionElement // the elements, <column or expression> WITH <operator>.
+	Where    string             // the predicate of a partial exclusion constraint, if any.
+}
+
+// parseExclusionConstraint parses an exclusion constraint definition.
+func parseExclusionConstraint(constraintDefinition string) *ExclusionConstraint {
+	rest, ok := strings.CutPrefix(constraintDefinition, "EXCLUDE USING ")
+	if !ok {
+		return nil
+	}
+
+	method, rest, ok := strings.Cut(rest, " ")
+	if !ok || !strings.HasPrefix(rest, "(") {
+		return nil
+	}
+
+	end := closingParenIndex(rest)
+	if end == -1 {
+		return nil
+	}
+
+	c := &ExclusionConstraint{Method: parseIndexType(method)}
+	for _, element := range splitTopLevel(rest[1:end], ',') {
+		idx := strings.LastIndex(element, " WITH ")
+		if idx == -1 {
+			return nil
+		}
+
+		c.Elements = append(c.Elements, ExclusionElement{
+			Column:   strings.TrimSpace(element[:idx]),
+			Operator: strings.TrimSpace(element[idx+len(" WITH "):]),
+		})
+	}
+
+	if where, ok := strings.CutPrefix(strings.TrimSpace(rest[end+1:]), "WHERE "); ok {
+		where = strings.TrimSpace(where)
+		if strings.HasPrefix(where, "(") && closingParenIndex(where) == len(where)-1 {
+			where = where[1 : len(where)-1]
+		}
+
+		c.Where = where
+	}
+
+	return c
+}
+
+// closingParenIndex returns the index of the parenthesis which closes the one s starts with, or -1.
+// Parentheses inside single-quoted string literals are ignored.
+func closingParenIndex(s string) int {
+	depth, inLiteral := 0, false
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\'':
+			inLiteral = !inLiteral
+		case inLiteral:
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// splitTopLevel splits s by sep, ignoring the separators inside parentheses and single-quoted string literals.
+func splitTopLevel(s string, sep byte) []string {
+	var (
+		parts     []string
+		depth     int
+		inLiteral bool
+		start     int
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\'':
+			inLiteral = !inLiteral
+		case inLiteral:
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
@@ -18,38 +18,7 @@ func BuildCreateTableQuery(td *Table) string {
 	columns := td.ListColumnsWithoutPresenter()
 	// Loop over the columns and append their definitions to the query
 	for i, col := range columns {
-		// Add the column name and type. pgx.Identifier.Sanitize double-quotes the name and
-		// doubles any embedded '"': the correct SQL identifier escaping, unlike strconv.Quote
-		// which produces Go string escaping (invalid inside a Postgres identifier).
-		query.WriteString(pgx.Identifier{col.Name}.Sanitize() + " " + col.Type.String())
-
-		// Add the type argument if any
-		if col.TypeArgument != "" {
-			fmt.Fprintf(&query, "(%s)", col.TypeArgument)
-		}
-
-		if col.GeneratedExpression != "" {
-			// Stored generated column: GENERATED ALWAYS AS (expr) STORED
-			fmt.Fprintf(&query, " GENERATED ALWAYS AS (%s) STORED", col.GeneratedExpression)
-		} else {
-			// Add the default value if any
-			if col.Default != "" {
-				query.WriteString(" DEFAULT " + col.Default)
-			}
-		}
-		// Add the NOT NULL constraint if applicable
-		if !col.Nullable {
-			query.WriteString(" NOT NULL")
-		}
-		// Add the UNIQUE constraint if applicable
-		if col.Unique {
-			query.WriteString(" UNIQUE")
-		}
-
-		// Add the CHECK constraint if any
-		if col.CheckConstraint != "" {
-			fmt.Fprintf(&query, " CHECK (%s)", col.CheckConstraint)
-		}
+		query.WriteString(BuildColumnDefinition(col))
 
 		// Add a comma separator if this is not the last column.
 		if i < len(columns)-1 {
@@ -57,9 +26,16 @@ func BuildCreateTableQuery(td *Table) string {
 		}
 	}
 
-	// Add the primary key constraint if any. We only allow one Primary Key column.
-	if primaryKey, ok := td.PrimaryKey(); ok {
-		fmt.Fprintf(&query, `, PRIMARY KEY ("%s")`, primaryKey.Name)
+	// Add the primary key constraint if any, a composite primary key lists all of its columns.
+	if primaryKeys := td.PrimaryKeys(); len(primaryKeys) > 0 {
+		query.WriteString(`, PRIMARY KEY (`)
+		for i, c := range primaryKeys {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, `"%s"`, c.Name)
+		}
+		query.WriteByte(')')
 	}
 
 	// Loop over the foreign key constraints and append them to the query
@@ -87,8 +63,19 @@ func BuildCreateTableQuery(td *Table) string {
 		fmt.Fprintf(&query, ", CONSTRAINT %s UNIQUE (%s)", idxName, strings.Join(colNames, ", "))
 	}
 
+	// Loop over the table-level CHECK and EXCLUDE constraints and append them to the query.
+	for _, c := range td.TableConstraints {
+		fmt.Fprintf(&query, ", CONSTRAINT %s %s", c.Name, c.Definition())
+	}
+
+	// Close the column definitions and add the partitioning, if the table is a partitioned one.
+	query.WriteByte(')')
+	if td.PartitionBy != nil {
+		query.WriteString(" PARTITION BY " + td.PartitionBy.String())
+	}
+
 	// Close the CREATE TABLE statement with a semicolon
-	query.WriteString(");")
+	query.WriteByte(';')
 
 	// Loop over the non-unique indexes and append them to the query as separate statements
 	for _, idx := range td.Indexes() {
@@ -97,5 +84,60 @@ func BuildCreateTableQuery(td *Table) string {
 			idx.Name, td.Name, idx.Type.String(), idx.ColumnName)
 	}
 
+	// Loop over the table-level indexes, e.g. composite, partial or expression ones.
+	for _, idx := range td.TableIndexes {
+		query.WriteString(BuildCreateTableIndexQuery(idx))
+	}
+
 	return query.String()
 }
+
+// BuildColumnType returns the data type of the column as it is written in a column definition,
+// e.g. "varchar(255)" or the name of its enum type.
+func BuildColumnType(col *Column) string {
+	typ := col.typeName()
+
+	// Add the type argument if any
+	if col.TypeArgument != "" {
+		typ += "(" + col.TypeArgument + ")"
+	}
+
+	return typ
+}
+
+// BuildColumnDefinition returns the definition of the column as it is written in
+// CREATE TABLE and ALTER TABLE ADD COLUMN queries, e.g. `"email" varchar(255) NOT NULL UNIQUE`.
+// Primary and foreign keys and indexes are not part of it.
+func BuildColumnDefinition(col *Column) string {
+	var b strings.Builder
+
+	// Add the column name and type. pgx.Identifier.Sanitize double-quotes the name and
+	// doubles any embedded '"': the correct SQL identifier escaping, unlike strconv.Quote
+	// which produces Go string escaping (invalid inside a Postgres identifier).
+	b.WriteString(pgx.Identifier{col.Name}.Sanitize() + " " + BuildColumnType(col))
+
+	if col.GeneratedExpression != "" {
+		// Stored generated column: GENERATED ALWAYS AS (expr) STORED
+		fmt.Fprintf(&b, " GENERATED ALWAYS AS (%s) STORED", col.GeneratedExpression)
+	} else {
+		// Add the default value if any
+		if col.Default != "" {
+			b.WriteString(" DEFAULT " + col.Default)
+		}
+	}
+	// Add the NOT NULL constraint if applicable
+	if !col.Nullable {
+		b.WriteString(" NOT NULL")
+	}
+	// Add the UNIQUE constraint if applicable
+	if col.Unique {
+		b.WriteString(" UNIQUE")
+	}
+
+	// Add the CHECK constraint if any
+	if col.CheckConstraint != "" {
+		fmt.Fprintf(&b, " CHECK (%s)", col.CheckConstraint)
+	}
+
+	return b.String()
+}
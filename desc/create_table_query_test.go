@@ -64,3 +64,26 @@ func TestBuildCreateTableQueryQuotesUniqueIndexColumnNames(t *testing.T) {
 		t.Fatalf("query still contains a backslash-escaped quote (strconv.Quote artifact): %s", got)
 	}
 }
+
+func TestBuildColumnDefinition(t *testing.T) {
+	tests := []struct {
+		column *Column
+		want   string
+	}{
+		{&Column{Name: "email", Type: CharacterVarying, TypeArgument: "255", Unique: true}, `"email" varchar(255) NOT NULL UNIQUE`},
+		{&Column{Name: "status", Type: Text, Default: "'active'", CheckConstraint: "status <> ''"}, `"status" text DEFAULT 'active' NOT NULL CHECK (status <> '')`},
+		{&Column{Name: "state", Type: Enumerated, Enum: &Enum{Name: "order_state"}, Nullable: true}, `"state" order_state`},
+		{&Column{Name: "total", Type: Integer, GeneratedExpression: "price * quantity", Default: "0"}, `"total" int GENERATED ALWAYS AS (price * quantity) STORED NOT NULL`},
+	}
+
+	for _, tt := range tests {
+		if got := BuildColumnDefinition(tt.column); got != tt.want {
+			t.Fatalf("definition mismatch:\ngot:  %s\nwant: %s", got, tt.want)
+		}
+	}
+
+	fk := ForeignKeyConstraint{ColumnName: "blog_id", ReferenceTableName: "blogs", ReferenceColumnName: "id", OnDelete: "CASCADE", Deferrable: true}
+	if got, want := BuildAddForeignKeyQuery("posts", fk), `ALTER TABLE posts ADD CONSTRAINT posts_blog_id_fkey FOREIGN KEY (blog_id) REFERENCES blogs (id) ON DELETE CASCADE DEFERRABLE;`; got != want {
+		t.Fatalf("query mismatch:\ngot:  %s\nwant: %s", got, want)
+	}
+}
@@ -95,6 +95,8 @@ const (
 	//
 	CIText // CIText is an extension data type that provides case-insensitive
 	HStore // hstore is an extension data type that provides key-value into a single column.
+	//
+	Enumerated // Enumerated represents a user-defined enum type, see Enum and Column.Enum.
 )
 
 var dataTypeText = map[DataType][]string{ // including their aliases.
@@ -163,6 +165,7 @@ var dataTypeText = map[DataType][]string{ // including their aliases.
 	DateMultiRange:        {"datemultirange"},
 	CIText:                {"citext"}, // case-insensitive text using the citext extension: CREATE EXTENSION citext;
 	HStore:                {"hstore"},
+	Enumerated:            {"enum"}, // the actual type name is the column's Enum.Name.
 }
 
 // String returns the first name of the data type, or a formatted string if the data type is unexpected.
@@ -328,7 +331,7 @@ func goTypeToDataType(typ reflect.Type) DataType {
 
 func dataTypeToGoType(dataType DataType) reflect.Type {
 	switch dataType {
-	case Text, CharacterVarying, UUID:
+	case Text, CharacterVarying, UUID, Enumerated:
 		return stringType
 	case Bytea:
 		return bytesType
@@ -3,14 +3,51 @@ package desc
 import "fmt"
 
 // BuildDeleteQuery builds and returns a SQL query for deleting one or more rows from a table.
+//
+// For a single-column primary key the returned values are the primary key values, which must be
+// passed as a single (array) bind parameter. For a composite primary key they are the flattened
+// primary key values of each row, one bind parameter each.
+//
+// If the table has a soft-delete column (see Column.SoftDelete) the rows are not removed,
+// instead the query sets the soft-delete column to now() on the rows that are not already
+// soft-deleted, e.g. UPDATE "users" SET "deleted_at" = now() WHERE "id" = ANY($1) AND "deleted_at" IS NULL;
+// Use BuildHardDeleteQuery to always remove the rows.
 func BuildDeleteQuery(td *Table, values []any) (string, []any, error) {
-	// extract the primary key column name and the primary key values from the table definition and the values
-	primaryKeyName, ids, err := extractPrimaryKeyValues(td, values)
+	if _, ok := td.SoftDeleteColumn(); !ok {
+		return BuildHardDeleteQuery(td, values)
+	}
+
+	condition, args, err := buildPrimaryKeysMatchCondition(td, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := buildSoftDeleteQuery(td, condition)
+	return query, args, nil
+}
+
+// BuildHardDeleteQuery builds and returns a SQL query for permanently deleting one or more rows from a table,
+// even if the table has a soft-delete column. Its values are the same as BuildDeleteQuery's.
+func BuildHardDeleteQuery(td *Table, values []any) (string, []any, error) {
+	// extract the primary key condition and the primary key values from the table definition and the values
+	condition, args, err := buildPrimaryKeysMatchCondition(td, values)
 	if err != nil {
 		return "", nil, err // return false and the wrapped error if extracting fails
 	}
 
-	// build the SQL query using the table name, the primary key name and a placeholder for the primary key values
-	query := fmt.Sprintf(`DELETE FROM "%s" WHERE "%s" = ANY($1);`, td.Name, primaryKeyName)
-	return query, ids, nil
+	query := fmt.Sprintf(`DELETE FROM "%s" WHERE %s;`, td.Name, condition)
+	return query, args, nil
+}
+
+// buildSoftDeleteQuery returns an UPDATE query which marks the rows matching the given
+// where condition (which may be empty) as soft-deleted. The table must have a soft-delete column.
+func buildSoftDeleteQuery(td *Table, where string) string {
+	c, _ := td.SoftDeleteColumn()
+
+	condition := td.SoftDeleteCondition(SoftDeleteScopeActive)
+	if where != "" {
+		condition = where + " AND " + condition
+	}
+
+	return fmt.Sprintf(`UPDATE "%s" SET "%s" = now() WHERE %s;`, td.Name, c.Name, condition)
 }
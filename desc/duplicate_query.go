@@ -2,20 +2,33 @@ package desc
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
 // BuildDuplicateQuery returns a query that duplicates a row by its primary key.
+// The query's arguments are the primary key values, see Table.PrimaryKeyValues,
+// followed by the tenant value if the table has a tenant column (see Column.Tenant).
+// A composite primary key must have a generated column (see Column.IsGenerated),
+// as the copy would otherwise have the same primary key as the original row.
 func BuildDuplicateQuery(td *Table, idPtr any) (string, error) {
 	primaryKey, ok := td.PrimaryKey() // get the primary key column definition from the table definition
 	if !ok {
 		return "", fmt.Errorf("duplicate: no primary key")
 	}
 
-	returningColumn := "" // a variable to store the name of the column to return after insertion
-	if idPtr != nil && ok {
-		// if idPtr is not nil, it means we want to get the primary key value of the inserted row
-		returningColumn = primaryKey.Name // assign the column name to returningColumn
+	primaryKeys := td.PrimaryKeys()
+	if len(primaryKeys) > 1 && !slices.ContainsFunc(primaryKeys, (*Column).IsGenerated) {
+		return "", fmt.Errorf("duplicate: composite primary key of table %s has no generated column", td.Name)
+	}
+
+	returningColumn := "" // a variable to store the name of the column(s) to return after insertion
+	if idPtr != nil {
+		// if idPtr is not nil, it means we want to get the primary key value(s) of the inserted row
+		var err error
+		if returningColumn, err = returningPrimaryKey(td, idPtr); err != nil {
+			return "", err
+		}
 	}
 
 	var b strings.Builder
@@ -67,12 +80,15 @@ func BuildDuplicateQuery(td *Table, idPtr any) (string, error) {
 	b.WriteString(" FROM ")
 	writeTableName(&b, td.SearchPath, td.Name)
 
-	// WHERE id = $1
-	buildWhereSubQueryByArguments(&b, Arguments{
-		{
-			Column: primaryKey,
-		},
-	})
+	// WHERE id = $1 (or blog_id = $1 AND tag_id = $2 for a composite primary key)
+	primaryKeyArgs := make(Arguments, 0, len(primaryKeys))
+	for _, c := range primaryKeys {
+		primaryKeyArgs = append(primaryKeyArgs, Argument{Column: c})
+	}
+	if c, ok := td.TenantColumn(); ok {
+		primaryKeyArgs = append(primaryKeyArgs, Argument{Column: c})
+	}
+	buildWhereSubQueryByArguments(&b, primaryKeyArgs)
 
 	// RETURNING id
 	// If returningColumn is not empty.
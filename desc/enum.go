@@ -0,0 +1,166 @@
+package desc
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Enum describes a PostgreSQL enumerated type, created with CREATE TYPE <name> AS ENUM (<values>).
+//
+// An enum is declared either by a Go string type which has a Values method,
+// e.g. func (Status) Values() []Status, or by the Schema.RegisterEnum method.
+// A column uses it through its `type` struct tag option, e.g. pg:"type=status".
+type Enum struct {
+	Name   string   // the name of the enum type, e.g. "status".
+	Values []string // the labels of the enum type, in their sort order, e.g. "draft", "published".
+}
+
+// NewEnum returns a new Enum of the given name and labels.
+// It returns an error if the name is not a valid identifier or the labels are empty or duplicated.
+func NewEnum(name string, values ...string) (*Enum, error) {
+	if err := validateIdentifier(name); err != nil {
+		return nil, fmt.Errorf("enum: %w", err)
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("enum: %s: at least one value is required", name)
+	}
+
+	for i, v := range values {
+		if v == "" {
+			return nil, fmt.Errorf("enum: %s: empty value", name)
+		}
+
+		if slices.Contains(values[:i], v) {
+			return nil, fmt.Errorf("enum: %s: duplicated value: %s", name, v)
+		}
+	}
+
+	return &Enum{Name: name, Values: slices.Clone(values)}, nil
+}
+
+// Equal reports whether the two enums have the same name and values, in the same order.
+func (e *Enum) Equal(other *Enum) bool {
+	return e.Name == other.Name && slices.Equal(e.Values, other.Values)
+}
+
+// BuildCreateEnumQuery returns the query which creates the given enum type, if it does not exist already.
+//
+// PostgreSQL has no CREATE TYPE IF NOT EXISTS, so the query is wrapped in a DO block
+// which ignores the duplicate_object error.
+func BuildCreateEnumQuery(e *Enum) string {
+	var b strings.Builder
+	b.WriteString(`DO $$ BEGIN CREATE TYPE `)
+	b.WriteString(e.Name)
+	b.WriteString(` AS ENUM (`)
+	for i, v := range e.Values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(quoteLiteral(v))
+	}
+	b.WriteString(`); EXCEPTION WHEN duplicate_object THEN NULL; END $$;`)
+
+	return b.String()
+}
+
+// quoteLiteral returns s as a SQL string literal, doubling any embedded single quote.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Enums returns the enum types used by the columns of the table, in column order.
+func (td *Table) Enums() []*Enum {
+	var enums []*Enum
+	for _, c := range td.Columns {
+		if c.Type != Enumerated || c.Enum == nil {
+			continue
+		}
+
+		if !slices.ContainsFunc(enums, func(e *Enum) bool { return e.Name == c.Enum.Name }) {
+			enums = append(enums, c.Enum)
+		}
+	}
+
+	return enums
+}
+
+// typeName returns the SQL name of the column's type: the name of its enum type
+// for Enumerated columns, otherwise the name of its data type.
+func (c *Column) typeName() string {
+	if c.Type == Enumerated && c.Enum != nil {
+		return c.Enum.Name
+	}
+
+	return c.Type.String()
+}
+
+// lookupEnumValues returns the labels declared by the Values method of the given Go type,
+// e.g. func (Status) Values() []Status, and reports whether it has one.
+// The type's underlying kind and its values' kind must be string.
+func lookupEnumValues(typ reflect.Type) ([]string, bool) {
+	typ = IndirectType(typ)
+	if typ.Kind() != reflect.String {
+		return nil, false
+	}
+
+	method, ok := typ.MethodByName("Values")
+	if !ok || method.Type.NumIn() != 1 || method.Type.NumOut() != 1 {
+		return nil, false
+	}
+
+	out := method.Type.Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.String {
+		return nil, false
+	}
+
+	valuesValue := method.Func.Call([]reflect.Value{reflect.Zero(typ)})[0]
+	values := make([]string, 0, valuesValue.Len())
+	for i := range valuesValue.Len() {
+		values = append(values, valuesValue.Index(i).String())
+	}
+
+	return values, true
+}
+
+// resolveEnumColumn resolves the enum type of a column whose `type` tag option (typeName) is not
+// a built-in data type, or which has no `type` option at all (empty typeName) and its field type
+// declares enum values. The known enums are searched by name first, then the field type's
+// Values method is used. It reports false if the column is not an enum one.
+func resolveEnumColumn(c *Column, typeName string, enums []*Enum) (bool, error) {
+	goValues, hasGoValues := lookupEnumValues(c.FieldType)
+
+	if typeName == "" {
+		if !hasGoValues {
+			return false, nil
+		}
+
+		typeName = SnakeCase(IndirectType(c.FieldType).Name())
+	}
+
+	var enum *Enum
+	if idx := slices.IndexFunc(enums, func(e *Enum) bool { return e.Name == typeName }); idx != -1 {
+		enum = enums[idx]
+
+		if hasGoValues && !slices.Equal(enum.Values, goValues) {
+			return false, fmt.Errorf("struct field: %s: enum: %s: the values of %s do not match the registered ones: %s vs %s",
+				c.FieldName, typeName, c.FieldType.String(), strings.Join(goValues, ", "), strings.Join(enum.Values, ", "))
+		}
+	} else if hasGoValues {
+		e, err := NewEnum(typeName, goValues...)
+		if err != nil {
+			return false, fmt.Errorf("struct field: %s: %w", c.FieldName, err)
+		}
+
+		enum = e
+	} else {
+		return false, nil
+	}
+
+	c.Type = Enumerated
+	c.Enum = enum
+	return true, nil
+}
@@ -0,0 +1,116 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type enumTestStatus string
+
+func (enumTestStatus) Values() []enumTestStatus {
+	return []enumTestStatus{"draft", "published", "it's archived"}
+}
+
+type enumTestPost struct {
+	ID       int64           `pg:"type=bigserial,primary"`
+	Status   enumTestStatus  `pg:"type=post_status,default='draft'::post_status"`
+	Previous *enumTestStatus `pg:"type=post_status,nullable"`
+	Kind     enumTestStatus  `pg:"kind"`
+	Priority string          `pg:"type=priority"`
+}
+
+func TestEnumColumns(t *testing.T) {
+	priority, err := NewEnum("priority", "low", "high")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	td, err := ConvertStructToTable("posts", reflect.TypeFor[enumTestPost](), priority)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedStatusValues := []string{"draft", "published", "it's archived"}
+
+	tests := []struct {
+		columnName string
+		enumName   string
+		values     []string
+	}{
+		{"status", "post_status", expectedStatusValues},
+		{"previous", "post_status", expectedStatusValues},
+		{"kind", "enum_test_status", expectedStatusValues}, // no type option: named after the Go type.
+		{"priority", "priority", []string{"low", "high"}},
+	}
+
+	for _, tt := range tests {
+		c := td.GetColumnByName(tt.columnName)
+		if c.Type != Enumerated || c.Enum == nil {
+			t.Fatalf("%s: expected an enum column but got: %s", tt.columnName, c.Type)
+		}
+
+		if expected := (&Enum{Name: tt.enumName, Values: tt.values}); !c.Enum.Equal(expected) {
+			t.Fatalf("%s: expected enum: %#v but got: %#v", tt.columnName, expected, c.Enum)
+		}
+	}
+
+	if got := len(td.Enums()); got != 3 {
+		t.Fatalf("expected 3 distinct enums but got: %d", got)
+	}
+
+	if expected, got := `pg:"name=status,type=post_status,default='draft'"`, td.GetColumnByName("status").FieldTagString(false); got != expected {
+		t.Fatalf("expected field tag: %s but got: %s", expected, got)
+	}
+
+	createTableQuery := BuildCreateTableQuery(td)
+	if expected := `"status" post_status DEFAULT 'draft'::post_status NOT NULL`; !strings.Contains(createTableQuery, expected) {
+		t.Fatalf("expected create table query to contain: %s but got: %s", expected, createTableQuery)
+	}
+
+	expectedEnumQuery := `DO $$ BEGIN CREATE TYPE post_status AS ENUM ('draft', 'published', 'it''s archived'); EXCEPTION WHEN duplicate_object THEN NULL; END $$;`
+	if got := BuildCreateEnumQuery(td.GetColumnByName("status").Enum); got != expectedEnumQuery {
+		t.Fatalf("expected create enum query:\n%s\nbut got:\n%s", expectedEnumQuery, got)
+	}
+}
+
+func TestEnumColumnsInvalid(t *testing.T) {
+	tests := []struct {
+		value       any
+		enums       []*Enum
+		expectedErr string
+	}{
+		{
+			value: struct {
+				Priority string `pg:"type=priority"`
+			}{},
+			expectedErr: "invalid data type",
+		},
+		{
+			value: struct {
+				Status string `pg:"type=enum"`
+			}{},
+			expectedErr: "expected the name of the enum type",
+		},
+		{
+			value: struct {
+				Status enumTestStatus `pg:"type=post_status"`
+			}{},
+			enums:       []*Enum{{Name: "post_status", Values: []string{"draft"}}},
+			expectedErr: "do not match the registered ones",
+		},
+	}
+
+	for i, tt := range tests {
+		_, err := ConvertStructToTable("posts", reflect.TypeOf(tt.value), tt.enums...)
+		if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+			t.Fatalf("[%d] expected error containing %q but got: %v", i, tt.expectedErr, err)
+		}
+	}
+
+	for _, values := range [][]string{nil, {"a", "a"}, {""}} {
+		if _, err := NewEnum("status", values...); err == nil {
+			t.Fatalf("expected error for enum values: %#v", values)
+		}
+	}
+}
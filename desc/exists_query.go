@@ -9,7 +9,16 @@ import (
 
 // BuildExistsQuery builds and returns an SQL query for checking of existing in a row in the table,
 // based on the given struct value.
+//
+// Soft-deleted rows (see Column.SoftDelete) are not taken into account,
+// use BuildScopedExistsQuery to control that.
 func BuildExistsQuery(td *Table, structValue reflect.Value) (string, []any, error) {
+	return BuildScopedExistsQuery(td, structValue, SoftDeleteScopeActive)
+}
+
+// BuildScopedExistsQuery same as BuildExistsQuery but it accepts the soft-delete scope of the rows to check against.
+// The scope has no effect on tables without a soft-delete column.
+func BuildScopedExistsQuery(td *Table, structValue reflect.Value, scope SoftDeleteScope) (string, []any, error) {
 	args, err := extractArguments(td, structValue, nil)
 	if err != nil {
 		return "", nil, err // return the error if finding arguments fails
@@ -21,13 +30,13 @@ func BuildExistsQuery(td *Table, structValue reflect.Value) (string, []any, erro
 
 	// build the SQL query using the table definition,
 	// the arguments and the returning column
-	query := buildExistsQuery(td, args)
+	query := buildExistsQuery(td, args, scope)
 	return query, args.Values(), nil
 }
 
 // buildExistsQuery builds and returns an SQL query for checking of existing in a row in the table,
 // based on the given arguments.
-func buildExistsQuery(td *Table, args Arguments) string {
+func buildExistsQuery(td *Table, args Arguments, scope SoftDeleteScope) string {
 	// Create a new strings.Builder
 	var b strings.Builder
 
@@ -36,6 +45,12 @@ func buildExistsQuery(td *Table, args Arguments) string {
 
 	buildWhereSubQueryByArguments(&b, args)
 
+	// Hide soft-deleted rows, if any.
+	if condition := td.SoftDeleteCondition(scope); condition != "" {
+		b.WriteString(" AND ")
+		b.WriteString(condition)
+	}
+
 	// Write the query (EXISTS) suffix
 	b.WriteString(")")
 
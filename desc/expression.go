@@ -0,0 +1,233 @@
+package desc
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var expressionCastRegex = regexp.MustCompile(`::"?[a-z_][a-z0-9_]*"?( varying| precision| with time zone| without time zone)?(\[\])?`)
+
+// equalExpressions reports whether the two SQL expressions are equal after removing type casts, double quotes,
+// whitespace, the default ASC ordering, case and the parentheses which do not change their meaning. It compares
+// the index keys and predicates (see TableIndex.Equal) and the constraint definitions (see
+// TableConstraint.EqualDefinition) of the code with the ones PostgreSQL rewrote and stored.
+func equalExpressions(a, b string) bool {
+	return normalizeExpression(a) == normalizeExpression(b)
+}
+
+// normalizeExpression returns the normalized form of an SQL expression, see equalExpressions.
+// PostgreSQL stores an expression with every operation in parentheses, e.g. "a + b * c" as
+// "(a + (b * c))", so the parentheses are removed only where the precedence of the operators
+// keeps the meaning: "((a + b) * c)" is normalized to "( a + b ) * c", not to "a + b * c".
+func normalizeExpression(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, " asc")
+	s = expressionCastRegex.ReplaceAllString(s, "")
+
+	tokens := tokenizeExpression(s)
+	for {
+		open, close, ok := findRedundantParentheses(tokens)
+		if !ok {
+			break
+		}
+
+		tokens = slices.Delete(tokens, close, close+1)
+		tokens = slices.Delete(tokens, open, open+1)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+const expressionOperatorChars = "+-*/<>=~!@#%^&|`?"
+
+// tokenizeExpression splits an SQL expression into its words (identifiers, keywords and numbers, without
+// their double quotes), string literals, operators and punctuation.
+func tokenizeExpression(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			end := i + 1
+			for end < len(s) {
+				if s[end] == '\'' {
+					if end+1 < len(s) && s[end+1] == '\'' { // an escaped quote.
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(s))
+			tokens = append(tokens, s[i:end])
+			i = end
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end == -1 {
+				end = len(s) - i - 1
+			}
+			tokens = append(tokens, s[i+1:i+1+end])
+			i += end + 2
+		case strings.IndexByte(expressionOperatorChars, c) != -1:
+			end := i + 1
+			for end < len(s) && strings.IndexByte(expressionOperatorChars, s[end]) != -1 {
+				end++
+			}
+			tokens = append(tokens, s[i:end])
+			i = end
+		case isExpressionWordChar(c):
+			end := i + 1
+			for end < len(s) && isExpressionWordChar(s[end]) {
+				end++
+			}
+			tokens = append(tokens, s[i:end])
+			i = end
+		default: // parentheses, brackets, commas and the rest.
+			tokens = append(tokens, s[i:i+1])
+			i++
+		}
+	}
+
+	return tokens
+}
+
+func isExpressionWordChar(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+// expressionPrecedence returns the precedence of the operator token at index i, following the PostgreSQL
+// operator precedence table, and reports whether it is an operator.
+func expressionPrecedence(tokens []string, i int) (int, bool) {
+	switch tokens[i] {
+	case "or":
+		return 1, true
+	case "and":
+		return 2, true
+	case "not":
+		return 3, true
+	case "is", "isnull", "notnull":
+		return 4, true
+	case "=", "<", ">", "<=", ">=", "<>", "!=":
+		return 5, true
+	case "like", "ilike", "similar", "between", "in":
+		return 6, true
+	case "+", "-":
+		if i == 0 || isExpressionPrefixPosition(tokens, i) {
+			return 11, true // unary.
+		}
+		return 8, true
+	case "*", "/", "%":
+		return 9, true
+	case "^":
+		return 10, true
+	}
+
+	if strings.IndexByte(expressionOperatorChars, tokens[i][0]) != -1 {
+		return 7, true // any other operator, e.g. || or @>.
+	}
+
+	return 0, false
+}
+
+// isExpressionPrefixPosition reports whether the token at index i starts an operand, e.g. a unary minus.
+func isExpressionPrefixPosition(tokens []string, i int) bool {
+	switch previous := tokens[i-1]; previous {
+	case "(", ",", "[":
+		return true
+	default:
+		_, isOperator := expressionPrecedence(tokens, i-1)
+		return isOperator
+	}
+}
+
+// findRedundantParentheses returns the indexes of the first pair of parentheses which can be removed
+// without changing the meaning of the expression: the ones around a single operand, a whole expression,
+// a whole function argument or an operation which binds tighter than the operators around it.
+func findRedundantParentheses(tokens []string) (open, close int, ok bool) {
+	var stack []int
+	for i, token := range tokens {
+		switch token {
+		case "(":
+			stack = append(stack, i)
+		case ")":
+			if len(stack) == 0 {
+				return 0, 0, false // unbalanced, keep them all.
+			}
+
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if isRedundantParentheses(tokens, open, i) {
+				return open, i, true
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+const (
+	expressionBoundary = -1      // no operator next to the parentheses.
+	expressionOperand  = 1 << 10 // the precedence of a single operand.
+)
+
+func isRedundantParentheses(tokens []string, open, close int) bool {
+	if close == open+1 {
+		return false // e.g. now().
+	}
+
+	left := expressionBoundary
+	if open > 0 {
+		switch previous := tokens[open-1]; previous {
+		case "(", ",", "[", "when", "then", "else", "where":
+		default:
+			precedence, isOperator := expressionPrecedence(tokens, open-1)
+			if !isOperator {
+				return false // e.g. the parentheses of a function call or an IN list.
+			}
+			left = precedence
+		}
+	}
+
+	right := expressionBoundary
+	if close < len(tokens)-1 {
+		switch next := tokens[close+1]; next {
+		case "[":
+			return false // e.g. (array_column)[1].
+		case "not": // e.g. NOT LIKE, NOT IN.
+			right = 6
+		default:
+			if precedence, isOperator := expressionPrecedence(tokens, close+1); isOperator {
+				right = precedence
+			}
+		}
+	}
+
+	inner := expressionOperand
+	depth := 0
+	for i := open + 1; i < close; i++ {
+		switch tokens[i] {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			depth--
+		case ",":
+			if depth == 0 {
+				return false // e.g. a row constructor.
+			}
+		default:
+			if depth == 0 {
+				if precedence, isOperator := expressionPrecedence(tokens, i); isOperator {
+					inner = min(inner, precedence)
+				}
+			}
+		}
+	}
+
+	// the operators are left-associative: (a - b) - c is a - b - c but a - (b - c) is not.
+	return inner > left && inner >= right
+}
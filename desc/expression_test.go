@@ -0,0 +1,33 @@
+package desc
+
+import "testing"
+
+func TestEqualExpressions(t *testing.T) {
+	tests := []struct {
+		code, database string
+		equal          bool
+	}{
+		{"lower(email)", "lower((email)::text)", true},
+		{"price > 0", "(price > (0)::numeric)", true},
+		{"CHECK (starts_at < ends_at)", "CHECK ((starts_at < ends_at))", true},
+		{"a + b * c", "(a + (b * c))", true},
+		{"(a + b) * c", "((a + b) * c)", true},
+		{"a - b - c", "((a - b) - c)", true},
+		{`"status" = 'active' AND deleted_at IS NULL`, "((status = 'active'::text) AND (deleted_at IS NULL))", true},
+		{"NOT cancelled OR a = b", "((NOT cancelled) OR (a = b))", true},
+		{"coalesce(a, b) > 0", "(COALESCE(a, b) > 0)", true},
+		{"a + b * c", "((a + b) * c)", false},
+		{"(a + b) * c", "(a + (b * c))", false},
+		{"a - (b - c)", "((a - b) - c)", false},
+		{"NOT (a AND b)", "((NOT a) AND b)", false},
+		{"a OR b AND c", "((a OR b) AND c)", false},
+		{"deleted_at IS NULL", "(deleted_at IS NOT NULL)", false},
+	}
+
+	for _, tt := range tests {
+		if got := equalExpressions(tt.code, tt.database); got != tt.equal {
+			t.Errorf("%s vs %s: expected equal=%v but got: %v (%q vs %q)",
+				tt.code, tt.database, tt.equal, got, normalizeExpression(tt.code), normalizeExpression(tt.database))
+		}
+	}
+}
@@ -24,12 +24,12 @@ const DefaultInsertBatchSize = 500
 // The returning column is an optional string that specifies which column to return after the insertion,
 // such as the primary key or any other generated value.
 func BuildInsertQuery(td *Table, structValue reflect.Value, idPtr any, forceOnConflictExpr string, upsert bool) (string, []any, error) {
-	returningColumn := "" // a variable to store the name of the column to return after insertion
+	returningColumn := "" // a variable to store the name of the column(s) to return after insertion
 	if idPtr != nil {
-		// if idPtr is not nil, it means we want to get the primary key value of the inserted row
-		columnDefinition, ok := td.PrimaryKey() // get the primary key column definition from the table definition
-		if ok {
-			returningColumn = columnDefinition.Name // assign the column name to returningColumn
+		// if idPtr is not nil, it means we want to get the primary key value(s) of the inserted row
+		var err error
+		if returningColumn, err = returningPrimaryKey(td, idPtr); err != nil {
+			return "", nil, err
 		}
 	}
 
@@ -57,12 +57,12 @@ type OnConflict struct {
 // which callers such as Repository.InsertSingleOnConflict surface as ErrNoRows instead of a
 // stale idPtr.
 func BuildInsertQueryOnConflict(td *Table, structValue reflect.Value, idPtr any, oc OnConflict) (string, []any, error) {
-	returningColumn := "" // a variable to store the name of the column to return after insertion
+	returningColumn := "" // a variable to store the name of the column(s) to return after insertion
 	if idPtr != nil {
-		// if idPtr is not nil, it means we want to get the primary key value of the inserted row
-		columnDefinition, ok := td.PrimaryKey() // get the primary key column definition from the table definition
-		if ok {
-			returningColumn = columnDefinition.Name // assign the column name to returningColumn
+		// if idPtr is not nil, it means we want to get the primary key value(s) of the inserted row
+		var err error
+		if returningColumn, err = returningPrimaryKey(td, idPtr); err != nil {
+			return "", nil, err
 		}
 	}
 
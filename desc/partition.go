@@ -0,0 +1,307 @@
+package desc
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PartitionStrategy is the partitioning method of a partitioned table.
+type PartitionStrategy uint8
+
+// These are the possible values for PartitionStrategy.
+const (
+	InvalidPartitionStrategy PartitionStrategy = iota // InvalidPartitionStrategy is the zero value for PartitionStrategy
+	PartitionRange                                    // PartitionRange partitions the table by ranges of the key, e.g. a month of created_at
+	PartitionList                                     // PartitionList partitions the table by a list of key values, e.g. the regions
+	PartitionHash                                     // PartitionHash partitions the table by the hash of the key, modulus a number of partitions
+)
+
+var partitionStrategyText = map[PartitionStrategy]string{
+	PartitionRange: "RANGE",
+	PartitionList:  "LIST",
+	PartitionHash:  "HASH",
+}
+
+// String returns the SQL text of the partition strategy, e.g. "RANGE".
+func (s PartitionStrategy) String() string {
+	if text, ok := partitionStrategyText[s]; ok {
+		return text
+	}
+
+	return fmt.Sprintf("PartitionStrategy(unexpected %d)", s)
+}
+
+// PartitionBy describes the partitioning of a partitioned table,
+// the PARTITION BY <strategy> (<columns>) clause of its CREATE TABLE statement.
+type PartitionBy struct {
+	Strategy PartitionStrategy // the partitioning method.
+	Columns  []string          // the partition key columns, e.g. "created_at".
+}
+
+// String returns the SQL text of the partitioning, e.g. RANGE ("created_at").
+func (p *PartitionBy) String() string {
+	columns := make([]string, 0, len(p.Columns))
+	for _, columnName := range p.Columns {
+		columns = append(columns, pgx.Identifier{columnName}.Sanitize())
+	}
+
+	return fmt.Sprintf("%s (%s)", p.Strategy, strings.Join(columns, ", "))
+}
+
+// Equal reports whether the two partitionings have the same strategy and key columns.
+func (p *PartitionBy) Equal(other *PartitionBy) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+
+	return p.Strategy == other.Strategy && slices.Equal(p.Columns, other.Columns)
+}
+
+var partitionKeyDefinitionRegex = regexp.MustCompile(`^(RANGE|LIST|HASH) \((.+)\)$`)
+
+// ParsePartitionBy parses the output of the pg_get_partkeydef function, e.g. "RANGE (created_at)".
+// It returns nil if the definition is empty, i.e. the table is not a partitioned one.
+func ParsePartitionBy(definition string) *PartitionBy {
+	matches := partitionKeyDefinitionRegex.FindStringSubmatch(definition)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	p := &PartitionBy{}
+	for s, text := range partitionStrategyText {
+		if text == matches[1] {
+			p.Strategy = s
+			break
+		}
+	}
+
+	for columnName := range strings.SplitSeq(matches[2], ", ") {
+		p.Columns = append(p.Columns, strings.Trim(columnName, `"`))
+	}
+
+	return p
+}
+
+// ValidatePartitionBy reports an error if the table's partitioning is invalid: its strategy is unknown,
+// it has no key columns or a key column does not exist, a list partitioning has more than one key column,
+// the table is not a base one, or its primary key or a unique constraint does not include
+// all of the key columns (PostgreSQL requires that).
+func (td *Table) ValidatePartitionBy() error {
+	p := td.PartitionBy
+	if p == nil {
+		return nil
+	}
+
+	if _, ok := partitionStrategyText[p.Strategy]; !ok {
+		return fmt.Errorf("partition: %s: invalid strategy: %s", td.Name, p.Strategy)
+	}
+
+	if td.Type != TableTypeBase {
+		return fmt.Errorf("partition: %s: only base tables can be partitioned", td.Name)
+	}
+
+	if len(p.Columns) == 0 {
+		return fmt.Errorf("partition: %s: at least one key column is required", td.Name)
+	}
+
+	if p.Strategy == PartitionList && len(p.Columns) > 1 {
+		return fmt.Errorf("partition: %s: list partitioning accepts one key column, not %d", td.Name, len(p.Columns))
+	}
+
+	for _, columnName := range p.Columns {
+		if td.GetColumnByName(columnName) == nil {
+			return fmt.Errorf("partition: %s: key column %q does not exist", td.Name, columnName)
+		}
+	}
+
+	if primaryKeys := td.PrimaryKeys(); len(primaryKeys) > 0 {
+		if err := td.validatePartitionKeyIncluded("primary key", primaryKeys); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range td.Columns {
+		if c.Unique {
+			if err := td.validatePartitionKeyIncluded("unique column "+c.Name, []*Column{c}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for indexName, columnNames := range td.UniqueIndexes() {
+		columns := make([]*Column, 0, len(columnNames))
+		for _, columnName := range columnNames {
+			columns = append(columns, td.GetColumnByName(columnName))
+		}
+
+		if err := td.validatePartitionKeyIncluded("unique index "+indexName, columns); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (td *Table) validatePartitionKeyIncluded(constraint string, columns []*Column) error {
+	for _, columnName := range td.PartitionBy.Columns {
+		if !slices.ContainsFunc(columns, func(c *Column) bool { return c.Name == columnName }) {
+			return fmt.Errorf("partition: %s: the %s must include the partition key column %q", td.Name, constraint, columnName)
+		}
+	}
+
+	return nil
+}
+
+// Partition describes a partition of a partitioned table, see DB.ListPartitions.
+type Partition struct {
+	TableName string // the name of the partitioned (parent) table.
+	Name      string // the name of the partition table.
+	Bound     string // the partition bound, e.g. FOR VALUES FROM ('2026-01-01 00:00:00') TO ('2026-02-01 00:00:00').
+}
+
+// PartitionBound is the bound of a partition, the FOR VALUES (or DEFAULT) clause
+// of its CREATE TABLE ... PARTITION OF statement. Only the fields of the parent's
+// partition strategy are used.
+//
+// The bound values are written as SQL literals: nil, strings, booleans, integers, floats
+// and time.Time values are supported.
+type PartitionBound struct {
+	From []any // RANGE: the inclusive lower bound, one value per key column.
+	To   []any // RANGE: the exclusive upper bound, one value per key column.
+
+	In []any // LIST: the key values of the partition.
+
+	Modulus   int // HASH: the number of partitions.
+	Remainder int // HASH: the remainder of the partition, 0 <= Remainder < Modulus.
+
+	Default bool // the default partition, which holds the rows no other partition accepts.
+}
+
+// Clause returns the SQL text of the bound, e.g. FOR VALUES FROM ('2026-01-01') TO ('2026-02-01').
+func (b PartitionBound) Clause() (string, error) {
+	switch {
+	case b.Default:
+		return "DEFAULT", nil
+	case len(b.From) > 0 || len(b.To) > 0:
+		if len(b.From) != len(b.To) {
+			return "", fmt.Errorf("partition bound: expected the same number of from and to values but got %d and %d", len(b.From), len(b.To))
+		}
+
+		from, err := formatPartitionBoundValues(b.From)
+		if err != nil {
+			return "", err
+		}
+
+		to, err := formatPartitionBoundValues(b.To)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)", from, to), nil
+	case len(b.In) > 0:
+		in, err := formatPartitionBoundValues(b.In)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("FOR VALUES IN (%s)", in), nil
+	case b.Modulus > 0:
+		if b.Remainder < 0 || b.Remainder >= b.Modulus {
+			return "", fmt.Errorf("partition bound: remainder %d out of range for modulus %d", b.Remainder, b.Modulus)
+		}
+
+		return fmt.Sprintf("FOR VALUES WITH (MODULUS %d, REMAINDER %d)", b.Modulus, b.Remainder), nil
+	default:
+		return "", fmt.Errorf("partition bound: empty bound")
+	}
+}
+
+func formatPartitionBoundValues(values []any) (string, error) {
+	texts := make([]string, 0, len(values))
+	for _, v := range values {
+		text, err := formatPartitionBoundValue(v)
+		if err != nil {
+			return "", err
+		}
+
+		texts = append(texts, text)
+	}
+
+	return strings.Join(texts, ", "), nil
+}
+
+// formatPartitionBoundValue returns the SQL literal of a partition bound value.
+func formatPartitionBoundValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return quoteLiteral(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), nil
+	case time.Time:
+		return quoteLiteral(v.Format("2006-01-02 15:04:05.999999Z07:00")), nil
+	default:
+		return "", fmt.Errorf("partition bound: unsupported value type: %T", v)
+	}
+}
+
+// BuildCreatePartitionQuery returns the query which creates the partition of the given partitioned table,
+// if it does not exist already.
+func BuildCreatePartitionQuery(tableName, partitionName string, bound PartitionBound) (string, error) {
+	clause, err := buildPartitionQueryClause(tableName, partitionName, bound)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s %s;", partitionName, tableName, clause), nil
+}
+
+// BuildAttachPartitionQuery returns the query which attaches an existing table as a partition
+// of the given partitioned table.
+func BuildAttachPartitionQuery(tableName, partitionName string, bound PartitionBound) (string, error) {
+	clause, err := buildPartitionQueryClause(tableName, partitionName, bound)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s %s;", tableName, partitionName, clause), nil
+}
+
+// BuildDetachPartitionQuery returns the query which detaches the partition of the given partitioned table,
+// the partition is kept as a standalone table.
+func BuildDetachPartitionQuery(tableName, partitionName string) (string, error) {
+	if err := validatePartitionNames(tableName, partitionName); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s;", tableName, partitionName), nil
+}
+
+func buildPartitionQueryClause(tableName, partitionName string, bound PartitionBound) (string, error) {
+	if err := validatePartitionNames(tableName, partitionName); err != nil {
+		return "", err
+	}
+
+	return bound.Clause()
+}
+
+func validatePartitionNames(tableName, partitionName string) error {
+	if err := validateIdentifier(tableName); err != nil {
+		return fmt.Errorf("partition: table name: %w", err)
+	}
+
+	if err := validateIdentifier(partitionName); err != nil {
+		return fmt.Errorf("partition: partition name: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type partitionTestEvent struct {
+	ID        int64     `pg:"type=bigserial,primary"`
+	CreatedAt time.Time `pg:"type=timestamp,primary"`
+	Region    string    `pg:"type=text"`
+	Name      string    `pg:"type=text"`
+}
+
+func TestPartitionBy(t *testing.T) {
+	td, err := ConvertStructToTable("events", reflect.TypeFor[partitionTestEvent]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	td.PartitionBy = &PartitionBy{Strategy: PartitionRange, Columns: []string{"created_at"}}
+	if err = td.ValidatePartitionBy(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := `PRIMARY KEY ("id", "created_at")) PARTITION BY RANGE ("created_at");`, BuildCreateTableQuery(td); !strings.Contains(got, expected) {
+		t.Fatalf("expected create table query to contain: %s but got: %s", expected, got)
+	}
+
+	if parsed := ParsePartitionBy("RANGE (created_at)"); !parsed.Equal(td.PartitionBy) {
+		t.Fatalf("expected the parsed partitioning to match but got: %#v", parsed)
+	}
+
+	if parsed := ParsePartitionBy(""); parsed != nil {
+		t.Fatalf("expected no partitioning but got: %#v", parsed)
+	}
+
+	invalid := []struct {
+		partitionBy *PartitionBy
+		expectedErr string
+	}{
+		{&PartitionBy{Strategy: PartitionRange}, "at least one key column is required"},
+		{&PartitionBy{Strategy: PartitionRange, Columns: []string{"missing"}}, `key column "missing" does not exist`},
+		{&PartitionBy{Strategy: PartitionList, Columns: []string{"region"}}, `the primary key must include the partition key column "region"`},
+		{&PartitionBy{Strategy: PartitionList, Columns: []string{"id", "created_at"}}, "list partitioning accepts one key column"},
+		{&PartitionBy{Columns: []string{"id"}}, "invalid strategy"},
+	}
+
+	for i, tt := range invalid {
+		td.PartitionBy = tt.partitionBy
+		if err = td.ValidatePartitionBy(); err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+			t.Fatalf("[%d] expected error containing %q but got: %v", i, tt.expectedErr, err)
+		}
+	}
+}
+
+func TestPartitionQueries(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		bound         PartitionBound
+		expectedQuery string
+	}{
+		{
+			bound:         PartitionBound{From: []any{from}, To: []any{from.AddDate(0, 1, 0)}},
+			expectedQuery: `CREATE TABLE IF NOT EXISTS events_p2026_01 PARTITION OF events FOR VALUES FROM ('2026-01-01 00:00:00Z') TO ('2026-02-01 00:00:00Z');`,
+		},
+		{
+			bound:         PartitionBound{In: []any{"eu", "it's"}},
+			expectedQuery: `CREATE TABLE IF NOT EXISTS events_p2026_01 PARTITION OF events FOR VALUES IN ('eu', 'it''s');`,
+		},
+		{
+			bound:         PartitionBound{Modulus: 4, Remainder: 1},
+			expectedQuery: `CREATE TABLE IF NOT EXISTS events_p2026_01 PARTITION OF events FOR VALUES WITH (MODULUS 4, REMAINDER 1);`,
+		},
+		{
+			bound:         PartitionBound{Default: true},
+			expectedQuery: `CREATE TABLE IF NOT EXISTS events_p2026_01 PARTITION OF events DEFAULT;`,
+		},
+	}
+
+	for i, tt := range tests {
+		query, err := BuildCreatePartitionQuery("events", "events_p2026_01", tt.bound)
+		if err != nil {
+			t.Fatalf("[%d] %v", i, err)
+		}
+
+		if query != tt.expectedQuery {
+			t.Fatalf("[%d] expected query:\n%s\nbut got:\n%s", i, tt.expectedQuery, query)
+		}
+	}
+
+	for i, bound := range []PartitionBound{{}, {From: []any{1}}, {Modulus: 2, Remainder: 2}, {In: []any{struct{}{}}}} {
+		if _, err := BuildCreatePartitionQuery("events", "events_p1", bound); err == nil {
+			t.Fatalf("[%d] expected an error for bound: %#v", i, bound)
+		}
+	}
+
+	if _, err := BuildDetachPartitionQuery("events", "events; DROP TABLE events"); err == nil {
+		t.Fatal("expected an error for an invalid partition name")
+	}
+}
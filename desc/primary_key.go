@@ -0,0 +1,214 @@
+package desc
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Key is the primary key value of a row, keyed by the primary key column names,
+// e.g. Key{"blog_id": 1, "tag_id": 2}. It is the ID type of a table with a composite primary
+// key (more than one field tagged as `primary`), accepted by every ID-based method
+// (SelectByID, DeleteByID, Duplicate, UpdateJSONB...). It can be used on single-column primary
+// keys too, e.g. Key{"id": 1}.
+type Key map[string]any
+
+// PrimaryKeys returns the primary key columns of the table, in their declaration order.
+// A table with a composite primary key has more than one.
+func (td *Table) PrimaryKeys() []*Column {
+	var columns []*Column
+	for _, c := range td.Columns {
+		if c.PrimaryKey {
+			columns = append(columns, c)
+		}
+	}
+
+	return columns
+}
+
+// returningPrimaryKey returns the RETURNING column(s) of a query which returns the primary key
+// of the row it inserts into idPtr, e.g. "id" or "blog_id, tag_id" for a composite primary key.
+// A composite primary key can only be returned into a *Key, so any other idPtr is rejected
+// before the query runs.
+func returningPrimaryKey(td *Table, idPtr any) (string, error) {
+	primaryKeys := td.PrimaryKeys()
+	if _, ok := idPtr.(*Key); !ok && len(primaryKeys) > 1 {
+		return "", fmt.Errorf("table: %s: composite primary key requires a *Key but got: %T", td.Name, idPtr)
+	}
+
+	primaryKeyNames := make([]string, 0, len(primaryKeys))
+	for _, c := range primaryKeys {
+		primaryKeyNames = append(primaryKeyNames, c.Name)
+	}
+
+	return strings.Join(primaryKeyNames, ", "), nil
+}
+
+// HasCompositePrimaryKey reports whether the table's primary key consists of more than one column.
+func (td *Table) HasCompositePrimaryKey() bool {
+	n := 0
+	for _, c := range td.Columns {
+		if c.PrimaryKey {
+			n++
+		}
+	}
+
+	return n > 1
+}
+
+// PrimaryKeyValues resolves the given id to the values of the table's primary key columns,
+// in PrimaryKeys order. The id can be:
+//   - a Key holding a value for every primary key column
+//   - a value (or a pointer to a value) of the table's struct type, its primary key fields are used
+//   - the plain primary key value, e.g. an int64 or a string, if the primary key is a single column.
+func (td *Table) PrimaryKeyValues(id any) ([]any, error) {
+	primaryKeys := td.PrimaryKeys()
+	if len(primaryKeys) == 0 {
+		return nil, fmt.Errorf("no primary key found in table definition: %s", td.Name)
+	}
+
+	switch v := id.(type) {
+	case nil:
+		return nil, fmt.Errorf("primary key value is nil")
+	case Key:
+		if len(v) != len(primaryKeys) {
+			return nil, fmt.Errorf("table: %s: expected %d primary key values but got %d", td.Name, len(primaryKeys), len(v))
+		}
+
+		values := make([]any, 0, len(primaryKeys))
+		for _, c := range primaryKeys {
+			value, ok := v[c.Name]
+			if !ok {
+				return nil, fmt.Errorf("table: %s: missing value for primary key column: %s", td.Name, c.Name)
+			}
+
+			values = append(values, value)
+		}
+
+		return values, nil
+	}
+
+	if td.StructType != nil && IndirectType(reflect.TypeOf(id)) == td.StructType {
+		structValue := IndirectValue(id)
+
+		values := make([]any, 0, len(primaryKeys))
+		for _, c := range primaryKeys {
+			value, err := ExtractPrimaryKeyValue(c, structValue)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+
+		return values, nil
+	}
+
+	if len(primaryKeys) > 1 {
+		return nil, fmt.Errorf("table: %s: composite primary key requires a Key or a %s value but got: %T", td.Name, td.GetHumanName(), id)
+	}
+
+	return []any{id}, nil
+}
+
+// PrimaryKeyCondition returns the SQL condition which matches a row by all of its primary key
+// columns, with bind parameters starting at startIndex, e.g. `"blog_id" = $1 AND "tag_id" = $2`.
+// The arguments for it are the ones returned by PrimaryKeyValues.
+func (td *Table) PrimaryKeyCondition(startIndex int) string {
+	var b strings.Builder
+	for i, c := range td.PrimaryKeys() {
+		if i > 0 {
+			b.WriteString(" AND ")
+		}
+
+		b.WriteString(`"` + c.Name + `" = $` + strconv.Itoa(startIndex+i))
+	}
+
+	return b.String()
+}
+
+// ExtractPrimaryKey returns the primary key value of the given struct value:
+// the value of the primary key field or, for a composite primary key, a Key of all of them.
+func ExtractPrimaryKey(td *Table, structValue reflect.Value) (any, error) {
+	primaryKeys := td.PrimaryKeys()
+	switch len(primaryKeys) {
+	case 0:
+		return nil, fmt.Errorf("no primary key found in table definition: %s", td.Name)
+	case 1:
+		return ExtractPrimaryKeyValue(primaryKeys[0], structValue)
+	default:
+		key := make(Key, len(primaryKeys))
+		for _, c := range primaryKeys {
+			value, err := ExtractPrimaryKeyValue(c, structValue)
+			if err != nil {
+				return nil, err
+			}
+
+			key[c.Name] = value
+		}
+
+		return key, nil
+	}
+}
+
+// buildPrimaryKeysMatchCondition returns the SQL condition which matches the rows of the given struct
+// values by their primary key, together with its arguments.
+//
+// For a single-column primary key it is `"id" = ANY($1)` and the arguments are the primary key values,
+// which must be passed as a single (array) bind parameter. For a composite primary key it is
+// `("blog_id","tag_id") IN (($1,$2),($3,$4))` and the arguments are the flattened primary key values,
+// one bind parameter each.
+func buildPrimaryKeysMatchCondition(td *Table, values []any) (string, []any, error) {
+	primaryKeys := td.PrimaryKeys()
+	if len(primaryKeys) == 0 {
+		return "", nil, fmt.Errorf("no primary key found in table definition: %s", td.Name)
+	}
+
+	if len(primaryKeys) == 1 {
+		primaryKeyName, ids, err := extractPrimaryKeyValues(td, values)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return `"` + primaryKeyName + `" = ANY($1)`, ids, nil
+	}
+
+	var b strings.Builder
+	b.WriteByte(leftParenLiteral)
+	for i, c := range primaryKeys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`"` + c.Name + `"`)
+	}
+	b.WriteString(") IN (")
+
+	args := make([]any, 0, len(values)*len(primaryKeys))
+	for i, value := range values {
+		structValue := IndirectValue(value)
+
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteByte(leftParenLiteral)
+		for j, c := range primaryKeys {
+			idValue, err := ExtractPrimaryKeyValue(c, structValue)
+			if err != nil {
+				return "", nil, err
+			}
+
+			if j > 0 {
+				b.WriteByte(',')
+			}
+
+			args = append(args, idValue)
+			b.WriteString("$" + strconv.Itoa(len(args)))
+		}
+		b.WriteByte(rightParenLiteral)
+	}
+	b.WriteByte(rightParenLiteral)
+
+	return b.String(), args, nil
+}
@@ -0,0 +1,203 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type primaryKeyTestPostTag struct {
+	PostID   int64  `pg:"type=bigint,primary"`
+	TagID    int64  `pg:"type=bigint,primary"`
+	Position int    `pg:"type=int"`
+	Label    string `pg:"type=text"`
+}
+
+// primaryKeyTestPostRevision has a composite primary key with a generated column.
+type primaryKeyTestPostRevision struct {
+	PostID int64  `pg:"type=bigint,primary"`
+	ID     string `pg:"type=uuid,primary,default=gen_random_uuid()"`
+	Body   string `pg:"type=text"`
+}
+
+func primaryKeyTestTable(t *testing.T) *Table {
+	t.Helper()
+
+	td, err := ConvertStructToTable("post_tags", reflect.TypeFor[primaryKeyTestPostTag]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return td
+}
+
+func TestPrimaryKeyValues(t *testing.T) {
+	td := primaryKeyTestTable(t)
+
+	if !td.HasCompositePrimaryKey() {
+		t.Fatalf("expected a composite primary key")
+	}
+
+	if got := len(td.PrimaryKeys()); got != 2 {
+		t.Fatalf("expected 2 primary key columns but got %d", got)
+	}
+
+	tests := []struct {
+		id             any
+		expectedValues []any
+		expectedErr    string
+	}{
+		{
+			id:             Key{"tag_id": int64(2), "post_id": int64(1)},
+			expectedValues: []any{int64(1), int64(2)},
+		},
+		{
+			id:             primaryKeyTestPostTag{PostID: 3, TagID: 4},
+			expectedValues: []any{int64(3), int64(4)},
+		},
+		{
+			id:             &primaryKeyTestPostTag{PostID: 5, TagID: 6},
+			expectedValues: []any{int64(5), int64(6)},
+		},
+		{
+			id:          Key{"post_id": int64(1)},
+			expectedErr: "expected 2 primary key values but got 1",
+		},
+		{
+			id:          Key{"post_id": int64(1), "position": 2},
+			expectedErr: "missing value for primary key column: tag_id",
+		},
+		{
+			id:          int64(1),
+			expectedErr: "composite primary key requires a Key",
+		},
+		{
+			id:          nil,
+			expectedErr: "primary key value is nil",
+		},
+	}
+
+	for i, tt := range tests {
+		values, err := td.PrimaryKeyValues(tt.id)
+		if tt.expectedErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Fatalf("[%d] expected error containing %q but got: %v", i, tt.expectedErr, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("[%d] %v", i, err)
+		}
+
+		if !reflect.DeepEqual(values, tt.expectedValues) {
+			t.Fatalf("[%d] expected values: %#v but got: %#v", i, tt.expectedValues, values)
+		}
+	}
+
+	if expected, got := `"post_id" = $2 AND "tag_id" = $3`, td.PrimaryKeyCondition(2); got != expected {
+		t.Fatalf("expected condition: %s but got: %s", expected, got)
+	}
+}
+
+func TestExtractPrimaryKey(t *testing.T) {
+	td := primaryKeyTestTable(t)
+
+	id, err := ExtractPrimaryKey(td, reflect.ValueOf(primaryKeyTestPostTag{PostID: 1, TagID: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (Key{"post_id": int64(1), "tag_id": int64(2)}); !reflect.DeepEqual(id, expected) {
+		t.Fatalf("expected key: %#v but got: %#v", expected, id)
+	}
+
+	// single-column primary keys keep returning the plain value.
+	single, err := ConvertStructToTable("documents", reflect.TypeFor[versionTestDocument]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err = ExtractPrimaryKey(single, reflect.ValueOf(versionTestDocument{ID: 7}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != int64(7) {
+		t.Fatalf("expected id: 7 but got: %#v", id)
+	}
+}
+
+func TestCompositePrimaryKeyQueries(t *testing.T) {
+	td := primaryKeyTestTable(t)
+
+	createQuery := BuildCreateTableQuery(td)
+	if expected := `PRIMARY KEY ("post_id", "tag_id")`; !strings.Contains(createQuery, expected) {
+		t.Fatalf("expected create table query to contain %s but got: %s", expected, createQuery)
+	}
+
+	primaryKey, _ := td.PrimaryKey()
+	value := primaryKeyTestPostTag{PostID: 1, TagID: 2, Position: 3, Label: "go"}
+
+	updateQuery, updateArgs, err := BuildUpdateQuery(value, []string{"label"}, true, primaryKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `UPDATE "post_tags" SET "label" = $1 WHERE "post_id" = $2 AND "tag_id" = $3 RETURNING "post_id";`; updateQuery != expected {
+		t.Fatalf("expected update query:\n%s\nbut got:\n%s", expected, updateQuery)
+	}
+
+	if expected := []any{"go", int64(1), int64(2)}; !reflect.DeepEqual(updateArgs, expected) {
+		t.Fatalf("expected update args: %#v but got: %#v", expected, updateArgs)
+	}
+
+	deleteQuery, deleteArgs, err := BuildDeleteQuery(td, []any{value, primaryKeyTestPostTag{PostID: 1, TagID: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `DELETE FROM "post_tags" WHERE ("post_id","tag_id") IN (($1,$2),($3,$4));`; deleteQuery != expected {
+		t.Fatalf("expected delete query:\n%s\nbut got:\n%s", expected, deleteQuery)
+	}
+
+	if expected := []any{int64(1), int64(2), int64(1), int64(5)}; !reflect.DeepEqual(deleteArgs, expected) {
+		t.Fatalf("expected delete args: %#v but got: %#v", expected, deleteArgs)
+	}
+
+	// the copy would have the same primary key as the original row.
+	var newID Key
+	if _, err = BuildDuplicateQuery(td, &newID); err == nil {
+		t.Fatal("expected an error for a composite primary key without a generated column")
+	}
+
+	generatedTD, err := ConvertStructToTable("post_revisions", reflect.TypeFor[primaryKeyTestPostRevision]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicateQuery, err := BuildDuplicateQuery(generatedTD, &newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `INSERT INTO "public"."post_revisions" (post_id,body) SELECT post_id,body FROM "public"."post_revisions" WHERE post_id = $1 AND id = $2 RETURNING post_id, id;`; duplicateQuery != expected {
+		t.Fatalf("expected duplicate query:\n%s\nbut got:\n%s", expected, duplicateQuery)
+	}
+
+	insertQuery, _, err := BuildInsertQuery(td, reflect.ValueOf(value), &newID, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := ` RETURNING post_id, tag_id;`; !strings.HasSuffix(insertQuery, expected) {
+		t.Fatalf("expected insert query to end with %s but got: %s", expected, insertQuery)
+	}
+
+	// only the first primary key column would be returned into a plain value.
+	var id int64
+	if _, _, err = BuildInsertQuery(td, reflect.ValueOf(value), &id, "", false); err == nil {
+		t.Fatal("expected an error for a composite primary key returned into a non-Key")
+	}
+}
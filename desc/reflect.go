@@ -64,6 +64,10 @@ func lookupStructFields(typ reflect.Type, parentIndex []int) (fields []reflect.S
 			continue
 		}
 
+		if IsRelationField(field) { // skip relation fields, they are not columns, see lookupRelations.
+			continue
+		}
+
 		fieldType := IndirectType(field.Type) // get the underlying type of the field
 
 		if fieldType.Kind() == reflect.Struct { // if the field is a struct itself and it's not time, flatten it
@@ -73,7 +77,7 @@ func lookupStructFields(typ reflect.Type, parentIndex []int) (fields []reflect.S
 					continue
 				}
 
-				if c, _ := convertStructFieldToColumnDefinion("", field); c != nil {
+				if c, _ := convertStructFieldToColumnDefinion("", field, nil); c != nil {
 					if c.Presenter {
 						continue
 					}
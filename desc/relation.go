@@ -0,0 +1,223 @@
+package desc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RelationType is the kind of a relation between two tables.
+type RelationType uint8
+
+// These are the possible values for RelationType.
+const (
+	InvalidRelation    RelationType = iota // InvalidRelation is the zero value for RelationType
+	RelationBelongsTo                      // RelationBelongsTo: the table holds the foreign key to the related row, e.g. Post.Blog
+	RelationHasMany                        // RelationHasMany: the related rows hold the foreign key to the table, e.g. Blog.Posts
+	RelationManyToMany                     // RelationManyToMany: a join table holds the foreign keys to both tables, e.g. Post.Tags
+)
+
+var relationTypeText = map[RelationType]string{
+	RelationBelongsTo:  "belongs_to",
+	RelationHasMany:    "has_many",
+	RelationManyToMany: "many_to_many",
+}
+
+// String returns the struct tag text of the relation type, e.g. "has_many".
+func (t RelationType) String() string {
+	if text, ok := relationTypeText[t]; ok {
+		return text
+	}
+
+	return fmt.Sprintf("RelationType(unexpected %d)", t)
+}
+
+func parseRelationType(s string) RelationType {
+	for t, text := range relationTypeText {
+		if text == s {
+			return t
+		}
+	}
+
+	return InvalidRelation
+}
+
+// Relation describes a struct field which holds the related rows of another table,
+// declared by the `rel` struct tag option, e.g.
+//
+//	type Blog struct {
+//		ID    int64  `pg:"type=bigserial,primary"`
+//		Posts []Post `pg:"rel=has_many,fk=blog_id"`
+//	}
+//
+//	type Post struct {
+//		ID     int64 `pg:"type=bigserial,primary"`
+//		BlogID int64 `pg:"type=bigint,ref=blogs(id)"`
+//		Blog   *Blog `pg:"rel=belongs_to,fk=blog_id"`
+//		Tags   []Tag `pg:"rel=many_to_many,join=post_tags,fk=post_id,join_fk=tag_id"`
+//	}
+//
+// A relation field is not a column: it is filled by the Repository.Preload method.
+// The fk (and join_fk) options may be omitted when the foreign key column is the only one
+// which references the related table through its `ref` struct tag option.
+type Relation struct {
+	// Name is the name of the struct field, e.g. "Posts".
+	Name string
+	// Type is the kind of the relation.
+	Type RelationType
+	// FieldIndex is the index of the struct field, see reflect.Value.FieldByIndex.
+	FieldIndex []int
+	// FieldType is the type of the struct field: a struct or a pointer to a struct for belongs-to relations,
+	// a slice of structs or pointers to structs otherwise.
+	FieldType reflect.Type
+	// TargetType is the struct type of the related table.
+	TargetType reflect.Type
+
+	// ForeignKey is the foreign key column: the table's one for belongs-to relations (e.g. "blog_id" of Post.Blog),
+	// the related table's one for has-many relations (e.g. "blog_id" of Blog.Posts) and the join table's one
+	// which references the table for many-to-many relations (e.g. "post_id" of Post.Tags).
+	ForeignKey string
+	// JoinTable is the join table of a many-to-many relation, e.g. "post_tags".
+	JoinTable string
+	// JoinForeignKey is the join table's column which references the related table
+	// in a many-to-many relation, e.g. "tag_id" of Post.Tags.
+	JoinForeignKey string
+}
+
+// IsRelationField reports whether the struct field is a relation field, i.e. its `pg` struct tag
+// has a `rel` option. Relation fields are not columns, see Relation.
+func IsRelationField(field reflect.StructField) bool {
+	for opt := range strings.SplitSeq(field.Tag.Get(DefaultTag), ",") {
+		if key, _, _ := strings.Cut(opt, "="); key == "rel" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupRelations returns the relations declared by the exported fields of the given struct type.
+func lookupRelations(typ reflect.Type) ([]*Relation, error) {
+	var relations []*Relation
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if field.PkgPath != "" || !IsRelationField(field) {
+			continue
+		}
+
+		rel, err := convertStructFieldToRelation(field)
+		if err != nil {
+			return nil, err
+		}
+
+		relations = append(relations, rel)
+	}
+
+	return relations, nil
+}
+
+// convertStructFieldToRelation parses the `pg` struct tag of a relation field.
+func convertStructFieldToRelation(field reflect.StructField) (*Relation, error) {
+	rel := &Relation{
+		Name:       field.Name,
+		FieldIndex: field.Index,
+		FieldType:  field.Type,
+	}
+
+	for opt := range strings.SplitSeq(field.Tag.Get(DefaultTag), ",") {
+		if opt == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok {
+			return nil, fmt.Errorf("struct field: %s: relation: option: %s: expected key value separated by '='", field.Name, opt)
+		}
+
+		switch key {
+		case "rel":
+			rel.Type = parseRelationType(value)
+			if rel.Type == InvalidRelation {
+				return nil, fmt.Errorf("struct field: %s: relation: invalid type: %s", field.Name, value)
+			}
+		case "fk":
+			rel.ForeignKey = value
+		case "join":
+			rel.JoinTable = value
+		case "join_fk":
+			rel.JoinForeignKey = value
+		default:
+			return nil, fmt.Errorf("struct field: %s: relation: unexpected tag option: %s", field.Name, key)
+		}
+	}
+
+	typ := field.Type
+	if rel.Type != RelationBelongsTo {
+		if typ.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("struct field: %s: relation: %s: expected a slice of structs but got: %s", field.Name, rel.Type, typ)
+		}
+
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("struct field: %s: relation: %s: expected a struct type but got: %s", field.Name, rel.Type, field.Type)
+	}
+
+	rel.TargetType = typ
+
+	if rel.Type == RelationManyToMany {
+		if err := validateIdentifier(rel.JoinTable); err != nil {
+			return nil, fmt.Errorf("struct field: %s: relation: join table: %w", field.Name, err)
+		}
+	} else if rel.JoinTable != "" || rel.JoinForeignKey != "" {
+		return nil, fmt.Errorf("struct field: %s: relation: %s: the join and join_fk options are for many_to_many relations", field.Name, rel.Type)
+	}
+
+	for _, columnName := range []string{rel.ForeignKey, rel.JoinForeignKey} {
+		if columnName == "" {
+			continue
+		}
+
+		if err := validateIdentifier(columnName); err != nil {
+			return nil, fmt.Errorf("struct field: %s: relation: foreign key: %w", field.Name, err)
+		}
+	}
+
+	return rel, nil
+}
+
+// GetRelation returns the relation of the given struct field name or nil.
+func (td *Table) GetRelation(name string) *Relation {
+	for _, rel := range td.Relations {
+		if rel.Name == name {
+			return rel
+		}
+	}
+
+	return nil
+}
+
+// ReferencingColumn returns the column of the table which references the given table through its
+// `ref` struct tag option. It returns nil if there is none or more than one, e.g. a self-referencing table.
+func (td *Table) ReferencingColumn(tableName string) *Column {
+	var found *Column
+	for _, c := range td.Columns {
+		if c.ReferenceTableName != tableName {
+			continue
+		}
+
+		if found != nil {
+			return nil // ambiguous.
+		}
+
+		found = c
+	}
+
+	return found
+}
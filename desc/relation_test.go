@@ -0,0 +1,111 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type relationTestBlog struct {
+	ID    int64              `pg:"type=bigserial,primary"`
+	Name  string             `pg:"type=varchar(255)"`
+	Posts []relationTestPost `pg:"rel=has_many,fk=blog_id"`
+}
+
+type relationTestPost struct {
+	ID     int64              `pg:"type=bigserial,primary"`
+	BlogID int64              `pg:"type=bigint,ref=blogs(id)"`
+	Blog   *relationTestBlog  `pg:"rel=belongs_to"`
+	Tags   []*relationTestTag `pg:"rel=many_to_many,join=post_tags,fk=post_id,join_fk=tag_id"`
+}
+
+type relationTestTag struct {
+	ID   int64  `pg:"type=bigserial,primary"`
+	Name string `pg:"type=text"`
+}
+
+func TestRelations(t *testing.T) {
+	td, err := ConvertStructToTable("posts", reflect.TypeFor[relationTestPost]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(td.Columns); got != 2 {
+		t.Fatalf("expected the relation fields not to be columns but got %d columns", got)
+	}
+
+	expected := []*Relation{
+		{
+			Name:       "Blog",
+			Type:       RelationBelongsTo,
+			FieldIndex: []int{2},
+			FieldType:  reflect.TypeFor[*relationTestBlog](),
+			TargetType: reflect.TypeFor[relationTestBlog](),
+		},
+		{
+			Name:           "Tags",
+			Type:           RelationManyToMany,
+			FieldIndex:     []int{3},
+			FieldType:      reflect.TypeFor[[]*relationTestTag](),
+			TargetType:     reflect.TypeFor[relationTestTag](),
+			ForeignKey:     "post_id",
+			JoinTable:      "post_tags",
+			JoinForeignKey: "tag_id",
+		},
+	}
+	if !reflect.DeepEqual(td.Relations, expected) {
+		t.Fatalf("expected relations:\n%#v\nbut got:\n%#v", expected, td.Relations)
+	}
+
+	if c := td.ReferencingColumn("blogs"); c == nil || c.Name != "blog_id" {
+		t.Fatalf("expected the blog_id column to reference the blogs table but got: %v", c)
+	}
+
+	blogTd, err := ConvertStructToTable("blogs", reflect.TypeFor[relationTestBlog]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rel := blogTd.GetRelation("Posts"); rel == nil || rel.Type != RelationHasMany || rel.ForeignKey != "blog_id" {
+		t.Fatalf("expected a has_many relation but got: %#v", rel)
+	}
+
+	if createTableQuery := BuildCreateTableQuery(blogTd); strings.Contains(createTableQuery, "posts") {
+		t.Fatalf("expected no relation column but got:\n%s", createTableQuery)
+	}
+}
+
+func TestRelationsInvalid(t *testing.T) {
+	tests := []struct {
+		typ         reflect.Type
+		expectedErr string
+	}{
+		{reflect.TypeFor[struct {
+			Blog relationTestBlog `pg:"rel=has_one"`
+		}](), "invalid type: has_one"},
+		{reflect.TypeFor[struct {
+			Posts relationTestPost `pg:"rel=has_many"`
+		}](), "expected a slice of structs"},
+		{reflect.TypeFor[struct {
+			Names []string `pg:"rel=has_many"`
+		}](), "expected a struct type"},
+		{reflect.TypeFor[struct {
+			Tags []relationTestTag `pg:"rel=many_to_many"`
+		}](), "join table: desc: invalid identifier"},
+		{reflect.TypeFor[struct {
+			Posts []relationTestPost `pg:"rel=has_many,join=blog_posts"`
+		}](), "the join and join_fk options are for many_to_many relations"},
+		{reflect.TypeFor[struct {
+			Posts []relationTestPost `pg:"rel=has_many,fk=blog id"`
+		}](), "foreign key: desc: invalid identifier"},
+		{reflect.TypeFor[struct {
+			Posts []relationTestPost `pg:"rel=has_many,type=text"`
+		}](), "unexpected tag option: type"},
+	}
+
+	for i, tt := range tests {
+		if _, err := ConvertStructToTable("blogs", tt.typ); err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+			t.Fatalf("[%d] expected error containing %q but got: %v", i, tt.expectedErr, err)
+		}
+	}
+}
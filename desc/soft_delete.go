@@ -0,0 +1,47 @@
+package desc
+
+// SoftDeleteScope controls which rows of a table with a soft-delete column (see
+// Column.SoftDelete) a query should see.
+type SoftDeleteScope uint8
+
+const (
+	// SoftDeleteScopeActive hides soft-deleted rows, i.e. rows whose soft-delete column is not NULL.
+	// It is the zero value, so a table with a soft-delete column hides its deleted rows by default.
+	SoftDeleteScopeActive SoftDeleteScope = iota
+	// SoftDeleteScopeAll sees every row, soft-deleted or not.
+	SoftDeleteScopeAll
+	// SoftDeleteScopeDeleted sees only soft-deleted rows.
+	SoftDeleteScopeDeleted
+)
+
+// SoftDeleteColumn returns the column marked with the `soft_delete` struct tag option
+// and reports whether the table has one.
+func (td *Table) SoftDeleteColumn() (*Column, bool) {
+	for _, c := range td.Columns {
+		if c.SoftDelete {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// SoftDeleteCondition returns the SQL boolean expression that filters the table's rows for the
+// given scope, e.g. `"deleted_at" IS NULL` for SoftDeleteScopeActive and
+// `"deleted_at" IS NOT NULL` for SoftDeleteScopeDeleted. It returns an empty string
+// when the table has no soft-delete column or the scope is SoftDeleteScopeAll.
+func (td *Table) SoftDeleteCondition(scope SoftDeleteScope) string {
+	c, ok := td.SoftDeleteColumn()
+	if !ok {
+		return ""
+	}
+
+	switch scope {
+	case SoftDeleteScopeActive:
+		return `"` + c.Name + `" IS NULL`
+	case SoftDeleteScopeDeleted:
+		return `"` + c.Name + `" IS NOT NULL`
+	default:
+		return ""
+	}
+}
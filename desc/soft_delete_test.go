@@ -0,0 +1,129 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type softDeleteTestUser struct {
+	ID        int64      `pg:"type=bigint,primary"`
+	Email     string     `pg:"type=text"`
+	DeletedAt *time.Time `pg:"type=timestamp,soft_delete"`
+}
+
+func TestSoftDeleteColumnTag(t *testing.T) {
+	td, err := ConvertStructToTable("users", reflect.TypeFor[softDeleteTestUser]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, ok := td.SoftDeleteColumn()
+	if !ok {
+		t.Fatal("expected a soft-delete column")
+	}
+
+	if c.Name != "deleted_at" || !c.Nullable {
+		t.Fatalf("expected a nullable deleted_at column but got: %s (nullable: %v)", c.Name, c.Nullable)
+	}
+
+	if expected, got := `"deleted_at" IS NULL`, td.SoftDeleteCondition(SoftDeleteScopeActive); got != expected {
+		t.Fatalf("expected active condition: %s but got: %s", expected, got)
+	}
+
+	if expected, got := `"deleted_at" IS NOT NULL`, td.SoftDeleteCondition(SoftDeleteScopeDeleted); got != expected {
+		t.Fatalf("expected deleted condition: %s but got: %s", expected, got)
+	}
+
+	if got := td.SoftDeleteCondition(SoftDeleteScopeAll); got != "" {
+		t.Fatalf("expected empty condition for all scope but got: %s", got)
+	}
+}
+
+func TestSoftDeleteColumnTagInvalid(t *testing.T) {
+	type invalidType struct {
+		ID        int64  `pg:"type=bigint,primary"`
+		DeletedAt string `pg:"type=text,soft_delete"`
+	}
+
+	if _, err := ConvertStructToTable("invalid_type", reflect.TypeFor[invalidType]()); err == nil || !strings.Contains(err.Error(), "soft_delete") {
+		t.Fatalf("expected a soft_delete data type error but got: %v", err)
+	}
+
+	type twoColumns struct {
+		ID        int64      `pg:"type=bigint,primary"`
+		DeletedAt *time.Time `pg:"type=timestamp,soft_delete"`
+		RemovedAt *time.Time `pg:"type=timestamp,soft_delete"`
+	}
+
+	if _, err := ConvertStructToTable("two_columns", reflect.TypeFor[twoColumns]()); err == nil || !strings.Contains(err.Error(), "only one column") {
+		t.Fatalf("expected a single soft_delete column error but got: %v", err)
+	}
+}
+
+func TestBuildDeleteQuerySoftDelete(t *testing.T) {
+	td, err := ConvertStructToTable("users", reflect.TypeFor[softDeleteTestUser]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []any{softDeleteTestUser{ID: 1}, softDeleteTestUser{ID: 2}}
+
+	query, ids, err := BuildDeleteQuery(td, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `UPDATE "users" SET "deleted_at" = now() WHERE "id" = ANY($1) AND "deleted_at" IS NULL;`
+	if query != expected {
+		t.Fatalf("expected query:\n%s\nbut got:\n%s", expected, query)
+	}
+
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 ids but got: %v", ids)
+	}
+
+	query, _, err = BuildHardDeleteQuery(td, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `DELETE FROM "users" WHERE "id" = ANY($1);`
+	if query != expected {
+		t.Fatalf("expected query:\n%s\nbut got:\n%s", expected, query)
+	}
+}
+
+func TestBuildExistsQuerySoftDelete(t *testing.T) {
+	td, err := ConvertStructToTable("users", reflect.TypeFor[softDeleteTestUser]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := reflect.ValueOf(softDeleteTestUser{ID: 1, Email: "john@example.com"})
+
+	tests := []struct {
+		scope    SoftDeleteScope
+		expected string
+	}{
+		{SoftDeleteScopeActive, `SELECT EXISTS(SELECT 1 FROM "users" WHERE id = $1 AND email = $2 AND "deleted_at" IS NULL);`},
+		{SoftDeleteScopeDeleted, `SELECT EXISTS(SELECT 1 FROM "users" WHERE id = $1 AND email = $2 AND "deleted_at" IS NOT NULL);`},
+		{SoftDeleteScopeAll, `SELECT EXISTS(SELECT 1 FROM "users" WHERE id = $1 AND email = $2);`},
+	}
+
+	for _, tt := range tests {
+		query, args, err := BuildScopedExistsQuery(td, value, tt.scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if query != tt.expected {
+			t.Fatalf("[%d] expected query:\n%s\nbut got:\n%s", tt.scope, tt.expected, query)
+		}
+
+		if len(args) != 2 || args[1] != "john@example.com" {
+			t.Fatalf("[%d] unexpected args: %v", tt.scope, args)
+		}
+	}
+}
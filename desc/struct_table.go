@@ -28,10 +28,29 @@ func validateIdentifier(name string) error {
 	return nil
 }
 
+// descriptionOptionRegex matches the start of the desc (or comment) struct tag option.
+var descriptionOptionRegex = regexp.MustCompile(`(^|,)(desc|comment)=`)
+
+// cutDescriptionOption returns the tag without its desc (or comment) option and the option's value.
+// The option must be the last one, as its value is the rest of the tag,
+// e.g. pg:"type=text,desc=The email address, in lowercase".
+func cutDescriptionOption(fieldTag string) (string, string) {
+	loc := descriptionOptionRegex.FindStringIndex(fieldTag)
+	if loc == nil {
+		return fieldTag, ""
+	}
+
+	return fieldTag[:loc[0]], fieldTag[loc[1]:]
+}
+
 // ConvertStructToTable takes a table name and a reflect.Type that represents a struct type
 // and returns a pointer to a Table that represents a table definition for the database
 // or an error if the conversion fails.
-func ConvertStructToTable(tableName string, typ reflect.Type) (*Table, error) {
+//
+// The optional enums are the known enum types a field's `type` struct tag option may refer to,
+// see Column.Enum. Enum types declared by the fields' Go types (through a Values method) are resolved
+// without them.
+func ConvertStructToTable(tableName string, typ reflect.Type, enums ...*Enum) (*Table, error) {
 	if kind := typ.Kind(); kind != reflect.Struct { // check if the type is a struct
 		return nil, fmt.Errorf("invalid type: expected a struct value but got: %s", kind.String()) // if not, return an error
 	}
@@ -51,8 +70,8 @@ func ConvertStructToTable(tableName string, typ reflect.Type) (*Table, error) {
 	pgFields := lookupFields(typ, nil)           // get the exported fields of the struct that have a non-empty and non-dash value for the 'pg' tag
 	columns := make([]*Column, 0, len(pgFields)) // make a slice of pointers to Column with the same capacity as the number of fields
 	for _, field := range pgFields {             // loop over the fields
-		column, err := convertStructFieldToColumnDefinion(tableName, field) // convert each field to a column definition
-		if err != nil {                                                     // if there is an error
+		column, err := convertStructFieldToColumnDefinion(tableName, field, enums) // convert each field to a column definition
+		if err != nil {                                                            // if there is an error
 			return nil, err // return the error
 		}
 
@@ -78,9 +97,58 @@ func ConvertStructToTable(tableName string, typ reflect.Type) (*Table, error) {
 	// use the slice of column definitions
 	definition.Columns = columns
 
+	if err := validateSingleColumnOption(definition, "soft_delete", func(c *Column) bool { return c.SoftDelete }); err != nil {
+		return nil, err
+	}
+
+	if err := validateSingleColumnOption(definition, "version", func(c *Column) bool { return c.Version }); err != nil {
+		return nil, err
+	}
+
+	if err := validateSingleColumnOption(definition, "tenant", func(c *Column) bool { return c.Tenant }); err != nil {
+		return nil, err
+	}
+
+	relations, err := lookupRelations(typ)
+	if err != nil {
+		return nil, err
+	}
+	definition.Relations = relations
+
+	// collect the table-level indexes declared by the struct, if it's a TableIndexer.
+	definition.AddTableIndexes(lookupTableIndexes(typ)...)
+	if err := definition.ValidateTableIndexes(); err != nil {
+		return nil, err
+	}
+
+	// collect the table-level constraints declared by the struct, if it's a TableConstrainer.
+	definition.AddTableConstraints(lookupTableConstraints(typ)...)
+	if err := definition.ValidateTableConstraints(); err != nil {
+		return nil, err
+	}
+
 	return definition, nil // return the table definition and no error
 }
 
+// validateSingleColumnOption reports an error if more than one column of the table
+// is marked with the given struct tag option, e.g. soft_delete.
+func validateSingleColumnOption(td *Table, option string, isMarked func(*Column) bool) error {
+	var name string
+	for _, c := range td.Columns {
+		if !isMarked(c) {
+			continue
+		}
+
+		if name != "" {
+			return fmt.Errorf("table: %s: %s: only one column can be marked, found: %s and %s", td.Name, option, name, c.Name)
+		}
+
+		name = c.Name
+	}
+
+	return nil
+}
+
 const (
 	leftParenLiteral  = '('
 	rightParenLiteral = ')'
@@ -160,7 +228,7 @@ const characterVaryingCastText = "::character varying"
 // end-user input. Identifiers, in contrast (the table name, every column name and every unique
 // index name), are validated by ConvertStructToTable against the safe bare-identifier charset
 // (see validateIdentifier) before any query builder can interpolate them.
-func convertStructFieldToColumnDefinion(tableName string, field reflect.StructField) (*Column, error) {
+func convertStructFieldToColumnDefinion(tableName string, field reflect.StructField, enums []*Enum) (*Column, error) {
 	c := &Column{
 		TableName:  tableName,
 		Name:       ToColumnName(field),
@@ -172,6 +240,13 @@ func convertStructFieldToColumnDefinion(tableName string, field reflect.StructFi
 	}
 
 	fieldTag := field.Tag.Get(DefaultTag)
+	// the desc option takes the rest of the tag, so the description may contain commas.
+	fieldTag, c.Description = cutDescriptionOption(fieldTag)
+
+	var (
+		hasTypeOption bool
+		typeName      string // the value of the type option, without its argument.
+	)
 
 	options := strings.SplitSeq(fieldTag, ",")
 	for opt := range options {
@@ -222,6 +297,8 @@ func convertStructFieldToColumnDefinion(tableName string, field reflect.StructFi
 			}
 
 			c.Type, _ = ParseDataType(value) // don't take the argument, as we need validation on this step, this syndax came from end-developer.
+			hasTypeOption = true
+			typeName = value
 
 			// make ts_vector types unscannable by-default.
 			if c.Type == TsVector {
@@ -369,6 +446,24 @@ func convertStructFieldToColumnDefinion(tableName string, field reflect.StructFi
 				return c, err
 			}
 			c.Unscannable = v
+		case "soft_delete":
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return c, err
+			}
+			c.SoftDelete = v
+		case "version":
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return c, err
+			}
+			c.Version = v
+		case "tenant":
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return c, err
+			}
+			c.Tenant = v
 		default:
 			if !strings.Contains(opt, ",") {
 				// we expect this is just a name (e.g. `pg:"id"`).
@@ -389,6 +484,20 @@ func convertStructFieldToColumnDefinion(tableName string, field reflect.StructFi
 		// c.AutoGenerated = true
 	}
 
+	if c.Type == Enumerated {
+		return c, fmt.Errorf("struct field: %s: enum: expected the name of the enum type, e.g. type=status, but got: %s", field.Name, fieldTag)
+	}
+
+	if !hasTypeOption || c.Type == InvalidDataType {
+		if !hasTypeOption {
+			typeName = ""
+		}
+
+		if _, err := resolveEnumColumn(c, typeName, enums); err != nil {
+			return c, err
+		}
+	}
+
 	if c.Password && c.Type == InvalidDataType {
 		c.Type = Text
 	}
@@ -397,6 +506,28 @@ func convertStructFieldToColumnDefinion(tableName string, field reflect.StructFi
 		return c, fmt.Errorf("struct field: %s: invalid data type on tag: %s", field.Name, fieldTag)
 	}
 
+	if c.SoftDelete {
+		if !c.Type.IsTime() {
+			return c, fmt.Errorf("struct field: %s: soft_delete: expected a time data type but got: %s", field.Name, c.Type.String())
+		}
+
+		// a soft-delete column is null for live rows.
+		c.Default = nullLiteral
+		c.Nullable = true
+	}
+
+	if c.Tenant && c.Nullable {
+		return c, fmt.Errorf("struct field: %s: tenant: the column can not be nullable", field.Name)
+	}
+
+	if c.Version {
+		switch c.Type {
+		case SmallInt, Integer, BigInt:
+		default:
+			return c, fmt.Errorf("struct field: %s: version: expected an integer data type but got: %s", field.Name, c.Type.String())
+		}
+	}
+
 	// add ::character varying to default value if it's a character varying type so insertion on database and comparing with schema match.
 	if c.Type == CharacterVarying {
 		if c.Default != "" {
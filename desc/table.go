@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -64,10 +65,29 @@ type Table struct {
 	StructType  reflect.Type `json:"-"` // the type of the struct that represents the table
 	SearchPath  string       // the search path for the table
 	Name        string       // the name of the table
-	Description string       // the description of the table
+	Description string       // the description (comment) of the table, e.g. from the pg.WithDescription option
 	Strict      bool         // if true then the select queries will return an error if a column is missing from the struct's fields
+	Audited     bool         // if true then the changes of the rows are recorded in the table's history table, set by the pg.Audited registration option
 	Columns     []*Column    // a slice of pointers to Column that represents the columns of the table
 
+	// TableIndexes are the table-level indexes of the table, declared by the TableIndexer interface
+	// or the pg.WithIndexes registration option. See TableIndex.
+	TableIndexes []*TableIndex
+	// TableConstraints are the table-level CHECK and EXCLUDE constraints of the table, declared by
+	// the TableConstrainer interface or the pg.WithConstraints registration option. See TableConstraint.
+	TableConstraints []*TableConstraint
+	// Relations are the relation fields of the struct, declared by the `rel` struct tag option.
+	// They are filled by the Repository.Preload method. See Relation.
+	Relations []*Relation
+
+	// PartitionBy is the partitioning of a partitioned table, set by a registration option
+	// (e.g. pg.PartitionByRange) or by DB.ListTables. It is nil for non-partitioned tables.
+	PartitionBy *PartitionBy
+	// PartitionOf is the name of the partitioned (parent) table of a partition, set by DB.ListTables.
+	PartitionOf string
+	// PartitionBound is the bound of a partition, e.g. FOR VALUES IN ('eu'), set by DB.ListTables.
+	PartitionBound string
+
 	// PasswordHandler is the password handler used to encrypt/decrypt this table's
 	// password column(s), set from Schema.HandlePassword when the table is registered.
 	// It is nil if the schema has no password handler configured.
@@ -305,6 +325,7 @@ func (td *Table) ForeignKeyColumnNames() []string {
 
 // PrimaryKey returns the primary key's column of the
 // row definition and reports if there is one.
+// For a composite primary key it returns the first of its columns, see PrimaryKeys.
 func (td *Table) PrimaryKey() (*Column, bool) { // TODO: think of making it a static variable but keep the function somehow.
 	for _, c := range td.Columns {
 		if c.PrimaryKey {
@@ -315,6 +336,42 @@ func (td *Table) PrimaryKey() (*Column, bool) { // TODO: think of making it a st
 	return nil, false
 }
 
+// VersionColumn returns the column marked with the `version` struct tag option,
+// used for optimistic locking, and reports whether the table has one.
+func (td *Table) VersionColumn() (*Column, bool) {
+	for _, c := range td.Columns {
+		if c.Version {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// TenantColumn returns the column marked with the `tenant` struct tag option,
+// used for row-level multitenancy, and reports whether the table has one.
+func (td *Table) TenantColumn() (*Column, bool) {
+	for _, c := range td.Columns {
+		if c.Tenant {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// TenantCondition returns the SQL boolean expression that matches the rows of the tenant
+// given as the $paramIndex bind parameter, e.g. `"tenant_id" = $3`.
+// It returns an empty string when the table has no tenant column.
+func (td *Table) TenantCondition(paramIndex int) string {
+	c, ok := td.TenantColumn()
+	if !ok {
+		return ""
+	}
+
+	return `"` + c.Name + `" = $` + strconv.Itoa(paramIndex)
+}
+
 // OnConflict returns the first (and only one valid) ON CONFLICT=$Conflict.
 // This is used to specify what action to take when a row conflicts with
 // an existing row in the table.
@@ -0,0 +1,179 @@
+package desc
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TableConstraint describes a named table-level constraint, one which the per-column `check`
+// struct tag option can not express: a CHECK constraint over several columns, e.g. "starts_at < ends_at",
+// or an EXCLUDE constraint, e.g. one which prevents overlapping bookings of the same room:
+//
+//	pg.TableConstraint{
+//		Name:    "bookings_no_overlap",
+//		Exclude: []pg.ExclusionElement{{Column: "room_id", Operator: "="}, {Column: "during", Operator: "&&"}},
+//	}
+//
+// Exactly one of Check and Exclude must be set. Table constraints are declared either by the struct
+// implementing the TableConstrainer interface or by the pg.WithConstraints option of the Schema.Register method.
+type TableConstraint struct {
+	// TableName is the name of the table the constraint belongs to, it is set on registration.
+	TableName string
+	// Name is the constraint's name, it is required.
+	Name string
+
+	// Check is the boolean expression of a CHECK constraint, e.g. "starts_at < ends_at".
+	Check string
+
+	// Exclude are the elements of an EXCLUDE constraint: no two rows may have all of
+	// their elements matching by the element's operator.
+	Exclude []ExclusionElement
+	// Method is the index access method of an EXCLUDE constraint, defaults to Gist.
+	// A gist exclusion with the "=" operator over a scalar column requires the btree_gist extension,
+	// which CreateSchema creates.
+	Method IndexType
+	// Where is the predicate of a partial EXCLUDE constraint, e.g. "NOT cancelled".
+	Where string
+}
+
+// ExclusionElement is an element of an EXCLUDE constraint, <column or expression> WITH <operator>.
+type ExclusionElement struct {
+	Column   string // the column name or a parenthesized expression, e.g. "during" or "(tstzrange(starts_at, ends_at))".
+	Operator string // the operator, e.g. "=" or "&&".
+}
+
+// TableConstrainer can be implemented by a table's struct to declare its table-level constraints.
+//
+// Example:
+//
+//	func (Booking) TableConstraints() []pg.TableConstraint {
+//		return []pg.TableConstraint{
+//			{Name: "bookings_period_check", Check: "starts_at < ends_at"},
+//		}
+//	}
+type TableConstrainer interface {
+	TableConstraints() []TableConstraint
+}
+
+var tableConstrainerType = reflect.TypeFor[TableConstrainer]()
+
+// lookupTableConstraints returns the table constraints declared by the given struct type,
+// if it (or a pointer to it) implements the TableConstrainer interface.
+func lookupTableConstraints(typ reflect.Type) []TableConstraint {
+	if typ.Implements(tableConstrainerType) {
+		return reflect.Zero(typ).Interface().(TableConstrainer).TableConstraints()
+	}
+
+	if reflect.PointerTo(typ).Implements(tableConstrainerType) {
+		return reflect.New(typ).Interface().(TableConstrainer).TableConstraints()
+	}
+
+	return nil
+}
+
+// AddTableConstraints appends the given constraints to the table's TableConstraints.
+// They are validated by ValidateTableConstraints.
+func (td *Table) AddTableConstraints(constraints ...TableConstraint) {
+	for _, c := range constraints {
+		c.Exclude = slices.Clone(c.Exclude)
+		td.TableConstraints = append(td.TableConstraints, &c)
+	}
+}
+
+// ValidateTableConstraints sets the table name and the default method of the table's constraints and
+// reports an error if a constraint is invalid: its name is not a valid identifier or it is used twice,
+// it sets both or none of Check and Exclude, or an exclusion element has no operator or refers to
+// a column which does not exist.
+func (td *Table) ValidateTableConstraints() error {
+	for i, c := range td.TableConstraints {
+		c.TableName = td.Name
+
+		if err := validateIdentifier(c.Name); err != nil {
+			return fmt.Errorf("table constraint: %s: name: %w", td.Name, err)
+		}
+
+		if slices.ContainsFunc(td.TableConstraints[:i], func(other *TableConstraint) bool { return other.Name == c.Name }) {
+			return fmt.Errorf("table constraint: %s: duplicated constraint name: %s", td.Name, c.Name)
+		}
+
+		if (strings.TrimSpace(c.Check) == "") == (len(c.Exclude) == 0) {
+			return fmt.Errorf("table constraint: %s: %s: expected either a check expression or exclusion elements", td.Name, c.Name)
+		}
+
+		if len(c.Exclude) == 0 {
+			continue
+		}
+
+		if c.Method == InvalidIndex {
+			c.Method = Gist
+		}
+
+		for _, element := range c.Exclude {
+			if strings.TrimSpace(element.Column) == "" || strings.TrimSpace(element.Operator) == "" {
+				return fmt.Errorf("table constraint: %s: %s: an exclusion element requires a column and an operator", td.Name, c.Name)
+			}
+
+			if name, ok := tableIndexColumnName(element.Column); ok && td.GetColumnByName(name) == nil {
+				return fmt.Errorf("table constraint: %s: %s: column %q does not exist", td.Name, c.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetTableConstraint returns the table constraint of the given name or nil.
+func (td *Table) GetTableConstraint(name string) *TableConstraint {
+	for _, c := range td.TableConstraints {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// RequiresBtreeGist reports whether the table has a gist EXCLUDE constraint with the "=" operator,
+// which requires the btree_gist extension for scalar columns.
+func (td *Table) RequiresBtreeGist() bool {
+	return slices.ContainsFunc(td.TableConstraints, func(c *TableConstraint) bool {
+		return c.Method == Gist && slices.ContainsFunc(c.Exclude, func(element ExclusionElement) bool {
+			return strings.TrimSpace(element.Operator) == "="
+		})
+	})
+}
+
+// Definition returns the SQL definition of the constraint, e.g. CHECK (starts_at < ends_at)
+// or EXCLUDE USING gist ("room_id" WITH =, "during" WITH &&).
+func (c *TableConstraint) Definition() string {
+	if len(c.Exclude) == 0 {
+		return fmt.Sprintf("CHECK (%s)", c.Check)
+	}
+
+	elements := make([]string, 0, len(c.Exclude))
+	for _, element := range c.Exclude {
+		column := strings.TrimSpace(element.Column)
+		if name, ok := tableIndexColumnName(column); ok {
+			column = pgx.Identifier{name}.Sanitize() + column[len(name):]
+		}
+
+		elements = append(elements, column+" WITH "+strings.TrimSpace(element.Operator))
+	}
+
+	definition := fmt.Sprintf("EXCLUDE USING %s (%s)", c.Method, strings.Join(elements, ", "))
+	if c.Where != "" {
+		definition += " WHERE (" + c.Where + ")"
+	}
+
+	return definition
+}
+
+// EqualDefinition reports whether the given definition, as reported by the database's
+// pg_get_constraintdef function, matches the constraint's one. See Constraint.Definition.
+func (c *TableConstraint) EqualDefinition(definition string) bool {
+	return equalExpressions(c.Definition(), definition)
+}
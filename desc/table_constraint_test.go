@@ -0,0 +1,116 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tableConstraintTestBooking struct {
+	ID        int64  `pg:"type=bigserial,primary"`
+	RoomID    int64  `pg:"type=bigint"`
+	During    string `pg:"type=tstzrange"`
+	StartsAt  string `pg:"type=timestamp"`
+	EndsAt    string `pg:"type=timestamp"`
+	Cancelled bool   `pg:"type=boolean"`
+}
+
+func (tableConstraintTestBooking) TableConstraints() []TableConstraint {
+	return []TableConstraint{
+		{Name: "bookings_period_check", Check: "starts_at < ends_at"},
+		{
+			Name:    "bookings_no_overlap",
+			Exclude: []ExclusionElement{{Column: "room_id", Operator: "="}, {Column: "during", Operator: "&&"}},
+			Where:   "NOT cancelled",
+		},
+	}
+}
+
+func TestTableConstraints(t *testing.T) {
+	td, err := ConvertStructToTable("bookings", reflect.TypeFor[tableConstraintTestBooking]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(td.TableConstraints); got != 2 {
+		t.Fatalf("expected 2 table constraints but got: %d", got)
+	}
+
+	if !td.RequiresBtreeGist() {
+		t.Fatal("expected the table to require the btree_gist extension")
+	}
+
+	createTableQuery := BuildCreateTableQuery(td)
+	expectedQueries := []string{
+		`CONSTRAINT bookings_period_check CHECK (starts_at < ends_at)`,
+		`CONSTRAINT bookings_no_overlap EXCLUDE USING gist ("room_id" WITH =, "during" WITH &&) WHERE (NOT cancelled)`,
+	}
+	for _, expected := range expectedQueries {
+		if !strings.Contains(createTableQuery, expected) {
+			t.Fatalf("expected create table query to contain:\n%s\nbut got:\n%s", expected, createTableQuery)
+		}
+	}
+
+	// the definitions reported by the database.
+	dbDefinitions := map[string]string{
+		"bookings_period_check": "CHECK (starts_at < ends_at)",
+		"bookings_no_overlap":   "EXCLUDE USING gist (room_id WITH =, during WITH &&) WHERE (NOT cancelled)",
+	}
+	for name, definition := range dbDefinitions {
+		if c := td.GetTableConstraint(name); !c.EqualDefinition(definition) {
+			t.Fatalf("expected the constraint to match the database one:\n%s\nvs\n%s", c.Definition(), definition)
+		}
+	}
+
+	if c := td.GetTableConstraint("bookings_no_overlap"); c.EqualDefinition("EXCLUDE USING gist (room_id WITH =, during WITH &&)") {
+		t.Fatal("expected a predicate mismatch")
+	}
+}
+
+func TestTableConstraintsInvalid(t *testing.T) {
+	tests := []struct {
+		constraint  TableConstraint
+		expectedErr string
+	}{
+		{TableConstraint{Check: "starts_at < ends_at"}, "invalid identifier"},
+		{TableConstraint{Name: "bookings_check"}, "expected either a check expression or exclusion elements"},
+		{TableConstraint{Name: "bookings_check", Check: "true", Exclude: []ExclusionElement{{Column: "during", Operator: "&&"}}}, "expected either a check expression or exclusion elements"},
+		{TableConstraint{Name: "bookings_excl", Exclude: []ExclusionElement{{Column: "during"}}}, "requires a column and an operator"},
+		{TableConstraint{Name: "bookings_excl", Exclude: []ExclusionElement{{Column: "missing", Operator: "&&"}}}, `column "missing" does not exist`},
+		{TableConstraint{Name: "bookings_period_check", Check: "true"}, "duplicated constraint name: bookings_period_check"},
+	}
+
+	for i, tt := range tests {
+		td, err := ConvertStructToTable("bookings", reflect.TypeFor[tableConstraintTestBooking]())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		td.AddTableConstraints(tt.constraint)
+
+		if err = td.ValidateTableConstraints(); err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+			t.Fatalf("[%d] expected error containing %q but got: %v", i, tt.expectedErr, err)
+		}
+	}
+}
+
+func TestParseExclusionConstraint(t *testing.T) {
+	c := &Constraint{ConstraintType: ExclusionConstraintType}
+	c.Build("EXCLUDE USING gist (room_id WITH =, tstzrange(starts_at, ends_at) WITH &&) WHERE (NOT cancelled)")
+
+	expected := &ExclusionConstraint{
+		Method: Gist,
+		Elements: []ExclusionElement{
+			{Column: "room_id", Operator: "="},
+			{Column: "tstzrange(starts_at, ends_at)", Operator: "&&"},
+		},
+		Where: "NOT cancelled",
+	}
+	if !reflect.DeepEqual(c.Exclusion, expected) {
+		t.Fatalf("expected:\n%#v\nbut got:\n%#v", expected, c.Exclusion)
+	}
+
+	if err := c.BuildColumn(&Column{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,242 @@
+package desc
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TableIndex describes a table-level index, one which the per-column `index` and `unique_index`
+// struct tag options can not express: a composite non-unique index, a partial index (Where),
+// an expression index (e.g. "lower(email)"), an index with INCLUDE columns or
+// an index of a specific access method (Method).
+//
+// Table indexes are declared either by the struct implementing the TableIndexer interface
+// or by the pg.WithIndexes option of the Schema.Register method.
+type TableIndex struct {
+	// TableName is the name of the table the index belongs to, it is set on registration.
+	TableName string
+	// Name is the index's name. It defaults to "<table>_<columns>_idx" ("_key" for unique ones)
+	// when all of Columns are column names, otherwise it is required.
+	Name string
+	// Columns are the key columns or expressions of the index, in order, e.g. "tenant_id",
+	// "created_at DESC" or "lower(email)". Like in PostgreSQL, an expression which
+	// is not a function call must be written inside parentheses, e.g. "(first_name || ' ' || last_name)".
+	Columns []string
+	// Unique reports whether the index is a unique one, CREATE UNIQUE INDEX.
+	Unique bool
+	// Method is the index's access method (btree, gin, gist, brin, ...), defaults to Btree.
+	Method IndexType
+	// Include are the non-key columns of the index, the INCLUDE (...) clause.
+	Include []string
+	// Where is the predicate of a partial index, e.g. "deleted_at IS NULL".
+	Where string
+}
+
+// TableIndexer can be implemented by a table's struct to declare its table-level indexes.
+//
+// Example:
+//
+//	func (Customer) TableIndexes() []pg.TableIndex {
+//		return []pg.TableIndex{
+//			{Columns: []string{"tenant_id", "created_at DESC"}},
+//			{Name: "customers_email_lower_idx", Columns: []string{"lower(email)"}, Unique: true, Where: "deleted_at IS NULL"},
+//		}
+//	}
+type TableIndexer interface {
+	TableIndexes() []TableIndex
+}
+
+var tableIndexerType = reflect.TypeFor[TableIndexer]()
+
+// lookupTableIndexes returns the table indexes declared by the given struct type,
+// if it (or a pointer to it) implements the TableIndexer interface.
+func lookupTableIndexes(typ reflect.Type) []TableIndex {
+	if typ.Implements(tableIndexerType) {
+		return reflect.Zero(typ).Interface().(TableIndexer).TableIndexes()
+	}
+
+	if reflect.PointerTo(typ).Implements(tableIndexerType) {
+		return reflect.New(typ).Interface().(TableIndexer).TableIndexes()
+	}
+
+	return nil
+}
+
+// AddTableIndexes appends the given indexes to the table's TableIndexes.
+// They are validated by ValidateTableIndexes.
+func (td *Table) AddTableIndexes(indexes ...TableIndex) {
+	for _, idx := range indexes {
+		idx.Columns = slices.Clone(idx.Columns)
+		idx.Include = slices.Clone(idx.Include)
+		td.TableIndexes = append(td.TableIndexes, &idx)
+	}
+}
+
+// ValidateTableIndexes sets the table name, the default name and method of the table's indexes and
+// reports an error if an index is invalid: it has no columns, its name is not a valid identifier or
+// it is used twice, a column name or an included column does not exist or
+// it is a unique index of a method other than btree.
+func (td *Table) ValidateTableIndexes() error {
+	for i, idx := range td.TableIndexes {
+		idx.TableName = td.Name
+
+		if len(idx.Columns) == 0 {
+			return fmt.Errorf("table index: %s: at least one column is required", td.Name)
+		}
+
+		if idx.Method == InvalidIndex {
+			idx.Method = Btree
+		}
+
+		if idx.Unique && idx.Method != Btree {
+			return fmt.Errorf("table index: %s: a unique index must use the btree method, not %s", td.Name, idx.Method)
+		}
+
+		for _, columnName := range idx.Columns {
+			if strings.TrimSpace(columnName) == "" {
+				return fmt.Errorf("table index: %s: empty column", td.Name)
+			}
+
+			if name, ok := tableIndexColumnName(columnName); ok && td.GetColumnByName(name) == nil {
+				return fmt.Errorf("table index: %s: column %q does not exist", td.Name, name)
+			}
+		}
+
+		for _, columnName := range idx.Include {
+			if td.GetColumnByName(columnName) == nil {
+				return fmt.Errorf("table index: %s: included column %q does not exist", td.Name, columnName)
+			}
+		}
+
+		if idx.Name == "" {
+			name, err := idx.defaultName()
+			if err != nil {
+				return fmt.Errorf("table index: %s: %w", td.Name, err)
+			}
+
+			idx.Name = name
+		}
+
+		if err := validateIdentifier(idx.Name); err != nil {
+			return fmt.Errorf("table index: %s: name: %w", td.Name, err)
+		}
+
+		if slices.ContainsFunc(td.TableIndexes[:i], func(other *TableIndex) bool { return other.Name == idx.Name }) {
+			return fmt.Errorf("table index: %s: duplicated index name: %s", td.Name, idx.Name)
+		}
+	}
+
+	return nil
+}
+
+// GetTableIndex returns the table index of the given name or nil.
+func (td *Table) GetTableIndex(name string) *TableIndex {
+	for _, idx := range td.TableIndexes {
+		if idx.Name == name {
+			return idx
+		}
+	}
+
+	return nil
+}
+
+// defaultName returns "<table>_<columns>_idx", or "<table>_<columns>_key" for a unique index.
+// It returns an error if one of the key columns is an expression.
+func (idx *TableIndex) defaultName() (string, error) {
+	names := make([]string, 0, len(idx.Columns))
+	for _, columnName := range idx.Columns {
+		name, ok := tableIndexColumnName(columnName)
+		if !ok {
+			return "", fmt.Errorf("a name is required for the expression index on: %s", columnName)
+		}
+
+		names = append(names, name)
+	}
+
+	suffix := "idx"
+	if idx.Unique {
+		suffix = "key"
+	}
+
+	return fmt.Sprintf("%s_%s_%s", idx.TableName, strings.Join(names, "_"), suffix), nil
+}
+
+var tableIndexColumnRegex = regexp.MustCompile(`(?i)^([A-Za-z_][A-Za-z0-9_$]*)((\s+(ASC|DESC))?(\s+NULLS\s+(FIRST|LAST))?)$`)
+
+// tableIndexColumnName returns the column name of a table index key, e.g. "created_at" of "created_at DESC",
+// and reports false if the key is an expression.
+func tableIndexColumnName(key string) (string, bool) {
+	matches := tableIndexColumnRegex.FindStringSubmatch(strings.TrimSpace(key))
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	return matches[1], true
+}
+
+// BuildCreateTableIndexQuery returns the CREATE INDEX IF NOT EXISTS query of the given table index.
+func BuildCreateTableIndexQuery(idx *TableIndex) string {
+	var b strings.Builder
+
+	b.WriteString("CREATE ")
+	if idx.Unique {
+		b.WriteString("UNIQUE ")
+	}
+	fmt.Fprintf(&b, "INDEX IF NOT EXISTS %s ON %s USING %s (", idx.Name, idx.TableName, idx.Method.String())
+
+	for i, key := range idx.Columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		key = strings.TrimSpace(key)
+		if name, ok := tableIndexColumnName(key); ok { // quote column names, keep the ordering options.
+			key = pgx.Identifier{name}.Sanitize() + key[len(name):]
+		}
+
+		b.WriteString(key)
+	}
+	b.WriteByte(')')
+
+	if len(idx.Include) > 0 {
+		include := make([]string, 0, len(idx.Include))
+		for _, columnName := range idx.Include {
+			include = append(include, pgx.Identifier{columnName}.Sanitize())
+		}
+
+		fmt.Fprintf(&b, " INCLUDE (%s)", strings.Join(include, ", "))
+	}
+
+	if idx.Where != "" {
+		b.WriteString(" WHERE " + idx.Where)
+	}
+
+	b.WriteByte(';')
+	return b.String()
+}
+
+// Equal reports whether the two table indexes have the same table, name, uniqueness, method,
+// key columns, included columns and predicate.
+//
+// PostgreSQL rewrites the expressions and predicates it stores (e.g. "lower(email)" is reported as
+// "lower(email::text)"), so they are compared after removing type casts, redundant parentheses,
+// double quotes, whitespace, the default ASC ordering and case, see equalExpressions.
+func (idx *TableIndex) Equal(other *TableIndex) bool {
+	return idx.TableName == other.TableName &&
+		idx.Name == other.Name &&
+		idx.Unique == other.Unique &&
+		idx.Method == other.Method &&
+		slices.EqualFunc(idx.Columns, other.Columns, equalExpressions) &&
+		slices.EqualFunc(idx.Include, other.Include, equalExpressions) &&
+		equalExpressions(idx.Where, other.Where)
+}
+
+// String returns the CREATE INDEX query of the table index.
+func (idx *TableIndex) String() string {
+	return BuildCreateTableIndexQuery(idx)
+}
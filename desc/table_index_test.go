@@ -0,0 +1,108 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tableIndexTestCustomer struct {
+	ID        int64    `pg:"type=bigserial,primary"`
+	TenantID  int64    `pg:"type=bigint"`
+	Email     string   `pg:"type=varchar(255)"`
+	Tags      []string `pg:"type=text[]"`
+	DeletedAt *string  `pg:"type=timestamp,nullable"`
+	CreatedAt string   `pg:"type=timestamp"`
+}
+
+func (tableIndexTestCustomer) TableIndexes() []TableIndex {
+	return []TableIndex{
+		{Columns: []string{"tenant_id", "created_at DESC"}, Include: []string{"email"}},
+		{Name: "customers_email_lower_idx", Columns: []string{"lower(email)"}, Unique: true, Where: "deleted_at IS NULL"},
+		{Columns: []string{"tags"}, Method: Gin},
+	}
+}
+
+func TestTableIndexes(t *testing.T) {
+	td, err := ConvertStructToTable("customers", reflect.TypeFor[tableIndexTestCustomer]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(td.TableIndexes); got != 3 {
+		t.Fatalf("expected 3 table indexes but got: %d", got)
+	}
+
+	createTableQuery := BuildCreateTableQuery(td)
+	expectedQueries := []string{
+		`CREATE INDEX IF NOT EXISTS customers_tenant_id_created_at_idx ON customers USING btree ("tenant_id", "created_at" DESC) INCLUDE ("email");`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS customers_email_lower_idx ON customers USING btree (lower(email)) WHERE deleted_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS customers_tags_idx ON customers USING gin ("tags");`,
+	}
+	for _, expected := range expectedQueries {
+		if !strings.Contains(createTableQuery, expected) {
+			t.Fatalf("expected create table query to contain:\n%s\nbut got:\n%s", expected, createTableQuery)
+		}
+	}
+
+	// the definition reported by the database.
+	dbIndex := &TableIndex{
+		TableName: "customers",
+		Name:      "customers_email_lower_idx",
+		Columns:   []string{"lower(email::text)"},
+		Unique:    true,
+		Method:    Btree,
+		Include:   []string{},
+		Where:     "deleted_at IS NULL",
+	}
+	if idx := td.GetTableIndex("customers_email_lower_idx"); !idx.Equal(dbIndex) {
+		t.Fatalf("expected the index to match the database one:\n%s\nvs\n%s", idx, dbIndex)
+	}
+
+	dbIndex.Where = "deleted_at IS NOT NULL"
+	if idx := td.GetTableIndex("customers_email_lower_idx"); idx.Equal(dbIndex) {
+		t.Fatal("expected a predicate mismatch")
+	}
+}
+
+func TestTableIndexesInvalid(t *testing.T) {
+	tests := []struct {
+		index       TableIndex
+		expectedErr string
+	}{
+		{TableIndex{}, "at least one column is required"},
+		{TableIndex{Columns: []string{"missing"}}, `column "missing" does not exist`},
+		{TableIndex{Columns: []string{"email"}, Include: []string{"missing"}}, `included column "missing" does not exist`},
+		{TableIndex{Columns: []string{"lower(email)"}}, "a name is required"},
+		{TableIndex{Columns: []string{"tags"}, Unique: true, Method: Gin}, "must use the btree method"},
+		{TableIndex{Name: "bad name", Columns: []string{"email"}}, "invalid identifier"},
+	}
+
+	type customer struct {
+		Email string   `pg:"type=varchar(255)"`
+		Tags  []string `pg:"type=text[]"`
+	}
+
+	for i, tt := range tests {
+		td, err := ConvertStructToTable("customers", reflect.TypeFor[customer]())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		td.AddTableIndexes(tt.index)
+
+		if err = td.ValidateTableIndexes(); err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+			t.Fatalf("[%d] expected error containing %q but got: %v", i, tt.expectedErr, err)
+		}
+	}
+
+	td, err := ConvertStructToTable("customers", reflect.TypeFor[tableIndexTestCustomer]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	td.AddTableIndexes(TableIndex{Columns: []string{"tags"}})
+	if err = td.ValidateTableIndexes(); err == nil || !strings.Contains(err.Error(), "duplicated index name: customers_tags_idx") {
+		t.Fatalf("expected a duplicated index name error but got: %v", err)
+	}
+}
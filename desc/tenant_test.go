@@ -0,0 +1,94 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tenantTestOrder struct {
+	ID       int64  `pg:"type=bigint,primary"`
+	TenantID int64  `pg:"type=bigint,tenant"`
+	Total    int    `pg:"type=int"`
+	Note     string `pg:"type=text"`
+}
+
+func TestBuildUpdateQueryTenant(t *testing.T) {
+	td, err := ConvertStructToTable("orders", reflect.TypeFor[tenantTestOrder]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := `"tenant_id" = $3`, td.TenantCondition(3); got != expected {
+		t.Fatalf("expected condition: %s but got: %s", expected, got)
+	}
+
+	primaryKey, _ := td.PrimaryKey()
+	value := tenantTestOrder{ID: 7, TenantID: 42, Total: 10, Note: "note"}
+
+	tests := []struct {
+		columnsToUpdate []string
+		expectedQuery   string
+		expectedArgs    []any
+	}{
+		{
+			expectedQuery: `UPDATE "orders" SET "total" = $2,"note" = $3 WHERE "tenant_id" = $1 AND "id" = $4;`,
+			expectedArgs:  []any{int64(42), 10, "note", int64(7)},
+		},
+		{
+			// the tenant column is never set from the value, even if asked to.
+			columnsToUpdate: []string{"total", "tenant_id"},
+			expectedQuery:   `UPDATE "orders" SET "total" = $2 WHERE "tenant_id" = $1 AND "id" = $3;`,
+			expectedArgs:    []any{int64(42), 10, int64(7)},
+		},
+	}
+
+	for i, tt := range tests {
+		query, args, err := BuildUpdateQuery(value, tt.columnsToUpdate, false, primaryKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if query != tt.expectedQuery {
+			t.Fatalf("[%d] expected query:\n%s\nbut got:\n%s", i, tt.expectedQuery, query)
+		}
+
+		if !reflect.DeepEqual(args, tt.expectedArgs) {
+			t.Fatalf("[%d] expected args: %#v but got: %#v", i, tt.expectedArgs, args)
+		}
+	}
+
+	if _, _, err = BuildUpdateQuery(value, []string{"tenant_id"}, false, primaryKey); err == nil {
+		t.Fatal("expected an error when only the tenant column is asked to be updated")
+	}
+
+	query, err := BuildDuplicateQuery(td, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(query, ` WHERE id = $1 AND tenant_id = $2;`) {
+		t.Fatalf("expected the duplicate query to match the tenant too but got:\n%s", query)
+	}
+}
+
+func TestTenantColumnTagInvalid(t *testing.T) {
+	type nullableTenant struct {
+		ID       int64  `pg:"type=bigint,primary"`
+		TenantID *int64 `pg:"type=bigint,nullable,tenant"`
+	}
+
+	if _, err := ConvertStructToTable("nullable_tenant", reflect.TypeFor[nullableTenant]()); err == nil || !strings.Contains(err.Error(), "tenant") {
+		t.Fatalf("expected a nullable tenant error but got: %v", err)
+	}
+
+	type twoTenants struct {
+		ID       int64 `pg:"type=bigint,primary"`
+		TenantID int64 `pg:"type=bigint,tenant"`
+		OrgID    int64 `pg:"type=bigint,tenant"`
+	}
+
+	if _, err := ConvertStructToTable("two_tenants", reflect.TypeFor[twoTenants]()); err == nil || !strings.Contains(err.Error(), "only one column") {
+		t.Fatalf("expected a single tenant column error but got: %v", err)
+	}
+}
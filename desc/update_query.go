@@ -9,20 +9,83 @@ import (
 
 // BuildUpdateQuery builds and returns an SQL query for updating a row in the table,
 // using the given struct value and the primary key.
+//
+// The row is matched by all of the table's primary key columns (see Table.PrimaryKeys), so a
+// composite primary key is supported too; primaryKey can be any of them. The primary key values
+// are the last returned arguments, in PrimaryKeys order. A primary key column is updated only if
+// it's listed in columnsToUpdate.
+//
+// If the table has a version column (see Column.Version) the value of that column is never
+// taken from columnsToUpdate, instead the query increments it, matches the row by its current
+// value too and returns the new one, e.g.
+// UPDATE "users" SET "email" = $1,"version" = "version" + 1 WHERE "id" = $2 AND "version" = $3 RETURNING "version";
+// In that case the current version value is the last returned argument, right after the primary key ones,
+// and the query returns no rows when the row was modified (or deleted) in the meantime.
+//
+// If the table has a tenant column (see Column.Tenant) its value is never updated either, instead the
+// row is matched by it too, e.g. UPDATE "orders" SET "total" = $2 WHERE "tenant_id" = $1 AND "id" = $3;
+// In that case the tenant value is the first returned argument, unless the tenant column is part of the primary key.
 func BuildUpdateQuery(value any, columnsToUpdate []string, reportNotFound bool, primaryKey *Column) (string, []any, error) {
-	args, err := extractUpdateArguments(value, columnsToUpdate, primaryKey)
+	td := primaryKey.Table
+	primaryKeys := td.PrimaryKeys()
+
+	args, err := extractUpdateArguments(value, columnsToUpdate, primaryKeys)
 	if err != nil {
 		return "", nil, err
 	}
 
-	shouldUpdateID := slices.Contains(columnsToUpdate, primaryKey.Name)
+	primaryKeyNames := make([]string, 0, len(primaryKeys))
+	primaryKeysToUpdate := make([]string, 0, len(primaryKeys))
+	for _, c := range primaryKeys {
+		primaryKeyNames = append(primaryKeyNames, c.Name)
+		if slices.Contains(columnsToUpdate, c.Name) {
+			primaryKeysToUpdate = append(primaryKeysToUpdate, c.Name)
+		}
+	}
+
+	versionColumn, hasVersion := td.VersionColumn()
+	if hasVersion {
+		// the version column is managed by the query itself.
+		args = slices.DeleteFunc(args, func(a Argument) bool { return a.Column == versionColumn })
+	}
+
+	tenantColumn, hasTenant := td.TenantColumn()
+	hasTenant = hasTenant && !tenantColumn.PrimaryKey // a primary key one is matched already.
+	if hasTenant {
+		// the tenant column is matched, not updated: move its value first.
+		tenantField := IndirectValue(value).FieldByIndex(tenantColumn.FieldIndex)
+		if !tenantField.CanInterface() {
+			return "", nil, fmt.Errorf("tenant field value cannot be extracted")
+		}
 
-	if len(args) == 1 { // the last one is the id.
+		args = slices.DeleteFunc(args, func(a Argument) bool { return a.Column == tenantColumn })
+		args = slices.Insert(args, 0, Argument{
+			Column: tenantColumn,
+			Value:  tenantField.Interface(),
+		})
+	}
+
+	if n := len(args) - len(primaryKeys); n == 0 || (hasTenant && n == 1) { // the last ones are the primary key values.
 		return "", nil, fmt.Errorf("no arguments found for update, maybe missing struct field tag of \"%s\"", DefaultTag)
 	}
 
+	versionColumnName := ""
+	if hasVersion {
+		versionField := IndirectValue(value).FieldByIndex(versionColumn.FieldIndex)
+		if !versionField.CanInterface() {
+			return "", nil, fmt.Errorf("version field value cannot be extracted")
+		}
+
+		// add the current version value after the primary key ones.
+		args = append(args, Argument{
+			Column: versionColumn,
+			Value:  versionField.Interface(),
+		})
+		versionColumnName = versionColumn.Name
+	}
+
 	// build the SQL query using the table definition and its primary key.
-	query, err := buildUpdateQuery(primaryKey.Table, args, primaryKey.Name, shouldUpdateID, reportNotFound)
+	query, err := buildUpdateQuery(td, args, primaryKeyNames, primaryKeysToUpdate, versionColumnName, reportNotFound)
 	if err != nil {
 		return "", nil, err
 	}
@@ -30,18 +93,28 @@ func BuildUpdateQuery(value any, columnsToUpdate []string, reportNotFound bool,
 	return query, args.Values(), nil
 }
 
-// extractUpdateArguments extracts the arguments from the given struct value and returns them.
-func extractUpdateArguments(value any, columnsToUpdate []string, primaryKey *Column) (Arguments, error) {
+// extractUpdateArguments extracts the arguments from the given struct value and returns them,
+// the primary key ones last.
+func extractUpdateArguments(value any, columnsToUpdate []string, primaryKeys []*Column) (Arguments, error) {
+	if len(primaryKeys) == 0 {
+		return nil, fmt.Errorf("no primary key found")
+	}
+
 	structValue := IndirectValue(value)
 
-	id, err := ExtractPrimaryKeyValue(primaryKey, structValue)
-	if err != nil {
-		return nil, err
+	ids := make([]any, 0, len(primaryKeys))
+	for _, primaryKey := range primaryKeys {
+		id, err := ExtractPrimaryKeyValue(primaryKey, structValue)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
 	}
 
 	columnsToUpdateLength := len(columnsToUpdate)
 
-	args, err := extractArguments(primaryKey.Table, structValue, func(fieldName string) bool {
+	args, err := extractArguments(primaryKeys[0].Table, structValue, func(fieldName string) bool {
 		if columnsToUpdateLength == 0 {
 			// full update.
 			return true
@@ -63,13 +136,15 @@ func extractUpdateArguments(value any, columnsToUpdate []string, primaryKey *Col
 		return nil, fmt.Errorf(`no arguments found for update, maybe missing struct field tag of "%s"`, DefaultTag)
 	}
 
-	// Add (or move) the primary key value as the last argument,
+	// Add (or move) the primary key values as the last arguments,
 	// move is a requiremend here in order to remove a duplicated primary key name in the query;
 	// this can happen if the specified column names to update do not match the database schema.
-	args.ShiftEnd(Argument{
-		Column: primaryKey,
-		Value:  id,
-	})
+	for i, primaryKey := range primaryKeys {
+		args.ShiftEnd(Argument{
+			Column: primaryKey,
+			Value:  ids[i],
+		})
+	}
 
 	return args, nil
 }
@@ -80,29 +155,42 @@ func extractUpdateArguments(value any, columnsToUpdate []string, primaryKey *Col
 // fails validatePasswordAlg; this mirrors the same guard on the insert paths in insert_query.go
 // (BuildInsertQuery/BuildBulkInsertQuery), so every crypt-emitting builder validates PasswordAlg
 // before it's interpolated into SQL.
-func buildUpdateQuery(td *Table, args Arguments, primaryKeyName string, shouldUpdateID bool, reportNotFound bool) (string, error) {
-	var b strings.Builder
+//
+// When versionColumnName is not empty, the last argument is the current version value, see BuildUpdateQuery.
+func buildUpdateQuery(td *Table, args Arguments, primaryKeyNames, primaryKeysToUpdate []string, versionColumnName string, reportNotFound bool) (string, error) {
+	var (
+		b     strings.Builder
+		where strings.Builder
+	)
 
 	b.WriteString(`UPDATE "` + td.Name + `" SET `)
 
-	var paramIndex int
+	var setCount int
 
 	for i, a := range args {
 		c := a.Column
 
-		if !shouldUpdateID && c.Name == primaryKeyName {
-			// Do not update ID if not specifically asked to.
-			// Fixes #1.
-			continue
+		paramName := "$" + strconv.Itoa(i+1) // starts from 1.
+
+		isVersion := versionColumnName != "" && c.Name == versionColumnName
+		if isVersion || c.Tenant || slices.Contains(primaryKeyNames, c.Name) {
+			// match the row by its primary key (and version and tenant) values.
+			if where.Len() > 0 {
+				where.WriteString(" AND ")
+			}
+			fmt.Fprintf(&where, `"%s" = %s`, c.Name, paramName)
+
+			if isVersion || c.Tenant || !slices.Contains(primaryKeysToUpdate, c.Name) {
+				// Do not update ID if not specifically asked to.
+				// Fixes #1.
+				continue
+			}
 		}
 
-		if i > 0 {
+		if setCount > 0 {
 			b.WriteByte(',')
 		}
-
-		paramIndex++ // starts from 1.
-		paramIndexStr := strconv.Itoa(paramIndex)
-		paramName := "$" + paramIndexStr
+		setCount++
 
 		if c.Password {
 			if td.PasswordHandler.canEncrypt() {
@@ -119,14 +207,17 @@ func buildUpdateQuery(td *Table, args Arguments, primaryKeyName string, shouldUp
 		fmt.Fprintf(&b, `"%s" = %s`, c.Name, paramName)
 	}
 
-	primaryKeyWhereIndex := paramIndex + 1
-	if shouldUpdateID { // if updating ID, then the last argument is the ID.
-		primaryKeyWhereIndex = paramIndex
+	if versionColumnName != "" {
+		b.WriteString(`,"` + versionColumnName + `" = "` + versionColumnName + `" + 1`)
 	}
-	b.WriteString(` WHERE "` + primaryKeyName + `" = $` + strconv.Itoa(primaryKeyWhereIndex))
 
-	if reportNotFound {
-		b.WriteString(` RETURNING "` + primaryKeyName + `"`)
+	b.WriteString(` WHERE `)
+	b.WriteString(where.String())
+
+	if versionColumnName != "" {
+		b.WriteString(` RETURNING "` + versionColumnName + `"`)
+	} else if reportNotFound {
+		b.WriteString(` RETURNING "` + primaryKeyNames[0] + `"`)
 	}
 
 	b.WriteByte(';')
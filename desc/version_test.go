@@ -0,0 +1,79 @@
+package desc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type versionTestDocument struct {
+	ID      int64  `pg:"type=bigint,primary"`
+	Title   string `pg:"type=text"`
+	Body    string `pg:"type=text"`
+	Version int    `pg:"type=int,version"`
+}
+
+func TestBuildUpdateQueryVersion(t *testing.T) {
+	td, err := ConvertStructToTable("documents", reflect.TypeFor[versionTestDocument]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primaryKey, _ := td.PrimaryKey()
+	value := versionTestDocument{ID: 7, Title: "title", Body: "body", Version: 3}
+
+	tests := []struct {
+		columnsToUpdate []string
+		reportNotFound  bool
+		expectedQuery   string
+		expectedArgs    []any
+	}{
+		{
+			expectedQuery: `UPDATE "documents" SET "title" = $1,"body" = $2,"version" = "version" + 1 WHERE "id" = $3 AND "version" = $4 RETURNING "version";`,
+			expectedArgs:  []any{"title", "body", int64(7), 3},
+		},
+		{
+			// the version column is never set from the value, even if asked to.
+			columnsToUpdate: []string{"title", "version"},
+			reportNotFound:  true,
+			expectedQuery:   `UPDATE "documents" SET "title" = $1,"version" = "version" + 1 WHERE "id" = $2 AND "version" = $3 RETURNING "version";`,
+			expectedArgs:    []any{"title", int64(7), 3},
+		},
+	}
+
+	for i, tt := range tests {
+		query, args, err := BuildUpdateQuery(value, tt.columnsToUpdate, tt.reportNotFound, primaryKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if query != tt.expectedQuery {
+			t.Fatalf("[%d] expected query:\n%s\nbut got:\n%s", i, tt.expectedQuery, query)
+		}
+
+		if !reflect.DeepEqual(args, tt.expectedArgs) {
+			t.Fatalf("[%d] expected args: %#v but got: %#v", i, tt.expectedArgs, args)
+		}
+	}
+}
+
+func TestVersionColumnTagInvalid(t *testing.T) {
+	type textVersion struct {
+		ID      int64  `pg:"type=bigint,primary"`
+		Version string `pg:"type=text,version"`
+	}
+
+	if _, err := ConvertStructToTable("text_version", reflect.TypeFor[textVersion]()); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Fatalf("expected a version data type error but got: %v", err)
+	}
+
+	type twoVersions struct {
+		ID       int64 `pg:"type=bigint,primary"`
+		Version  int   `pg:"type=int,version"`
+		Revision int   `pg:"type=int,version"`
+	}
+
+	if _, err := ConvertStructToTable("two_versions", reflect.TypeFor[twoVersions]()); err == nil || !strings.Contains(err.Error(), "only one column") {
+		t.Fatalf("expected a single version column error but got: %v", err)
+	}
+}
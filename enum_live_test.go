@@ -0,0 +1,91 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestEnumSchema' -v .
+
+type enumLiveStatus string
+
+const (
+	enumLiveStatusDraft     enumLiveStatus = "draft"
+	enumLiveStatusPublished enumLiveStatus = "published"
+)
+
+func (enumLiveStatus) Values() []enumLiveStatus {
+	return []enumLiveStatus{enumLiveStatusDraft, enumLiveStatusPublished}
+}
+
+type enumLiveArticle struct {
+	ID     string         `pg:"type=uuid,primary"`
+	Status enumLiveStatus `pg:"type=test_article_status,default='draft'"`
+	Level  string         `pg:"type=test_article_level"`
+}
+
+const enumScratchTable = "test_enum_articles"
+
+func TestEnumSchema(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegisterEnum("test_article_level", "low", "high").
+		MustRegister(enumScratchTable, enumLiveArticle{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropEnumTestTypes := func() {
+		dropTestTables(ctx, db, enumScratchTable)
+		if _, err := db.Exec(ctx, "DROP TYPE IF EXISTS test_article_status, test_article_level;"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dropEnumTestTypes()
+	defer dropEnumTestTypes()
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.CheckSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRepository[enumLiveArticle](db)
+
+	var id string
+	if err = repo.InsertSingle(ctx, enumLiveArticle{Status: enumLiveStatusPublished, Level: "high"}, &id); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.SelectByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != enumLiveStatusPublished || got.Level != "high" {
+		t.Fatalf("unexpected article: %#v", got)
+	}
+
+	if err = repo.InsertSingle(ctx, enumLiveArticle{Status: "unknown", Level: "low"}, nil); err == nil {
+		t.Fatal("expected an error for a value outside of the enum")
+	}
+
+	// a value added on the database side is reported.
+	if _, err = db.Exec(ctx, "ALTER TYPE test_article_level ADD VALUE 'medium' BEFORE 'high';"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.CheckSchema(ctx)
+	if err == nil || !strings.Contains(err.Error(), `enum "test_article_level" has wrong values`) {
+		t.Fatalf("expected an enum values mismatch error but got: %v", err)
+	}
+}
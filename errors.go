@@ -349,3 +349,106 @@ func IsErrColumnNotExists(err error, col string) bool {
 
 	return strings.Contains(err.Error(), wantText)
 }
+
+// ErrStaleEntity is the sentinel every *StaleEntityError matches through errors.Is.
+// It is returned by the update methods of a table with a `version` column (optimistic locking)
+// when the row's version in the database no longer matches the struct value's one.
+var ErrStaleEntity = errors.New("pg: stale entity")
+
+// StaleEntityError is returned by Update, UpdateOnlyColumns and UpdateExceptColumns
+// (and their DB counterparts) when a table has a `version` column and no row matched
+// both the primary key and the version of the value being updated: the row was updated
+// (or deleted) by someone else since the value was read.
+//
+// Use errors.Is(err, ErrStaleEntity) to check for it or errors.AsType[*StaleEntityError](err)
+// to read its fields.
+type StaleEntityError struct {
+	// TableName is the name of the table the update was issued against.
+	TableName string
+	// ID is the primary key value of the stale entity.
+	ID any
+	// Version is the (stale) version value the update expected.
+	Version any
+}
+
+// Error implements the error interface.
+func (e *StaleEntityError) Error() string {
+	return fmt.Sprintf("pg: stale entity: %s: id: %v: version %v was modified or deleted concurrently", e.TableName, e.ID, e.Version)
+}
+
+// Is reports whether target is ErrStaleEntity.
+func (e *StaleEntityError) Is(target error) bool {
+	return target == ErrStaleEntity
+}
+
+// ErrMigrationModified is the sentinel every *MigrationModifiedError matches through errors.Is.
+var ErrMigrationModified = errors.New("pg: migration modified")
+
+// MigrationModifiedError is returned by Migrate, MigratePlan and MigrateTo when the files of applied migrations
+// were edited after they were applied: their checksum no longer matches the recorded one.
+//
+// Use errors.Is(err, ErrMigrationModified) to check for it or errors.AsType[*MigrationModifiedError](err)
+// to read its fields.
+type MigrationModifiedError struct {
+	// Versions are the versions of the modified migrations, in ascending order.
+	Versions []string
+}
+
+// Error implements the error interface.
+func (e *MigrationModifiedError) Error() string {
+	return fmt.Sprintf("pg: migration modified after it was applied: %s", strings.Join(e.Versions, ", "))
+}
+
+// Is reports whether target is ErrMigrationModified.
+func (e *MigrationModifiedError) Is(target error) bool {
+	return target == ErrMigrationModified
+}
+
+// ErrMigrationOutOfOrder is the sentinel every *MigrationOutOfOrderError matches through errors.Is.
+var ErrMigrationOutOfOrder = errors.New("pg: migration out of order")
+
+// MigrationOutOfOrderError is returned by Migrate, MigratePlan and MigrateTo when pending migrations sort before
+// the latest applied one, e.g. a file merged from a branch after a later one was deployed.
+//
+// Use errors.Is(err, ErrMigrationOutOfOrder) to check for it or errors.AsType[*MigrationOutOfOrderError](err)
+// to read its fields.
+type MigrationOutOfOrderError struct {
+	// Versions are the versions of the out-of-order migrations, in ascending order.
+	Versions []string
+	// Latest is the version of the latest applied migration.
+	Latest string
+}
+
+// Error implements the error interface.
+func (e *MigrationOutOfOrderError) Error() string {
+	return fmt.Sprintf("pg: migration out of order: %s: sorts before the applied %s", strings.Join(e.Versions, ", "), e.Latest)
+}
+
+// Is reports whether target is ErrMigrationOutOfOrder.
+func (e *MigrationOutOfOrderError) Is(target error) bool {
+	return target == ErrMigrationOutOfOrder
+}
+
+// ErrMigrationDirty is the sentinel every *MigrationDirtyError matches through errors.Is.
+var ErrMigrationDirty = errors.New("pg: migration dirty")
+
+// MigrationDirtyError is returned by Migrate, MigratePlan, MigrateDown and MigrateTo when a
+// no-transaction migration failed, or its process stopped, half-way: the database may be partially
+// migrated, so nothing runs until the migration is fixed by hand and resolved with MigrateResolve.
+//
+// Use errors.Is(err, ErrMigrationDirty) to check for it or errors.AsType[*MigrationDirtyError](err)
+// to read its fields.
+type MigrationDirtyError struct {
+	// Versions are the versions of the dirty migrations, in ascending order.
+	Versions []string
+}
+
+// Error implements the error interface.
+func (e *MigrationDirtyError) Error() string {
+	return fmt.Sprintf("pg: migration dirty: %s: resolve it with MigrateResolve", strings.Join(e.Versions, ", "))
+}
+
+// Is reports whether target is ErrMigrationDirty.
+func (e *MigrationDirtyError) Is(target error) bool {
+	return target == ErrMigrationDirty
+}
@@ -346,3 +346,56 @@ func TestAsConstraintError(t *testing.T) {
 		}
 	})
 }
+
+func TestStaleEntityError(t *testing.T) {
+	var err error = fmt.Errorf("update: %w", &StaleEntityError{TableName: "documents", ID: 7, Version: 3})
+
+	if !errors.Is(err, ErrStaleEntity) {
+		t.Fatalf("expected errors.Is(err, ErrStaleEntity) to be true for: %v", err)
+	}
+
+	staleErr, ok := errors.AsType[*StaleEntityError](err)
+	if !ok {
+		t.Fatalf("expected a *StaleEntityError in: %v", err)
+	}
+
+	if staleErr.TableName != "documents" || staleErr.ID != 7 || staleErr.Version != 3 {
+		t.Fatalf("unexpected stale entity error fields: %#v", staleErr)
+	}
+
+	if errors.Is(err, ErrNoRows) {
+		t.Fatal("expected a stale entity error to not match ErrNoRows")
+	}
+}
+
+func TestMigrationErrors(t *testing.T) {
+	var err error = fmt.Errorf("migrate: %w", &MigrationModifiedError{Versions: []string{"0001_init.sql", "0002_users.sql"}})
+
+	if !errors.Is(err, ErrMigrationModified) || errors.Is(err, ErrMigrationOutOfOrder) {
+		t.Fatalf("expected only errors.Is(err, ErrMigrationModified) to be true for: %v", err)
+	}
+
+	if expected, got := "migrate: pg: migration modified after it was applied: 0001_init.sql, 0002_users.sql", err.Error(); got != expected {
+		t.Fatalf("expected error %q but got: %q", expected, got)
+	}
+
+	err = &MigrationOutOfOrderError{Versions: []string{"0002_users.sql"}, Latest: "0003_orders.sql"}
+
+	if !errors.Is(err, ErrMigrationOutOfOrder) || errors.Is(err, ErrMigrationModified) {
+		t.Fatalf("expected only errors.Is(err, ErrMigrationOutOfOrder) to be true for: %v", err)
+	}
+
+	if expected, got := "pg: migration out of order: 0002_users.sql: sorts before the applied 0003_orders.sql", err.Error(); got != expected {
+		t.Fatalf("expected error %q but got: %q", expected, got)
+	}
+
+	err = fmt.Errorf("migrate: %w", &MigrationDirtyError{Versions: []string{"0004_index.sql"}})
+
+	if !errors.Is(err, ErrMigrationDirty) || errors.Is(err, ErrMigrationModified) {
+		t.Fatalf("expected only errors.Is(err, ErrMigrationDirty) to be true for: %v", err)
+	}
+
+	if expected, got := "migrate: pg: migration dirty: 0004_index.sql: resolve it with MigrateResolve", err.Error(); got != expected {
+		t.Fatalf("expected error %q but got: %q", expected, got)
+	}
+}
@@ -11,16 +11,22 @@ package gen
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
 	"go/format"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/kataras/pg"
+	"github.com/kataras/pg/desc"
 
 	"golang.org/x/mod/modfile"
 )
@@ -48,7 +54,8 @@ type ImportOptions struct {
 // GenerateSchemaFromDatabase connects to the database identified by i.ConnString, reads
 // its tables (via pg.DB.ListTables, filtered by i.ListTables) and writes one Go source
 // file per table plus a schema.go that registers all of them under a pg.Schema, laid out
-// according to e. It also runs goimports (see GoImportsTool) over e.RootDir afterwards, if
+// according to e. The database enum types (see pg.DB.ListEnums) are written as Go string
+// types with one constant per value to an enums.go next to schema.go, which registers them too. It also runs goimports (see GoImportsTool) over e.RootDir afterwards, if
 // the tool is found on PATH.
 func GenerateSchemaFromDatabase(ctx context.Context, i ImportOptions, e ExportOptions) error {
 	// TODO: Add support for base-type entities.
@@ -79,10 +86,18 @@ func GenerateSchemaFromDatabase(ctx context.Context, i ImportOptions, e ExportOp
 		return err
 	}
 
+	// partitions share the columns of their partitioned table, which is registered with its partitioning instead.
+	tables = slices.DeleteFunc(tables, func(table *pg.Table) bool { return table.PartitionOf != "" })
+
 	if len(tables) == 0 {
 		return nil
 	}
 
+	enums, err := db.ListEnums(ctx)
+	if err != nil {
+		return err
+	}
+
 	checkAndPrintTableColumnsMissingTypes(i, e, tables)
 
 	schemaFilename := e.GetFileName(e.RootDir, "schema.go")
@@ -100,7 +115,7 @@ func GenerateSchemaFromDatabase(ctx context.Context, i ImportOptions, e ExportOp
 	// fmt.Printf("Root import path: %s\n", rootImportPath)
 
 	rootPackageName := e.GetPackageName("")
-	schemaData, err := generateSchemaFile(&e, rootPackageName, rootImportPath, goModuleName, db.ConnectionOptions.Database, tables)
+	schemaData, err := generateSchemaFile(&e, rootPackageName, rootImportPath, goModuleName, db.ConnectionOptions.Database, tables, enums)
 	if err != nil {
 		return fmt.Errorf("generate schema: %s: %w", schemaFilename, err)
 	}
@@ -110,6 +125,19 @@ func GenerateSchemaFromDatabase(ctx context.Context, i ImportOptions, e ExportOp
 		return fmt.Errorf("write schema: %s: %w", schemaFilename, err)
 	}
 
+	if len(enums) > 0 {
+		enumsFilename := e.GetFileName(e.RootDir, "enums.go")
+		enumsData, err := generateEnumsFile(rootPackageName, enums)
+		if err != nil {
+			return fmt.Errorf("generate enums: %s: %w", enumsFilename, err)
+		}
+
+		err = os.WriteFile(enumsFilename, enumsData, e.FileMode)
+		if err != nil {
+			return fmt.Errorf("write enums: %s: %w", enumsFilename, err)
+		}
+	}
+
 	for _, td := range tables {
 		if err := validateTableFileName(td.Name); err != nil {
 			return fmt.Errorf("table: %s: %w", td.Name, err)
@@ -211,18 +239,45 @@ import (
 // Usage:
 // db, err := pg.Open(context.Background(), Schema, "connection_string_secret_here")
 var Schema = pg.NewSchema().
+	{{- range .Enums}}
+	MustRegisterEnum("{{.Name}}", {{.ValuesText}}).
+	{{- end}}
 	{{- $length := len .Tables }} 
 	{{- range $i, $table := .Tables}}
-	MustRegister("{{$table.TableName}}", {{$table.StructInitText}} {{- if $table.ReadOnly}}, pg.View {{- end}}){{- if eq $length (add $i 1)}}{{- else}}.{{- end}}
+	MustRegister("{{$table.TableName}}", {{$table.StructInitText}} {{- if $table.ReadOnly}}, pg.View {{- end}} {{- if $table.PartitionByText}}, {{$table.PartitionByText}} {{- end}}){{- if eq $length (add $i 1)}}{{- else}}.{{- end}}
 	{{- end}}
 `))
 
-func generateSchemaFile(e *ExportOptions, packageName, rootImportPath, goModuleName, databaseName string /* we need it to import the generated table entity files */, tables []*pg.Table) ([]byte, error) {
+// partitionByText returns the registration option of the given partitioning,
+// e.g. pg.PartitionByRange("created_at"), or an empty string if the table is not partitioned.
+func partitionByText(p *desc.PartitionBy) string {
+	if p == nil {
+		return ""
+	}
+
+	var funcName string
+	switch p.Strategy {
+	case desc.PartitionRange:
+		funcName = "PartitionByRange"
+	case desc.PartitionList:
+		funcName = "PartitionByList"
+	case desc.PartitionHash:
+		funcName = "PartitionByHash"
+	default:
+		return ""
+	}
+
+	return fmt.Sprintf("pg.%s(%s)", funcName, quoteStrings(p.Columns))
+}
+
+func generateSchemaFile(e *ExportOptions, packageName, rootImportPath, goModuleName, databaseName string /* we need it to import the generated table entity files */, tables []*pg.Table, enums []*pg.Enum) ([]byte, error) {
 	type TableSchemaData struct {
 		ImportPath     string // e.g. github.com/kataras/pg/gen/_testdata/customer
 		TableName      string // e.g. customers
 		StructInitText string // e.g customer.Customer{}
 		ReadOnly       bool
+		// e.g. pg.PartitionByRange("created_at")
+		PartitionByText string
 	}
 
 	tableSchemaTmplData := make([]TableSchemaData, 0, len(tables))
@@ -243,16 +298,31 @@ func generateSchemaFile(e *ExportOptions, packageName, rootImportPath, goModuleN
 
 		tableSchemaTmplData = append(tableSchemaTmplData,
 			TableSchemaData{
-				ImportPath:     tableFullPackageName,
-				TableName:      table.Name,
-				StructInitText: structInitText,
-				ReadOnly:       table.IsReadOnly(),
+				ImportPath:      tableFullPackageName,
+				TableName:       table.Name,
+				StructInitText:  structInitText,
+				ReadOnly:        table.IsReadOnly(),
+				PartitionByText: partitionByText(table.PartitionBy),
 			})
 	}
 
+	type EnumSchemaData struct {
+		Name       string // e.g. status
+		ValuesText string // e.g. "draft", "published"
+	}
+
+	enumSchemaTmplData := make([]EnumSchemaData, 0, len(enums))
+	for _, enum := range enums {
+		enumSchemaTmplData = append(enumSchemaTmplData, EnumSchemaData{
+			Name:       enum.Name,
+			ValuesText: quoteStrings(enum.Values),
+		})
+	}
+
 	tmplData := map[string]any{
 		"PackageName":  packageName,
 		"DatabaseName": databaseName,
+		"Enums":        enumSchemaTmplData,
 		"Tables":       tableSchemaTmplData,
 	}
 
@@ -269,6 +339,111 @@ func generateSchemaFile(e *ExportOptions, packageName, rootImportPath, goModuleN
 	return result, nil
 }
 
+var generateEnumsTmpl = template.Must(
+	template.New("").Parse(`
+// Code generated by pg. DO NOT EDIT.
+package {{.PackageName}}
+
+{{range .Enums}}
+// {{.TypeName}} represents the values of the {{.Name}} database enum type.
+type {{.TypeName}} string
+
+// The {{.TypeName}} values, in their database sort order.
+const (
+	{{- $typeName := .TypeName }}
+	{{- range .Values}}
+	{{.ConstName}} {{$typeName}} = {{.Text}}
+	{{- end}}
+)
+
+// Values returns all the {{.TypeName}} values, in their database sort order.
+func ({{.TypeName}}) Values() []{{.TypeName}} {
+	return []{{.TypeName}}{ {{- range $i, $v := .Values}}{{if $i}}, {{end}}{{$v.ConstName}}{{end -}} }
+}
+{{end}}
+`))
+
+func generateEnumsFile(packageName string, enums []*pg.Enum) ([]byte, error) {
+	type EnumValueData struct {
+		ConstName string // e.g. StatusDraft
+		Text      string // e.g. "draft"
+	}
+
+	type EnumData struct {
+		Name     string // e.g. status
+		TypeName string // e.g. Status
+		Values   []EnumValueData
+	}
+
+	enumsTmplData := make([]EnumData, 0, len(enums))
+	for _, enum := range enums {
+		typeName := desc.PascalCase(enum.Name)
+
+		values := make([]EnumValueData, 0, len(enum.Values))
+		for _, value := range enum.Values {
+			values = append(values, EnumValueData{
+				ConstName: typeName + enumValueToIdentifier(value),
+				Text:      strconv.Quote(value),
+			})
+		}
+
+		enumsTmplData = append(enumsTmplData, EnumData{
+			Name:     enum.Name,
+			TypeName: typeName,
+			Values:   values,
+		})
+	}
+
+	tmplData := map[string]any{
+		"PackageName": packageName,
+		"Enums":       enumsTmplData,
+	}
+
+	var buf bytes.Buffer
+	if err := generateEnumsTmpl.Execute(&buf, tmplData); err != nil {
+		return nil, fmt.Errorf("execute: %w", err)
+	}
+
+	result, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format source: %w\n%s", err, buf.String())
+	}
+
+	return result, nil
+}
+
+// enumValueToIdentifier converts an enum value, e.g. "in-progress", to the PascalCase suffix
+// of its Go constant name, e.g. "InProgress". Characters which are not valid in a Go identifier
+// act as word separators; a value without any valid one is hex-encoded.
+func enumValueToIdentifier(value string) string {
+	words := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		return "X" + hex.EncodeToString([]byte(value))
+	}
+
+	var b strings.Builder
+	for _, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		b.WriteRune(unicode.ToUpper(first))
+		b.WriteString(strings.ToLower(word[size:]))
+	}
+
+	return b.String()
+}
+
+// quoteStrings returns the given values as a comma-separated list of Go string literals.
+func quoteStrings(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, strconv.Quote(value))
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
 func findNearestGoModName(rootDir string) (string, string, error) {
 	if rootDir == "" {
 		// Get the current working directory
@@ -0,0 +1,63 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kataras/pg"
+	"github.com/kataras/pg/desc"
+)
+
+func TestGenerateEnumsFile(t *testing.T) {
+	enums := []*pg.Enum{
+		{Name: "order_status", Values: []string{"pending", "in-progress", "done"}},
+	}
+
+	data, err := generateEnumsFile("definition", enums)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(data)
+	for _, expected := range []string{
+		"package definition",
+		"type OrderStatus string",
+		`OrderStatusPending    OrderStatus = "pending"`,
+		`OrderStatusInProgress OrderStatus = "in-progress"`,
+		`OrderStatusDone       OrderStatus = "done"`,
+		"return []OrderStatus{OrderStatusPending, OrderStatusInProgress, OrderStatusDone}",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Fatalf("expected generated enums to contain:\n%s\nbut got:\n%s", expected, got)
+		}
+	}
+
+	schemaData, err := generateSchemaFile(&ExportOptions{GetPackageName: func(string) string { return "definition" }},
+		"definition", "", "", "test_db", []*pg.Table{{Name: "orders", StructName: "Order"}}, enums)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `MustRegisterEnum("order_status", "pending", "in-progress", "done").
+	MustRegister("orders", Order{})`; !strings.Contains(string(schemaData), expected) {
+		t.Fatalf("expected generated schema to contain:\n%s\nbut got:\n%s", expected, schemaData)
+	}
+}
+
+func TestGenerateSchemaFilePartitionBy(t *testing.T) {
+	tables := []*pg.Table{
+		{Name: "events", StructName: "Event", PartitionBy: &desc.PartitionBy{Strategy: desc.PartitionRange, Columns: []string{"created_at"}}},
+		{Name: "regions", StructName: "Region"},
+	}
+
+	schemaData, err := generateSchemaFile(&ExportOptions{GetPackageName: func(string) string { return "definition" }},
+		"definition", "", "", "test_db", tables, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `MustRegister("events", Event{}, pg.PartitionByRange("created_at")).
+	MustRegister("regions", Region{})`; !strings.Contains(string(schemaData), expected) {
+		t.Fatalf("expected generated schema to contain:\n%s\nbut got:\n%s", expected, schemaData)
+	}
+}
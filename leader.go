@@ -0,0 +1,202 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LeaderOptions holds the options of a Leader, see DB.Campaign.
+type LeaderOptions struct {
+	// HeartbeatInterval is the interval of the leader to check its connection, which holds the lock.
+	// A leader which loses its connection is demoted within about an interval. Defaults to 5 seconds.
+	HeartbeatInterval time.Duration
+	// RetryInterval is the interval of a follower to campaign for the lock again. Defaults to 5 seconds.
+	RetryInterval time.Duration
+	// OnElected, if not nil, is called when the instance becomes the leader, with a context
+	// which is canceled when it is demoted, e.g. to start the singleton workers. It must not block.
+	OnElected func(ctx context.Context)
+	// OnDemoted, if not nil, is called when the instance stops being the leader,
+	// because it lost its connection or it resigned (see Leader.Close). It must not block.
+	OnDemoted func()
+	// OnError, if not nil, is called with the errors of the database, e.g. a lost connection,
+	// which the campaign recovers from by itself.
+	OnError func(error)
+}
+
+// Leader is a leader election among the instances of an application, see DB.Campaign.
+type Leader struct {
+	db     *DB
+	key    string
+	opts   LeaderOptions
+	leader atomic.Bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Campaign starts a leader election, under the given key, among the instances of an application
+// which run it against the same database, e.g. the replicas of a service which should run a singleton
+// worker. The leader is the instance which holds the session-scoped advisory lock of the key (see
+// AdvisoryLockKey) on a dedicated connection: it checks the connection every heartbeat interval
+// and, if it is lost, it is demoted and it campaigns again. The followers try to acquire the lock
+// every retry interval. The election lasts until the returned Leader is closed or the context is done.
+//
+// Note that a leader which loses its connection, e.g. on a network partition, learns of its demotion
+// on its next heartbeat, after the lock may have been acquired by another instance:
+// make the work of the leader idempotent or guard it with a transaction-scoped lock too.
+//
+// Example:
+//
+//	leader, err := db.Campaign(ctx, "billing", pg.LeaderOptions{
+//		OnElected: func(ctx context.Context) { go runBilling(ctx) },
+//	})
+//	if err != nil { ... }
+//	defer leader.Close(ctx)
+func (db *DB) Campaign(ctx context.Context, key string, opts LeaderOptions) (*Leader, error) {
+	if key == "" {
+		return nil, fmt.Errorf("leader: empty key")
+	}
+
+	if opts.HeartbeatInterval <= 0 {
+		opts.HeartbeatInterval = 5 * time.Second
+	}
+
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l := &Leader{db: db, key: key, opts: opts, cancel: cancel, done: make(chan struct{})}
+	go l.campaign(ctx)
+
+	return l, nil
+}
+
+// Key returns the key of the election.
+func (l *Leader) Key() string {
+	return l.key
+}
+
+// IsLeader reports whether the instance is the leader.
+func (l *Leader) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// Close resigns, if the instance is the leader, and stops the campaign. It waits until the lock
+// is released or the context is done.
+func (l *Leader) Close(ctx context.Context) error {
+	l.cancel()
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Leader) campaign(ctx context.Context) {
+	defer close(l.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		conn, err := l.tryLock(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			l.reportError(err)
+		} else if conn != nil {
+			l.lead(ctx, conn)
+		}
+
+		timer.Reset(l.opts.RetryInterval)
+	}
+}
+
+// tryLock tries to acquire the lock on a pooled connection and, only if it is acquired, takes the
+// connection out of the pool, so it is dedicated to the leader. It returns a nil connection if the
+// lock is held by another instance, and then the connection goes back to the pool.
+func (l *Leader) tryLock(ctx context.Context) (*pgx.Conn, error) {
+	pooled, err := l.db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("leader: %s: %w", l.key, err)
+	}
+
+	var locked bool
+	if err = pooled.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", AdvisoryLockKey(l.key)).Scan(&locked); err != nil || !locked {
+		pooled.Release() // the pool destroys it if the query broke it.
+
+		if err != nil {
+			return nil, fmt.Errorf("leader: %s: %w", l.key, err)
+		}
+
+		return nil, nil
+	}
+
+	return pooled.Hijack(), nil
+}
+
+// lead holds the leadership until the connection is lost or the context is done.
+// The connection is closed on return, which releases the lock.
+func (l *Leader) lead(ctx context.Context, conn *pgx.Conn) {
+	electedCtx, demote := context.WithCancel(ctx)
+	l.leader.Store(true)
+	if l.opts.OnElected != nil {
+		l.opts.OnElected(electedCtx)
+	}
+
+	defer func() {
+		// demote first, then release the lock, so the next leader is elected after this one stopped.
+		l.leader.Store(false)
+		demote()
+		if l.opts.OnDemoted != nil {
+			l.opts.OnDemoted()
+		}
+
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.opts.HeartbeatInterval)
+		_ = conn.Close(closeCtx)
+		cancel()
+	}()
+
+	ticker := time.NewTicker(l.opts.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, l.opts.HeartbeatInterval)
+		err := conn.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				l.reportError(fmt.Errorf("leader: %s: heartbeat: %w", l.key, err))
+			}
+
+			return
+		}
+	}
+}
+
+func (l *Leader) reportError(err error) {
+	if l.opts.OnError != nil {
+		l.opts.OnError(err)
+	}
+}
@@ -146,6 +146,94 @@ func (l *Listener) Close(ctx context.Context) error {
 	return nil
 }
 
+// workerListener is the listener of a background worker, e.g. Outbox.Dispatch and Queue.Work:
+// unlike a Listener, it survives a lost connection by listening again, see run.
+type workerListener struct {
+	db      *DB
+	channel string
+
+	mu       sync.Mutex // guards listener and closed.
+	listener *Listener
+	closed   bool
+}
+
+// listenWorker listens to the channel and returns the workerListener which keeps listening to it,
+// see workerListener.run.
+func (db *DB) listenWorker(ctx context.Context, channel string) (*workerListener, error) {
+	listener, err := db.Listen(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &workerListener{db: db, channel: channel, listener: listener}, nil
+}
+
+// run calls notify with the payload of each notification ("" for an empty one) until the context
+// is done or the workerListener is closed. When the connection is lost it reports the error to onError
+// and listens again, with a full jitter backoff up to maxDelay between the failed attempts;
+// the notifications sent in the meantime are lost, so the worker has to poll as well.
+func (w *workerListener) run(ctx context.Context, maxDelay time.Duration, notify func(payload string), onError func(error)) {
+	backoff := RetryOptions{BaseDelay: min(defaultBaseDelay, maxDelay), MaxDelay: maxDelay}
+
+	w.mu.Lock()
+	listener := w.listener
+	w.mu.Unlock()
+
+	for {
+		notification, err := listener.Accept(ctx)
+		switch {
+		case err == nil:
+			notify(notification.Payload)
+			continue
+		case errors.Is(err, ErrEmptyPayload):
+			notify("")
+			continue
+		case errors.Is(err, ErrListenerClosed) || ctx.Err() != nil:
+			return
+		}
+
+		onError(err)
+		_ = listener.Close(ctx) // UNLISTEN fails on a lost connection, which closes it instead of releasing it.
+
+		for attempt := 1; ; attempt++ {
+			if waitBackoff(ctx, backoff, attempt) != nil {
+				return
+			}
+
+			if listener, err = w.db.Listen(ctx, w.channel); err == nil {
+				break
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			onError(err)
+		}
+
+		w.mu.Lock()
+		closed := w.closed
+		if !closed {
+			w.listener = listener
+		}
+		w.mu.Unlock()
+
+		if closed {
+			_ = listener.Close(ctx)
+			return
+		}
+	}
+}
+
+// Close closes the current listener; a run which is listening again closes the new one itself.
+func (w *workerListener) Close(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	return w.listener.Close(ctx)
+}
+
 // notifyJSON sends a notification of any type to the underline database listener.
 func notifyJSON(ctx context.Context, db *DB, channel string, payload any) error {
 	b, err := json.Marshal(payload)
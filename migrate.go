@@ -2,30 +2,31 @@ package pg
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"hash/fnv"
 	"io/fs"
-	"regexp"
+	"os"
 	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// migrateLockKey is the fixed advisory-lock key Migrate takes for the duration of its
-// transaction: computed once, at package init time, from the FNV-1a hash of
-// "kataras/pg/migrate" (fixed string, no randomness, so it is stable across processes and
-// restarts). See Migrate's doc for why it takes this lock.
-var migrateLockKey = func() int64 {
-	h := fnv.New64a()
-	_, _ = h.Write([]byte("kataras/pg/migrate"))
-	return int64(h.Sum64())
-}()
-
-// migrateTableNameRegex matches a bare, unquoted PostgreSQL identifier: it must start with a
-// letter or underscore, followed by letters, digits, underscores or dollar signs only. Same
-// character class as desc's (unexported) identifierRegex (see desc/struct_table.go), borrowed
-// here because MigrateOptions.TableName is interpolated directly into DDL (CREATE TABLE,
-// SELECT, INSERT) rather than passed as a bind parameter, so it must be validated before any
-// of that SQL is built.
-var migrateTableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+// migrateLockKey is the fixed advisory-lock key Migrate takes for the duration of the
+// call: computed once, at package init time, from the FNV-1a hash of
+// "kataras/pg/migrate" (see AdvisoryLockKey; fixed string, no randomness, so it is stable across
+// processes and restarts). Only one concurrent caller acquires it and runs the pending migrations,
+// while the rest block until it is done, then see them applied and apply nothing themselves.
+// It is a session lock (pg_advisory_lock) on a connection pinned from the pool, so a crashed or
+// killed process can never leave it stuck, or, if the DB is already a transaction, a
+// transaction-scoped one (pg_advisory_xact_lock) which lasts until the caller commits or rolls back.
+var migrateLockKey = AdvisoryLockKey(migrateLockName)
+
+// migrateLockName is the name of the advisory lock Migrate takes, see migrateLockKey.
+const migrateLockName = "kataras/pg/migrate"
 
 const (
 	defaultMigrateTableName = "schema_migrations"
@@ -35,67 +36,451 @@ const (
 // MigrateOptions configures DB.Migrate. The zero value applies the documented defaults.
 type MigrateOptions struct {
 	// TableName is the migration-tracking table, created on demand if it does not already
-	// exist. Defaults to "schema_migrations" when empty. Must be a bare identifier (see
-	// migrateTableNameRegex); Migrate rejects anything else before running any SQL, since the
+	// exist, with a row per applied migration: its version, applied_at, its down SQL (see
+	// MigrateDown), the checksum of its file, its dirty state (see MigrateResolve) and its duration_ms, hostname and application_name
+	// (see MigrationInfo). The newer columns are added to a table created by an earlier release.
+	// Defaults to "schema_migrations" when empty. Must be a bare identifier (see
+	// ValidateIdentifier); Migrate rejects anything else before running any SQL, since the
 	// name is quoted with QuoteIdentifier and interpolated into DDL rather than bound as a
 	// parameter.
 	TableName string
 	// Pattern is the fs.Glob pattern selecting migration filenames within the fs.FS passed to
-	// Migrate. Defaults to "*.sql" when empty.
+	// Migrate, relative to its root, so pass an fs.Sub view of a subdirectory. Defaults to "*.sql"
+	// when empty. The bare filename of a file is its version, except for a "*.down.*" file, which is
+	// the down direction of its "*.up.*" pair, see MigrateDown. Its contents run as a single
+	// simple-protocol Exec, as ExecFiles runs them, and an empty file is recorded without running.
 	Pattern string
+	// Modified is the policy for the applied migrations whose checksum, the hex SHA-256 of the file
+	// recorded when it was applied, no longer matches their files, e.g. an edited file which would
+	// otherwise have no effect, see MigrationModifiedError. A migration applied by an earlier
+	// release, which recorded no checksum, gets one instead, and a missing file is not reported.
+	// Defaults to MigrationFail.
+	Modified MigrationPolicy
+	// OutOfOrder is the policy for the pending migrations which sort before the latest applied one,
+	// e.g. merged from a long-lived branch, see MigrationOutOfOrderError. Defaults to MigrationFail.
+	OutOfOrder MigrationPolicy
+	// OnWarning, if not nil, is called with the *MigrationModifiedError or *MigrationOutOfOrderError
+	// of a MigrationWarn policy.
+	OnWarning func(error)
+	// GoMigrations are the migrations written in Go, applied in version order along with the files,
+	// in the same transaction, and recorded in the same tracking table, without a checksum, see Register.
+	GoMigrations []GoMigration
+}
+
+// MigrationFunc is the up or the down function of a GoMigration. It runs in the transaction of the
+// call, through tx, like the SQL migrations.
+type MigrationFunc func(ctx context.Context, tx *DB) error
+
+// GoMigration is a migration written in Go, e.g. a data backfill which needs application code.
+type GoMigration struct {
+	// Version is the version of the migration, sorted with the filenames of the SQL migrations,
+	// e.g. "0007_backfill_slugs" runs after "0006_add_slug.sql" and before "0008_slug_not_null.sql".
+	Version string
+	// Up applies the migration.
+	Up MigrationFunc
+	// Down, if not nil, reverts the migration, see MigrateDown.
+	Down MigrationFunc
 }
 
-// Migrate applies the not-yet-applied .sql files found in fsys, in ascending lexical order of
-// their filenames, and returns the filenames it applied, in the order they were applied (an
-// empty/nil slice, with a nil error, if none were pending). Lexical order is why migrations
-// should be named with a zero-padded numeric prefix, e.g. "0001_init.sql", "0002_add_users.sql".
-// That convention keeps lexical and intended order identical.
+// Register registers a Go migration with the given version and up function, see GoMigration.
 //
-// The whole call runs inside a SINGLE database transaction (via DB.InTransaction): PostgreSQL's
-// DDL is transactional, so if any pending file fails (a syntax error, a constraint violation,
-// anything) the transaction rolls back and every earlier file applied by this same call is
-// undone along with it. Nothing is left half-applied, and the tracking table ends up exactly as
-// it was before the call; see DB.InTransaction's doc for exactly how the commit/rollback/error
-// propagation works. A file's contents are executed as-is via Exec (mirroring how ExecFiles
-// runs a file's contents), so a file containing several ';'-separated statements runs all of
-// them as a single simple-protocol Exec, the same way ExecFiles does.
+// Example:
 //
-// Before touching any file, the transaction takes a Postgres advisory lock
-// (pg_advisory_xact_lock) under a fixed key (see migrateLockKey). This is what makes it safe
-// for several instances of an application (e.g. replicas starting up concurrently) to call
-// Migrate against the same database at the same time: only one of them acquires the lock and
-// actually runs the pending files, while the rest block until it commits or rolls back, then
-// see the now-applied versions already in the tracking table and apply nothing themselves.
-// Concurrent callers must never double-apply the same file. The lock is transaction-scoped
-// ("xact"), so it is released automatically the instant the transaction ends (commit or
-// rollback), and a crashed or killed process can never leave it stuck. Then the transaction
-// creates the tracking table (opts.TableName, default "schema_migrations") if missing, with
-// `CREATE TABLE IF NOT EXISTS <table> (version text PRIMARY KEY, applied_at timestamptz NOT
-// NULL DEFAULT now())`, reads the versions already recorded there, and executes+records only
-// the files not already present, recording each one's bare filename (as matched by
-// opts.Pattern, e.g. "0001_init.sql", not a full path) as its version. A file with empty
-// contents (after reading) is not executed (there is nothing to run) but is still recorded
-// as applied, the same as any other file, so it counts toward "already applied" on the next
-// call and is never retried.
+//	opts := new(pg.MigrateOptions)
+//	opts.Register("0007_backfill_slugs", func(ctx context.Context, tx *pg.DB) error {
+//		posts, err := pg.NewRepository[Post](tx).Select(ctx, "SELECT * FROM posts WHERE slug IS NULL")
+//		if err != nil {
+//			return err
+//		}
+//		for _, post := range posts {
+//			if _, err = tx.Exec(ctx, "UPDATE posts SET slug = $2 WHERE id = $1", post.ID, slugify(post.Title)); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	})
+//	applied, err := db.Migrate(ctx, fsys, opts)
+func (opts *MigrateOptions) Register(version string, up MigrationFunc) {
+	opts.GoMigrations = append(opts.GoMigrations, GoMigration{Version: version, Up: up})
+}
+
+// MigrationPolicy controls how Migrate handles a modified or an out-of-order migration,
+// see MigrateOptions.
+type MigrationPolicy int
+
+const (
+	// MigrationFail fails the call, before applying any migration. It is the default policy.
+	MigrationFail MigrationPolicy = iota
+	// MigrationWarn reports the error to MigrateOptions.OnWarning and continues.
+	MigrationWarn
+	// MigrationAllow continues silently: a modified migration is not applied again and an
+	// out-of-order one is applied in its lexical position, as Migrate did before checksums.
+	MigrationAllow
+)
+
+// Migrate applies the pending migrations, the files of fsys which match opts.Pattern and the
+// opts.GoMigrations, in ascending lexical order of their versions, e.g. "0001_init.sql", "0002_add_users.sql",
+// and returns the versions it applied (on an error, the ones committed before it). opts may be nil.
 //
-// fsys is any fs.FS, typically an embed.FS. For migrations kept in a subdirectory of an
-// embedded tree, pass an fs.Sub view rooted at that subdirectory (fsys, err := fs.Sub(embedded,
-// "migrations")) so that opts.Pattern matches the migration filenames directly instead of
-// needing a directory prefix.
+// The migrations run in a single transaction, so a failed one rolls back all of them, unless a file
+// has the no-transaction directive (see MigrateResolve), and under an advisory lock, so several
+// instances which start up at the same time never apply the same migration twice. Before applying
+// anything, the ones recorded in the tracking table (see MigrateOptions.TableName) are verified
+// against the files (see MigrateOptions.Modified and OutOfOrder). MigrationStatus and MigratePlan
+// report the applied and the pending migrations without running any.
 //
-// opts may be nil, in which case the documented defaults apply.
+// fsys is typically an fs.Sub view of the migrations directory of an embed.FS. On a DB returned by
+// ForTenant the migrations run, and are recorded, once per tenant, see ForEachTenant: they see its
+// search_path, so they should use unqualified table names. MigrateDown and MigrateTo revert the
+// migrations which have a down direction.
 //
-// Deliberately excluded: there is no down/rollback direction, no checksum recorded or verified
-// for a file that was already applied (so silently editing an already-applied file has no
-// effect on future runs), and no detection of or special handling for a file that lands
-// lexically before one already applied ("out-of-order" migrations just apply wherever their
-// filename sorts, with no warning). This is meant to be the smallest honest migration runner,
-// not a full migration framework: reach for golang-migrate/migrate or pressly/goose if you
-// need any of the above.
+// This is meant to be a small migration runner, not a full migration framework: reach for
+// golang-migrate/migrate or pressly/goose if you need more, e.g. other database backends.
 func (db *DB) Migrate(ctx context.Context, fsys fs.FS, opts *MigrateOptions) (applied []string, err error) {
+	m, err := db.newMigrator(fsys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, _, err = m.run(ctx, db, func(db *DB, alreadyApplied []string) ([]migrationStep, error) {
+		if err := m.verify(ctx, db, alreadyApplied, ""); err != nil {
+			return nil, err
+		}
+
+		var steps []migrationStep
+		for _, mig := range m.migrations {
+			if !slices.Contains(alreadyApplied, mig.version) {
+				steps = append(steps, m.applyStep(mig))
+			}
+		}
+
+		return steps, nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the last steps applied migrations, in descending version order, and returns
+// the versions it reverted (fewer than steps if fewer were applied). Each one is reverted by its
+// down SQL and removed from the tracking table; a migration without a down direction fails the call.
+//
+// The down direction of a migration is either a paired file, "0002_add_users.down.sql" for
+// "0002_add_users.up.sql" (the version is the up filename, the down file is never applied as a
+// migration of its own), or a section of the same file, after a line which reads "-- +down" (see
+// migrateDownMarker). Migrate records the down SQL in the down_sql column of the tracking table,
+// so a migration can be reverted even by a build whose files no longer include it, e.g. the previous
+// release after a bad deploy. The down function of a GoMigration is not recorded: reverting it
+// requires its registration. fsys and opts are the ones passed to Migrate: the down SQL of the files
+// is preferred over the one recorded when the migration was applied.
+//
+// It keeps the guarantees of Migrate: the call runs inside a single transaction, unless a down SQL
+// has the no-transaction directive, under the same advisory lock, so a failed down SQL reverts nothing.
+func (db *DB) MigrateDown(ctx context.Context, fsys fs.FS, opts *MigrateOptions, steps int) (reverted []string, err error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("migrate: down: steps must be positive, got: %d", steps)
+	}
+
+	m, err := db.newMigrator(fsys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	_, reverted, err = m.run(ctx, db, func(db *DB, alreadyApplied []string) ([]migrationStep, error) {
+		var plan []migrationStep
+		for _, version := range slices.Backward(alreadyApplied) {
+			if len(plan) == steps {
+				break
+			}
+
+			step, err := m.revertStep(ctx, db, version)
+			if err != nil {
+				return nil, err
+			}
+
+			plan = append(plan, step)
+		}
+
+		return plan, nil
+	})
+	return reverted, err
+}
+
+// MigrateTo migrates the database to the given version: it reverts the applied migrations
+// after it, in descending order (see MigrateDown), and then it applies the pending ones up to it,
+// included, in ascending order (see Migrate). It returns the versions it applied and reverted.
+// The version is the filename of a migration or its prefix before the first '_' or '.', e.g.
+// "0002" for "0002_add_users.up.sql".
+//
+// It keeps the guarantees of Migrate: the call runs inside a single transaction, unless a migration
+// has the no-transaction directive, under the same advisory lock, and it verifies the migrations up
+// to the version the same way.
+func (db *DB) MigrateTo(ctx context.Context, fsys fs.FS, opts *MigrateOptions, version string) (applied, reverted []string, err error) {
+	m, err := db.newMigrator(fsys, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return m.run(ctx, db, func(db *DB, alreadyApplied []string) ([]migrationStep, error) {
+		target, ok := m.resolveVersion(version, alreadyApplied)
+		if !ok {
+			return nil, fmt.Errorf("migrate: to: unknown version: %s", version)
+		}
+
+		if err := m.verify(ctx, db, alreadyApplied, target); err != nil {
+			return nil, err
+		}
+
+		var steps []migrationStep
+		for _, v := range slices.Backward(alreadyApplied) {
+			if v <= target {
+				break
+			}
+
+			step, err := m.revertStep(ctx, db, v)
+			if err != nil {
+				return nil, err
+			}
+
+			steps = append(steps, step)
+		}
+
+		for _, mig := range m.migrations {
+			if mig.version > target {
+				break
+			}
+
+			if !slices.Contains(alreadyApplied, mig.version) {
+				steps = append(steps, m.applyStep(mig))
+			}
+		}
+
+		return steps, nil
+	})
+}
+
+// MigrateResolve resolves a dirty migration after it was fixed by hand: applied true marks it as
+// applied, false removes it, so it is applied again by the next Migrate.
+//
+// Some statements, e.g. CREATE INDEX CONCURRENTLY, cannot run in a transaction: a migration file with
+// a line which reads "-- pg:no-transaction" (see migrateNoTransactionDirective) runs outside of the
+// transaction of Migrate, still under its advisory lock, so not on a DB which is already a transaction.
+// The pending migrations before it are committed first, then its statements are executed one by one,
+// and the ones after it run in a new transaction. Such a migration is recorded as dirty before it runs
+// and as applied after its last statement: if one fails, or the process stops half-way, the database
+// may be partially migrated, so every later Migrate, MigratePlan, MigrateDown and MigrateTo fails with
+// a *MigrationDirtyError (errors.Is ErrMigrationDirty) until the migration is resolved. The directive
+// of a down section or file applies to it only.
+func (db *DB) MigrateResolve(ctx context.Context, opts *MigrateOptions, version string, applied bool) error {
+	m, err := db.newMigrator(nil, opts)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE FROM " + m.quotedTable + " WHERE version = $1 AND dirty"
+	if applied {
+		query = "UPDATE " + m.quotedTable + " SET dirty = false WHERE version = $1 AND dirty"
+	}
+
+	tag, err := db.Exec(ctx, query, version)
+	if err != nil {
+		return fmt.Errorf("migrate: resolve %s: %w", version, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("migrate: resolve %s: not a dirty migration", version)
+	}
+
+	return nil
+}
+
+// migrateDownMarker is the line which separates the up and the down sections of a migration file.
+const migrateDownMarker = "-- +down"
+
+// migration is a migration loaded from the fs.FS passed to Migrate.
+type migration struct {
+	version  string // the filename of its (up) file.
+	up       string
+	down     string
+	hasDown  bool
+	checksum string // the hex SHA-256 of up, empty for a Go migration.
+
+	goMigration *GoMigration // not nil for a Go migration.
+}
+
+// migrator holds the state shared by Migrate, MigrateDown and MigrateTo.
+type migrator struct {
+	quotedTable string
+	migrations  []migration // in ascending version order.
+	opts        MigrateOptions
+	checksums   map[string]string // the recorded checksums per applied version, empty if not recorded.
+}
+
+// MigrationInfo is the status of a migration, see MigrationStatus.
+type MigrationInfo struct {
+	// Version is the version of the migration, see Migrate.
+	Version string
+	// Pending reports whether the migration is not applied yet.
+	Pending bool
+	// OutOfOrder reports whether the pending migration sorts before the latest applied one,
+	// see MigrationOutOfOrderError.
+	OutOfOrder bool
+	// Modified reports whether the checksum of the applied migration does not match its file,
+	// see MigrationModifiedError.
+	Modified bool
+	// Missing reports whether the applied migration is no longer found in the files or the
+	// Go migrations.
+	Missing bool
+	// Dirty reports whether the applied no-transaction migration failed half-way, see MigrateResolve.
+	Dirty bool
+	// NoTransaction reports whether the migration has the no-transaction directive, see MigrateResolve.
+	NoTransaction bool
+	// AppliedAt is the time the migration was applied, zero if it is pending.
+	AppliedAt time.Time
+	// Duration is the execution time of the migration, zero if it is pending or it was applied
+	// by an earlier release, which did not record it.
+	Duration time.Duration
+	// Hostname is the hostname of the machine the migration was applied from, if recorded.
+	Hostname string
+	// ApplicationName is the application_name of the session the migration was applied from,
+	// if recorded.
+	ApplicationName string
+}
+
+// MigrationStatus returns the status of every migration, applied or pending, in ascending version order:
+// the migrations of fsys and opts (see Migrate) and the ones of the tracking table which are no longer
+// found there. It runs no migration, takes no lock and creates nothing: all the migrations are pending if
+// the tracking table does not exist yet.
+//
+// Example:
+//
+//	infos, err := db.MigrationStatus(ctx, fsys, nil)
+//	if err != nil {
+//		return err
+//	}
+//	for _, info := range infos {
+//		if info.Pending {
+//			fmt.Printf("%s: pending\n", info.Version)
+//		} else {
+//			fmt.Printf("%s: applied at %s in %s\n", info.Version, info.AppliedAt, info.Duration)
+//		}
+//	}
+func (db *DB) MigrationStatus(ctx context.Context, fsys fs.FS, opts *MigrateOptions) ([]MigrationInfo, error) {
+	m, err := db.newMigrator(fsys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := m.readRecordsIfExists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest string
+	if len(records) > 0 {
+		latest = records[len(records)-1].Version
+	}
+
+	infos := make([]MigrationInfo, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		info := MigrationInfo{Version: mig.version, NoTransaction: mig.goMigration == nil && hasNoTransactionDirective(mig.up)}
+
+		i := slices.IndexFunc(records, func(record migrationRecord) bool { return record.Version == mig.version })
+		if i == -1 {
+			info.Pending = true
+			info.OutOfOrder = mig.version < latest
+		} else {
+			records[i].fill(&info)
+			info.Modified = mig.goMigration == nil && records[i].Checksum != "" && records[i].Checksum != mig.checksum
+		}
+
+		infos = append(infos, info)
+	}
+
+	for _, record := range records {
+		if !slices.ContainsFunc(m.migrations, func(mig migration) bool { return mig.version == record.Version }) {
+			info := MigrationInfo{Version: record.Version, Missing: true}
+			record.fill(&info)
+			infos = append(infos, info)
+		}
+	}
+
+	slices.SortFunc(infos, func(a, b MigrationInfo) int { return strings.Compare(a.Version, b.Version) })
+	return infos, nil
+}
+
+// fill fills the applied fields of info.
+func (record migrationRecord) fill(info *MigrationInfo) {
+	info.Dirty = record.Dirty
+	info.AppliedAt = record.AppliedAt
+	info.Duration = time.Duration(record.DurationMS) * time.Millisecond
+	info.Hostname = record.Hostname
+	info.ApplicationName = record.ApplicationName
+}
+
+// PlannedMigration is a migration Migrate would apply, see MigratePlan.
+type PlannedMigration struct {
+	// Version is the version of the migration, see Migrate.
+	Version string
+	// SQL is the up SQL of the migration, empty for a Go migration.
+	SQL string
+	// Go reports whether the migration is a Go migration, whose function cannot be shown.
+	Go bool
+	// NoTransaction reports whether the migration has the no-transaction directive, see MigrateResolve.
+	NoTransaction bool
+}
+
+// MigratePlan returns the migrations Migrate would apply, in the order it would apply them, with their SQL,
+// without executing them, e.g. to show the reviewers of a release exactly what its deployment will run.
+// It fails the way Migrate would, e.g. with a *MigrationModifiedError, a *MigrationOutOfOrderError or a
+// *MigrationDirtyError, but, like MigrationStatus, it takes no lock and writes nothing.
+//
+// Example:
+//
+//	plan, err := db.MigratePlan(ctx, fsys, nil)
+//	if err != nil {
+//		return err
+//	}
+//	for _, planned := range plan {
+//		fmt.Printf("-- %s\n%s\n", planned.Version, planned.SQL)
+//	}
+func (db *DB) MigratePlan(ctx context.Context, fsys fs.FS, opts *MigrateOptions) ([]PlannedMigration, error) {
+	m, err := db.newMigrator(fsys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := m.readRecordsIfExists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	alreadyApplied, err := m.alreadyApplied(records)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.verify(ctx, nil, alreadyApplied, ""); err != nil {
+		return nil, err
+	}
+
+	var plan []PlannedMigration
+	for _, mig := range m.migrations {
+		if slices.Contains(alreadyApplied, mig.version) {
+			continue
+		}
+
+		step := m.applyStep(mig)
+		plan = append(plan, PlannedMigration{Version: mig.version, SQL: step.sql, Go: step.fn != nil, NoTransaction: step.noTx})
+	}
+
+	return plan, nil
+}
+
+func (db *DB) newMigrator(fsys fs.FS, opts *MigrateOptions) (*migrator, error) {
 	tableName := defaultMigrateTableName
 	pattern := defaultMigratePattern
+	var policies MigrateOptions
 	if opts != nil {
+		policies = *opts
 		if opts.TableName != "" {
 			tableName = opts.TableName
 		}
@@ -104,70 +489,666 @@ func (db *DB) Migrate(ctx context.Context, fsys fs.FS, opts *MigrateOptions) (ap
 		}
 	}
 
-	if !migrateTableNameRegex.MatchString(tableName) {
-		return nil, fmt.Errorf("migrate: invalid table name: %q: must match %s", tableName, migrateTableNameRegex.String())
+	if err := ValidateIdentifier(tableName); err != nil {
+		return nil, fmt.Errorf("migrate: invalid table name: %w", err)
 	}
 
+	migrations, err := loadMigrations(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range policies.GoMigrations {
+		goMigration := &policies.GoMigrations[i]
+		if goMigration.Version == "" || goMigration.Up == nil {
+			return nil, fmt.Errorf("migrate: go migration %q: empty version or nil up function", goMigration.Version)
+		}
+
+		if slices.ContainsFunc(migrations, func(mig migration) bool { return mig.version == goMigration.Version }) {
+			return nil, fmt.Errorf("migrate: go migration %q: duplicate version", goMigration.Version)
+		}
+
+		migrations = append(migrations, migration{
+			version:     goMigration.Version,
+			hasDown:     goMigration.Down != nil,
+			goMigration: goMigration,
+		})
+	}
+	slices.SortFunc(migrations, func(a, b migration) int { return strings.Compare(a.version, b.version) })
+
+	quotedTable := QuoteIdentifier(tableName)
+	if db.tenant != "" {
+		quotedTable = QuoteIdentifier(db.searchPath) + "." + quotedTable
+	}
+
+	return &migrator{quotedTable: quotedTable, migrations: migrations, opts: policies}, nil
+}
+
+// loadMigrations reads the files of fsys which match pattern into migrations, in ascending version order,
+// pairing the "*.up.*" files with their "*.down.*" ones and splitting the files with a down section.
+func loadMigrations(fsys fs.FS, pattern string) ([]migration, error) {
 	names, err := fs.Glob(fsys, pattern)
 	if err != nil {
 		return nil, fmt.Errorf("migrate: glob %q: %w", pattern, err)
 	}
 	slices.Sort(names)
 
-	quotedTable := QuoteIdentifier(tableName)
+	var (
+		migrations []migration
+		paired     = make(map[string]struct{})
+	)
+	for _, name := range names {
+		if strings.Contains(name, ".down.") {
+			continue
+		}
 
-	err = db.InTransaction(ctx, func(db *DB) error {
-		if _, err := db.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrateLockKey); err != nil {
-			return fmt.Errorf("migrate: advisory lock: %w", err)
+		contents, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: read %s: %w", name, err)
 		}
 
-		createSQL := fmt.Sprintf(
-			"CREATE TABLE IF NOT EXISTS %s (version text PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now())",
-			quotedTable)
-		if _, err := db.Exec(ctx, createSQL); err != nil {
-			return fmt.Errorf("migrate: create tracking table: %w", err)
+		mig := migration{version: name}
+		mig.up, mig.down, mig.hasDown = splitMigration(string(contents))
+		mig.checksum = migrationChecksum(mig.up)
+
+		if i := strings.LastIndex(name, ".up."); i != -1 {
+			downName := name[:i] + ".down." + name[i+len(".up."):]
+			if slices.Contains(names, downName) {
+				if mig.hasDown {
+					return nil, fmt.Errorf("migrate: %s: both a %q section and a %s file", name, migrateDownMarker, downName)
+				}
+
+				down, err := fs.ReadFile(fsys, downName)
+				if err != nil {
+					return nil, fmt.Errorf("migrate: read %s: %w", downName, err)
+				}
+
+				mig.down, mig.hasDown = string(down), true
+				paired[downName] = struct{}{}
+			}
 		}
 
-		alreadyApplied, err := db.QuerySlice[string](ctx, "SELECT version FROM "+quotedTable)
-		if err != nil {
-			return fmt.Errorf("migrate: read tracking table: %w", err)
+		migrations = append(migrations, mig)
+	}
+
+	for _, name := range names {
+		if _, ok := paired[name]; !ok && strings.Contains(name, ".down.") {
+			return nil, fmt.Errorf("migrate: %s: down migration without an up one", name)
 		}
+	}
 
-		appliedSet := make(map[string]struct{}, len(alreadyApplied))
-		for _, version := range alreadyApplied {
-			appliedSet[version] = struct{}{}
+	return migrations, nil
+}
+
+// migrationChecksum returns the hex SHA-256 of the up SQL of a migration.
+func migrationChecksum(up string) string {
+	sum := sha256.Sum256([]byte(up))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitMigration splits the contents of a migration file at its migrateDownMarker line, if any.
+func splitMigration(contents string) (up, down string, hasDown bool) {
+	rest := contents
+	offset := 0
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		if strings.TrimSpace(line) == migrateDownMarker {
+			return contents[:offset], next, true
 		}
 
-		insertSQL := "INSERT INTO " + quotedTable + " (version) VALUES ($1)"
+		offset += len(line) + 1
+		rest = next
+	}
 
-		for _, name := range names {
-			if _, ok := appliedSet[name]; ok {
-				continue
-			}
+	return contents, "", false
+}
 
-			contents, err := fs.ReadFile(fsys, name)
-			if err != nil {
-				return fmt.Errorf("migrate: read %s: %w", name, err)
+// resolveVersion returns the full version of the given one, a version or its prefix before the first
+// '_' or '.', among the loaded and the applied migrations.
+func (m *migrator) resolveVersion(version string, alreadyApplied []string) (string, bool) {
+	matches := func(v string) bool {
+		return v == version || strings.HasPrefix(v, version+"_") || strings.HasPrefix(v, version+".")
+	}
+
+	for _, mig := range m.migrations {
+		if matches(mig.version) {
+			return mig.version, true
+		}
+	}
+
+	for _, v := range alreadyApplied {
+		if matches(v) {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// migrationStep is a migration to apply, or to revert, planned by a run.
+type migrationStep struct {
+	mig    migration
+	revert bool
+	sql    string        // the SQL to execute, unless fn is not nil.
+	fn     MigrationFunc // the function of a Go migration.
+	noTx   bool          // see migrateNoTransactionDirective.
+}
+
+// applyStep returns the step which applies a migration.
+func (m *migrator) applyStep(mig migration) migrationStep {
+	step := migrationStep{mig: mig, sql: mig.up, noTx: hasNoTransactionDirective(mig.up)}
+	if mig.goMigration != nil {
+		step.fn = mig.goMigration.Up
+	}
+
+	return step
+}
+
+// revertStep returns the step which reverts an applied migration with the down SQL of its files, or else
+// the recorded one, or the down function of a Go migration.
+func (m *migrator) revertStep(ctx context.Context, db *DB, version string) (migrationStep, error) {
+	step := migrationStep{mig: migration{version: version}, revert: true}
+
+	i := slices.IndexFunc(m.migrations, func(mig migration) bool { return mig.version == version })
+	if i != -1 {
+		step.mig = m.migrations[i]
+	}
+
+	switch {
+	case step.mig.goMigration != nil:
+		if step.mig.goMigration.Down == nil {
+			return step, fmt.Errorf("migrate: revert %s: no down migration", version)
+		}
+
+		step.fn = step.mig.goMigration.Down
+	case step.mig.hasDown:
+		step.sql = step.mig.down
+	default:
+		var recorded *string
+		if err := db.QueryRow(ctx, "SELECT down_sql FROM "+m.quotedTable+" WHERE version = $1", version).Scan(&recorded); err != nil {
+			return step, fmt.Errorf("migrate: read %s: %w", version, err)
+		}
+
+		if recorded == nil {
+			return step, fmt.Errorf("migrate: revert %s: no down migration", version)
+		}
+
+		step.sql = *recorded
+	}
+
+	step.noTx = step.fn == nil && hasNoTransactionDirective(step.sql)
+	return step, nil
+}
+
+// run holds the migrate advisory lock and runs the steps planned by plan, with the versions already
+// recorded in the tracking table, in ascending order, creating the table if missing. The consecutive
+// transactional steps run in a single transaction and each no-transaction one outside of it.
+// It returns the applied and the reverted versions of the committed steps.
+//
+// The lock is a session one and everything runs on its connection, pinned from the pool, so it
+// outlasts the transactions; if db is already a transaction it is a transaction-scoped one instead,
+// so it lasts until the caller commits, and no-transaction steps are not allowed.
+func (m *migrator) run(ctx context.Context, db *DB, plan func(db *DB, alreadyApplied []string) ([]migrationStep, error)) (applied, reverted []string, err error) {
+	var conn *pgxpool.Conn // the session of the lock, nil if db is a transaction.
+	if !db.IsTransaction() {
+		lock, err := db.AdvisoryLock(ctx, migrateLockName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrate: %w", err)
+		}
+		defer lock.Unlock(context.WithoutCancel(ctx))
+
+		conn = lock.conn
+	}
+
+	var (
+		steps   []migrationStep
+		next    int
+		planned bool
+	)
+	for !planned || next < len(steps) {
+		var batchApplied, batchReverted []string
+
+		err = m.inTransaction(ctx, db, conn, func(db *DB) error {
+			if !planned {
+				alreadyApplied, err := m.prepare(ctx, db, conn == nil)
+				if err != nil {
+					return err
+				}
+
+				if steps, err = plan(db, alreadyApplied); err != nil {
+					return err
+				}
 			}
 
-			if len(contents) > 0 {
-				if _, err = db.Exec(ctx, string(contents)); err != nil {
-					return fmt.Errorf("migrate: exec %s: %w", name, err)
+			for ; next < len(steps) && !steps[next].noTx; next++ {
+				if err := m.runStep(ctx, db, steps[next]); err != nil {
+					return err
 				}
+
+				batchApplied, batchReverted = appendStep(batchApplied, batchReverted, steps[next])
+			}
+
+			return nil
+		})
+		if err != nil {
+			return applied, reverted, err
+		}
+
+		planned = true
+		applied, reverted = append(applied, batchApplied...), append(reverted, batchReverted...)
+
+		if next < len(steps) {
+			if conn == nil {
+				return applied, reverted, fmt.Errorf("migrate: %s: a no-transaction migration cannot run in a transaction", steps[next].mig.version)
 			}
 
-			if _, err = db.Exec(ctx, insertSQL, name); err != nil {
-				return fmt.Errorf("migrate: record %s: %w", name, err)
+			if err = m.runNoTransactionStep(ctx, conn, steps[next]); err != nil {
+				return applied, reverted, err
 			}
 
-			applied = append(applied, name)
+			applied, reverted = appendStep(applied, reverted, steps[next])
+			next++
 		}
+	}
 
-		return nil
-	})
+	return applied, reverted, nil
+}
+
+// inTransaction runs fn in a transaction on conn, committing it if fn returns nil, or,
+// if conn is nil, through db.InTransaction.
+func (m *migrator) inTransaction(ctx context.Context, db *DB, conn *pgxpool.Conn, fn func(db *DB) error) error {
+	if conn == nil {
+		return db.InTransaction(ctx, fn)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: begin: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx)) // no-op after Commit.
+
+	if err = db.setLocalSettings(ctx, tx); err != nil {
+		return err
+	}
+
+	if err = fn(db.clone(tx)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("migrate: commit: %w", err)
+	}
+
+	return nil
+}
+
+func appendStep(applied, reverted []string, step migrationStep) ([]string, []string) {
+	if step.revert {
+		return applied, append(reverted, step.mig.version)
+	}
+
+	return append(applied, step.mig.version), reverted
+}
+
+// prepare takes the transaction-scoped lock, if inTransaction, creates the tracking table if missing
+// and returns the versions already recorded there, in ascending order. It fails if one of them is dirty.
+func (m *migrator) prepare(ctx context.Context, db *DB, inTransaction bool) ([]string, error) {
+	if inTransaction { // else the session lock is held by run.
+		if _, err := db.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrateLockKey); err != nil {
+			return nil, fmt.Errorf("migrate: advisory lock: %w", err)
+		}
+	}
+
+	if db.tenant != "" {
+		if _, err := db.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+QuoteIdentifier(db.searchPath)); err != nil {
+			return nil, fmt.Errorf("migrate: create tenant schema: %w", err)
+		}
+	}
+
+	createSQL := fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %[1]s (version text PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now(), down_sql text, checksum text, "+
+			"dirty boolean NOT NULL DEFAULT false, duration_ms bigint, hostname text, application_name text);"+
+			"ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS down_sql text, ADD COLUMN IF NOT EXISTS checksum text, ADD COLUMN IF NOT EXISTS dirty boolean NOT NULL DEFAULT false, "+
+			"ADD COLUMN IF NOT EXISTS duration_ms bigint, ADD COLUMN IF NOT EXISTS hostname text, ADD COLUMN IF NOT EXISTS application_name text;",
+		m.quotedTable)
+	if _, err := db.Exec(ctx, createSQL); err != nil {
+		return nil, fmt.Errorf("migrate: create tracking table: %w", err)
+	}
+
+	records, err := m.readRecords(ctx, db)
 	if err != nil {
 		return nil, err
 	}
 
-	return applied, nil
+	return m.alreadyApplied(records)
+}
+
+// migrationRecord is a row of the tracking table, see Migrate.
+type migrationRecord struct {
+	Version         string    `json:"version"`
+	AppliedAt       time.Time `json:"applied_at"`
+	Checksum        string    `json:"checksum"`
+	Dirty           bool      `json:"dirty"`
+	DurationMS      int64     `json:"duration_ms"`
+	Hostname        string    `json:"hostname"`
+	ApplicationName string    `json:"application_name"`
+}
+
+// readRecords reads the rows of the tracking table, in ascending version order, and keeps their
+// checksums. Each row is read as a JSON object, so the columns missing from a table created by an
+// earlier release read as zero values.
+func (m *migrator) readRecords(ctx context.Context, db *DB) ([]migrationRecord, error) {
+	rows, err := db.Query(ctx, "SELECT to_jsonb(t) FROM "+m.quotedTable+" t")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: read tracking table: %w", err)
+	}
+
+	var records []migrationRecord
+	m.checksums = make(map[string]string)
+	for rows.Next() {
+		var record migrationRecord
+		if err = rows.Scan(&record); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("migrate: read tracking table: %w", err)
+		}
+
+		records = append(records, record)
+		m.checksums[record.Version] = record.Checksum
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("migrate: read tracking table: %w", err)
+	}
+
+	// the same, lexical, order as the files.
+	slices.SortFunc(records, func(a, b migrationRecord) int { return strings.Compare(a.Version, b.Version) })
+	return records, nil
+}
+
+// readRecordsIfExists is like readRecords but it reads no records, instead of creating the tracking table,
+// if it does not exist yet.
+func (m *migrator) readRecordsIfExists(ctx context.Context, db *DB) ([]migrationRecord, error) {
+	var exists bool
+	if err := db.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", m.quotedTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("migrate: read tracking table: %w", err)
+	}
+
+	if !exists {
+		m.checksums = make(map[string]string)
+		return nil, nil
+	}
+
+	return m.readRecords(ctx, db)
+}
+
+// alreadyApplied returns the versions of the records. It fails if one of them is dirty.
+func (m *migrator) alreadyApplied(records []migrationRecord) ([]string, error) {
+	var (
+		alreadyApplied []string
+		dirty          []string
+	)
+	for _, record := range records {
+		if record.Dirty {
+			dirty = append(dirty, record.Version)
+		}
+
+		alreadyApplied = append(alreadyApplied, record.Version)
+	}
+
+	if len(dirty) > 0 {
+		return nil, &MigrationDirtyError{Versions: dirty}
+	}
+
+	return alreadyApplied, nil
+}
+
+// verify checks the applied migrations, and the pending ones up to limit (all if empty), against
+// the files, see Migrate, and records the missing checksums of the applied ones, unless db is nil.
+func (m *migrator) verify(ctx context.Context, db *DB, alreadyApplied []string, limit string) error {
+	var (
+		modified   []string
+		outOfOrder []string
+		latest     string
+	)
+	for _, version := range alreadyApplied {
+		if limit == "" || version <= limit {
+			latest = version
+		}
+	}
+
+	for _, mig := range m.migrations {
+		if limit != "" && mig.version > limit {
+			break
+		}
+
+		recorded, applied := m.checksums[mig.version]
+		switch {
+		case !applied:
+			if mig.version < latest {
+				outOfOrder = append(outOfOrder, mig.version)
+			}
+		case recorded == "":
+			if mig.goMigration != nil || db == nil {
+				continue // a Go migration has no checksum.
+			}
+
+			query := "UPDATE " + m.quotedTable + " SET checksum = $2 WHERE version = $1"
+			if _, err := db.Exec(ctx, query, mig.version, mig.checksum); err != nil {
+				return fmt.Errorf("migrate: record checksum %s: %w", mig.version, err)
+			}
+		case recorded != mig.checksum:
+			modified = append(modified, mig.version)
+		}
+	}
+
+	if len(modified) > 0 {
+		if err := m.handle(m.opts.Modified, &MigrationModifiedError{Versions: modified}); err != nil {
+			return err
+		}
+	}
+
+	if len(outOfOrder) > 0 {
+		if err := m.handle(m.opts.OutOfOrder, &MigrationOutOfOrderError{Versions: outOfOrder, Latest: latest}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handle applies a MigrationPolicy to a verification error.
+func (m *migrator) handle(policy MigrationPolicy, err error) error {
+	switch policy {
+	case MigrationWarn:
+		if m.opts.OnWarning != nil {
+			m.opts.OnWarning(err)
+		}
+		return nil
+	case MigrationAllow:
+		return nil
+	default:
+		return err
+	}
+}
+
+// runStep applies or reverts a migration, in the transaction of db, and records it.
+func (m *migrator) runStep(ctx context.Context, db *DB, step migrationStep) error {
+	action := "exec"
+	if step.revert {
+		action = "revert"
+	}
+
+	start := time.Now()
+	if step.fn != nil {
+		if err := step.fn(ctx, db); err != nil {
+			return fmt.Errorf("migrate: %s %s: %w", action, step.mig.version, err)
+		}
+	} else if strings.TrimSpace(step.sql) != "" {
+		if _, err := db.Exec(ctx, step.sql); err != nil {
+			return fmt.Errorf("migrate: %s %s: %w", action, step.mig.version, err)
+		}
+	}
+
+	return m.record(ctx, db, step, false, time.Since(start))
+}
+
+// migrateExecer is implemented by *DB and by the *pgxpool.Conn of the migrate advisory lock.
+type migrateExecer interface {
+	Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error)
+}
+
+// record records an applied migration, with its down SQL, checksum, duration and the hostname and
+// application_name it was applied from, or removes a reverted one. A dirty applied migration is recorded,
+// without a duration, or a reverted one is kept, as dirty.
+func (m *migrator) record(ctx context.Context, db migrateExecer, step migrationStep, dirty bool, duration time.Duration) error {
+	if step.revert {
+		query := "DELETE FROM " + m.quotedTable + " WHERE version = $1"
+		if dirty {
+			query = "UPDATE " + m.quotedTable + " SET dirty = true WHERE version = $1"
+		}
+
+		if _, err := db.Exec(ctx, query, step.mig.version); err != nil {
+			return fmt.Errorf("migrate: unrecord %s: %w", step.mig.version, err)
+		}
+
+		return nil
+	}
+
+	var down, checksum any // NULL without a down SQL or a checksum.
+	if step.mig.goMigration == nil {
+		if step.mig.hasDown {
+			down = step.mig.down
+		}
+		checksum = step.mig.checksum
+	}
+
+	var durationMS any // NULL while dirty.
+	if !dirty {
+		durationMS = duration.Milliseconds()
+	}
+
+	var hostname any // NULL if unknown.
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hostname = name
+	}
+
+	query := "INSERT INTO " + m.quotedTable + " (version, down_sql, checksum, dirty, duration_ms, hostname, application_name) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, NULLIF(current_setting('application_name'), '')) " +
+		"ON CONFLICT (version) DO UPDATE SET dirty = EXCLUDED.dirty, duration_ms = EXCLUDED.duration_ms"
+	if _, err := db.Exec(ctx, query, step.mig.version, down, checksum, dirty, durationMS, hostname); err != nil {
+		return fmt.Errorf("migrate: record %s: %w", step.mig.version, err)
+	}
+
+	return nil
+}
+
+// runNoTransactionStep applies or reverts a no-transaction migration: it records it as dirty, executes its
+// statements one by one, outside of a transaction, and then records it as applied or removes it.
+// If a statement fails the migration stays dirty, see MigrateResolve.
+func (m *migrator) runNoTransactionStep(ctx context.Context, conn *pgxpool.Conn, step migrationStep) error {
+	if err := m.record(ctx, conn, step, true, 0); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	action := "exec"
+	if step.revert {
+		action = "revert"
+	}
+
+	for _, statement := range splitStatements(step.sql) {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("migrate: %s %s: no transaction: the migration is dirty, fix it and see MigrateResolve: %w", action, step.mig.version, err)
+		}
+	}
+
+	return m.record(ctx, conn, step, false, time.Since(start))
+}
+
+// migrateNoTransactionDirective is the line which makes a migration, or its down section or file,
+// run outside of a transaction, e.g. for CREATE INDEX CONCURRENTLY.
+const migrateNoTransactionDirective = "-- pg:no-transaction"
+
+// hasNoTransactionDirective reports whether the SQL has a migrateNoTransactionDirective line.
+func hasNoTransactionDirective(sql string) bool {
+	for line := range strings.Lines(sql) {
+		if strings.TrimSpace(line) == migrateNoTransactionDirective {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitStatements splits SQL into its ';'-terminated statements, skipping the empty ones and
+// the ';' inside of quoted strings and identifiers, dollar-quoted strings and comments.
+// A no-transaction migration runs its statements one by one, as PostgreSQL runs the statements of a
+// single multi-statement query in an implicit transaction.
+func splitStatements(sql string) []string {
+	var (
+		statements []string
+		start      int
+	)
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"':
+			if end := strings.IndexByte(sql[i+1:], c); end != -1 {
+				i += end + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end != -1 {
+				i += end
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end != -1 {
+				i += end + 3
+			} else {
+				i = len(sql)
+			}
+		case c == '$':
+			if tag := dollarQuoteTag(sql[i:]); tag != "" {
+				if end := strings.Index(sql[i+len(tag):], tag); end != -1 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(sql)
+				}
+			}
+		case c == ';':
+			statements = appendStatement(statements, sql[start:i])
+			start = i + 1
+		}
+	}
+
+	return appendStatement(statements, sql[min(start, len(sql)):])
+}
+
+// dollarQuoteTag returns the dollar-quote tag, e.g. "$$" or "$body$", which s starts with, if any.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 1 && c >= '0' && c <= '9'):
+		default:
+			return "" // e.g. a $1 parameter.
+		}
+	}
+
+	return ""
+}
+
+// appendStatement appends a statement which has more than comments and white space.
+func appendStatement(statements []string, statement string) []string {
+	for line := range strings.Lines(statement) {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return append(statements, strings.TrimSpace(statement))
+		}
+	}
+
+	return statements
 }
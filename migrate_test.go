@@ -2,6 +2,7 @@ package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
@@ -199,3 +200,498 @@ func TestMigrateBadTableName(t *testing.T) {
 		t.Fatalf("error = %v, want it to mention an invalid table name", err)
 	}
 }
+
+// TestLoadMigrations verifies, without a database, how the up and down directions of the
+// migrations are read: paired ".up."/".down." files, a "-- +down" section and a plain file.
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create.up.sql":   &fstest.MapFile{Data: []byte("CREATE TABLE a (id int);")},
+		"0001_create.down.sql": &fstest.MapFile{Data: []byte("DROP TABLE a;")},
+		"0002_seed.sql":        &fstest.MapFile{Data: []byte("INSERT INTO a VALUES (1);\n  -- +down  \nDELETE FROM a;\n")},
+		"0003_plain.sql":       &fstest.MapFile{Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := loadMigrations(fsys, "*.sql")
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+
+	want := []migration{
+		{version: "0001_create.up.sql", up: "CREATE TABLE a (id int);", down: "DROP TABLE a;", hasDown: true},
+		{version: "0002_seed.sql", up: "INSERT INTO a VALUES (1);\n", down: "DELETE FROM a;\n", hasDown: true},
+		{version: "0003_plain.sql", up: "SELECT 1;"},
+	}
+	for i := range want {
+		want[i].checksum = migrationChecksum(want[i].up)
+	}
+	if !slices.Equal(migrations, want) {
+		t.Fatalf("migrations = %#v, want %#v", migrations, want)
+	}
+
+	fsys["0004_orphan.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	if _, err = loadMigrations(fsys, "*.sql"); err == nil || !strings.Contains(err.Error(), "without an up one") {
+		t.Fatalf("error = %v, want it to report the down file without an up one", err)
+	}
+	delete(fsys, "0004_orphan.down.sql")
+
+	fsys["0001_create.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE a (id int);\n-- +down\nDROP TABLE a;")}
+	if _, err = loadMigrations(fsys, "*.sql"); err == nil || !strings.Contains(err.Error(), "both") {
+		t.Fatalf("error = %v, want it to report both a down section and a down file", err)
+	}
+}
+
+// TestMigrateDown applies three migrations, reverts the last one with MigrateDown, moves back and
+// forth with MigrateTo, and verifies that a migration without a down direction cannot be reverted
+// and that the down SQL recorded in the tracking table is used once its files are gone.
+func TestMigrateDown(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	trackingTable := migrateTestSuffix("test_migrate_ledger_down")
+	dataTable := migrateTestSuffix("test_migrate_data_down")
+	defer dropMigrateTestTables(t, db, trackingTable, dataTable)
+
+	quotedData := QuoteIdentifier(dataTable)
+	fsys := fstest.MapFS{
+		"0001_create.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("CREATE TABLE %s (id bigint PRIMARY KEY);", quotedData)),
+		},
+		"0002_seed.up.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("INSERT INTO %s (id) VALUES (1), (2);", quotedData)),
+		},
+		"0002_seed.down.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("DELETE FROM %s WHERE id IN (1, 2);", quotedData)),
+		},
+		"0003_seed_more.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("INSERT INTO %[1]s (id) VALUES (3);\n-- +down\nDELETE FROM %[1]s WHERE id = 3;", quotedData)),
+		},
+	}
+	opts := &MigrateOptions{TableName: trackingTable}
+
+	countRows := func() int64 {
+		t.Helper()
+
+		count, err := db.Count(ctx, "SELECT COUNT(*) FROM "+quotedData)
+		if err != nil {
+			t.Fatalf("count data table: %v", err)
+		}
+		return count
+	}
+
+	if _, err = db.Migrate(ctx, fsys, opts); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	reverted, err := db.MigrateDown(ctx, fsys, opts, 1)
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if want := []string{"0003_seed_more.sql"}; !slices.Equal(reverted, want) {
+		t.Fatalf("reverted = %v, want %v", reverted, want)
+	}
+	if count := countRows(); count != 2 {
+		t.Fatalf("data table row count = %d, want 2", count)
+	}
+
+	applied, reverted, err := db.MigrateTo(ctx, fsys, opts, "0001")
+	if err != nil {
+		t.Fatalf("MigrateTo: %v", err)
+	}
+	if len(applied) != 0 || !slices.Equal(reverted, []string{"0002_seed.up.sql"}) {
+		t.Fatalf("applied = %v, reverted = %v, want none and [0002_seed.up.sql]", applied, reverted)
+	}
+
+	applied, reverted, err = db.MigrateTo(ctx, fsys, opts, "0003_seed_more.sql")
+	if err != nil {
+		t.Fatalf("MigrateTo: %v", err)
+	}
+	if want := []string{"0002_seed.up.sql", "0003_seed_more.sql"}; !slices.Equal(applied, want) || len(reverted) != 0 {
+		t.Fatalf("applied = %v, reverted = %v, want %v and none", applied, reverted, want)
+	}
+	if count := countRows(); count != 3 {
+		t.Fatalf("data table row count = %d, want 3", count)
+	}
+
+	// the files are gone, e.g. the previous release after a bad deploy: the recorded down SQL is used.
+	reverted, err = db.MigrateDown(ctx, fstest.MapFS{}, opts, 2)
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if want := []string{"0003_seed_more.sql", "0002_seed.up.sql"}; !slices.Equal(reverted, want) {
+		t.Fatalf("reverted = %v, want %v", reverted, want)
+	}
+	if count := countRows(); count != 0 {
+		t.Fatalf("data table row count = %d, want 0", count)
+	}
+
+	// 0001_create.sql has no down direction: nothing is reverted.
+	if reverted, err = db.MigrateDown(ctx, fsys, opts, 1); err == nil || !strings.Contains(err.Error(), "no down migration") {
+		t.Fatalf("MigrateDown: reverted = %v, error = %v, want a missing down migration error", reverted, err)
+	}
+
+	if _, _, err = db.MigrateTo(ctx, fsys, opts, "0009"); err == nil || !strings.Contains(err.Error(), "unknown version") {
+		t.Fatalf("MigrateTo: error = %v, want an unknown version error", err)
+	}
+}
+
+// TestMigrateDrift verifies the checksum and out-of-order checks of Migrate and their policies:
+// an edited applied file and a pending file which sorts before the latest applied one fail the
+// call by default, are reported to OnWarning by MigrationWarn and pass with MigrationAllow.
+func TestMigrateDrift(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	trackingTable := migrateTestSuffix("test_migrate_ledger_drift")
+	defer dropMigrateTestTables(t, db, trackingTable)
+
+	fsys := fstest.MapFS{
+		"0001_first.sql": &fstest.MapFile{Data: []byte("SELECT 1;")},
+		"0003_third.sql": &fstest.MapFile{Data: []byte("SELECT 3;")},
+	}
+	opts := &MigrateOptions{TableName: trackingTable}
+
+	if _, err = db.Migrate(ctx, fsys, opts); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	fsys["0001_first.sql"] = &fstest.MapFile{Data: []byte("SELECT 1; -- fixed")}
+	fsys["0002_second.sql"] = &fstest.MapFile{Data: []byte("SELECT 2;")}
+
+	applied, err := db.Migrate(ctx, fsys, opts)
+	modifiedErr, ok := errors.AsType[*MigrationModifiedError](err)
+	if !ok || !errors.Is(err, ErrMigrationModified) || !slices.Equal(modifiedErr.Versions, []string{"0001_first.sql"}) {
+		t.Fatalf("Migrate: error = %v, want a MigrationModifiedError for 0001_first.sql", err)
+	}
+	if applied != nil {
+		t.Fatalf("applied = %v, want nil on error", applied)
+	}
+
+	var warnings []error
+	opts.Modified = MigrationWarn
+	opts.OnWarning = func(err error) { warnings = append(warnings, err) }
+
+	_, err = db.Migrate(ctx, fsys, opts)
+	outOfOrderErr, ok := errors.AsType[*MigrationOutOfOrderError](err)
+	if !ok || !errors.Is(err, ErrMigrationOutOfOrder) || !slices.Equal(outOfOrderErr.Versions, []string{"0002_second.sql"}) || outOfOrderErr.Latest != "0003_third.sql" {
+		t.Fatalf("Migrate: error = %v, want a MigrationOutOfOrderError for 0002_second.sql", err)
+	}
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrMigrationModified) {
+		t.Fatalf("warnings = %v, want the modified migration", warnings)
+	}
+
+	opts.OutOfOrder = MigrationAllow
+	applied, err = db.Migrate(ctx, fsys, opts)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if want := []string{"0002_second.sql"}; !slices.Equal(applied, want) {
+		t.Fatalf("applied = %v, want %v", applied, want)
+	}
+}
+
+// TestMigrateGoMigrations verifies that registered Go migrations interleave with the files by
+// version, run in the same transaction, are recorded in the same tracking table and are reverted
+// by their down function.
+func TestMigrateGoMigrations(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	trackingTable := migrateTestSuffix("test_migrate_ledger_go")
+	dataTable := migrateTestSuffix("test_migrate_data_go")
+	defer dropMigrateTestTables(t, db, trackingTable, dataTable)
+
+	quotedData := QuoteIdentifier(dataTable)
+	fsys := fstest.MapFS{
+		"0001_create.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("CREATE TABLE %s (id bigint PRIMARY KEY, slug text);", quotedData)),
+		},
+		"0003_not_null.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("ALTER TABLE %[1]s ALTER COLUMN slug SET NOT NULL;\n-- +down\nALTER TABLE %[1]s ALTER COLUMN slug DROP NOT NULL;", quotedData)),
+		},
+	}
+
+	opts := &MigrateOptions{TableName: trackingTable}
+	opts.Register("0002_backfill", func(ctx context.Context, tx *DB) error {
+		if !tx.IsTransaction() {
+			return errors.New("expected the migration to run in a transaction")
+		}
+
+		_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (id, slug) VALUES (1, $1);", quotedData), strings.ToLower("Hello-World"))
+		return err
+	})
+
+	applied, err := db.Migrate(ctx, fsys, opts)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if want := []string{"0001_create.sql", "0002_backfill", "0003_not_null.sql"}; !slices.Equal(applied, want) {
+		t.Fatalf("applied = %v, want %v", applied, want)
+	}
+
+	var slug string
+	if err = db.QueryRow(ctx, "SELECT slug FROM "+quotedData+" WHERE id = 1").Scan(&slug); err != nil {
+		t.Fatalf("read slug: %v", err)
+	}
+	if slug != "hello-world" {
+		t.Fatalf("slug = %q, want hello-world", slug)
+	}
+
+	// the Go migration has no down function yet: nothing is reverted.
+	if _, err = db.MigrateDown(ctx, fsys, opts, 2); err == nil || !strings.Contains(err.Error(), "no down migration") {
+		t.Fatalf("MigrateDown: error = %v, want a missing down migration error", err)
+	}
+
+	opts.GoMigrations[0].Down = func(ctx context.Context, tx *DB) error {
+		_, err := tx.Exec(ctx, "DELETE FROM "+quotedData)
+		return err
+	}
+
+	reverted, err := db.MigrateDown(ctx, fsys, opts, 2)
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if want := []string{"0003_not_null.sql", "0002_backfill"}; !slices.Equal(reverted, want) {
+		t.Fatalf("reverted = %v, want %v", reverted, want)
+	}
+
+	count, err := db.Count(ctx, "SELECT COUNT(*) FROM "+quotedData)
+	if err != nil {
+		t.Fatalf("count data table: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("data table row count = %d, want 0", count)
+	}
+
+	duplicate := &MigrateOptions{TableName: trackingTable}
+	duplicate.Register("0001_create.sql", opts.GoMigrations[0].Up)
+	if _, err = db.Migrate(ctx, fsys, duplicate); err == nil || !strings.Contains(err.Error(), "duplicate version") {
+		t.Fatalf("Migrate: error = %v, want a duplicate version error", err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := "-- pg:no-transaction\n" +
+		"CREATE INDEX CONCURRENTLY users_email ON users (email);\n" +
+		"INSERT INTO notes (body) VALUES ('a; b', E'it''s; fine');\n" +
+		"CREATE FUNCTION f() RETURNS text AS $body$ SELECT 'x;y'; $body$ LANGUAGE sql;\n" +
+		"/* a; comment */ SELECT $1::int; -- trailing; comment\n" +
+		"SELECT \"a;b\" FROM t\n"
+
+	want := []string{
+		"-- pg:no-transaction\nCREATE INDEX CONCURRENTLY users_email ON users (email)",
+		"INSERT INTO notes (body) VALUES ('a; b', E'it''s; fine')",
+		"CREATE FUNCTION f() RETURNS text AS $body$ SELECT 'x;y'; $body$ LANGUAGE sql",
+		"/* a; comment */ SELECT $1::int",
+		"-- trailing; comment\nSELECT \"a;b\" FROM t",
+	}
+	if got := splitStatements(sql); !slices.Equal(got, want) {
+		t.Fatalf("splitStatements = %q, want %q", got, want)
+	}
+
+	if got := splitStatements("-- only a comment;\n;\n"); len(got) != 0 {
+		t.Fatalf("splitStatements = %q, want none", got)
+	}
+
+	if !hasNoTransactionDirective("SELECT 1;\n  -- pg:no-transaction  \n") {
+		t.Fatal("hasNoTransactionDirective = false, want true")
+	}
+	if hasNoTransactionDirective("SELECT 1; -- pg:no-transaction\n") {
+		t.Fatal("hasNoTransactionDirective = true for a trailing comment, want false")
+	}
+}
+
+func TestMigrateNoTransaction(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	trackingTable := migrateTestSuffix("test_migrate_ledger_notx")
+	dataTable := migrateTestSuffix("test_migrate_data_notx")
+	defer dropMigrateTestTables(t, db, trackingTable, dataTable)
+
+	quotedData := QuoteIdentifier(dataTable)
+	quotedIndex := QuoteIdentifier(dataTable + "_email")
+	fsys := fstest.MapFS{
+		"0001_create.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("CREATE TABLE %s (id bigint PRIMARY KEY, email text);", quotedData)),
+		},
+		"0002_index.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("-- pg:no-transaction\nCREATE INDEX CONCURRENTLY %[1]s ON %[2]s (email);\n"+
+				"-- +down\n-- pg:no-transaction\nDROP INDEX CONCURRENTLY %[1]s;", quotedIndex, quotedData)),
+		},
+		"0003_insert.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("INSERT INTO %s (id, email) VALUES (1, 'a@example.com');", quotedData)),
+		},
+	}
+
+	opts := &MigrateOptions{TableName: trackingTable}
+	applied, err := db.Migrate(ctx, fsys, opts)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if want := []string{"0001_create.sql", "0002_index.sql", "0003_insert.sql"}; !slices.Equal(applied, want) {
+		t.Fatalf("applied = %v, want %v", applied, want)
+	}
+
+	var indexes int
+	if err = db.QueryRow(ctx, "SELECT COUNT(*) FROM pg_indexes WHERE indexname = $1", dataTable+"_email").Scan(&indexes); err != nil {
+		t.Fatalf("read indexes: %v", err)
+	}
+	if indexes != 1 {
+		t.Fatalf("index count = %d, want 1", indexes)
+	}
+
+	// a no-transaction migration cannot run in the transaction of the caller.
+	err = db.InTransaction(ctx, func(tx *DB) error {
+		_, err := tx.MigrateDown(ctx, fsys, opts, 2)
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot run in a transaction") {
+		t.Fatalf("MigrateDown in a transaction: error = %v, want a no-transaction error", err)
+	}
+
+	reverted, err := db.MigrateDown(ctx, fsys, opts, 2)
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if want := []string{"0003_insert.sql", "0002_index.sql"}; !slices.Equal(reverted, want) {
+		t.Fatalf("reverted = %v, want %v", reverted, want)
+	}
+
+	// the second statement fails after the first one was executed: the migration is left dirty.
+	fsys["0002_index.sql"] = &fstest.MapFile{
+		Data: []byte(fmt.Sprintf("-- pg:no-transaction\nCREATE INDEX CONCURRENTLY %s ON %s (email);\nSELECT missing_column FROM %[2]s;", quotedIndex, quotedData)),
+	}
+
+	applied, err = db.Migrate(ctx, fsys, opts)
+	if err == nil {
+		t.Fatal("Migrate: error = nil, want the failed statement")
+	}
+	if len(applied) != 0 {
+		t.Fatalf("applied = %v, want none", applied)
+	}
+
+	if _, err = db.Migrate(ctx, fsys, opts); !errors.Is(err, ErrMigrationDirty) {
+		t.Fatalf("Migrate: error = %v, want ErrMigrationDirty", err)
+	}
+
+	dirtyErr, ok := errors.AsType[*MigrationDirtyError](err)
+	if !ok || !slices.Equal(dirtyErr.Versions, []string{"0002_index.sql"}) {
+		t.Fatalf("dirty error = %#v, want the 0002_index.sql version", dirtyErr)
+	}
+
+	if err = db.MigrateResolve(ctx, opts, "0003_insert.sql", true); err == nil {
+		t.Fatal("MigrateResolve: error = nil, want a not dirty migration error")
+	}
+
+	// fixed by hand: the index exists, the migration is resolved as applied.
+	if err = db.MigrateResolve(ctx, opts, "0002_index.sql", true); err != nil {
+		t.Fatalf("MigrateResolve: %v", err)
+	}
+
+	applied, err = db.Migrate(ctx, fsys, opts)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if want := []string{"0003_insert.sql"}; !slices.Equal(applied, want) {
+		t.Fatalf("applied = %v, want %v", applied, want)
+	}
+}
+
+func TestMigrationStatus(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	trackingTable := migrateTestSuffix("test_migrate_ledger_status")
+	dataTable := migrateTestSuffix("test_migrate_data_status")
+	defer dropMigrateTestTables(t, db, trackingTable, dataTable)
+
+	quotedData := QuoteIdentifier(dataTable)
+	fsys := fstest.MapFS{
+		"0001_create.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("CREATE TABLE %s (id bigint PRIMARY KEY);", quotedData)),
+		},
+		"0002_insert.sql": &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("INSERT INTO %s (id) VALUES (1);", quotedData)),
+		},
+	}
+	opts := &MigrateOptions{TableName: trackingTable}
+
+	// the tracking table does not exist yet: everything is pending and nothing is created.
+	infos, err := db.MigrationStatus(ctx, fsys, opts)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if len(infos) != 2 || !infos[0].Pending || !infos[1].Pending {
+		t.Fatalf("infos = %#v, want 2 pending migrations", infos)
+	}
+
+	plan, err := db.MigratePlan(ctx, fsys, opts)
+	if err != nil {
+		t.Fatalf("MigratePlan: %v", err)
+	}
+	if len(plan) != 2 || plan[0].Version != "0001_create.sql" || plan[1].SQL != string(fsys["0002_insert.sql"].Data) {
+		t.Fatalf("plan = %#v, want the 2 files", plan)
+	}
+
+	var exists bool
+	if err = db.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", trackingTable).Scan(&exists); err != nil {
+		t.Fatalf("read tracking table: %v", err)
+	}
+	if exists {
+		t.Fatal("tracking table exists after MigrationStatus and MigratePlan, want none")
+	}
+
+	delete(fsys, "0002_insert.sql")
+	if _, err = db.Migrate(ctx, fsys, opts); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	// 0002 is restored and 0001 edited after it was applied, and a migration was applied by another build.
+	fsys["0001_create.sql"].Data = append(fsys["0001_create.sql"].Data, '\n')
+	fsys["0002_insert.sql"] = &fstest.MapFile{Data: []byte(fmt.Sprintf("INSERT INTO %s (id) VALUES (1);", quotedData))}
+	if _, err = db.Exec(ctx, "INSERT INTO "+QuoteIdentifier(trackingTable)+" (version) VALUES ('0003_other.sql')"); err != nil {
+		t.Fatalf("record 0003_other.sql: %v", err)
+	}
+
+	infos, err = db.MigrationStatus(ctx, fsys, opts)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("infos = %#v, want 3 migrations", infos)
+	}
+
+	if info := infos[0]; info.Version != "0001_create.sql" || info.Pending || !info.Modified || info.AppliedAt.IsZero() || info.Hostname == "" {
+		t.Fatalf("infos[0] = %#v, want the applied and modified 0001_create.sql", info)
+	}
+	if info := infos[1]; info.Version != "0002_insert.sql" || !info.Pending || !info.OutOfOrder {
+		t.Fatalf("infos[1] = %#v, want the pending and out-of-order 0002_insert.sql", info)
+	}
+	if info := infos[2]; info.Version != "0003_other.sql" || info.Pending || !info.Missing {
+		t.Fatalf("infos[2] = %#v, want the missing 0003_other.sql", info)
+	}
+
+	if _, err = db.MigratePlan(ctx, fsys, opts); !errors.Is(err, ErrMigrationModified) {
+		t.Fatalf("MigratePlan: error = %v, want ErrMigrationModified", err)
+	}
+}
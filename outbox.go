@@ -0,0 +1,386 @@
+package pg
+
+import (
+	"context"
+	jsonv1 "encoding/json"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// This file adds a transactional outbox: the events of a change are written to an outbox table
+// in the same transaction as the change (see Outbox.Publish), so they are published if and only if
+// the change is committed, and a dispatcher delivers them to the message bus afterwards
+// (see Outbox.Dispatch), without a two-phase commit.
+
+// OutboxOptions holds the options of an Outbox, see DB.Outbox.
+type OutboxOptions struct {
+	// Table is the name of the outbox table, in the search path of the DB.
+	// Defaults to "outbox".
+	Table string
+	// Channel is the name of the postgres channel Publish notifies (at commit)
+	// to wake up the dispatchers, see Dispatch. Defaults to "outbox_messages".
+	Channel string
+	// BatchSize is the maximum number of messages a dispatcher claims at once,
+	// see DispatchBatch. Defaults to 100.
+	BatchSize int
+	// PollInterval is the interval of the dispatchers to look for messages without a notification,
+	// e.g. the ones whose retry is due or the ones published while a dispatcher was reconnecting.
+	// It also bounds the backoff of reconnecting. Defaults to 5 seconds.
+	PollInterval time.Duration
+	// Retry controls the redelivery of a message whose handler failed, with the semantics of
+	// InTransactionRetry: a message is delivered up to Retry.MaxAttempts times, with a full jitter
+	// backoff between Retry.BaseDelay and Retry.MaxDelay, and then it is marked as failed until
+	// it is requeued by RetryFailed. Retry.IsRetryable, if not nil, reports whether a handler error
+	// should be retried at all; unlike InTransactionRetry, every handler error is retried by default.
+	// Retry.TxOptions is not used.
+	// Defaults to 25 deliveries with a backoff from 1 second up to 1 hour, so a message waits out
+	// an outage of the message bus of several hours.
+	Retry RetryOptions
+	// OnError, if not nil, is called by Dispatch with the errors of the database, e.g. a lost
+	// connection, which the dispatcher recovers from by itself. Handler errors are not reported,
+	// they are recorded in the last_error column of the message.
+	OnError func(error)
+}
+
+// Outbox is a transactional outbox, see DB.Outbox. It is safe for concurrent use.
+type Outbox struct {
+	db   *DB
+	opts OutboxOptions
+}
+
+// OutboxMessage is a message of the outbox, delivered to the handler of Outbox.Dispatch.
+type OutboxMessage struct {
+	ID        int64             // the sequential ID of the message.
+	Topic     string            // the topic the message was published to.
+	Payload   jsonv1.RawMessage // the JSON payload of the message.
+	CreatedAt time.Time         // the time the message was published.
+	Attempts  int               // the number of the previous, failed, deliveries of the message.
+}
+
+// OutboxHandler delivers a message of the outbox, e.g. to a message bus.
+// A nil error marks the message as sent, any other error schedules its redelivery, see OutboxOptions.Retry.
+type OutboxHandler func(ctx context.Context, msg OutboxMessage) error
+
+// Outbox returns a new Outbox which stores its messages in the table of the options.
+// Create its table with CreateTable (e.g. after CreateSchema).
+//
+// Example:
+//
+//	outbox, err := db.Outbox(pg.OutboxOptions{})
+//	if err != nil { ... }
+//	if err = outbox.CreateTable(ctx); err != nil { ... }
+//
+//	err = db.InTransaction(ctx, func(tx *pg.DB) error {
+//		if err := pg.NewRepository[Order](tx).InsertSingle(ctx, order, &order.ID); err != nil {
+//			return err
+//		}
+//
+//		return outbox.Publish(ctx, tx, "orders.created", order)
+//	})
+//
+//	dispatcher, err := outbox.Dispatch(ctx, func(ctx context.Context, msg pg.OutboxMessage) error {
+//		return bus.Publish(ctx, msg.Topic, msg.Payload)
+//	})
+//	if err != nil { ... }
+//	defer dispatcher.Close(ctx)
+func (db *DB) Outbox(opts OutboxOptions) (*Outbox, error) {
+	if opts.Table == "" {
+		opts.Table = "outbox"
+	}
+
+	if opts.Channel == "" {
+		opts.Channel = "outbox_messages"
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+
+	if opts.Retry.MaxAttempts == 0 {
+		opts.Retry.MaxAttempts = 25
+	}
+
+	if opts.Retry.BaseDelay <= 0 {
+		opts.Retry.BaseDelay = time.Second
+	}
+
+	if opts.Retry.MaxDelay <= 0 {
+		opts.Retry.MaxDelay = time.Hour
+	}
+
+	opts.Retry = normalizeRetryOptions(opts.Retry)
+
+	if err := validateListenTableIdentifier("table", opts.Table); err != nil {
+		return nil, fmt.Errorf("outbox: %w", err)
+	}
+
+	return &Outbox{db: db, opts: opts}, nil
+}
+
+// tableName returns the quoted, schema-qualified name of the outbox table.
+func (o *Outbox) tableName() string {
+	return QuoteIdentifier(o.db.searchPath) + "." + QuoteIdentifier(o.opts.Table)
+}
+
+// CreateTable creates the outbox table and its index of the unsent messages, if they do not exist.
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id bigserial PRIMARY KEY,
+		topic text NOT NULL,
+		payload jsonb NOT NULL,
+		created_at timestamptz NOT NULL DEFAULT now(),
+		attempts integer NOT NULL DEFAULT 0,
+		next_attempt_at timestamptz NOT NULL DEFAULT now(),
+		last_error text,
+		sent_at timestamptz,
+		failed_at timestamptz
+	);
+	CREATE INDEX IF NOT EXISTS %s ON %s (next_attempt_at, id) WHERE sent_at IS NULL AND failed_at IS NULL;`,
+		o.tableName(), QuoteIdentifier(o.opts.Table+"_unsent_idx"), o.tableName())
+
+	if _, err := o.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("outbox: create table: %w", err)
+	}
+
+	return nil
+}
+
+// Publish writes a message with the given topic and payload to the outbox, in the transaction of tx,
+// e.g. the one of a DB.InTransaction function, so it is published if and only if the transaction
+// commits. The payload is marshaled to JSON, a json.RawMessage is stored as it is.
+// The dispatchers are notified on commit.
+//
+// It returns an error if tx is not in a transaction.
+func (o *Outbox) Publish(ctx context.Context, tx *DB, topic string, payload any) error {
+	if !tx.IsTransaction() {
+		return fmt.Errorf("outbox: publish: %s: the database is not in a transaction", topic)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("outbox: publish: %s: %w", topic, err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (topic, payload) VALUES ($1, $2); `, o.tableName())
+	if _, err = tx.Exec(ctx, query, topic, string(b)); err != nil {
+		return fmt.Errorf("outbox: publish: %s: %w", topic, err)
+	}
+
+	// pg_notify within a transaction is delivered on commit, once per channel and payload.
+	if _, err = tx.Exec(ctx, `SELECT pg_notify($1, $2);`, o.opts.Channel, topic); err != nil {
+		return fmt.Errorf("outbox: publish: %s: notify: %w", topic, err)
+	}
+
+	return nil
+}
+
+// RetryFailed requeues the failed messages with the given IDs, or all of them if no ID is given,
+// e.g. after the outage of the message bus they failed on is over: their attempts start over,
+// their delivery is due immediately and the dispatchers are notified. It returns the number of the
+// requeued messages.
+func (o *Outbox) RetryFailed(ctx context.Context, ids ...int64) (int64, error) {
+	query := fmt.Sprintf(`UPDATE %s SET failed_at = NULL, attempts = 0, next_attempt_at = now()
+		WHERE failed_at IS NOT NULL AND sent_at IS NULL AND (cardinality($1::bigint[]) = 0 OR id = ANY($1));`, o.tableName())
+
+	if ids == nil {
+		ids = []int64{}
+	}
+
+	tag, err := o.db.Exec(ctx, query, ids)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: retry failed: %w", err)
+	}
+
+	requeued := tag.RowsAffected()
+	if requeued > 0 {
+		if _, err = o.db.Exec(ctx, `SELECT pg_notify($1, '');`, o.opts.Channel); err != nil {
+			return requeued, fmt.Errorf("outbox: retry failed: notify: %w", err)
+		}
+	}
+
+	return requeued, nil
+}
+
+// PurgeSent deletes the messages which were sent before the given duration, e.g. 7 days, and
+// returns their number. The sent messages are kept in the outbox table, for inspection, until
+// they are purged; call it periodically, e.g. from a cron job.
+func (o *Outbox) PurgeSent(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE sent_at < now() - $1::interval;`, o.tableName())
+	tag, err := o.db.Exec(ctx, query, olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: purge sent: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// DispatchBatch claims up to OutboxOptions.BatchSize unsent messages whose delivery is due, oldest first,
+// delivers them to the handler one after the other and records the result of each one. It returns
+// the number of the claimed messages and the delay until the next retry of a failed one, zero for none.
+//
+// The messages are claimed with SELECT ... FOR UPDATE SKIP LOCKED in a transaction which lasts
+// until they are delivered, so any number of dispatchers can run concurrently, e.g. one per
+// process, without delivering a message twice. The delivery is at least once: if the transaction
+// fails to commit, e.g. the connection is lost, the messages are delivered again.
+func (o *Outbox) DispatchBatch(ctx context.Context, handler OutboxHandler) (claimed int, retryIn time.Duration, err error) {
+	err = o.db.InTransaction(ctx, func(tx *DB) error {
+		query := fmt.Sprintf(`SELECT id, topic, payload, created_at, attempts FROM %s
+		WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= now()
+		ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED;`, o.tableName())
+
+		rows, err := tx.Query(ctx, query, o.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		var messages []OutboxMessage
+		for rows.Next() {
+			var msg OutboxMessage
+			if err = rows.Scan(&msg.ID, &msg.Topic, &msg.Payload, &msg.CreatedAt, &msg.Attempts); err != nil {
+				rows.Close()
+				return err
+			}
+
+			messages = append(messages, msg)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		claimed = len(messages)
+		for _, msg := range messages {
+			handlerErr := handler(ctx, msg)
+			if handlerErr == nil {
+				if _, err = tx.Exec(ctx, fmt.Sprintf(`UPDATE %s SET sent_at = now() WHERE id = $1;`, o.tableName()), msg.ID); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			attempts := msg.Attempts + 1
+			if attempts >= o.opts.Retry.MaxAttempts || (o.opts.Retry.IsRetryable != nil && !o.opts.Retry.IsRetryable(handlerErr)) {
+				query := fmt.Sprintf(`UPDATE %s SET attempts = $2, last_error = $3, failed_at = now() WHERE id = $1;`, o.tableName())
+				if _, err = tx.Exec(ctx, query, msg.ID, attempts, handlerErr.Error()); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			delay := backoffDelay(o.opts.Retry, attempts)
+			if retryIn == 0 || delay < retryIn {
+				retryIn = max(delay, time.Millisecond)
+			}
+
+			query := fmt.Sprintf(`UPDATE %s SET attempts = $2, last_error = $3, next_attempt_at = now() + $4::interval WHERE id = $1;`, o.tableName())
+			if _, err = tx.Exec(ctx, query, msg.ID, attempts, handlerErr.Error(), delay); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("outbox: dispatch: %w", err)
+	}
+
+	return claimed, retryIn, nil
+}
+
+// Dispatch starts a dispatcher goroutine which delivers the messages of the outbox to the handler
+// (see DispatchBatch) until the returned Closer is closed or the context is done.
+//
+// The dispatcher listens to OutboxOptions.Channel, so a published message is delivered as soon as
+// its transaction commits, and it polls the outbox every OutboxOptions.PollInterval as a fallback.
+// A full batch is followed by the next one immediately. Database errors are reported to
+// OutboxOptions.OnError and retried on the next poll; a lost listen connection is reconnected
+// with a backoff up to OutboxOptions.PollInterval.
+func (o *Outbox) Dispatch(ctx context.Context, handler OutboxHandler) (Closer, error) {
+	if handler == nil {
+		return nil, errors.New("outbox: dispatch: handler is nil")
+	}
+
+	listener, err := o.db.listenWorker(ctx, o.opts.Channel)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: dispatch: listen: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	d := &outboxDispatcher{cancel: cancel, listener: listener}
+
+	wake := make(chan struct{}, 1)
+	d.wg.Go(func() {
+		listener.run(ctx, o.opts.PollInterval, func(string) {
+			select {
+			case wake <- struct{}{}:
+			default: // a wake-up is already pending.
+			}
+		}, func(err error) {
+			o.reportError(fmt.Errorf("outbox: dispatch: listen: %w", err))
+		})
+	})
+
+	d.wg.Go(func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			case <-wake:
+			}
+
+			wait := o.opts.PollInterval
+
+			claimed, retryIn, err := o.DispatchBatch(ctx, handler)
+			switch {
+			case err != nil:
+				if ctx.Err() != nil {
+					return
+				}
+
+				o.reportError(err)
+			case claimed == o.opts.BatchSize:
+				wait = 0
+			case retryIn > 0:
+				wait = min(wait, retryIn)
+			}
+
+			timer.Reset(wait)
+		}
+	})
+
+	return d, nil
+}
+
+func (o *Outbox) reportError(err error) {
+	if o.opts.OnError != nil {
+		o.opts.OnError(err)
+	}
+}
+
+// outboxDispatcher is the Closer of Outbox.Dispatch.
+type outboxDispatcher struct {
+	cancel   context.CancelFunc
+	listener *workerListener
+	wg       sync.WaitGroup
+}
+
+// Close stops the dispatcher and waits for the delivery of its current batch, if any.
+func (d *outboxDispatcher) Close(ctx context.Context) error {
+	d.cancel()
+	err := d.listener.Close(ctx)
+	d.wg.Wait()
+	return err
+}
@@ -0,0 +1,227 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestOutbox' -v .
+
+const outboxScratchTable = "test_outbox"
+
+func TestOutboxDispatchBatch(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, outboxScratchTable)
+	defer dropTestTables(ctx, db, outboxScratchTable)
+
+	outbox, err := db.Outbox(OutboxOptions{
+		Table: outboxScratchTable,
+		Retry: RetryOptions{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = outbox.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// a rolled back transaction publishes nothing.
+	errRollback := errors.New("rollback")
+	err = db.InTransaction(ctx, func(tx *DB) error {
+		if err := outbox.Publish(ctx, tx, "orders.created", map[string]int{"id": 0}); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected the rollback error but got: %v", err)
+	}
+
+	err = db.InTransaction(ctx, func(tx *DB) error {
+		if err := outbox.Publish(ctx, tx, "orders.created", map[string]int{"id": 1}); err != nil {
+			return err
+		}
+
+		return outbox.Publish(ctx, tx, "orders.failed", map[string]int{"id": 2})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var delivered []string
+	handler := func(_ context.Context, msg OutboxMessage) error {
+		if msg.Topic == "orders.failed" {
+			return errors.New("bus unavailable")
+		}
+
+		delivered = append(delivered, msg.Topic+" "+string(msg.Payload))
+		return nil
+	}
+
+	claimed, retryIn, err := outbox.DispatchBatch(ctx, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed != 2 || retryIn <= 0 {
+		t.Fatalf("expected 2 claimed messages and a retry but got: %d, %s", claimed, retryIn)
+	}
+
+	if len(delivered) != 1 || delivered[0] != `orders.created {"id":1}` {
+		t.Fatalf("unexpected deliveries: %v", delivered)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	// the second, and last, attempt of the failed message.
+	if claimed, _, err = outbox.DispatchBatch(ctx, handler); err != nil || claimed != 1 {
+		t.Fatalf("expected the failed message to be claimed again but got: %d: %v", claimed, err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if claimed, _, err = outbox.DispatchBatch(ctx, handler); err != nil || claimed != 0 {
+		t.Fatalf("expected no claimed messages after the last attempt but got: %d: %v", claimed, err)
+	}
+
+	var lastError string
+	if err = db.QueryRow(ctx, `SELECT last_error FROM `+outboxScratchTable+` WHERE failed_at IS NOT NULL`).Scan(&lastError); err != nil {
+		t.Fatal(err)
+	}
+
+	if lastError != "bus unavailable" {
+		t.Fatalf("expected the handler error to be recorded but got: %q", lastError)
+	}
+
+	// the failed message is requeued once the bus is available again.
+	requeued, err := outbox.RetryFailed(ctx)
+	if err != nil || requeued != 1 {
+		t.Fatalf("expected 1 requeued message but got: %d: %v", requeued, err)
+	}
+
+	delivered = nil
+	handler = func(_ context.Context, msg OutboxMessage) error {
+		delivered = append(delivered, msg.Topic)
+		return nil
+	}
+
+	if claimed, _, err = outbox.DispatchBatch(ctx, handler); err != nil || claimed != 1 || len(delivered) != 1 || delivered[0] != "orders.failed" {
+		t.Fatalf("expected the requeued message to be delivered but got: %d: %v: %v", claimed, delivered, err)
+	}
+
+	if requeued, err = outbox.RetryFailed(ctx, 1, 2); err != nil || requeued != 0 {
+		t.Fatalf("expected no failed messages to requeue but got: %d: %v", requeued, err)
+	}
+
+	if purged, err := outbox.PurgeSent(ctx, time.Hour); err != nil || purged != 0 {
+		t.Fatalf("expected no sent messages older than an hour but got: %d: %v", purged, err)
+	}
+
+	purged, err := outbox.PurgeSent(ctx, 0)
+	if err != nil || purged != 2 {
+		t.Fatalf("expected the 2 sent messages to be purged but got: %d: %v", purged, err)
+	}
+}
+
+func TestOutboxDispatch(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, outboxScratchTable)
+	defer dropTestTables(ctx, db, outboxScratchTable)
+
+	listenErrors := make(chan error, 1)
+	outbox, err := db.Outbox(OutboxOptions{
+		Table:        outboxScratchTable,
+		PollInterval: time.Hour,
+		OnError: func(err error) {
+			select {
+			case listenErrors <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = outbox.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make(chan OutboxMessage, 1)
+	dispatcher, err := outbox.Dispatch(ctx, func(_ context.Context, msg OutboxMessage) error {
+		messages <- msg
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dispatcher.Close(ctx)
+
+	time.Sleep(100 * time.Millisecond) // let the first, empty, poll run.
+
+	err = db.InTransaction(ctx, func(tx *DB) error {
+		return outbox.Publish(ctx, tx, "orders.created", "order")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the poll interval is an hour: the notification wakes the dispatcher up.
+	select {
+	case msg := <-messages:
+		if msg.Topic != "orders.created" || string(msg.Payload) != `"order"` {
+			t.Fatalf("unexpected message: %#v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the message was not dispatched")
+	}
+	// the dispatcher listens again after its connection is lost.
+	_, err = db.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE pid <> pg_backend_pid() AND query = $1;`,
+		"LISTEN "+QuoteIdentifier(outbox.opts.Channel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-listenErrors:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lost connection was not reported")
+	}
+
+	time.Sleep(500 * time.Millisecond) // let the dispatcher listen again.
+
+	err = db.InTransaction(ctx, func(tx *DB) error {
+		return outbox.Publish(ctx, tx, "orders.paid", "order")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-messages:
+		if msg.Topic != "orders.paid" {
+			t.Fatalf("unexpected message: %#v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the message was not dispatched after the connection was lost")
+	}
+}
@@ -0,0 +1,41 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOutboxOptions(t *testing.T) {
+	db := &DB{schema: NewSchema(), searchPath: "public"}
+
+	outbox, err := db.Outbox(OutboxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := `"public"."outbox"`, outbox.tableName(); got != expected {
+		t.Fatalf("expected table %s but got: %s", expected, got)
+	}
+
+	opts := outbox.opts
+	if opts.Channel != "outbox_messages" || opts.BatchSize != 100 || opts.PollInterval != 5*time.Second {
+		t.Fatalf("unexpected defaults: %#v", opts)
+	}
+
+	if opts.Retry.MaxAttempts != 25 || opts.Retry.BaseDelay != time.Second || opts.Retry.MaxDelay != time.Hour {
+		t.Fatalf("expected the outbox retry defaults but got: %#v", opts.Retry)
+	}
+
+	if _, err = db.Outbox(OutboxOptions{Table: `outbox"; DROP TABLE users; --`}); err == nil {
+		t.Fatal("expected an error for an invalid table name")
+	}
+
+	if err = outbox.Publish(context.Background(), db, "orders.created", 1); err == nil {
+		t.Fatal("expected an error for a database which is not in a transaction")
+	}
+
+	if _, err = outbox.Dispatch(context.Background(), nil); err == nil {
+		t.Fatal("expected an error for a nil handler")
+	}
+}
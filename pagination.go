@@ -47,15 +47,17 @@ type PageOptions struct {
 // for why, and for where it must come from. See buildPaginatedQuery for the exact assembly and
 // bind-numbering rules.
 //
-// Row scanning for the page query is identical to Repository.Select (SelectPaginated delegates
-// to it directly): rows are converted to []T via desc.Table.RowsToStruct against the repository's
-// table descriptor.
+// Row scanning for the page query is identical to Repository.Select: rows are converted to []T
+// via desc.Table.RowsToStruct against the repository's table descriptor, and soft-deleted rows are
+// hidden from both the page and the total the same way Select hides them.
 func (repo *Repository[T]) SelectPaginated(ctx context.Context, page PageOptions, query string, args ...any) ([]T, int64, error) {
-	query = trimQuery(query)
+	query, args, err := repo.scoped(ctx, trimQuery(query), args)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	total := int64(-1)
 	if !page.WithoutTotal {
-		var err error
 		total, err = repo.db.Count(ctx, "SELECT COUNT(*) FROM ("+query+") AS _pg_total", args...)
 		if err != nil {
 			return nil, 0, err
@@ -71,7 +73,7 @@ func (repo *Repository[T]) SelectPaginated(ctx context.Context, page PageOptions
 	allArgs = append(allArgs, args...)
 	allArgs = append(allArgs, pageArgs...)
 
-	items, err := repo.Select(ctx, pageQuery, allArgs...)
+	items, err := repo.selectRows(ctx, pageQuery, allArgs...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -85,15 +87,20 @@ func (repo *Repository[T]) SelectPaginated(ctx context.Context, page PageOptions
 // for it, unlike the fake struct fields tagged `presenter` this replaces.
 //
 // Unlike SelectPaginated, SelectWithTotal does not derive a separate COUNT query, does not
-// append ORDER BY/LIMIT/OFFSET, and does not trim query: it runs query and args exactly as
-// given, so the caller is responsible for the COUNT(*) OVER() AS total_count column and any
+// append ORDER BY/LIMIT/OFFSET, and does not trim query: it runs query and args as given (only
+// hiding soft-deleted rows and filling the relations of Preload the way Select does), so the caller is responsible for the COUNT(*) OVER() AS total_count column and any
 // ordering/pagination clauses it wants. Use it when the caller already has (or needs) full
 // control over the query shape; use SelectPaginated when a plain SELECT plus PageOptions is
 // enough.
 //
 // Zero rows returns (empty, 0, nil).
 func (repo *Repository[T]) SelectWithTotal(ctx context.Context, query string, args ...any) ([]T, int64, error) {
-	rows, err := repo.db.Query(ctx, query, args...)
+	query, args, err := repo.scoped(ctx, query, args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.db.queryRead(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -103,6 +110,10 @@ func (repo *Repository[T]) SelectWithTotal(ctx context.Context, query string, ar
 		return nil, 0, err
 	}
 
+	if err = repo.preload(ctx, items); err != nil {
+		return nil, 0, err // see Preload.
+	}
+
 	return items, total, nil
 }
 
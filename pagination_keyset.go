@@ -0,0 +1,326 @@
+package pg
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json/jsontext"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kataras/pg/desc"
+)
+
+// CursorOptions describes keyset (cursor) pagination for SelectAfter.
+type CursorOptions struct {
+	// OrderBy are the sort columns, in order, each one optionally followed by ASC or DESC,
+	// e.g. "created_at DESC". They are validated against the table's columns and must not be nullable.
+	// The missing primary key columns are appended, in the direction of the last sort column,
+	// so that the order is total and stable. An empty OrderBy sorts by the primary key.
+	OrderBy []string
+	// Limit is the maximum number of rows per page, it is required.
+	Limit int64
+	// After is the Next cursor token of a page: the page starts right after that page's last row.
+	After string
+	// Before is the Prev cursor token of a page: the page ends right before that page's first row.
+	// Only one of After and Before can be set.
+	Before string
+}
+
+// Cursors holds the opaque tokens of the pages around a page returned by SelectAfter.
+// An empty token means there is no such page.
+type Cursors struct {
+	Next string // the CursorOptions.After value of the next page.
+	Prev string // the CursorOptions.Before value of the previous page.
+}
+
+// SelectAfter executes query (a SELECT without its own ORDER BY, LIMIT, OFFSET or a trailing semicolon)
+// and returns a page of its rows, ordered by opts.OrderBy, together with the cursor tokens of the next
+// and previous pages.
+//
+// Unlike SelectPaginated, which skips OFFSET rows and counts all of them, SelectAfter filters by the
+// sort-key values of the row the cursor token encodes, e.g. WHERE ("created_at", "id") < ($1, $2),
+// so that the cost of a page does not depend on its depth, given an index on the sort columns.
+// No total is returned.
+//
+// Example:
+//
+//	opts := pg.CursorOptions{OrderBy: []string{"created_at DESC"}, Limit: 20, After: r.URL.Query().Get("cursor")}
+//	items, cursors, err := repo.SelectAfter(ctx, opts, "SELECT * FROM posts WHERE author_id = $1", authorID)
+//	// respond with items and cursors.Next.
+func (repo *Repository[T]) SelectAfter(ctx context.Context, opts CursorOptions, query string, args ...any) ([]T, Cursors, error) {
+	if opts.Limit <= 0 {
+		return nil, Cursors{}, fmt.Errorf("pg: cursor: a positive limit is required")
+	}
+
+	if opts.After != "" && opts.Before != "" {
+		return nil, Cursors{}, fmt.Errorf("pg: cursor: only one of after and before can be set")
+	}
+
+	columns, err := keysetColumns(repo.td, opts.OrderBy)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	token, backward := opts.After, false
+	if opts.Before != "" {
+		token, backward = opts.Before, true
+	}
+
+	var values []any
+	if token != "" {
+		if values, err = decodeCursor(token, columns); err != nil {
+			return nil, Cursors{}, err
+		}
+	}
+
+	query, args, err = repo.scoped(ctx, trimQuery(query), args)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	pageQuery, pageArgs := buildKeysetQuery(query, columns, values, backward, opts.Limit, len(args)+1)
+
+	allArgs := make([]any, 0, len(args)+len(pageArgs))
+	allArgs = append(allArgs, args...)
+	allArgs = append(allArgs, pageArgs...)
+
+	rows, err := repo.db.queryRead(ctx, pageQuery, allArgs...)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	items, err := repo.td.RowsToStruct[T](rows)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	hasMore := int64(len(items)) > opts.Limit // one more row than the limit was selected.
+	if hasMore {
+		items = items[:opts.Limit]
+	}
+
+	if backward { // selected in the reverse order, nearest to the cursor first.
+		slices.Reverse(items)
+	}
+
+	var cursors Cursors
+	if len(items) > 0 {
+		if hasMore || backward {
+			if cursors.Next, err = encodeCursor(columns, &items[len(items)-1]); err != nil {
+				return nil, Cursors{}, err
+			}
+		}
+
+		if (hasMore && backward) || opts.After != "" {
+			if cursors.Prev, err = encodeCursor(columns, &items[0]); err != nil {
+				return nil, Cursors{}, err
+			}
+		}
+	}
+
+	if err = repo.preload(ctx, items); err != nil {
+		return nil, Cursors{}, err
+	}
+
+	return items, cursors, nil
+}
+
+// keysetColumn is a sort column of SelectAfter.
+type keysetColumn struct {
+	column     *desc.Column
+	descending bool
+}
+
+// keysetColumns validates the CursorOptions.OrderBy entries against the table's columns
+// and appends the missing primary key columns.
+func keysetColumns(td *desc.Table, orderBy []string) ([]keysetColumn, error) {
+	columns := make([]keysetColumn, 0, len(orderBy)+1)
+	contains := func(c *desc.Column) bool {
+		return slices.ContainsFunc(columns, func(kc keysetColumn) bool { return kc.column == c })
+	}
+
+	for _, entry := range orderBy {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("pg: cursor: invalid order by entry %q", entry)
+		}
+
+		c := td.GetColumnByName(fields[0])
+		if c == nil {
+			return nil, fmt.Errorf("pg: cursor: unknown column %q for table %q", fields[0], td.Name)
+		}
+
+		if c.Nullable {
+			return nil, fmt.Errorf("pg: cursor: column %q of table %q is nullable", c.Name, td.Name)
+		}
+
+		if contains(c) {
+			return nil, fmt.Errorf("pg: cursor: duplicated column %q", c.Name)
+		}
+
+		kc := keysetColumn{column: c}
+		if len(fields) == 2 {
+			switch strings.ToUpper(fields[1]) {
+			case "ASC":
+			case "DESC":
+				kc.descending = true
+			default:
+				return nil, fmt.Errorf("pg: cursor: invalid order by entry %q", entry)
+			}
+		}
+
+		columns = append(columns, kc)
+	}
+
+	primaryKeys := td.PrimaryKeys()
+	if len(primaryKeys) == 0 {
+		return nil, fmt.Errorf("pg: cursor: table %q has no primary key to order by", td.Name)
+	}
+
+	descending := len(columns) > 0 && columns[len(columns)-1].descending
+	for _, c := range primaryKeys {
+		if !contains(c) {
+			columns = append(columns, keysetColumn{column: c, descending: descending})
+		}
+	}
+
+	return columns, nil
+}
+
+// buildKeysetQuery wraps query with the keyset filter of the given cursor values (if any), the ORDER BY
+// of the columns (reversed if backward) and a LIMIT of limit+1 rows, so the caller can tell whether
+// there are more rows. The cursor values and the limit are bind parameters numbered from startIndex.
+func buildKeysetQuery(query string, columns []keysetColumn, values []any, backward bool, limit int64, startIndex int) (string, []any) {
+	var b strings.Builder
+	b.WriteString("SELECT * FROM (" + query + ") AS _pg_keyset")
+
+	args := make([]any, 0, len(values)+1)
+	if len(values) > 0 {
+		names := make([]string, len(columns))
+		placeholders := make([]string, len(columns))
+		operators := make([]string, len(columns))
+		for i, kc := range columns {
+			names[i] = QuoteIdentifier(kc.column.Name)
+			placeholders[i] = fmt.Sprintf("$%d", startIndex+i)
+
+			operators[i] = ">"
+			if kc.descending != backward {
+				operators[i] = "<"
+			}
+		}
+
+		if uniform := !slices.ContainsFunc(operators, func(op string) bool { return op != operators[0] }); uniform {
+			// a row comparison, which can use a multi-column index.
+			fmt.Fprintf(&b, " WHERE (%s) %s (%s)", strings.Join(names, ", "), operators[0], strings.Join(placeholders, ", "))
+		} else {
+			// (a > $1) OR (a = $1 AND b < $2) OR ...
+			ors := make([]string, len(columns))
+			for i := range columns {
+				ands := make([]string, 0, i+1)
+				for j := range i {
+					ands = append(ands, names[j]+" = "+placeholders[j])
+				}
+
+				ands = append(ands, names[i]+" "+operators[i]+" "+placeholders[i])
+				ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+			}
+
+			b.WriteString(" WHERE " + strings.Join(ors, " OR "))
+		}
+
+		args = append(args, values...)
+		startIndex += len(values)
+	}
+
+	orderBy := make([]string, len(columns))
+	for i, kc := range columns {
+		direction := "ASC"
+		if kc.descending != backward {
+			direction = "DESC"
+		}
+
+		orderBy[i] = QuoteIdentifier(kc.column.Name) + " " + direction
+	}
+
+	fmt.Fprintf(&b, " ORDER BY %s LIMIT $%d", strings.Join(orderBy, ", "), startIndex)
+	args = append(args, limit+1)
+
+	return b.String(), args
+}
+
+// cursorToken is the JSON form of a cursor token: the sort columns and the sort-key values of a row.
+type cursorToken struct {
+	Columns []string         `json:"c"`
+	Values  []jsontext.Value `json:"v"`
+}
+
+// encodeCursor returns the cursor token of the row the valuePtr (a pointer to a T value) points to.
+func encodeCursor(columns []keysetColumn, valuePtr any) (string, error) {
+	v, ok := structPointer(valuePtr)
+	if !ok {
+		return "", fmt.Errorf("pg: cursor: nil value")
+	}
+
+	token := cursorToken{
+		Columns: make([]string, 0, len(columns)),
+		Values:  make([]jsontext.Value, 0, len(columns)),
+	}
+	for _, kc := range columns {
+		field, ok := relationField(v, kc.column)
+		if !ok {
+			return "", fmt.Errorf("pg: cursor: column %q has no value", kc.column.Name)
+		}
+
+		value, err := json.Marshal(field.Interface())
+		if err != nil {
+			return "", fmt.Errorf("pg: cursor: column %q: %w", kc.column.Name, err)
+		}
+
+		token.Columns = append(token.Columns, kc.column.Name)
+		token.Values = append(token.Values, value)
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// ErrInvalidCursor is returned by SelectAfter for a malformed cursor token
+// or one of a different ordering.
+var ErrInvalidCursor = errors.New("pg: cursor: invalid token")
+
+// decodeCursor returns the sort-key values the cursor token encodes, as values of the columns' field types.
+func decodeCursor(s string, columns []keysetColumn) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var token cursorToken
+	if err = json.Unmarshal(data, &token); err != nil || len(token.Columns) != len(columns) || len(token.Values) != len(columns) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]any, 0, len(columns))
+	for i, kc := range columns {
+		if token.Columns[i] != kc.column.Name {
+			return nil, fmt.Errorf("%w: it was created for a different ordering", ErrInvalidCursor)
+		}
+
+		value := reflect.New(indirectFieldType(kc.column))
+		if err = json.Unmarshal(token.Values[i], value.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: column %q: %w", ErrInvalidCursor, kc.column.Name, err)
+		}
+
+		values = append(values, value.Elem().Interface())
+	}
+
+	return values, nil
+}
@@ -0,0 +1,128 @@
+package pg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type keysetTestPost struct {
+	ID        int64     `pg:"type=bigserial,primary"`
+	Title     string    `pg:"type=varchar(255)"`
+	Score     int       `pg:"type=integer"`
+	CreatedAt time.Time `pg:"type=timestamp"`
+	DeletedAt time.Time `pg:"type=timestamp,nullable"`
+}
+
+// TestBuildKeysetQuery exercises the keyset SQL assembly and the cursor tokens directly,
+// without a live database: see pagination_live_test.go for the round-trip tests.
+func TestBuildKeysetQuery(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("posts", keysetTestPost{})
+	td, err := schema.Get(reflect.TypeFor[keysetTestPost]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		columns, err := keysetColumns(td, []string{"created_at DESC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, args := buildKeysetQuery("SELECT * FROM posts WHERE title <> $1", columns, nil, false, 10, 2)
+
+		wantQuery := `SELECT * FROM (SELECT * FROM posts WHERE title <> $1) AS _pg_keyset ORDER BY "created_at" DESC, "id" DESC LIMIT $2`
+		if query != wantQuery {
+			t.Fatalf("query: got %q, want %q", query, wantQuery)
+		}
+
+		if wantArgs := []any{int64(11)}; !reflect.DeepEqual(args, wantArgs) {
+			t.Fatalf("args: got %#v, want %#v", args, wantArgs)
+		}
+	})
+
+	t.Run("uniform directions", func(t *testing.T) {
+		columns, err := keysetColumns(td, []string{"created_at DESC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		createdAt := time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC)
+		token, err := encodeCursor(columns, &keysetTestPost{ID: 42, CreatedAt: createdAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := decodeCursor(token, columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if wantValues := []any{createdAt, int64(42)}; !reflect.DeepEqual(values, wantValues) {
+			t.Fatalf("values: got %#v, want %#v", values, wantValues)
+		}
+
+		query, args := buildKeysetQuery("SELECT * FROM posts", columns, values, false, 10, 1)
+		wantQuery := `SELECT * FROM (SELECT * FROM posts) AS _pg_keyset WHERE ("created_at", "id") < ($1, $2) ORDER BY "created_at" DESC, "id" DESC LIMIT $3`
+		if query != wantQuery {
+			t.Fatalf("query: got %q, want %q", query, wantQuery)
+		}
+
+		if len(args) != 3 {
+			t.Fatalf("args: got %#v", args)
+		}
+
+		query, _ = buildKeysetQuery("SELECT * FROM posts", columns, values, true, 10, 1)
+		wantQuery = `SELECT * FROM (SELECT * FROM posts) AS _pg_keyset WHERE ("created_at", "id") > ($1, $2) ORDER BY "created_at" ASC, "id" ASC LIMIT $3`
+		if query != wantQuery {
+			t.Fatalf("backward query: got %q, want %q", query, wantQuery)
+		}
+	})
+
+	t.Run("mixed directions", func(t *testing.T) {
+		columns, err := keysetColumns(td, []string{"score DESC", "title"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, _ := buildKeysetQuery("SELECT * FROM posts", columns, []any{10, "a", int64(1)}, false, 5, 1)
+		wantQuery := `SELECT * FROM (SELECT * FROM posts) AS _pg_keyset WHERE ("score" < $1) OR ("score" = $1 AND "title" > $2) OR ("score" = $1 AND "title" = $2 AND "id" > $3) ORDER BY "score" DESC, "title" ASC, "id" ASC LIMIT $4`
+		if query != wantQuery {
+			t.Fatalf("query: got %q, want %q", query, wantQuery)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for i, orderBy := range [][]string{{"missing"}, {"deleted_at"}, {"title sideways"}, {"title", "title DESC"}} {
+			if _, err := keysetColumns(td, orderBy); err == nil {
+				t.Fatalf("[%d] expected an error for order by: %v", i, orderBy)
+			}
+		}
+
+		columns, err := keysetColumns(td, []string{"title"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := encodeCursor(columns, &keysetTestPost{ID: 1, Title: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		otherColumns, err := keysetColumns(td, []string{"score"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, tt := range []struct {
+			token   string
+			columns []keysetColumn
+		}{{"not base64!", columns}, {"e30", columns}, {token, otherColumns}} {
+			if _, err = decodeCursor(tt.token, tt.columns); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("[%d] expected ErrInvalidCursor but got: %v", i, err)
+			}
+		}
+	})
+}
@@ -2,14 +2,16 @@ package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 )
 
 // These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
 // the PG_CONNSTRING environment variable. Run with, e.g.:
 //
-//	PG_CONNSTRING="..." go test -run 'TestRepositorySelectPaginated|TestRepositorySelectWithTotal' -v .
+//	PG_CONNSTRING="..." go test -run 'TestRepositorySelectPaginated|TestRepositorySelectWithTotal|TestRepositorySelectAfter' -v .
 
 // paginationItem is a scratch entity registered only for this file's tests, so
 // SelectPaginated/SelectWithTotal can be exercised through a real Repository[T] instead of raw
@@ -264,3 +266,92 @@ func TestRepositorySelectWithTotal(t *testing.T) {
 		}
 	})
 }
+
+// TestRepositorySelectAfter verifies SelectAfter's keyset pagination end to end: walking forward
+// through every page with the Next tokens over a multi-column ordering with the primary key
+// tiebreaker, walking back with the Prev tokens, and rejecting a tampered token.
+func TestRepositorySelectAfter(t *testing.T) {
+	db, err := openPaginationTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	if err = setupPaginationScratchTable(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	defer dropTestTables(ctx, db, paginationScratchTable)
+
+	const rowCount = 10
+	if err = seedPaginationScratchTable(ctx, db, rowCount); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRepository[paginationItem](db)
+	query := fmt.Sprintf("SELECT id, name, category FROM %s WHERE category <> $1", paginationScratchTable)
+
+	// category DESC puts the single "b" row first, the "a" rows are ordered by the id tiebreaker (DESC).
+	opts := CursorOptions{OrderBy: []string{"category DESC"}, Limit: 4}
+
+	var (
+		pages [][]paginationItem
+		prevs []string
+	)
+	for {
+		items, cursors, err := repo.SelectAfter(ctx, opts, query, "does-not-exist")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pages = append(pages, items)
+		prevs = append(prevs, cursors.Prev)
+
+		if cursors.Next == "" {
+			break
+		}
+
+		opts.After = cursors.Next
+	}
+
+	if len(pages) != 3 || len(pages[0]) != 4 || len(pages[1]) != 4 || len(pages[2]) != 2 {
+		t.Fatalf("expected pages of 4, 4 and 2 items but got: %v", pages)
+	}
+
+	if prevs[0] != "" {
+		t.Fatalf("expected no previous page of the first page but got: %q", prevs[0])
+	}
+
+	var ids []int64
+	for _, page := range pages {
+		for _, item := range page {
+			ids = append(ids, item.ID)
+		}
+	}
+
+	wantIDs := []int64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Fatalf("expected ids %v but got: %v", wantIDs, ids)
+	}
+
+	// back from the last page to the second one.
+	opts.After, opts.Before = "", prevs[2]
+	items, cursors, err := repo.SelectAfter(ctx, opts, query, "does-not-exist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(items, pages[1]) {
+		t.Fatalf("expected the second page %v but got: %v", pages[1], items)
+	}
+
+	if cursors.Next == "" || cursors.Prev == "" {
+		t.Fatalf("expected both cursors of the second page but got: %#v", cursors)
+	}
+
+	opts.Before = "tampered"
+	if _, _, err = repo.SelectAfter(ctx, opts, query, "does-not-exist"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor but got: %v", err)
+	}
+}
@@ -0,0 +1,369 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kataras/pg/desc"
+)
+
+// This file adds declarative table partitioning. A table is declared as partitioned on registration, e.g.
+//
+//	schema.MustRegister("events", Event{}, pg.PartitionByRange("created_at"))
+//
+// CreateSchema creates it with a PARTITION BY clause and CheckSchema compares its partitioning with
+// the database one. Its partitions are managed by DB.CreatePartition, AttachPartition, DetachPartition,
+// ListPartitions and, for time ranges, EnsureRangePartitions.
+
+// PartitionByRange returns a TableFilterFunc which declares the table as partitioned by ranges of the given key columns,
+// e.g. PARTITION BY RANGE (created_at).
+func PartitionByRange(columns ...string) TableFilterFunc {
+	return partitionBy(desc.PartitionRange, columns)
+}
+
+// PartitionByList returns a TableFilterFunc which declares the table as partitioned by lists of values of the given key column,
+// e.g. PARTITION BY LIST (region).
+func PartitionByList(column string) TableFilterFunc {
+	return partitionBy(desc.PartitionList, []string{column})
+}
+
+// PartitionByHash returns a TableFilterFunc which declares the table as partitioned by the hash of the given key columns,
+// e.g. PARTITION BY HASH (customer_id).
+func PartitionByHash(columns ...string) TableFilterFunc {
+	return partitionBy(desc.PartitionHash, columns)
+}
+
+func partitionBy(strategy desc.PartitionStrategy, columns []string) TableFilterFunc {
+	return func(td *desc.Table) bool {
+		td.PartitionBy = &desc.PartitionBy{Strategy: strategy, Columns: slices.Clone(columns)}
+		return true
+	}
+}
+
+// RangeBound returns the bound of a range partition, FOR VALUES FROM (from) TO (to).
+// The from value is inclusive and the to value is exclusive.
+func RangeBound(from, to any) PartitionBound {
+	return PartitionBound{From: []any{from}, To: []any{to}}
+}
+
+// ListBound returns the bound of a list partition, FOR VALUES IN (values...).
+func ListBound(values ...any) PartitionBound {
+	return PartitionBound{In: values}
+}
+
+// HashBound returns the bound of a hash partition, FOR VALUES WITH (MODULUS modulus, REMAINDER remainder).
+func HashBound(modulus, remainder int) PartitionBound {
+	return PartitionBound{Modulus: modulus, Remainder: remainder}
+}
+
+// DefaultBound returns the bound of the default partition, which holds the rows no other partition accepts.
+func DefaultBound() PartitionBound {
+	return PartitionBound{Default: true}
+}
+
+// CreatePartition creates the partitionName table as a partition of the tableName partitioned table,
+// if it does not exist already.
+//
+// Example:
+//
+//	err := db.CreatePartition(ctx, "events", "events_p2026_01", pg.RangeBound(
+//		time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+//		time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+//	))
+func (db *DB) CreatePartition(ctx context.Context, tableName, partitionName string, bound PartitionBound) error {
+	query, err := desc.BuildCreatePartitionQuery(tableName, partitionName, bound)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, query)
+	return err
+}
+
+// AttachPartition attaches the existing partitionName table as a partition of the tableName partitioned table.
+func (db *DB) AttachPartition(ctx context.Context, tableName, partitionName string, bound PartitionBound) error {
+	query, err := desc.BuildAttachPartitionQuery(tableName, partitionName, bound)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, query)
+	return err
+}
+
+// DetachPartition detaches the partitionName partition of the tableName partitioned table.
+// The partition is kept as a standalone table, e.g. to be archived or dropped.
+func (db *DB) DetachPartition(ctx context.Context, tableName, partitionName string) error {
+	query, err := desc.BuildDetachPartitionQuery(tableName, partitionName)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, query)
+	return err
+}
+
+// ListPartitions returns the partitions of the tableName partitioned table, ordered by their name.
+func (db *DB) ListPartitions(ctx context.Context, tableName string) ([]*Partition, error) {
+	query := `SELECT
+	parent.relname AS table_name,
+	child.relname AS partition_name,
+	pg_get_expr(child.relpartbound, child.oid) AS partition_bound
+FROM pg_catalog.pg_inherits i
+	JOIN pg_catalog.pg_class parent ON parent.oid = i.inhparent
+	JOIN pg_catalog.pg_class child ON child.oid = i.inhrelid
+	JOIN pg_catalog.pg_namespace n ON n.oid = parent.relnamespace
+WHERE n.nspname = $1 AND parent.relname = $2 AND child.relispartition
+ORDER BY child.relname;`
+
+	return db.scanQuery(ctx, func(rows Rows) (*Partition, error) {
+		var p Partition
+		err := rows.Scan(&p.TableName, &p.Name, &p.Bound)
+		return &p, err
+	}, query, db.searchPath, tableName)
+}
+
+// PartitionInterval is the length of each partition created by EnsureRangePartitions:
+// a number of days, or a number of months and years, not both.
+type PartitionInterval struct {
+	Years  int
+	Months int
+	Days   int
+}
+
+// The common partition intervals.
+var (
+	PartitionDaily   = PartitionInterval{Days: 1}
+	PartitionWeekly  = PartitionInterval{Days: 7}
+	PartitionMonthly = PartitionInterval{Months: 1}
+	PartitionYearly  = PartitionInterval{Years: 1}
+)
+
+// partitionEpoch is the Monday the day intervals are aligned to, see PartitionInterval.truncate.
+var partitionEpoch = time.Date(1970, time.January, 5, 0, 0, 0, 0, time.UTC)
+
+// truncate returns the start of the partition t belongs to. The partitions are aligned to fixed boundaries,
+// whatever the day EnsureRangePartitions runs: a day interval to multiples of its days since a Monday, e.g.
+// the ISO week of t, starting on Monday, for weekly intervals and its midnight for daily ones, and a month
+// interval to multiples of its months since the year 0, e.g. the first day of the month of t for monthly
+// intervals, of its quarter for 3 months and of its year for yearly ones.
+func (i PartitionInterval) truncate(t time.Time) time.Time {
+	if i.Days > 0 {
+		days := int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Sub(partitionEpoch) / (24 * time.Hour))
+		days -= floorMod(days, i.Days)
+		return time.Date(partitionEpoch.Year(), partitionEpoch.Month(), partitionEpoch.Day()+days, 0, 0, 0, 0, t.Location())
+	}
+
+	months := t.Year()*12 + int(t.Month()) - 1
+	months -= floorMod(months, i.Years*12+i.Months)
+	return time.Date(months/12, time.Month(months%12+1), 1, 0, 0, 0, 0, t.Location())
+}
+
+func floorMod(a, b int) int {
+	return ((a % b) + b) % b
+}
+
+// partitionName returns the name of the partition which starts at start,
+// e.g. events_p2026 (yearly), events_p2026_01 (monthly) or events_p2026_01_15.
+func (i PartitionInterval) partitionName(tableName string, start time.Time) string {
+	switch {
+	case i.Days == 0 && i.Months == 0:
+		return tableName + start.Format("_p2006")
+	case i.Days == 0:
+		return tableName + start.Format("_p2006_01")
+	default:
+		return tableName + start.Format("_p2006_01_02")
+	}
+}
+
+// EnsureRangePartitions creates the missing partitions of the tableName range-partitioned table
+// which cover the time range [from, to), one per interval, e.g. a partition per month.
+// The first partition starts at the beginning of the interval from belongs to (e.g. the first day of its month,
+// or the Monday of its week for PartitionWeekly), in from's location. It returns the names of the created
+// partitions, e.g. events_p2026_01.
+//
+// Existing partitions are found by their bounds, so it is safe to call it periodically, e.g. to create
+// the next months' partitions ahead of time. A partition whose range is already covered is skipped and
+// one which would overlap an existing partition fails the call.
+//
+// Example:
+//
+//	now := time.Now().UTC()
+//	created, err := db.EnsureRangePartitions(ctx, "events", now, now.AddDate(0, 3, 0), pg.PartitionMonthly)
+func (db *DB) EnsureRangePartitions(ctx context.Context, tableName string, from, to time.Time, interval PartitionInterval) ([]string, error) {
+	if interval.Years < 0 || interval.Months < 0 || interval.Days < 0 || interval == (PartitionInterval{}) ||
+		(interval.Days > 0 && (interval.Years > 0 || interval.Months > 0)) {
+		return nil, fmt.Errorf("ensure range partitions: %s: invalid interval: %+v", tableName, interval)
+	}
+
+	if td, err := db.schema.GetByTableName(tableName); err == nil {
+		if p := td.PartitionBy; p == nil || p.Strategy != desc.PartitionRange || len(p.Columns) != 1 {
+			return nil, fmt.Errorf("ensure range partitions: %s: the table is not partitioned by a single column range", tableName)
+		}
+	}
+
+	partitions, err := db.ListPartitions(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := partitionTimeRanges(partitions, from.Location())
+
+	var created []string
+	for start := interval.truncate(from); start.Before(to); {
+		end := start.AddDate(interval.Years, interval.Months, interval.Days)
+
+		partitionName := interval.partitionName(tableName, start)
+		r, overlaps := overlappingTimeRange(ranges, start, end)
+		switch {
+		case coversTimeRange(ranges, start, end):
+			// created already, maybe as partitions of another interval.
+		case overlaps:
+			return created, fmt.Errorf("ensure range partitions: %s: the partition %s [%s, %s) overlaps the existing partition %s [%s, %s)",
+				tableName, partitionName, start, end, r.name, r.from, r.to)
+		case slices.ContainsFunc(partitions, func(p *Partition) bool { return p.Name == partitionName }):
+			// a partition whose bound could not be read as a time range.
+		default:
+			if err = db.CreatePartition(ctx, tableName, partitionName, RangeBound(start, end)); err != nil {
+				return created, err
+			}
+
+			created = append(created, partitionName)
+		}
+
+		start = end
+	}
+
+	return created, nil
+}
+
+// partitionTimeRange is the [from, to) time range of an existing range partition.
+type partitionTimeRange struct {
+	name     string
+	from, to time.Time
+}
+
+var partitionRangeBoundRegex = regexp.MustCompile(`^FOR VALUES FROM \((.+)\) TO \((.+)\)$`)
+
+// partitionTimeRanges returns the time ranges of the partitions with a single time key, as reported by
+// ListPartitions, ordered by their start. The bound values without a time zone are read in loc.
+func partitionTimeRanges(partitions []*Partition, loc *time.Location) []partitionTimeRange {
+	var ranges []partitionTimeRange
+	for _, p := range partitions {
+		matches := partitionRangeBoundRegex.FindStringSubmatch(p.Bound)
+		if len(matches) == 0 {
+			continue // e.g. the DEFAULT partition.
+		}
+
+		from, ok := parsePartitionBoundTime(matches[1], loc)
+		if !ok {
+			continue
+		}
+
+		to, ok := parsePartitionBoundTime(matches[2], loc)
+		if !ok {
+			continue
+		}
+
+		ranges = append(ranges, partitionTimeRange{name: p.Name, from: from, to: to})
+	}
+
+	slices.SortFunc(ranges, func(a, b partitionTimeRange) int { return a.from.Compare(b.from) })
+	return ranges
+}
+
+// The layouts of the timestamp and timestamptz partition bound values.
+var partitionBoundTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parsePartitionBoundTime parses a partition bound value, e.g. '2026-01-01 00:00:00' or MINVALUE.
+func parsePartitionBoundTime(value string, loc *time.Location) (time.Time, bool) {
+	switch value {
+	case "MINVALUE":
+		return time.Time{}, true
+	case "MAXVALUE":
+		return time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC), true
+	}
+
+	if len(value) < 2 || value[0] != '\'' || value[len(value)-1] != '\'' {
+		return time.Time{}, false // e.g. a multi-column or a numeric bound.
+	}
+	value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+
+	for _, layout := range partitionBoundTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// coversTimeRange reports whether the ranges, ordered by their start, cover [from, to).
+func coversTimeRange(ranges []partitionTimeRange, from, to time.Time) bool {
+	for _, r := range ranges {
+		if !r.from.After(from) && r.to.After(from) {
+			from = r.to
+		}
+	}
+
+	return !from.Before(to)
+}
+
+// overlappingTimeRange returns the first of the ranges which overlaps [from, to).
+func overlappingTimeRange(ranges []partitionTimeRange, from, to time.Time) (partitionTimeRange, bool) {
+	for _, r := range ranges {
+		if r.from.Before(to) && r.to.After(from) {
+			return r, true
+		}
+	}
+
+	return partitionTimeRange{}, false
+}
+
+// setTablesPartitioning sets the partitioning of the partitioned tables and the parent table and bound
+// of the partitions of the given tables, by querying the pg_class table.
+func (db *DB) setTablesPartitioning(ctx context.Context, tables []*desc.Table) error {
+	query := `SELECT
+	c.relname AS table_name,
+	COALESCE(pg_get_partkeydef(c.oid), '') AS partition_key,
+	COALESCE(parent.relname, '') AS partition_of,
+	COALESCE(pg_get_expr(c.relpartbound, c.oid), '') AS partition_bound
+FROM pg_catalog.pg_class c
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_catalog.pg_inherits i ON i.inhrelid = c.oid AND c.relispartition
+	LEFT JOIN pg_catalog.pg_class parent ON parent.oid = i.inhparent
+WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND (c.relkind = 'p' OR c.relispartition);`
+
+	rows, err := db.Query(ctx, query, db.searchPath)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, partitionKey, partitionOf, partitionBound string
+		if err = rows.Scan(&tableName, &partitionKey, &partitionOf, &partitionBound); err != nil {
+			return err
+		}
+
+		for _, table := range tables {
+			if table.Name == tableName {
+				table.PartitionBy = desc.ParsePartitionBy(partitionKey)
+				table.PartitionOf = partitionOf
+				table.PartitionBound = partitionBound
+				break
+			}
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,123 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestPartitionedTable' -v .
+
+type partitionLiveEvent struct {
+	ID        string    `pg:"type=uuid,primary"`
+	CreatedAt time.Time `pg:"type=timestamp,primary"`
+	Name      string    `pg:"type=text"`
+}
+
+const partitionScratchTable = "test_partition_events"
+
+func TestPartitionedTable(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(partitionScratchTable, partitionLiveEvent{}, PartitionByRange("created_at"))
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, partitionScratchTable, "test_partition_events_archive")
+	defer dropTestTables(ctx, db, partitionScratchTable, "test_partition_events_archive")
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	created, err := db.EnsureRangePartitions(ctx, partitionScratchTable, from, from.AddDate(0, 3, 0), PartitionMonthly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "test_partition_events_p2026_01,test_partition_events_p2026_02,test_partition_events_p2026_03,test_partition_events_p2026_04"; strings.Join(created, ",") != expected {
+		t.Fatalf("expected created partitions: %s but got: %v", expected, created)
+	}
+
+	// existing partitions are kept.
+	created, err = db.EnsureRangePartitions(ctx, partitionScratchTable, from, from.AddDate(0, 1, 0), PartitionMonthly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 0 {
+		t.Fatalf("expected no new partitions but got: %v", created)
+	}
+
+	// the weeks of February are covered by the monthly partitions, found by their bounds.
+	created, err = db.EnsureRangePartitions(ctx, partitionScratchTable, from.AddDate(0, 0, 20), from.AddDate(0, 1, 10), PartitionWeekly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 0 {
+		t.Fatalf("expected no new weekly partitions but got: %v", created)
+	}
+
+	if err = db.CheckSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRepository[partitionLiveEvent](db)
+	if err = repo.InsertSingle(ctx, partitionLiveEvent{CreatedAt: from, Name: "a"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// no partition accepts it.
+	if err = repo.InsertSingle(ctx, partitionLiveEvent{CreatedAt: from.AddDate(1, 0, 0), Name: "b"}, nil); err == nil {
+		t.Fatal("expected an error for a row outside of the partitions")
+	}
+
+	if err = db.DetachPartition(ctx, partitionScratchTable, "test_partition_events_p2026_04"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Exec(ctx, "ALTER TABLE test_partition_events_p2026_04 RENAME TO test_partition_events_archive;"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.CreatePartition(ctx, partitionScratchTable, "test_partition_events_default", DefaultBound()); err != nil {
+		t.Fatal(err)
+	}
+
+	partitions, err := db.ListPartitions(ctx, partitionScratchTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(partitions) != 4 || partitions[3].Name != "test_partition_events_p2026_03" || partitions[0].Bound != "DEFAULT" {
+		t.Fatalf("unexpected partitions: %#v", partitions)
+	}
+
+	tables, err := db.ListTables(ctx, ListTablesOptions{TableNames: []string{partitionScratchTable, "test_partition_events_p2026_01"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range tables {
+		switch table.Name {
+		case partitionScratchTable:
+			if table.PartitionBy == nil || table.PartitionBy.String() != `RANGE ("created_at")` {
+				t.Fatalf("expected a range partitioned table but got: %#v", table.PartitionBy)
+			}
+		default:
+			if table.PartitionOf != partitionScratchTable || !strings.HasPrefix(table.PartitionBound, "FOR VALUES FROM") {
+				t.Fatalf("expected a partition of %s but got: %s %s", partitionScratchTable, table.PartitionOf, table.PartitionBound)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package pg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPartitionInterval(t *testing.T) {
+	at := time.Date(2026, 10, 16, 13, 45, 0, 0, time.UTC) // a Friday.
+
+	tests := []struct {
+		interval      PartitionInterval
+		expectedStart time.Time
+		expectedName  string
+	}{
+		{PartitionDaily, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), "events_p2026_10_16"},
+		{PartitionWeekly, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), "events_p2026_10_12"},
+		{PartitionMonthly, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), "events_p2026_10"},
+		{PartitionInterval{Months: 3}, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), "events_p2026_10"},
+		{PartitionInterval{Months: 6}, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), "events_p2026_07"},
+		{PartitionYearly, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "events_p2026"},
+	}
+
+	for i, tt := range tests {
+		start := tt.interval.truncate(at)
+		if !start.Equal(tt.expectedStart) {
+			t.Fatalf("[%d] expected start: %s but got: %s", i, tt.expectedStart, start)
+		}
+
+		if name := tt.interval.partitionName("events", start); name != tt.expectedName {
+			t.Fatalf("[%d] expected partition name: %s but got: %s", i, tt.expectedName, name)
+		}
+	}
+
+	// the weekly partitions start on the same Monday whatever the day they are created.
+	for day := 12; day <= 18; day++ {
+		if start := PartitionWeekly.truncate(time.Date(2026, 10, day, 23, 0, 0, 0, time.UTC)); start.Day() != 12 {
+			t.Fatalf("expected the week of October %d to start on the 12th but got: %s", day, start)
+		}
+	}
+}
+
+func TestPartitionTimeRanges(t *testing.T) {
+	partitions := []*Partition{
+		{Name: "events_default", Bound: "DEFAULT"},
+		{Name: "events_p2026_10_19", Bound: "FOR VALUES FROM ('2026-10-19 00:00:00+00') TO ('2026-10-26 00:00:00+00')"},
+		{Name: "events_p2026_10_12", Bound: "FOR VALUES FROM ('2026-10-12 00:00:00') TO ('2026-10-19 00:00:00')"},
+		{Name: "events_old", Bound: "FOR VALUES FROM (MINVALUE) TO ('2026-01-01')"},
+	}
+
+	ranges := partitionTimeRanges(partitions, time.UTC)
+	if len(ranges) != 3 || ranges[0].name != "events_old" || ranges[1].name != "events_p2026_10_12" {
+		t.Fatalf("unexpected ranges: %#v", ranges)
+	}
+
+	week := func(day int) (time.Time, time.Time) {
+		from := time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 0, 7)
+	}
+
+	if from, to := week(12); !coversTimeRange(ranges, from, to) {
+		t.Fatal("expected the week of the 12th to be covered")
+	}
+
+	if from, _ := week(12); !coversTimeRange(ranges, from, from.AddDate(0, 0, 14)) {
+		t.Fatal("expected two consecutive weeks to be covered")
+	}
+
+	// a week which starts on a Thursday, as a previous release created.
+	from, to := week(15)
+	if r, ok := overlappingTimeRange(ranges, from, to); !ok || r.name != "events_p2026_10_12" {
+		t.Fatalf("expected the week of the 15th to overlap the week of the 12th but got: %#v", r)
+	}
+
+	if from, to := week(26); coversTimeRange(ranges, from, to) {
+		t.Fatal("expected the week of the 26th not to be covered")
+	} else if _, ok := overlappingTimeRange(ranges, from, to); ok {
+		t.Fatal("expected the week of the 26th not to overlap")
+	}
+}
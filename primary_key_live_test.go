@@ -0,0 +1,163 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestRepositoryCompositePrimaryKey' -v .
+
+type compositeKeyPostTag struct {
+	PostID int64          `pg:"type=bigint,primary"`
+	TagID  int64          `pg:"type=bigint,primary"`
+	Label  string         `pg:"type=varchar(255)"`
+	Extra  map[string]any `pg:"type=jsonb"`
+}
+
+const compositeKeyScratchTable = "test_composite_post_tags"
+
+func TestRepositoryCompositePrimaryKey(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(compositeKeyScratchTable, compositeKeyPostTag{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, compositeKeyScratchTable)
+	if _, err = db.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE %s (post_id BIGINT NOT NULL, tag_id BIGINT NOT NULL, label VARCHAR(255) NOT NULL, extra JSONB, PRIMARY KEY (post_id, tag_id))",
+		compositeKeyScratchTable)); err != nil {
+		t.Fatal(err)
+	}
+	defer dropTestTables(ctx, db, compositeKeyScratchTable)
+
+	repo := NewRepository[compositeKeyPostTag](db)
+
+	if err = repo.Insert(ctx,
+		compositeKeyPostTag{PostID: 1, TagID: 1, Label: "a"},
+		compositeKeyPostTag{PostID: 1, TagID: 2, Label: "b"},
+		compositeKeyPostTag{PostID: 2, TagID: 1, Label: "c"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.SelectByID(ctx, Key{"post_id": 1, "tag_id": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Label != "b" {
+		t.Fatalf("expected label b but got: %#v", got)
+	}
+
+	got.Label = "b2"
+	if _, err = repo.UpdateOnlyColumns(ctx, []string{"label"}, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.UpdateJSONB(ctx, compositeKeyScratchTable, "extra", Key{"post_id": 1, "tag_id": 2}, map[string]any{"k": "v"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// a struct value holding the primary key fields is accepted as the id too.
+	got, err = repo.SelectByID(ctx, compositeKeyPostTag{PostID: 1, TagID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Label != "b2" || got.Extra["k"] != "v" {
+		t.Fatalf("unexpected row after updates: %#v", got)
+	}
+
+	// only the row matching both key columns is removed.
+	deleted, err := repo.DeleteByID(ctx, Key{"post_id": 1, "tag_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !deleted {
+		t.Fatalf("expected the row to be deleted")
+	}
+
+	if _, err = repo.Delete(ctx, compositeKeyPostTag{PostID: 2, TagID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := repo.Count(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", compositeKeyScratchTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected 1 remaining row but got: %d", count)
+	}
+}
+
+type compositeKeyPostRevision struct {
+	PostID int64  `pg:"type=bigint,primary"`
+	ID     string `pg:"type=uuid,primary,default=gen_random_uuid()"`
+	Body   string `pg:"type=text"`
+}
+
+const compositeKeyRevisionsScratchTable = "test_composite_post_revisions"
+
+func TestRepositoryCompositePrimaryKeyDuplicate(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(compositeKeyScratchTable, compositeKeyPostTag{})
+	schema.MustRegister(compositeKeyRevisionsScratchTable, compositeKeyPostRevision{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, compositeKeyScratchTable, compositeKeyRevisionsScratchTable)
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer dropTestTables(ctx, db, compositeKeyScratchTable, compositeKeyRevisionsScratchTable)
+
+	// without a generated column the copy would have the same primary key.
+	tags := NewRepository[compositeKeyPostTag](db)
+	if err = tags.Insert(ctx, compositeKeyPostTag{PostID: 1, TagID: 1, Label: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var newKey Key
+	if err = tags.Duplicate(ctx, Key{"post_id": 1, "tag_id": 1}, &newKey); err == nil {
+		t.Fatal("expected an error for a composite primary key without a generated column")
+	}
+
+	revisions := NewRepository[compositeKeyPostRevision](db)
+	var key Key
+	if err = revisions.InsertSingle(ctx, compositeKeyPostRevision{PostID: 1, Body: "a"}, &key); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = revisions.Duplicate(ctx, key, &newKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if newKey["post_id"] != key["post_id"] || newKey["id"] == key["id"] {
+		t.Fatalf("expected a copy with the same post_id and a new id but got: %v from: %v", newKey, key)
+	}
+
+	duplicated, err := revisions.SelectByID(ctx, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if duplicated.Body != "a" {
+		t.Fatalf("expected the copied body but got: %#v", duplicated)
+	}
+}
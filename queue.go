@@ -0,0 +1,581 @@
+package pg
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// This file adds a durable job queue on top of a PostgreSQL table: Queue.Enqueue adds a job
+// (in the caller's transaction, if any) and the worker pool of Queue.Work claims the due jobs with
+// SELECT ... FOR UPDATE SKIP LOCKED, so any number of processes can work on the same queue.
+//
+// A job is leased to a worker for QueueOptions.VisibilityTimeout, renewed while its handler runs:
+// if the worker stops renewing it, e.g. its process crashed, the job is claimed again by another worker. A failed job
+// is retried with exponential backoff up to its maximum attempts and then it is kept in the
+// dead-letter state ("dead") for inspection.
+
+// Job states, the values of the status column of the queue table.
+const (
+	jobStatusPending = "pending"
+	jobStatusRunning = "running"
+	jobStatusDead    = "dead"
+)
+
+// QueueOptions holds the options of a Queue, see NewQueue.
+type QueueOptions struct {
+	// Table is the name of the queue table, in the search path of the DB.
+	// All queues may share the same table, their jobs are told apart by the name of the queue.
+	// Defaults to "queue_jobs".
+	Table string
+	// Channel is the name of the postgres channel Enqueue notifies (at commit) to wake up the workers,
+	// see Work. Defaults to "queue_jobs".
+	Channel string
+	// VisibilityTimeout is the duration a job is leased to the worker which claimed it. The worker
+	// renews the lease every third of it while the handler runs, so a job may run longer than it,
+	// and once the lease expires, e.g. its process crashed, the job can be claimed again by another
+	// worker. The context of the handler is canceled when its lease is lost, e.g. the renewals
+	// failed until it expired. Defaults to 5 minutes.
+	VisibilityTimeout time.Duration
+	// PollInterval is the interval of the workers to look for due jobs without a notification,
+	// e.g. the ones scheduled for later, the retries or the ones enqueued while the workers were listening again
+	// after a lost connection. It also bounds the backoff of listening again. Defaults to 5 seconds.
+	PollInterval time.Duration
+	// Retry controls the retries of a job: a job whose handler failed is scheduled again after a
+	// full jitter exponential backoff between Retry.BaseDelay and Retry.MaxDelay, and after its
+	// Retry.MaxAttempts-th run it is moved to the dead-letter state (see QueueStats.Dead), as is a job
+	// whose handler error Retry.IsRetryable, if not nil, rejects or whose payload does not decode to T.
+	// A panic of the handler is retried like an error and a job whose lease expired counts as a run.
+	// Retry.TxOptions is not used.
+	// Defaults to 5 attempts with a backoff from 1 second up to 1 hour, so a job is given time
+	// to wait out an outage of the service it calls.
+	Retry RetryOptions
+	// OnError, if not nil, is called by the workers with the errors they keep running after:
+	// a failed claim, which is retried on the next poll, a lost listen connection, which is
+	// reconnected, and a job whose state could not be recorded, which is claimed again once its
+	// lease expires. The errors of the handlers are not reported, they are stored in the
+	// last_error column of their job.
+	OnError func(error)
+}
+
+// Queue is a durable queue of jobs with a payload of type T, see NewQueue. It is safe for concurrent use.
+type Queue[T any] struct {
+	db   *DB
+	name string
+	opts QueueOptions
+}
+
+// Job is a job of a Queue, passed to the handler of Queue.Work.
+type Job[T any] struct {
+	ID         int64     // the sequential ID of the job.
+	Payload    T         // the payload of the job, as it was enqueued.
+	Attempt    int       // the number of the attempt, starting from 1.
+	Priority   int       // the priority of the job, see EnqueueOptions.
+	EnqueuedAt time.Time // the time the job was enqueued.
+	LastError  string    // the error of the previous attempt, if any.
+}
+
+// EnqueueOptions holds the options of Queue.Enqueue.
+type EnqueueOptions struct {
+	// RunAt is the time the job should run at, defaults to now.
+	RunAt time.Time
+	// Priority is the priority of the job: the due jobs of higher priority are claimed first.
+	Priority int
+	// DedupeKey, if not empty, makes the Enqueue a no-op while a pending or running job
+	// of the queue has the same key, e.g. "report:" + customerID.
+	DedupeKey string
+}
+
+// QueueHandler runs a job of a Queue. A nil error completes the job,
+// any other error schedules its retry, see QueueOptions.Retry.
+type QueueHandler[T any] func(ctx context.Context, job Job[T]) error
+
+// QueueStats holds the number of the jobs of a queue per state, see Queue.Stats.
+type QueueStats struct {
+	Pending   int64 // the jobs which are due and wait for a worker.
+	Scheduled int64 // the jobs which are scheduled for later, including the retries.
+	Running   int64 // the jobs which are leased to a worker.
+	Dead      int64 // the jobs which failed all of their attempts.
+}
+
+// NewQueue returns a new Queue with the given name whose jobs are stored in the table of the options.
+// Create its table with CreateTable (e.g. after CreateSchema).
+//
+// Example:
+//
+//	emails, err := pg.NewQueue[Email](db, "emails", pg.QueueOptions{})
+//	if err != nil { ... }
+//	if err = emails.CreateTable(ctx); err != nil { ... }
+//
+//	_, err = emails.Enqueue(ctx, Email{To: "john@example.com"}, pg.EnqueueOptions{})
+//
+//	workers, err := emails.Work(ctx, 10, func(ctx context.Context, job pg.Job[Email]) error {
+//		return send(ctx, job.Payload)
+//	})
+//	if err != nil { ... }
+//	defer workers.Close(ctx)
+func NewQueue[T any](db *DB, name string, opts QueueOptions) (*Queue[T], error) {
+	if name == "" {
+		return nil, errors.New("queue: empty name")
+	}
+
+	if opts.Table == "" {
+		opts.Table = "queue_jobs"
+	}
+
+	if opts.Channel == "" {
+		opts.Channel = "queue_jobs"
+	}
+
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 5 * time.Minute
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+
+	if opts.Retry.MaxAttempts == 0 {
+		opts.Retry.MaxAttempts = 5
+	}
+
+	if opts.Retry.BaseDelay <= 0 {
+		opts.Retry.BaseDelay = time.Second
+	}
+
+	if opts.Retry.MaxDelay <= 0 {
+		opts.Retry.MaxDelay = time.Hour
+	}
+
+	opts.Retry = normalizeRetryOptions(opts.Retry)
+
+	if err := validateListenTableIdentifier("table", opts.Table); err != nil {
+		return nil, fmt.Errorf("queue: %s: %w", name, err)
+	}
+
+	return &Queue[T]{db: db, name: name, opts: opts}, nil
+}
+
+// Name returns the name of the queue.
+func (q *Queue[T]) Name() string {
+	return q.name
+}
+
+// WithDB returns a shallow copy of the queue bound to the given database instance, e.g. a transaction:
+// its jobs are enqueued if and only if the transaction commits.
+func (q *Queue[T]) WithDB(db *DB) *Queue[T] {
+	c := *q
+	c.db = db
+	return &c
+}
+
+// tableName returns the quoted, schema-qualified name of the queue table.
+func (q *Queue[T]) tableName() string {
+	return QuoteIdentifier(q.db.searchPath) + "." + QuoteIdentifier(q.opts.Table)
+}
+
+// CreateTable creates the queue table and its indexes, if they do not exist.
+func (q *Queue[T]) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+		id bigserial PRIMARY KEY,
+		queue text NOT NULL,
+		payload jsonb NOT NULL,
+		priority integer NOT NULL DEFAULT 0,
+		run_at timestamptz NOT NULL DEFAULT now(),
+		dedupe_key text,
+		status text NOT NULL DEFAULT 'pending',
+		attempts integer NOT NULL DEFAULT 0,
+		locked_until timestamptz,
+		last_error text,
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+	CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (queue, priority DESC, run_at, id) WHERE status IN ('pending', 'running');
+	CREATE UNIQUE INDEX IF NOT EXISTS %[3]s ON %[1]s (queue, dedupe_key) WHERE dedupe_key IS NOT NULL AND status IN ('pending', 'running');`,
+		q.tableName(), QuoteIdentifier(q.opts.Table+"_claim_idx"), QuoteIdentifier(q.opts.Table+"_dedupe_key_idx"))
+
+	if _, err := q.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("queue: %s: create table: %w", q.name, err)
+	}
+
+	return nil
+}
+
+// Enqueue adds a job with the given payload, marshaled to JSON, to the queue and notifies the workers.
+// It returns the ID of the job, or zero if it was deduplicated (see EnqueueOptions.DedupeKey).
+// On a queue bound to a transaction (see WithDB) the job is enqueued, and the workers are notified,
+// when the transaction commits.
+func (q *Queue[T]) Enqueue(ctx context.Context, payload T, opts EnqueueOptions) (int64, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("queue: %s: enqueue: %w", q.name, err)
+	}
+
+	var (
+		runAt     any // NULL for now, as the clocks of the database and the caller may differ.
+		dedupeKey any
+	)
+	if !opts.RunAt.IsZero() {
+		runAt = opts.RunAt
+	}
+	if opts.DedupeKey != "" {
+		dedupeKey = opts.DedupeKey
+	}
+
+	query := fmt.Sprintf(`WITH job AS (
+		INSERT INTO %s (queue, payload, priority, run_at, dedupe_key) VALUES ($1, $2, $3, COALESCE($4, now()), $5)
+		ON CONFLICT (queue, dedupe_key) WHERE dedupe_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING
+		RETURNING id
+	) SELECT id, pg_notify($6, $1) FROM job;`, q.tableName())
+
+	var id int64
+	err = q.db.QueryRow(ctx, query, q.name, string(b), opts.Priority, runAt, dedupeKey, q.opts.Channel).Scan(&id, nil)
+	if err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return 0, nil // deduplicated.
+		}
+
+		return 0, fmt.Errorf("queue: %s: enqueue: %w", q.name, err)
+	}
+
+	return id, nil
+}
+
+// claim leases up to limit due jobs to the caller, higher priority first, and moves the expired running
+// jobs which have no attempts left to the dead-letter state. The expired running jobs with attempts
+// left are claimed again as if they were pending.
+func (q *Queue[T]) claim(ctx context.Context, limit int) ([]Job[T], error) {
+	query := fmt.Sprintf(`UPDATE %s SET status = 'dead', locked_until = NULL, last_error = 'visibility timeout'
+		WHERE queue = $1 AND status = 'running' AND locked_until < now() AND attempts >= $2;`, q.tableName())
+	if _, err := q.db.Exec(ctx, query, q.name, q.opts.Retry.MaxAttempts); err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`UPDATE %[1]s AS j SET status = 'running', attempts = j.attempts + 1, locked_until = now() + $3::interval
+		FROM (
+			SELECT id FROM %[1]s
+			WHERE queue = $1 AND ((status = 'pending' AND run_at <= now()) OR (status = 'running' AND locked_until < now()))
+			ORDER BY priority DESC, run_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) AS due
+		WHERE j.id = due.id
+		RETURNING j.id, j.payload, j.attempts, j.priority, j.created_at, COALESCE(j.last_error, '');`, q.tableName())
+
+	rows, err := q.db.Query(ctx, query, q.name, limit, q.opts.VisibilityTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		jobs        []Job[T]
+		undecodable []Job[T]
+		decodeErrs  []error
+	)
+	for rows.Next() {
+		var (
+			job     Job[T]
+			payload []byte
+		)
+		if err = rows.Scan(&job.ID, &payload, &job.Attempt, &job.Priority, &job.EnqueuedAt, &job.LastError); err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal(payload, &job.Payload); err != nil {
+			undecodable = append(undecodable, job)
+			decodeErrs = append(decodeErrs, fmt.Errorf("decode payload: %w", err))
+			continue
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	// the claim statement must finish before the next one: on a transaction its connection is
+	// busy until then, and on the pool it holds the row locks the next one waits for.
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, job := range undecodable {
+		// never retried, the payload does not fit T.
+		if err = q.fail(ctx, job, decodeErrs[i], false); err != nil {
+			return nil, err
+		}
+	}
+
+	return jobs, nil
+}
+
+// renew extends the lease of a running job by QueueOptions.VisibilityTimeout from now.
+// It reports false if the job was leased to another worker or its state was recorded meanwhile.
+func (q *Queue[T]) renew(ctx context.Context, job Job[T]) (bool, error) {
+	query := fmt.Sprintf(`UPDATE %s SET locked_until = now() + $3::interval
+		WHERE id = $1 AND status = 'running' AND attempts = $2;`, q.tableName())
+	tag, err := q.db.Exec(ctx, query, job.ID, job.Attempt, q.opts.VisibilityTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// keepLease renews the lease of a running job every third of QueueOptions.VisibilityTimeout until
+// the context is done. It calls lost, e.g. to cancel the handler, when the job was leased to
+// another worker or its lease is about to expire because it could not be renewed.
+func (q *Queue[T]) keepLease(ctx context.Context, job Job[T], lost func()) {
+	interval := q.opts.VisibilityTimeout / 3
+	expires := time.Now().Add(q.opts.VisibilityTimeout)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := q.renew(ctx, job)
+		switch {
+		case err == nil && renewed:
+			expires = time.Now().Add(q.opts.VisibilityTimeout)
+		case err == nil:
+			lost()
+			return
+		default:
+			if ctx.Err() != nil {
+				return
+			}
+
+			q.reportError(fmt.Errorf("queue: %s: job: %d: renew lease: %w", q.name, job.ID, err))
+			if time.Until(expires) < interval { // it would expire before the next renewal.
+				lost()
+				return
+			}
+		}
+	}
+}
+
+// complete deletes a job which was run successfully, unless it was leased to another worker meanwhile.
+func (q *Queue[T]) complete(ctx context.Context, job Job[T]) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND status = 'running' AND attempts = $2;`, q.tableName())
+	_, err := q.db.Exec(ctx, query, job.ID, job.Attempt)
+	return err
+}
+
+// fail schedules the retry of a failed job or moves it to the dead-letter state,
+// unless it was leased to another worker meanwhile.
+func (q *Queue[T]) fail(ctx context.Context, job Job[T], jobErr error, retryable bool) error {
+	if !retryable || job.Attempt >= q.opts.Retry.MaxAttempts || (q.opts.Retry.IsRetryable != nil && !q.opts.Retry.IsRetryable(jobErr)) {
+		query := fmt.Sprintf(`UPDATE %s SET status = 'dead', locked_until = NULL, last_error = $3
+			WHERE id = $1 AND status = 'running' AND attempts = $2;`, q.tableName())
+		_, err := q.db.Exec(ctx, query, job.ID, job.Attempt, jobErr.Error())
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET status = 'pending', locked_until = NULL, last_error = $3, run_at = now() + $4::interval
+		WHERE id = $1 AND status = 'running' AND attempts = $2;`, q.tableName())
+	_, err := q.db.Exec(ctx, query, job.ID, job.Attempt, jobErr.Error(), backoffDelay(q.opts.Retry, job.Attempt))
+	return err
+}
+
+// run runs a claimed job, renewing its lease while the handler runs, and records its result.
+// The context of the handler is canceled if the lease is lost, see keepLease.
+func (q *Queue[T]) run(ctx context.Context, handler QueueHandler[T], job Job[T]) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	var lease sync.WaitGroup
+	lease.Go(func() {
+		q.keepLease(jobCtx, job, cancel)
+	})
+
+	err := runJob(jobCtx, handler, job)
+	cancel()
+	lease.Wait()
+
+	// record the result even if the workers are being closed.
+	ctx = context.WithoutCancel(ctx)
+	if err == nil {
+		err = q.complete(ctx, job)
+	} else {
+		err = q.fail(ctx, job, err, true)
+	}
+
+	if err != nil {
+		q.reportError(fmt.Errorf("queue: %s: job: %d: %w", q.name, job.ID, err))
+	}
+}
+
+// runJob calls the handler and converts its panic to an error.
+func runJob[T any](ctx context.Context, handler QueueHandler[T], job Job[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// Work starts a pool of the given number of workers which run the jobs of the queue with the handler,
+// until the returned Closer is closed or the context is done. Closing it stops the claiming of new jobs
+// and waits for the running ones until the context of Close is done, when their context is canceled too.
+//
+// The workers listen to QueueOptions.Channel, so an enqueued job runs as soon as its transaction commits,
+// and they poll the queue every QueueOptions.PollInterval as a fallback, e.g. for the jobs scheduled for later.
+// A lost listen connection is reconnected with a backoff up to QueueOptions.PollInterval.
+// Any number of processes can work on the same queue.
+func (q *Queue[T]) Work(ctx context.Context, concurrency int, handler QueueHandler[T]) (Closer, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("queue: %s: work: handler is nil", q.name)
+	}
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	listener, err := q.db.listenWorker(ctx, q.opts.Channel)
+	if err != nil {
+		return nil, fmt.Errorf("queue: %s: work: listen: %w", q.name, err)
+	}
+
+	claimCtx, stopClaiming := context.WithCancel(ctx)
+	jobsCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancelJobs) // the jobs are canceled when the parent context is done.
+	w := &queueWorkers{stopClaiming: stopClaiming, cancelJobs: cancelJobs, stop: stop, listener: listener}
+
+	wake := make(chan struct{}, 1)
+	signal := func() {
+		select {
+		case wake <- struct{}{}:
+		default: // a wake-up is already pending.
+		}
+	}
+
+	w.loops.Go(func() {
+		listener.run(claimCtx, q.opts.PollInterval, func(payload string) {
+			if payload == q.name {
+				signal()
+			}
+		}, func(err error) {
+			q.reportError(fmt.Errorf("queue: %s: work: listen: %w", q.name, err))
+		})
+	})
+
+	busy := make(chan struct{}, concurrency) // a token per running job.
+	w.loops.Go(func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-claimCtx.Done():
+				return
+			case <-timer.C:
+			case <-wake:
+			}
+
+			wait := q.opts.PollInterval
+
+			if free := concurrency - len(busy); free > 0 {
+				jobs, err := q.claim(claimCtx, free)
+				if err != nil {
+					if claimCtx.Err() != nil {
+						return
+					}
+
+					q.reportError(fmt.Errorf("queue: %s: claim: %w", q.name, err))
+				}
+
+				for _, job := range jobs {
+					busy <- struct{}{}
+					w.jobs.Go(func() {
+						defer signal() // a worker is free, claim more jobs.
+						defer func() { <-busy }()
+
+						q.run(jobsCtx, handler, job)
+					})
+				}
+
+				if len(jobs) == free {
+					wait = 0 // there may be more due jobs, claim them when a worker is free.
+				}
+			}
+
+			if wait == 0 {
+				if len(busy) < concurrency {
+					signal()
+				}
+
+				continue
+			}
+
+			timer.Reset(wait)
+		}
+	})
+
+	return w, nil
+}
+
+func (q *Queue[T]) reportError(err error) {
+	if q.opts.OnError != nil {
+		q.opts.OnError(err)
+	}
+}
+
+// queueWorkers is the Closer of Queue.Work.
+type queueWorkers struct {
+	stopClaiming context.CancelFunc
+	cancelJobs   context.CancelFunc
+	stop         func() bool
+	listener     *workerListener
+	loops        sync.WaitGroup // the listen and claim loops.
+	jobs         sync.WaitGroup // the running jobs.
+}
+
+// Close stops claiming jobs and waits for the running ones until the context is done,
+// when it cancels their context and waits for them to return.
+func (w *queueWorkers) Close(ctx context.Context) error {
+	w.stopClaiming()
+	err := w.listener.Close(ctx)
+	w.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		w.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		w.cancelJobs()
+		<-done
+	}
+
+	w.cancelJobs()
+	w.stop()
+	return err
+}
+
+// Stats returns the number of the jobs of the queue per state.
+func (q *Queue[T]) Stats(ctx context.Context) (QueueStats, error) {
+	query := fmt.Sprintf(`SELECT
+		COUNT(*) FILTER (WHERE status = 'pending' AND run_at <= now()),
+		COUNT(*) FILTER (WHERE status = 'pending' AND run_at > now()),
+		COUNT(*) FILTER (WHERE status = 'running'),
+		COUNT(*) FILTER (WHERE status = 'dead')
+		FROM %s WHERE queue = $1;`, q.tableName())
+
+	var stats QueueStats
+	err := q.db.QueryRow(ctx, query, q.name).Scan(&stats.Pending, &stats.Scheduled, &stats.Running, &stats.Dead)
+	if err != nil {
+		return stats, fmt.Errorf("queue: %s: stats: %w", q.name, err)
+	}
+
+	return stats, nil
+}
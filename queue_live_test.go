@@ -0,0 +1,346 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestQueue' -v .
+
+const queueScratchTable = "test_queue_jobs"
+
+type queueLiveEmail struct {
+	To string `json:"to"`
+}
+
+func TestQueueEnqueue(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, queueScratchTable)
+	defer dropTestTables(ctx, db, queueScratchTable)
+
+	queue, err := NewQueue[queueLiveEmail](db, "emails", QueueOptions{Table: queueScratchTable})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = queue.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// a rolled back transaction enqueues nothing.
+	errRollback := errors.New("rollback")
+	err = db.InTransaction(ctx, func(tx *DB) error {
+		if _, err := queue.WithDB(tx).Enqueue(ctx, queueLiveEmail{To: "rollback@example.com"}, EnqueueOptions{}); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected the rollback error but got: %v", err)
+	}
+
+	id, err := queue.Enqueue(ctx, queueLiveEmail{To: "john@example.com"}, EnqueueOptions{DedupeKey: "welcome:john"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id == 0 {
+		t.Fatal("expected the ID of the enqueued job")
+	}
+
+	if id, err = queue.Enqueue(ctx, queueLiveEmail{To: "john@example.com"}, EnqueueOptions{DedupeKey: "welcome:john"}); err != nil || id != 0 {
+		t.Fatalf("expected the duplicate job to be skipped but got: %d: %v", id, err)
+	}
+
+	if _, err = queue.Enqueue(ctx, queueLiveEmail{To: "later@example.com"}, EnqueueOptions{RunAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// another queue of the same table.
+	other, err := NewQueue[queueLiveEmail](db, "reports", QueueOptions{Table: queueScratchTable})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = other.Enqueue(ctx, queueLiveEmail{To: "reports@example.com"}, EnqueueOptions{DedupeKey: "welcome:john"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := queue.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (QueueStats{Pending: 1, Scheduled: 1}); stats != expected {
+		t.Fatalf("expected stats %#v but got: %#v", expected, stats)
+	}
+}
+
+func TestQueueWork(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, queueScratchTable)
+	defer dropTestTables(ctx, db, queueScratchTable)
+
+	queue, err := NewQueue[queueLiveEmail](db, "emails", QueueOptions{
+		Table:        queueScratchTable,
+		PollInterval: 20 * time.Millisecond,
+		Retry:        RetryOptions{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = queue.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := make(chan Job[queueLiveEmail], 10)
+	workers, err := queue.Work(ctx, 2, func(_ context.Context, job Job[queueLiveEmail]) error {
+		if job.Payload.To == "invalid" {
+			return errors.New("invalid recipient")
+		}
+
+		sent <- job
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer workers.Close(ctx)
+
+	if _, err = queue.Enqueue(ctx, queueLiveEmail{To: "invalid"}, EnqueueOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = queue.Enqueue(ctx, queueLiveEmail{To: "john@example.com"}, EnqueueOptions{Priority: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case job := <-sent:
+		if job.Payload.To != "john@example.com" || job.Attempt != 1 || job.Priority != 10 {
+			t.Fatalf("unexpected job: %#v", job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job was not run")
+	}
+
+	// the failed job is retried once and then it is moved to the dead-letter state.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats, err := queue.Stats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if stats == (QueueStats{Dead: 1}) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a dead job but got: %#v", stats)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	var (
+		attempts  int
+		lastError string
+	)
+	if err = db.QueryRow(ctx, `SELECT attempts, last_error FROM `+queueScratchTable+` WHERE status = 'dead'`).Scan(&attempts, &lastError); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 2 || lastError != "invalid recipient" {
+		t.Fatalf("expected 2 attempts and the handler error but got: %d, %q", attempts, lastError)
+	}
+}
+
+func TestQueueVisibilityTimeout(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, queueScratchTable)
+	defer dropTestTables(ctx, db, queueScratchTable)
+
+	queue, err := NewQueue[queueLiveEmail](db, "emails", QueueOptions{Table: queueScratchTable, VisibilityTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = queue.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = queue.Enqueue(ctx, queueLiveEmail{To: "john@example.com"}, EnqueueOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a worker which claims the job and never finishes it, e.g. its process crashed.
+	jobs, err := queue.claim(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 claimed job but got: %d", len(jobs))
+	}
+
+	if jobs, err = queue.claim(ctx, 10); err != nil || len(jobs) != 0 {
+		t.Fatalf("expected the leased job to be invisible but got: %d: %v", len(jobs), err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if jobs, err = queue.claim(ctx, 10); err != nil || len(jobs) != 1 || jobs[0].Attempt != 2 {
+		t.Fatalf("expected the expired job to be claimed again but got: %#v: %v", jobs, err)
+	}
+}
+
+func TestQueueUndecodablePayload(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, queueScratchTable)
+	defer dropTestTables(ctx, db, queueScratchTable)
+
+	queue, err := NewQueue[queueLiveEmail](db, "emails", QueueOptions{Table: queueScratchTable})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = queue.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = queue.Enqueue(ctx, queueLiveEmail{To: "john@example.com"}, EnqueueOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Exec(ctx, `INSERT INTO `+queueScratchTable+` (queue, payload) VALUES ('emails', '"not an email"')`); err != nil {
+		t.Fatal(err)
+	}
+
+	// a claim on a transaction records the undecodable job on the same connection.
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+
+	jobs, err := queue.WithDB(tx).claim(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) != 1 || jobs[0].Payload.To != "john@example.com" {
+		t.Fatalf("expected only the decodable job to be claimed but got: %#v", jobs)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := queue.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Dead != 1 || stats.Running != 1 {
+		t.Fatalf("expected 1 dead and 1 running job but got: %#v", stats)
+	}
+}
+
+func TestQueueLeaseRenewal(t *testing.T) {
+	db, err := openEmptyTestConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, queueScratchTable)
+	defer dropTestTables(ctx, db, queueScratchTable)
+
+	queue, err := NewQueue[queueLiveEmail](db, "emails", QueueOptions{
+		Table:             queueScratchTable,
+		VisibilityTimeout: 150 * time.Millisecond,
+		PollInterval:      20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = queue.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// a job which runs longer than the visibility timeout is not claimed by another worker.
+	var runs atomic.Int32
+	done := make(chan error, 10)
+	handler := func(ctx context.Context, job Job[queueLiveEmail]) error {
+		runs.Add(1)
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-ctx.Done():
+		}
+
+		done <- ctx.Err()
+		return nil
+	}
+
+	for range 2 {
+		workers, err := queue.Work(ctx, 1, handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer workers.Close(ctx)
+	}
+
+	if _, err = queue.Enqueue(ctx, queueLiveEmail{To: "john@example.com"}, EnqueueOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatalf("expected the handler to keep its lease but its context is done: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job was not run")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("expected the job to run once but it ran %d times", n)
+	}
+}
@@ -0,0 +1,46 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueueOptions(t *testing.T) {
+	db := &DB{schema: NewSchema(), searchPath: "public"}
+
+	queue, err := NewQueue[string](db, "emails", QueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := `"public"."queue_jobs"`, queue.tableName(); got != expected {
+		t.Fatalf("expected table %s but got: %s", expected, got)
+	}
+
+	opts := queue.opts
+	if opts.Channel != "queue_jobs" || opts.VisibilityTimeout != 5*time.Minute || opts.PollInterval != 5*time.Second {
+		t.Fatalf("unexpected defaults: %#v", opts)
+	}
+
+	if opts.Retry.MaxAttempts != 5 || opts.Retry.BaseDelay != time.Second || opts.Retry.MaxDelay != time.Hour {
+		t.Fatalf("unexpected retry defaults: %#v", opts.Retry)
+	}
+
+	if _, err = NewQueue[string](db, "", QueueOptions{}); err == nil {
+		t.Fatal("expected an error for an empty queue name")
+	}
+
+	if _, err = NewQueue[string](db, "emails", QueueOptions{Table: `jobs"; DROP TABLE users; --`}); err == nil {
+		t.Fatal("expected an error for an invalid table name")
+	}
+
+	tx := &DB{schema: db.schema, searchPath: "public"}
+	if bound := queue.WithDB(tx); bound.db != tx || queue.db != db || bound.Name() != "emails" {
+		t.Fatal("expected WithDB to return a copy of the queue bound to the given database")
+	}
+
+	if _, err = queue.Work(context.Background(), 1, nil); err == nil {
+		t.Fatal("expected an error for a nil handler")
+	}
+}
@@ -0,0 +1,504 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kataras/pg/desc"
+)
+
+// This file adds relation preloading. A struct field tagged with the `rel` option holds the related
+// rows of another registered table, e.g.
+//
+//	type Blog struct {
+//		ID    int64  `pg:"type=bigserial,primary"`
+//		Name  string `pg:"type=varchar(255)"`
+//		Posts []Post `pg:"rel=has_many,fk=blog_id"`
+//	}
+//
+// and Repository.Preload fills it for every selected value:
+//
+//	blogs, err := blogs.Preload("Posts").Select(ctx, "SELECT * FROM blogs ORDER BY name;")
+//
+// Each relation is loaded with one batched WHERE <key> = ANY($1) query (two for many-to-many relations)
+// for all of the selected values, instead of one query per value. See desc.Relation for the supported relations.
+
+// Preload returns a shallow copy of the repository whose Select, SelectSingle, SelectByID, SelectPaginated,
+// SelectWithTotal and SelectAfter methods, and Find, also fill the given relation fields of the selected values,
+// SelectIter does not. A relation is given by the name of
+// its struct field, e.g. "Posts", and a nested relation by its path, e.g. "Posts.Tags" fills the Tags of the Posts.
+//
+// Soft-deleted related rows are hidden the same way as the repository's ones, see WithDeleted.
+func (repo *Repository[T]) Preload(relations ...string) *Repository[T] {
+	r := *repo
+	r.preloads = append(slices.Clone(repo.preloads), relations...)
+	return &r
+}
+
+// preload fills the relation fields of the given values, see Preload.
+func (repo *Repository[T]) preload(ctx context.Context, values []T) error {
+	if len(repo.preloads) == 0 || len(values) == 0 {
+		return nil
+	}
+
+	parents := make([]reflect.Value, 0, len(values))
+	for i := range values {
+		if v, ok := structPointer(&values[i]); ok {
+			parents = append(parents, v)
+		}
+	}
+
+	return repo.db.preloadRelations(ctx, repo.td, repo.scope, parents, repo.preloads)
+}
+
+// structPointer returns the pointer to the struct value of the given pointer to a T value,
+// dereferenced if T is a pointer type, and reports false if it is nil.
+func structPointer(valuePtr any) (reflect.Value, bool) {
+	v := reflect.ValueOf(valuePtr)
+	for v.Elem().Kind() == reflect.Pointer { // T is a pointer type.
+		if v.Elem().IsNil() {
+			return reflect.Value{}, false
+		}
+
+		v = v.Elem()
+	}
+
+	return v, v.Elem().Kind() == reflect.Struct
+}
+
+// preloadSingle is preload for a single value.
+func (repo *Repository[T]) preloadSingle(ctx context.Context, value T) (T, error) {
+	values := []T{value}
+	err := repo.preload(ctx, values)
+	return values[0], err
+}
+
+// preloadRelations fills the relations of the given paths (e.g. "Posts" or "Posts.Tags")
+// of the parents, pointers to values of the td's struct type.
+func (db *DB) preloadRelations(ctx context.Context, td *desc.Table, scope desc.SoftDeleteScope, parents []reflect.Value, paths []string) error {
+	// group the paths by their first relation, keeping their order.
+	var names []string
+	nested := make(map[string][]string)
+	for _, path := range paths {
+		name, rest, _ := strings.Cut(path, ".")
+		if _, ok := nested[name]; !ok {
+			names = append(names, name)
+			nested[name] = nil
+		}
+
+		if rest != "" {
+			nested[name] = append(nested[name], rest)
+		}
+	}
+
+	for _, name := range names {
+		rel := td.GetRelation(name)
+		if rel == nil {
+			return fmt.Errorf("preload: %s: unknown relation: %s", td.Name, name)
+		}
+
+		targetTd, err := db.schema.Get(rel.TargetType)
+		if err != nil {
+			return fmt.Errorf("preload: %s: %s: %w", td.Name, name, err)
+		}
+
+		var (
+			targets []reflect.Value   // the loaded related values.
+			links   [][]reflect.Value // the related values of each parent.
+		)
+
+		switch rel.Type {
+		case desc.RelationBelongsTo:
+			targets, links, err = db.loadBelongsTo(ctx, td, targetTd, rel, scope, parents)
+		case desc.RelationHasMany:
+			targets, links, err = db.loadHasMany(ctx, td, targetTd, rel, scope, parents)
+		case desc.RelationManyToMany:
+			targets, links, err = db.loadManyToMany(ctx, td, targetTd, rel, scope, parents)
+		}
+		if err != nil {
+			return fmt.Errorf("preload: %s: %s: %w", td.Name, name, err)
+		}
+
+		// fill the nested relations before the related values are copied to the parents' fields.
+		if len(nested[name]) > 0 && len(targets) > 0 {
+			if err = db.preloadRelations(ctx, targetTd, scope, targets, nested[name]); err != nil {
+				return err
+			}
+		}
+
+		for i, parent := range parents {
+			setRelationField(parent.Elem().FieldByIndex(rel.FieldIndex), links[i])
+		}
+	}
+
+	return nil
+}
+
+// loadBelongsTo loads the related rows of a belongs-to relation, e.g. the Blog of each Post,
+// by the parents' foreign key column.
+func (db *DB) loadBelongsTo(ctx context.Context, td, targetTd *desc.Table, rel *desc.Relation, scope desc.SoftDeleteScope, parents []reflect.Value) ([]reflect.Value, [][]reflect.Value, error) {
+	fk, err := relationForeignKey(td, rel.ForeignKey, targetTd.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ref, err := referencedColumn(targetTd, fk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targets, err := db.selectRelated(ctx, targetTd, scope, ref, relationKeys(parents, fk))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byKey := make(map[string]reflect.Value, len(targets))
+	for _, target := range targets {
+		if key, ok := relationKey(target, ref); ok {
+			byKey[key] = target
+		}
+	}
+
+	links := make([][]reflect.Value, len(parents))
+	for i, parent := range parents {
+		if key, ok := relationKey(parent, fk); ok {
+			if target, ok := byKey[key]; ok {
+				links[i] = []reflect.Value{target}
+			}
+		}
+	}
+
+	return targets, links, nil
+}
+
+// loadHasMany loads the related rows of a has-many relation, e.g. the Posts of each Blog,
+// by the related table's foreign key column.
+func (db *DB) loadHasMany(ctx context.Context, td, targetTd *desc.Table, rel *desc.Relation, scope desc.SoftDeleteScope, parents []reflect.Value) ([]reflect.Value, [][]reflect.Value, error) {
+	fk, err := relationForeignKey(targetTd, rel.ForeignKey, td.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ref, err := referencedColumn(td, fk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targets, err := db.selectRelated(ctx, targetTd, scope, fk, relationKeys(parents, ref))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byKey := make(map[string][]reflect.Value)
+	for _, target := range targets {
+		if key, ok := relationKey(target, fk); ok {
+			byKey[key] = append(byKey[key], target)
+		}
+	}
+
+	links := make([][]reflect.Value, len(parents))
+	for i, parent := range parents {
+		if key, ok := relationKey(parent, ref); ok {
+			links[i] = byKey[key]
+		}
+	}
+
+	return targets, links, nil
+}
+
+// loadManyToMany loads the related rows of a many-to-many relation, e.g. the Tags of each Post,
+// with a query on the join table and one on the related table.
+func (db *DB) loadManyToMany(ctx context.Context, td, targetTd *desc.Table, rel *desc.Relation, scope desc.SoftDeleteScope, parents []reflect.Value) ([]reflect.Value, [][]reflect.Value, error) {
+	joinTd, _ := db.schema.GetByTableName(rel.JoinTable) // the join table does not have to be registered.
+
+	fkName, ownerKey, err := joinTableColumn(joinTd, td, rel.ForeignKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	joinFkName, targetKey, err := joinTableColumn(joinTd, targetTd, rel.JoinForeignKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ownerKeys := relationKeys(parents, ownerKey)
+	if ownerKeys.Len() == 0 {
+		return nil, make([][]reflect.Value, len(parents)), nil
+	}
+
+	where, args := fmt.Sprintf(" WHERE %s = ANY($1)", QuoteIdentifier(fkName)), []any{ownerKeys.Interface()}
+	if joinTd != nil {
+		if where, args, err = andTenant(ctx, joinTd, where, args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT %s, %s FROM %s.%s%s ORDER BY %s;`,
+		QuoteIdentifier(fkName), QuoteIdentifier(joinFkName), QuoteIdentifier(db.searchPath), QuoteIdentifier(rel.JoinTable),
+		where, QuoteIdentifier(joinFkName))
+
+	rows, err := db.queryRead(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	type pair struct{ owner, target string }
+	var (
+		pairs      []pair
+		targetKeys = reflect.MakeSlice(reflect.SliceOf(indirectFieldType(targetKey)), 0, 0)
+		seen       = make(map[string]struct{})
+	)
+	for rows.Next() {
+		owner := reflect.New(indirectFieldType(ownerKey))
+		target := reflect.New(indirectFieldType(targetKey))
+		if err = rows.Scan(owner.Interface(), target.Interface()); err != nil {
+			return nil, nil, err
+		}
+
+		p := pair{owner: fmt.Sprint(owner.Elem().Interface()), target: fmt.Sprint(target.Elem().Interface())}
+		pairs = append(pairs, p)
+
+		if _, ok := seen[p.target]; !ok {
+			seen[p.target] = struct{}{}
+			targetKeys = reflect.Append(targetKeys, target.Elem())
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	targets, err := db.selectRelated(ctx, targetTd, scope, targetKey, targetKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byKey := make(map[string]reflect.Value, len(targets))
+	for _, target := range targets {
+		if key, ok := relationKey(target, targetKey); ok {
+			byKey[key] = target
+		}
+	}
+
+	byOwner := make(map[string][]reflect.Value)
+	for _, p := range pairs {
+		if target, ok := byKey[p.target]; ok { // a soft-deleted target is missing.
+			byOwner[p.owner] = append(byOwner[p.owner], target)
+		}
+	}
+
+	links := make([][]reflect.Value, len(parents))
+	for i, parent := range parents {
+		if key, ok := relationKey(parent, ownerKey); ok {
+			links[i] = byOwner[key]
+		}
+	}
+
+	return targets, links, nil
+}
+
+// selectRelated selects the rows of the related table whose column matches one of the given keys,
+// ordered by the table's primary key, and returns pointers to them.
+func (db *DB) selectRelated(ctx context.Context, td *desc.Table, scope desc.SoftDeleteScope, column *desc.Column, keys reflect.Value) ([]reflect.Value, error) {
+	if keys.Len() == 0 {
+		return nil, nil
+	}
+
+	where := andCondition(fmt.Sprintf(" WHERE %s = ANY($1)", QuoteIdentifier(column.Name)), td.SoftDeleteCondition(scope))
+	where, args, err := andTenant(ctx, td, where, []any{keys.Interface()})
+	if err != nil {
+		return nil, err
+	}
+
+	var orderBy string
+	if primaryKeys := td.PrimaryKeys(); len(primaryKeys) > 0 {
+		names := make([]string, 0, len(primaryKeys))
+		for _, c := range primaryKeys {
+			names = append(names, QuoteIdentifier(c.Name))
+		}
+
+		orderBy = " ORDER BY " + strings.Join(names, ", ")
+	}
+
+	query := fmt.Sprintf(`SELECT * FROM %s.%s%s%s;`, QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), where, orderBy)
+
+	rows, err := db.queryRead(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []reflect.Value
+	for rows.Next() {
+		value := reflect.New(td.StructType)
+		if err = desc.ConvertRowsToStruct(td, rows, value.Interface()); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// relationForeignKey returns the foreign key column of td: the one of the given name or,
+// if it is empty, the only one which references the refTableName table.
+func relationForeignKey(td *desc.Table, name, refTableName string) (*desc.Column, error) {
+	if name == "" {
+		if c := td.ReferencingColumn(refTableName); c != nil {
+			return c, nil
+		}
+
+		return nil, fmt.Errorf("no single column of %s references %s, set the fk option", td.Name, refTableName)
+	}
+
+	c := td.GetColumnByName(name)
+	if c == nil {
+		return nil, fmt.Errorf("foreign key column %q of %s not found", name, td.Name)
+	}
+
+	return c, nil
+}
+
+// referencedColumn returns the column of td the given foreign key column references:
+// the one of its `ref` struct tag option or the primary key of td.
+func referencedColumn(td *desc.Table, fk *desc.Column) (*desc.Column, error) {
+	if fk != nil && fk.ReferenceTableName == td.Name && fk.ReferenceColumnName != "" {
+		if c := td.GetColumnByName(fk.ReferenceColumnName); c != nil {
+			return c, nil
+		}
+	}
+
+	primaryKeys := td.PrimaryKeys()
+	if len(primaryKeys) != 1 {
+		return nil, fmt.Errorf("%s: expected a single column primary key but got %d columns", td.Name, len(primaryKeys))
+	}
+
+	return primaryKeys[0], nil
+}
+
+// joinTableColumn returns the name of the join table's column which references td and the column of td it references.
+// The name is resolved from the join table's definition, if it is registered, when the given one is empty.
+func joinTableColumn(joinTd, td *desc.Table, name string) (string, *desc.Column, error) {
+	var fk *desc.Column
+	if joinTd != nil {
+		var err error
+		if fk, err = relationForeignKey(joinTd, name, td.Name); err != nil {
+			return "", nil, err
+		}
+
+		name = fk.Name
+	}
+
+	if name == "" {
+		return "", nil, fmt.Errorf("the join table's column which references %s is required, set the fk and join_fk options", td.Name)
+	}
+
+	ref, err := referencedColumn(td, fk)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return name, ref, nil
+}
+
+// relationKeys returns the distinct non-null values of the column of the given values,
+// as a typed slice for the ANY($1) query argument.
+func relationKeys(values []reflect.Value, c *desc.Column) reflect.Value {
+	keys := reflect.MakeSlice(reflect.SliceOf(indirectFieldType(c)), 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+
+	for _, value := range values {
+		field, ok := relationField(value, c)
+		if !ok {
+			continue
+		}
+
+		key := fmt.Sprint(field.Interface())
+		if _, ok = seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		keys = reflect.Append(keys, field)
+	}
+
+	return keys
+}
+
+// relationKey returns the text of the value's column, used to match the related values,
+// and reports false if it is null.
+func relationKey(value reflect.Value, c *desc.Column) (string, bool) {
+	field, ok := relationField(value, c)
+	if !ok {
+		return "", false
+	}
+
+	return fmt.Sprint(field.Interface()), true
+}
+
+// relationField returns the struct field of the column of the given pointer to a struct value,
+// dereferenced, and reports false if it is a nil pointer.
+func relationField(value reflect.Value, c *desc.Column) (reflect.Value, bool) {
+	field, err := value.Elem().FieldByIndexErr(c.FieldIndex)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+
+	for field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return reflect.Value{}, false
+		}
+
+		field = field.Elem()
+	}
+
+	return field, true
+}
+
+// indirectFieldType returns the type of the column's struct field, without its pointer.
+func indirectFieldType(c *desc.Column) reflect.Type {
+	typ := c.FieldType
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	return typ
+}
+
+// setRelationField sets the relation field to the given related values (pointers to structs):
+// the first one (or nil) for a struct or a pointer field, all of them for a slice field.
+// A slice field of a value without related rows is set to an empty slice.
+func setRelationField(field reflect.Value, targets []reflect.Value) {
+	switch field.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(field.Type(), 0, len(targets))
+		for _, target := range targets {
+			if field.Type().Elem().Kind() == reflect.Pointer {
+				s = reflect.Append(s, target)
+			} else {
+				s = reflect.Append(s, target.Elem())
+			}
+		}
+
+		field.Set(s)
+	case reflect.Pointer:
+		if len(targets) == 0 {
+			field.SetZero()
+			return
+		}
+
+		field.Set(targets[0])
+	default:
+		if len(targets) == 0 {
+			field.SetZero()
+			return
+		}
+
+		field.Set(targets[0].Elem())
+	}
+}
@@ -0,0 +1,164 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestRepositoryPreload' -v .
+
+type relationLiveBlog struct {
+	ID    int64              `pg:"type=bigserial,primary"`
+	Name  string             `pg:"type=varchar(255)"`
+	Posts []relationLivePost `pg:"rel=has_many"`
+}
+
+type relationLivePost struct {
+	ID     int64              `pg:"type=bigserial,primary"`
+	BlogID int64              `pg:"type=bigint,ref=test_rel_blogs(id)"`
+	Title  string             `pg:"type=varchar(255)"`
+	Blog   *relationLiveBlog  `pg:"rel=belongs_to,fk=blog_id"`
+	Tags   []*relationLiveTag `pg:"rel=many_to_many,join=test_rel_post_tags"`
+}
+
+type relationLiveTag struct {
+	ID   int64  `pg:"type=bigserial,primary"`
+	Name string `pg:"type=varchar(255)"`
+}
+
+type relationLivePostTag struct {
+	PostID int64 `pg:"type=bigint,primary,ref=test_rel_posts(id)"`
+	TagID  int64 `pg:"type=bigint,primary,ref=test_rel_tags(id)"`
+}
+
+func TestRepositoryPreload(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("test_rel_blogs", relationLiveBlog{})
+	schema.MustRegister("test_rel_posts", relationLivePost{})
+	schema.MustRegister("test_rel_tags", relationLiveTag{})
+	schema.MustRegister("test_rel_post_tags", relationLivePostTag{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	scratchTables := []string{"test_rel_post_tags", "test_rel_posts", "test_rel_tags", "test_rel_blogs"}
+	dropTestTables(ctx, db, scratchTables...)
+	defer dropTestTables(ctx, db, scratchTables...)
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var goBlogID, rustBlogID, emptyBlogID int64
+	blogs := NewRepository[relationLiveBlog](db)
+	for name, idPtr := range map[string]*int64{"go": &goBlogID, "rust": &rustBlogID, "empty": &emptyBlogID} {
+		if err = blogs.InsertSingle(ctx, relationLiveBlog{Name: name}, idPtr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var generics, iterators, ownership int64
+	posts := NewRepository[relationLivePost](db)
+	for _, p := range []struct {
+		post  relationLivePost
+		idPtr *int64
+	}{
+		{relationLivePost{BlogID: goBlogID, Title: "generics"}, &generics},
+		{relationLivePost{BlogID: goBlogID, Title: "iterators"}, &iterators},
+		{relationLivePost{BlogID: rustBlogID, Title: "ownership"}, &ownership},
+	} {
+		if err = posts.InsertSingle(ctx, p.post, p.idPtr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var languageTagID, tutorialTagID int64
+	tags := NewRepository[relationLiveTag](db)
+	if err = tags.InsertSingle(ctx, relationLiveTag{Name: "language"}, &languageTagID); err != nil {
+		t.Fatal(err)
+	}
+	if err = tags.InsertSingle(ctx, relationLiveTag{Name: "tutorial"}, &tutorialTagID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = NewRepository[relationLivePostTag](db).Insert(ctx,
+		relationLivePostTag{PostID: generics, TagID: languageTagID},
+		relationLivePostTag{PostID: generics, TagID: tutorialTagID},
+		relationLivePostTag{PostID: ownership, TagID: languageTagID},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// has-many and nested many-to-many.
+	list, err := blogs.Preload("Posts", "Posts.Tags").Select(ctx, "SELECT * FROM test_rel_blogs ORDER BY id;")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 3 {
+		t.Fatalf("expected 3 blogs but got: %d", len(list))
+	}
+
+	if got := len(list[0].Posts); got != 2 {
+		t.Fatalf("expected 2 posts of the go blog but got: %d", got)
+	}
+
+	if got := list[0].Posts[0]; got.Title != "generics" || len(got.Tags) != 2 || got.Tags[0].Name != "language" || got.Tags[1].Name != "tutorial" {
+		t.Fatalf("expected the generics post with its 2 tags but got: %#v", got)
+	}
+
+	if got := list[0].Posts[1]; got.Title != "iterators" || got.Tags == nil || len(got.Tags) != 0 {
+		t.Fatalf("expected the iterators post with an empty list of tags but got: %#v", got)
+	}
+
+	if got := list[1].Posts; len(got) != 1 || got[0].Title != "ownership" {
+		t.Fatalf("expected the ownership post of the rust blog but got: %#v", got)
+	}
+
+	if got := list[2].Posts; got == nil || len(got) != 0 {
+		t.Fatalf("expected an empty list of posts but got: %#v", got)
+	}
+
+	// belongs-to.
+	post, err := posts.Preload("Blog").SelectByID(ctx, ownership)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if post.Blog == nil || post.Blog.Name != "rust" {
+		t.Fatalf("expected the rust blog but got: %#v", post.Blog)
+	}
+
+	// SelectWithTotal preloads the same way.
+	withTotal, total, err := posts.Preload("Blog").SelectWithTotal(ctx, "SELECT *, COUNT(*) OVER() AS total_count FROM test_rel_posts ORDER BY id;")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 3 || len(withTotal) != 3 || withTotal[0].Blog == nil || withTotal[0].Blog.Name != "go" {
+		t.Fatalf("expected 3 posts with their blogs but got: %d: %#v", total, withTotal)
+	}
+
+	// the repository without Preload does not fill the relations.
+	post, err = posts.SelectByID(ctx, ownership)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if post.Blog != nil {
+		t.Fatalf("expected no blog but got: %#v", post.Blog)
+	}
+
+	_, err = posts.Preload("Comments").Select(ctx, "SELECT * FROM test_rel_posts;")
+	if err == nil || !strings.Contains(err.Error(), "unknown relation: Comments") {
+		t.Fatalf("expected an unknown relation error but got: %v", err)
+	}
+}
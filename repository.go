@@ -17,6 +17,10 @@ type Repository[T any] struct {
 	db *DB // a field that holds a pointer to a DB instance.
 
 	td *desc.Table // cache table definition to make it even faster on serve-time.
+
+	scope desc.SoftDeleteScope // which rows of a soft-delete table the read methods see, see WithDeleted and OnlyDeleted.
+
+	preloads []string // the relations the read methods fill, see Preload.
 }
 
 // NewRepository creates and returns a new Repository instance for a given type T and a DB instance.
@@ -58,7 +62,12 @@ func (repo *Repository[T]) Table() *desc.Table {
 }
 
 // QueryRow executes a query that returns at most one row and returns it as a Row instance.
+// If the repository is read-only, the query runs on a read replica, if any, see WithReadReplicas.
 func (repo *Repository[T]) QueryRow(ctx context.Context, query string, args ...any) Row {
+	if repo.IsReadOnly() {
+		return repo.db.queryRowRead(ctx, query, args...)
+	}
+
 	return repo.db.QueryRow(ctx, query, args...)
 }
 
@@ -70,7 +79,14 @@ func (repo *Repository[T]) QueryBoolean(ctx context.Context, query string, args
 // Count executes a query that returns a single numeric value (typically a COUNT(*) or other
 // aggregate over the repository's table) and returns it as an int64. A query that yields no
 // rows counts as zero; see DB.Count.
+//
+// Soft-deleted rows of the repository's table are hidden, see WithDeleted.
 func (repo *Repository[T]) Count(ctx context.Context, query string, args ...any) (int64, error) {
+	query, args, err := repo.scoped(ctx, query, args)
+	if err != nil {
+		return 0, err
+	}
+
 	return repo.db.Count(ctx, query, args...)
 }
 
@@ -90,7 +106,12 @@ func (repo *Repository[T]) OrderBy(column string, descending bool, extraColumns
 }
 
 // Query executes a query that returns multiple rows and returns them as a Rows instance and an error.
+// If the repository is read-only, the query runs on a read replica, if any, see WithReadReplicas.
 func (repo *Repository[T]) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	if repo.IsReadOnly() {
+		return repo.db.queryRead(ctx, query, args...)
+	}
+
 	return repo.db.Query(ctx, query, args...)
 }
 
@@ -122,15 +143,43 @@ func (repo *Repository[T]) InTransaction(ctx context.Context, fn func(*Repositor
 	}
 
 	return repo.db.InTransaction(ctx, func(db *DB) error {
-		txRepo := &Repository[T]{
-			db: db,
-			td: repo.td,
-		}
-
-		return fn(txRepo)
+		return fn(repo.withDB(db))
 	})
 }
 
+// withDB returns a shallow copy of the repository bound to the given database instance,
+// e.g. a transactional one.
+func (repo *Repository[T]) withDB(db *DB) *Repository[T] {
+	r := *repo
+	r.db = db
+	return &r
+}
+
+// scoped rewrites a read query so the rows of the repository's table are filtered according to
+// the repository's soft-delete scope and the tenant of the context, see DB.scopeTable.
+// The tenant ID, if any, is appended to the returned arguments.
+func (repo *Repository[T]) scoped(ctx context.Context, query string, args []any) (string, []any, error) {
+	tenantCondition, args, err := tenantCondition(ctx, repo.td, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	condition := repo.td.SoftDeleteCondition(repo.scope)
+	switch {
+	case condition == "":
+		condition = tenantCondition
+	case tenantCondition != "":
+		condition += " AND " + tenantCondition
+	}
+
+	query, err = repo.db.scopeTable(repo.td, condition, query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query, args, nil
+}
+
 // IsTransaction returns true if the underline database is already in a transaction or false otherwise.
 func (repo *Repository[T]) IsTransaction() bool {
 	return repo.db.IsTransaction()
@@ -142,8 +191,21 @@ func (repo *Repository[T]) IsReadOnly() bool {
 }
 
 // Select executes a SQL query and returns a slice of values of type T that match the query results.
+//
+// If the table has a soft_delete column, unqualified references to the table inside the query
+// see only the rows that are not soft-deleted, see WithDeleted and OnlyDeleted.
 func (repo *Repository[T]) Select(ctx context.Context, query string, args ...any) ([]T, error) {
-	rows, err := repo.db.Query(ctx, query, args...) // execute the query using repo.db.Query and pass in the arguments
+	query, args, err := repo.scoped(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.selectRows(ctx, query, args...)
+}
+
+// selectRows is Select without the soft-delete scoping of the query.
+func (repo *Repository[T]) selectRows(ctx context.Context, query string, args ...any) ([]T, error) {
+	rows, err := repo.db.queryRead(ctx, query, args...) // execute the query using db.queryRead (a read replica, if any) and pass in the arguments
 	if err != nil {
 		return nil, err // return nil and the error if the query fails
 	}
@@ -153,6 +215,10 @@ func (repo *Repository[T]) Select(ctx context.Context, query string, args ...any
 		return nil, err // return nil and the error if the conversion fails
 	}
 
+	if err = repo.preload(ctx, list); err != nil {
+		return nil, err // return nil and the error if loading the relations fails, see Preload
+	}
+
 	return list, nil // return the slice of values and nil as no error occurred
 }
 
@@ -160,21 +226,35 @@ func (repo *Repository[T]) Select(ctx context.Context, query string, args ...any
 func (repo *Repository[T]) SelectSingle(ctx context.Context, query string, args ...any) (T, error) {
 	var value T // declare a zero value of type T
 
-	rows, err := repo.db.Query(ctx, query, args...) // execute the query using repo.db.Query and pass in the arguments
+	query, args, err := repo.scoped(ctx, query, args)
+	if err != nil {
+		return value, err
+	}
+
+	rows, err := repo.db.queryRead(ctx, query, args...) // execute the query using db.queryRead (a read replica, if any) and pass in the arguments
 	if err != nil {
 		return value, err // return the zero value and the error if the query fails
 	}
 
 	value, err = repo.td.RowToStruct[T](rows) // convert the first row returned by the query to a value of type T using RowToStruct
-	return value, err                         // return the value and the error from RowToStruct (nil or not)
+	if err != nil {
+		return value, err // return the value and the error from RowToStruct
+	}
+
+	return repo.preloadSingle(ctx, value) // fill the relations, see Preload
 }
 
 // SelectByID selects a row from a table by matching the id column with the given argument and returns the row or ErrNoRows.
+// For a table with a composite primary key the id is a Key or a T value holding the primary key fields.
 func (repo *Repository[T]) SelectByID(ctx context.Context, id any) (T, error) {
 	var value T // declare a zero value of type T
 
-	err := repo.db.selectTableRecordByID(ctx, repo.td, &value, id)
-	return value, err
+	err := repo.db.selectTableRecordByID(ctx, repo.td, repo.scope, &value, id)
+	if err != nil {
+		return value, err
+	}
+
+	return repo.preloadSingle(ctx, value)
 }
 
 // SelectByUsernameAndPassword selects a row from a table by matching the username and password columns with the given arguments
@@ -182,13 +262,13 @@ func (repo *Repository[T]) SelectByID(ctx context.Context, id any) (T, error) {
 func (repo *Repository[T]) SelectByUsernameAndPassword(ctx context.Context, username, plainPassword string) (T, error) {
 	var value T // declare a zero value of type T
 
-	err := repo.db.selectTableRecordByUsernameAndPassword(ctx, repo.td, &value, username, plainPassword)
+	err := repo.db.selectTableRecordByUsernameAndPassword(ctx, repo.td, repo.scope, &value, username, plainPassword)
 	return value, err
 }
 
 // Exists returns true if a row exists in the table that matches the given value's non-zero fields or false otherwise.
 func (repo *Repository[T]) Exists(ctx context.Context, value T) (bool, error) {
-	return repo.db.tableRecordExists(ctx, repo.td, desc.IndirectValue(value))
+	return repo.db.tableRecordExists(ctx, repo.td, repo.scope, desc.IndirectValue(value))
 }
 
 // ErrIsReadOnly is returned by Insert and InsertSingle if the repository is read-only.
@@ -246,12 +326,12 @@ func (repo *Repository[T]) InsertMany(ctx context.Context, values ...T) error {
 			end := min(start+batchSize, len(values))
 			batch := values[start:end]
 
-			structValues := make([]reflect.Value, len(batch))
-			for i := range batch {
-				structValues[i] = desc.IndirectValue(batch[i])
+			structValues, err := bindTenants(ctx, repo.td, batch)
+			if err != nil {
+				return err
 			}
 
-			query, args, err := desc.BuildBulkInsertQuery(repo.td, structValues, "", false)
+			query, args, err := desc.BuildBulkInsertQuery(repo.db.tenantTable(repo.td), structValues, "", false)
 			if err != nil {
 				return err
 			}
@@ -266,6 +346,7 @@ func (repo *Repository[T]) InsertMany(ctx context.Context, values ...T) error {
 // InsertSingle inserts a single value of type T into the database by calling repo.db.InsertSingle with the value and the idPtr.
 //
 // If it is not null then the value is updated by its primary key value.
+// For a table with a composite primary key idPtr should be a *Key.
 func (repo *Repository[T]) InsertSingle(ctx context.Context, value T, idPtr any) error {
 	if repo.IsReadOnly() {
 		return ErrIsReadOnly
@@ -352,12 +433,12 @@ func (repo *Repository[T]) UpsertMany(ctx context.Context, forceOnConflictExpr s
 			end := min(start+batchSize, len(values))
 			batch := values[start:end]
 
-			structValues := make([]reflect.Value, len(batch))
-			for i := range batch {
-				structValues[i] = desc.IndirectValue(batch[i])
+			structValues, err := bindTenants(ctx, repo.td, batch)
+			if err != nil {
+				return err
 			}
 
-			query, args, err := desc.BuildBulkInsertQuery(repo.td, structValues, forceOnConflictExpr, true)
+			query, args, err := desc.BuildBulkInsertQuery(repo.db.tenantTable(repo.td), structValues, forceOnConflictExpr, true)
 			if err != nil {
 				return err
 			}
@@ -381,6 +462,8 @@ func (repo *Repository[T]) UpsertSingle(ctx context.Context, forceOnConflictExpr
 }
 
 // Delete deletes one or more values of type T from the database by their primary key values.
+//
+// If the table has a soft_delete column the rows are marked as deleted instead, see HardDelete.
 func (repo *Repository[T]) Delete(ctx context.Context, values ...T) (int64, error) {
 	if repo.IsReadOnly() {
 		return 0, ErrIsReadOnly
@@ -395,7 +478,9 @@ func (repo *Repository[T]) Delete(ctx context.Context, values ...T) (int64, erro
 }
 
 // DeleteByID deletes a single row from a table by matching the id column with the given argument and
-// reports whether the entry was removed or not.
+// reports whether the entry was removed or not. For a table with a composite primary key the id is a Key.
+//
+// If the table has a soft_delete column the row is marked as deleted instead, see HardDelete.
 //
 // The difference between Delete and DeleteByID is that
 // DeleteByID accepts just the id value instead of the whole entity structure value.
@@ -404,12 +489,21 @@ func (repo *Repository[T]) DeleteByID(ctx context.Context, id any) (bool, error)
 }
 
 // Update updates one or more values of type T in the database by their primary key values.
+//
+// If the table has a `version` column (optimistic locking), each row is updated only if it still
+// has the value's version, which is then incremented. The new version is written back to the
+// given values when they are pointers or when they are passed as a spread slice
+// (repo.Update(ctx, items...)); if a row's version has changed in the meantime,
+// or the row is gone, a *StaleEntityError (matching ErrStaleEntity) is returned
+// and, on a multi-value call, the whole update is rolled back. In a transaction the
+// previous versions are written back if it rolls back.
 func (repo *Repository[T]) Update(ctx context.Context, values ...T) (int64, error) {
 	return repo.UpdateOnlyColumns(ctx, nil, values...)
 }
 
 // UpdateExceptColumns updates one or more values of type T in the database by their primary key values.
 // The columnsToExcept parameter can be used to specify which columns should NOT be updated.
+// The `version` column, if any, is handled exactly as in Update.
 func (repo *Repository[T]) UpdateExceptColumns(ctx context.Context, columnsToExcept []string, values ...T) (int64, error) {
 	columnsToUpdate := repo.td.ListColumnNamesExcept(columnsToExcept...)
 	return repo.UpdateOnlyColumns(ctx, columnsToUpdate, values...)
@@ -418,6 +512,7 @@ func (repo *Repository[T]) UpdateExceptColumns(ctx context.Context, columnsToExc
 // UpdateOnlyColumns updates one or more values of type T in the database by their primary key values.
 //
 // The columnsToUpdate parameter can be used to specify which columns should be updated.
+// The `version` column, if any, is handled exactly as in Update.
 func (repo *Repository[T]) UpdateOnlyColumns(ctx context.Context, columnsToUpdate []string, values ...T) (int64, error) {
 	if repo.IsReadOnly() {
 		return 0, ErrIsReadOnly
@@ -427,7 +522,7 @@ func (repo *Repository[T]) UpdateOnlyColumns(ctx context.Context, columnsToUpdat
 		return 0, nil
 	}
 
-	valuesAsInterfaces := toInterfaces(values)
+	valuesAsInterfaces := toAddressableInterfaces(values)
 	return repo.db.updateTableRecords(ctx, repo.td, columnsToUpdate, false, valuesAsInterfaces)
 }
 
@@ -442,7 +537,7 @@ func (repo *Repository[T]) UpdateOnlyColumnsReportNoRows(ctx context.Context, co
 		return false, nil
 	}
 
-	valuesAsInterfaces := toInterfaces(values)
+	valuesAsInterfaces := toAddressableInterfaces(values)
 	_, err := repo.db.updateTableRecords(ctx, repo.td, columnsToUpdate, true, valuesAsInterfaces)
 	if err != nil {
 		if errors.Is(err, ErrNoRows) {
@@ -455,6 +550,22 @@ func (repo *Repository[T]) UpdateOnlyColumnsReportNoRows(ctx context.Context, co
 	return true, nil
 }
 
+// toAddressableInterfaces is like toInterfaces but it keeps the values addressable:
+// it returns pointers to the elements of values, unless T is already a pointer type,
+// so values generated by the database (e.g. a new version) can be written back to them.
+func toAddressableInterfaces[T any](values []T) []any {
+	if reflect.TypeFor[T]().Kind() == reflect.Pointer {
+		return toInterfaces(values)
+	}
+
+	valuesAsInterfaces := make([]any, len(values))
+	for i := range values {
+		valuesAsInterfaces[i] = &values[i]
+	}
+
+	return valuesAsInterfaces
+}
+
 func toInterfaces[T any](values []T) []any {
 	valuesAsInterfaces := make([]any, len(values)) // create a slice of interfaces to store the values
 	for i, value := range values {
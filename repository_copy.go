@@ -2,7 +2,6 @@ package pg
 
 import (
 	"context"
-	"reflect"
 
 	"github.com/kataras/pg/desc"
 
@@ -40,9 +39,9 @@ func (repo *Repository[T]) CopyFrom(ctx context.Context, values []T) (int64, err
 		return 0, nil
 	}
 
-	structValues := make([]reflect.Value, len(values))
-	for i := range values {
-		structValues[i] = desc.IndirectValue(values[i])
+	structValues, err := bindTenants(ctx, repo.td, values)
+	if err != nil {
+		return 0, err
 	}
 
 	plan, err := desc.BuildCopyPlan(repo.td, structValues)
@@ -54,6 +53,6 @@ func (repo *Repository[T]) CopyFrom(ctx context.Context, values []T) (int64, err
 		return plan.Row(structValues[i])
 	})
 
-	tableName := Identifier{repo.td.SearchPath, repo.td.Name}
+	tableName := Identifier{repo.db.tenantTable(repo.td).SearchPath, repo.td.Name}
 	return repo.db.CopyFrom(ctx, tableName, plan.ColumnNames, rowSrc)
 }
@@ -0,0 +1,192 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// Finder is a fluent, typed SELECT builder over the table of a Repository, returned by Repository.Find:
+//
+//	customers, err := repo.Find().
+//		Where(pg.Where("created_at >= $1", since)).
+//		WhereEq("country", country).
+//		OrderBy("name", false).
+//		Limit(20).Offset(40).
+//		All(ctx)
+//
+// Every column name given to its methods (Columns, WhereEq and OrderBy) is validated against the
+// repository's table definition and quoted, so they may come from user input, e.g. a sort column of
+// an HTTP query. The fragments of the Conditions given to Where are raw SQL, see Conditions.
+//
+// The first invalid column is reported by the terminal method (All, One, Count or Iter).
+// A Finder is not safe for concurrent use; build one per query.
+type Finder[T any] struct {
+	repo *Repository[T]
+
+	columns []string // the quoted columns of the SELECT list, all of them if empty.
+	where   *Conditions
+	orderBy []string // the validated, quoted `"column" ASC|DESC` fragments.
+	limit   int64
+	offset  int64
+
+	err error // the first validation error.
+}
+
+// Find returns a new Finder which selects rows of the repository's table.
+// Soft-deleted rows are hidden and the relations of Preload are filled, the same way as Select.
+func (repo *Repository[T]) Find() *Finder[T] {
+	return &Finder[T]{
+		repo:  repo,
+		where: Where(""),
+	}
+}
+
+// Columns limits the SELECT list to the given columns, the fields of the other columns are left to their zero values.
+func (f *Finder[T]) Columns(columns ...string) *Finder[T] {
+	for _, column := range columns {
+		c := f.repo.td.GetColumnByName(column)
+		if c == nil {
+			f.setErr(fmt.Errorf("pg: find: unknown column %q for table %q", column, f.repo.td.Name))
+			return f
+		}
+
+		f.columns = append(f.columns, QuoteIdentifier(c.Name))
+	}
+
+	return f
+}
+
+// Where appends the fragments of the given conditions, joined with AND to the ones already in the Finder.
+// Their placeholders are renumbered, see Conditions.Build. A nil conditions is a no-op.
+func (f *Finder[T]) Where(conditions *Conditions) *Finder[T] {
+	if conditions != nil {
+		f.where.fragments = append(f.where.fragments, conditions.fragments...)
+	}
+
+	return f
+}
+
+// WhereEq appends a `"column" = value` condition. The column is validated against the table definition.
+func (f *Finder[T]) WhereEq(column string, value any) *Finder[T] {
+	c := f.repo.td.GetColumnByName(column)
+	if c == nil {
+		f.setErr(fmt.Errorf("pg: find: unknown column %q for table %q", column, f.repo.td.Name))
+		return f
+	}
+
+	f.where.And(QuoteIdentifier(c.Name)+" = $1", value)
+	return f
+}
+
+// OrderBy appends a sort column, validated the same way as Repository.OrderBy:
+// an empty column falls back to created_at, updated_at or the primary key.
+func (f *Finder[T]) OrderBy(column string, descending bool) *Finder[T] {
+	fragment, err := f.repo.OrderBy(column, descending)
+	if err != nil {
+		f.setErr(err)
+		return f
+	}
+
+	f.orderBy = append(f.orderBy, fragment)
+	return f
+}
+
+// Limit sets the maximum number of rows to select; zero or negative selects all of them.
+func (f *Finder[T]) Limit(limit int64) *Finder[T] {
+	f.limit = limit
+	return f
+}
+
+// Offset sets the number of rows to skip; zero or negative skips none.
+func (f *Finder[T]) Offset(offset int64) *Finder[T] {
+	f.offset = offset
+	return f
+}
+
+func (f *Finder[T]) setErr(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// All executes the query and returns the selected rows.
+func (f *Finder[T]) All(ctx context.Context) ([]T, error) {
+	query, args, err := f.build(f.limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.repo.Select(ctx, query, args...)
+}
+
+// One executes the query and returns its first row, or ErrNoRows.
+func (f *Finder[T]) One(ctx context.Context) (T, error) {
+	query, args, err := f.build(1)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return f.repo.SelectSingle(ctx, query, args...)
+}
+
+// Count returns the number of rows which match the conditions of the Finder.
+// Its ordering, limit and offset are ignored.
+func (f *Finder[T]) Count(ctx context.Context) (int64, error) {
+	query, args, err := f.buildCount()
+	if err != nil {
+		return 0, err
+	}
+
+	return f.repo.Count(ctx, query, args...)
+}
+
+// Iter executes the query and returns a lazy, single-use iterator over its rows, see Repository.SelectIter.
+// The relations of Preload are not filled.
+func (f *Finder[T]) Iter(ctx context.Context) iter.Seq2[T, error] {
+	query, args, err := f.build(f.limit)
+	if err != nil {
+		return func(yield func(T, error) bool) {
+			var zero T
+			yield(zero, err)
+		}
+	}
+
+	return f.repo.SelectIter(ctx, query, args...)
+}
+
+// build returns the SELECT query of the Finder, with the given limit, and its arguments.
+func (f *Finder[T]) build(limit int64) (string, []any, error) {
+	if f.err != nil {
+		return "", nil, f.err
+	}
+
+	selectList := "*"
+	if len(f.columns) > 0 {
+		selectList = strings.Join(f.columns, ", ")
+	}
+
+	clause, args := f.where.Build(1)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", selectList, QuoteIdentifier(f.repo.td.Name), clause)
+
+	page := PageOptions{
+		Limit:   limit,
+		Offset:  f.offset,
+		OrderBy: strings.Join(f.orderBy, ", "),
+	}
+	query, pageArgs := buildPaginatedQuery(query, page, f.where.NextIndex(1))
+
+	return query + ";", append(args, pageArgs...), nil
+}
+
+// buildCount returns the COUNT query of the Finder and its arguments.
+func (f *Finder[T]) buildCount() (string, []any, error) {
+	if f.err != nil {
+		return "", nil, f.err
+	}
+
+	clause, args := f.where.Build(1)
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s;", QuoteIdentifier(f.repo.td.Name), clause), args, nil
+}
@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestRepositoryFind' -v .
+
+type findLiveCustomer struct {
+	ID      string `pg:"type=uuid,primary"`
+	Name    string `pg:"type=varchar(255)"`
+	Country string `pg:"type=varchar(2)"`
+}
+
+const findScratchTable = "test_find_customers"
+
+func TestRepositoryFind(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(findScratchTable, findLiveCustomer{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, findScratchTable)
+	defer dropTestTables(ctx, db, findScratchTable)
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRepository[findLiveCustomer](db)
+	if err = repo.Insert(ctx,
+		findLiveCustomer{Name: "alice", Country: "GR"},
+		findLiveCustomer{Name: "bob", Country: "GR"},
+		findLiveCustomer{Name: "carol", Country: "GR"},
+		findLiveCustomer{Name: "dave", Country: "IT"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := repo.Find().WhereEq("country", "GR").OrderBy("name", true).Limit(2).Offset(1).All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 2 || list[0].Name != "bob" || list[1].Name != "alice" {
+		t.Fatalf("expected bob and alice but got: %#v", list)
+	}
+
+	count, err := repo.Find().WhereEq("country", "GR").Where(Where("name <> $1", "alice")).Limit(1).Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected a count of 2 but got: %d", count)
+	}
+
+	one, err := repo.Find().Columns("name").WhereEq("country", "IT").One(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if one.Name != "dave" || one.ID != "" {
+		t.Fatalf("expected only the name of dave but got: %#v", one)
+	}
+
+	if _, err = repo.Find().WhereEq("country", "FR").One(ctx); !errors.Is(err, ErrNoRows) {
+		t.Fatalf("expected ErrNoRows but got: %v", err)
+	}
+
+	var names []string
+	for c, err := range repo.Find().OrderBy("name", false).Iter(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, c.Name)
+	}
+
+	if len(names) != 4 || names[0] != "alice" || names[3] != "dave" {
+		t.Fatalf("expected the 4 names in order but got: %v", names)
+	}
+}
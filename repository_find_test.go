@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type findTestCustomer struct {
+	ID        int64  `pg:"type=bigserial,primary"`
+	Name      string `pg:"type=varchar(255)"`
+	Country   string `pg:"type=varchar(2)"`
+	CreatedAt string `pg:"type=timestamp"`
+}
+
+// TestFinderBuild exercises the Finder's SQL assembly and bind-numbering rules directly,
+// without a live database.
+func TestFinderBuild(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("customers", findTestCustomer{})
+	repo := NewRepository[findTestCustomer](&DB{schema: schema, searchPath: "public"})
+
+	t.Run("all", func(t *testing.T) {
+		f := repo.Find().
+			Columns("id", "name").
+			Where(Where("created_at >= $1", "2026-01-01").And("name ILIKE $1 OR name ILIKE $2", "a%", "b%")).
+			WhereEq("Country", "GR").
+			OrderBy("name", false).
+			OrderBy("", true).
+			Limit(20).
+			Offset(40)
+
+		query, args, err := f.build(f.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantQuery := `SELECT "id", "name" FROM "customers" WHERE (created_at >= $1) AND (name ILIKE $2 OR name ILIKE $3) AND ("country" = $4) ORDER BY "name" ASC, "created_at" DESC LIMIT $5 OFFSET $6;`
+		if query != wantQuery {
+			t.Fatalf("query: got %q, want %q", query, wantQuery)
+		}
+
+		wantArgs := []any{"2026-01-01", "a%", "b%", "GR", int64(20), int64(40)}
+		if !reflect.DeepEqual(args, wantArgs) {
+			t.Fatalf("args: got %#v, want %#v", args, wantArgs)
+		}
+
+		query, args, err = f.buildCount()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantQuery = `SELECT COUNT(*) FROM "customers" WHERE (created_at >= $1) AND (name ILIKE $2 OR name ILIKE $3) AND ("country" = $4);`
+		if query != wantQuery {
+			t.Fatalf("count query: got %q, want %q", query, wantQuery)
+		}
+
+		if len(args) != 4 {
+			t.Fatalf("count args: got %#v", args)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		query, args, err := repo.Find().build(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if wantQuery := `SELECT * FROM "customers" WHERE TRUE LIMIT $1;`; query != wantQuery {
+			t.Fatalf("query: got %q, want %q", query, wantQuery)
+		}
+
+		if wantArgs := []any{int64(1)}; !reflect.DeepEqual(args, wantArgs) {
+			t.Fatalf("args: got %#v, want %#v", args, wantArgs)
+		}
+	})
+
+	t.Run("unknown columns", func(t *testing.T) {
+		for i, f := range []*Finder[findTestCustomer]{
+			repo.Find().Columns("id", "password"),
+			repo.Find().WhereEq("name; DROP TABLE customers", 1),
+			repo.Find().OrderBy("missing", false),
+		} {
+			if _, _, err := f.build(0); err == nil || !strings.Contains(err.Error(), "unknown column") {
+				t.Fatalf("[%d] expected an unknown column error but got: %v", i, err)
+			}
+
+			if _, _, err := f.buildCount(); err == nil {
+				t.Fatalf("[%d] expected an unknown column error", i)
+			}
+		}
+	})
+}
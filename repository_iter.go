@@ -64,8 +64,13 @@ import (
 //     immediately issue another query against the same *DB with no special cleanup of their
 //     own.
 //
+// Soft-deleted rows are hidden the same way Select hides them.
+//
 // # What SelectIter does not do
 //
+// The relations of Preload are not filled: their queries would need the connection the rows hold
+// until the iteration ends. Use Select, or SelectAfter to read a large table in batches, instead.
+//
 // Server-side cursors are deliberately not part of this API. pgx already streams query
 // results row-by-row over the wire as rows.Next() is called; it does not buffer the entire
 // result set in memory before SelectIter (or Select) ever sees the first row, which is the
@@ -105,7 +110,13 @@ func (repo *Repository[T]) SelectIter(ctx context.Context, query string, args ..
 	return func(yield func(T, error) bool) {
 		var zero T
 
-		rows, err := repo.db.Query(ctx, query, args...)
+		query, args, err := repo.scoped(ctx, query, args)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		rows, err := repo.db.queryRead(ctx, query, args...)
 		if err != nil {
 			yield(zero, err)
 			return
@@ -148,7 +159,7 @@ func (db *DB) QueryIter[T any](ctx context.Context, query string, args ...any) i
 	return func(yield func(T, error) bool) {
 		var zero T
 
-		rows, err := db.Query(ctx, query, args...)
+		rows, err := db.queryRead(ctx, query, args...)
 		if err != nil {
 			yield(zero, err)
 			return
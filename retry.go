@@ -206,13 +206,20 @@ func (db *DB) beginTx(ctx context.Context, opts pgx.TxOptions) (*DB, error) {
 	if db.tx != nil {
 		tx, err = db.tx.Begin(ctx)
 	} else {
-		tx, err = db.Pool.BeginTx(ctx, opts)
+		tx, err = db.Pool.BeginTx(db.poolContext(ctx), opts)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	txDB := db.clone(tx)
+	if db.tx == nil {
+		if err = db.setLocalSettings(ctx, tx); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+
+	txDB := db.cloneTx(tx)
 	return txDB, nil
 }
 
@@ -328,11 +335,6 @@ func (repo *Repository[T]) InTransactionRetry(ctx context.Context, opts RetryOpt
 	}
 
 	return repo.db.InTransactionRetry(ctx, opts, func(db *DB) error {
-		txRepo := &Repository[T]{
-			db: db,
-			td: repo.td,
-		}
-
-		return fn(txRepo)
+		return fn(repo.withDB(db))
 	})
 }
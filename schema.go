@@ -12,7 +12,7 @@ import (
 
 // Schema is a type that represents a schema for the database.
 type Schema struct {
-	// mu guards structCache, orderedTypes and tableNameCache against concurrent
+	// mu guards structCache, orderedTypes, tableNameCache and enums against concurrent
 	// Register calls and concurrent reads (Get, GetByTableName, Tables, Last, etc).
 	mu sync.RWMutex
 
@@ -26,6 +26,10 @@ type Schema struct {
 	// lookup instead of a linear scan over structCache. Access must be
 	// guarded by mu.
 	tableNameCache map[string]*desc.Table
+	// enums holds the enum types registered by RegisterEnum or declared
+	// by the registered tables' columns, in registration order.
+	// Access must be guarded by mu.
+	enums []*desc.Enum
 
 	passwordHandler *desc.PasswordHandler // cache for tables.
 	// The name of the "updated_at" column. Defaults to "updated_at" but it can be modified,
@@ -41,6 +45,16 @@ type Schema struct {
 	// Strict reports whether the schema should be strict on the database side.
 	// It's enabled by default.
 	Strict bool
+
+	// TenantRowLevelSecurity enables the PostgreSQL row-level security policies of the tables with a
+	// tenant column (see WithTenant), as a safety net for the raw queries which are not scoped to the
+	// tenant: CreateSchema creates the policies, every transaction sets the TenantSetting run-time
+	// parameter to the tenant of its context (SET LOCAL) and so do the connections of the pools
+	// opened by Open, for the queries outside of a transaction. It must be set before Open.
+	//
+	// The policies apply to the owner of the tables too: a query of those tables without a tenant
+	// in its context sees no rows, use a role with BYPASSRLS for administrative tasks.
+	TenantRowLevelSecurity bool
 }
 
 // NewSchema creates and returns a new Schema with an initialized struct cache.
@@ -116,6 +130,56 @@ var Presenter = func(td *desc.Table) bool {
 	return true
 }
 
+// WithIndexes returns a TableFilterFunc which declares the given table-level indexes of the table,
+// as an alternative to the TableIndexer interface. CreateSchema creates them and CheckSchema verifies them.
+//
+// Example:
+//
+//	schema.MustRegister("customers", Customer{}, pg.WithIndexes(
+//		pg.TableIndex{Columns: []string{"tenant_id", "created_at DESC"}, Include: []string{"email"}},
+//		pg.TableIndex{Name: "customers_email_lower_idx", Columns: []string{"lower(email)"}, Unique: true, Where: "deleted_at IS NULL"},
+//		pg.TableIndex{Columns: []string{"tags"}, Method: desc.Gin},
+//	))
+func WithIndexes(indexes ...TableIndex) TableFilterFunc {
+	return func(td *desc.Table) bool {
+		td.AddTableIndexes(indexes...)
+		return true
+	}
+}
+
+// WithConstraints returns a TableFilterFunc which declares the given table-level CHECK and EXCLUDE constraints
+// of the table, as an alternative to the TableConstrainer interface. CreateSchema creates them and CheckSchema verifies them.
+//
+// Example:
+//
+//	schema.MustRegister("bookings", Booking{}, pg.WithConstraints(
+//		pg.TableConstraint{Name: "bookings_period_check", Check: "starts_at < ends_at"},
+//		pg.TableConstraint{
+//			Name:    "bookings_no_overlap",
+//			Exclude: []pg.ExclusionElement{{Column: "room_id", Operator: "="}, {Column: "during", Operator: "&&"}},
+//		},
+//	))
+func WithConstraints(constraints ...TableConstraint) TableFilterFunc {
+	return func(td *desc.Table) bool {
+		td.AddTableConstraints(constraints...)
+		return true
+	}
+}
+
+// WithDescription returns a TableFilterFunc which sets the description of the table.
+// CreateSchema writes it as the table's comment, COMMENT ON TABLE, and CheckSchema compares it
+// with the database one. Columns declare theirs through the `desc` struct tag option.
+//
+// Example:
+//
+//	schema.MustRegister("customers", Customer{}, pg.WithDescription("The customers of the shop"))
+func WithDescription(description string) TableFilterFunc {
+	return func(td *desc.Table) bool {
+		td.Description = description
+		return true
+	}
+}
+
 // MustRegister same as "Register" but it panics on errors and returns the Schema instance instead of the Table one.
 func (s *Schema) MustRegister(tableName string, emptyStructValue any, opts ...TableFilterFunc) *Schema {
 	td, err := s.Register(tableName, emptyStructValue, opts...) // call Register with the same arguments
@@ -138,14 +202,26 @@ func (s *Schema) MustRegister(tableName string, emptyStructValue any, opts ...Ta
 func (s *Schema) Register(tableName string, emptyStructValue any, opts ...TableFilterFunc) (*desc.Table, error) {
 	typ := desc.IndirectType(reflect.TypeOf(emptyStructValue)) // get the underlying type of the struct value
 
-	td, err := desc.ConvertStructToTable(tableName, typ) // convert the type to a table definition
-	if err != nil {                                      // if there is an error
+	s.mu.RLock()
+	enums := slices.Clone(s.enums)
+	s.mu.RUnlock()
+
+	td, err := desc.ConvertStructToTable(tableName, typ, enums...) // convert the type to a table definition
+	if err != nil {                                                // if there is an error
 		return nil, err // return the error
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// collect the enum types declared by the table's Go field types.
+	tableEnums := td.Enums()
+	for _, e := range tableEnums {
+		if err = s.checkEnum(e); err != nil {
+			return nil, fmt.Errorf("%s: %w", tableName, err)
+		}
+	}
+
 	td.RegisteredPosition = len(s.structCache) + 1 // assign the registered position as the current size of the cache plus one
 	td.PasswordHandler = s.passwordHandler
 
@@ -155,13 +231,91 @@ func (s *Schema) Register(tableName string, emptyStructValue any, opts ...TableF
 		}
 	}
 
+	// validate the table indexes, constraints and partitioning added by the options,
+	// e.g. WithIndexes, WithConstraints and PartitionByRange.
+	if err = td.ValidateTableIndexes(); err != nil {
+		return nil, err
+	}
+
+	if err = td.ValidateTableConstraints(); err != nil {
+		return nil, err
+	}
+
+	if err = td.ValidatePartitionBy(); err != nil {
+		return nil, err
+	}
+
 	s.structCache[typ] = td // store the table definition in the cache with the type as the key
 	s.orderedTypes = append(s.orderedTypes, typ)
 	s.tableNameCache[td.Name] = td // keep the by-table-name lookup cache in sync
 
+	for _, e := range tableEnums {
+		if !slices.ContainsFunc(s.enums, func(registered *desc.Enum) bool { return registered.Name == e.Name }) {
+			s.enums = append(s.enums, e)
+		}
+	}
+
 	return td, nil // return the table definition and no error
 }
 
+// MustRegisterEnum same as "RegisterEnum" but it panics on errors and returns the Schema instance.
+func (s *Schema) MustRegisterEnum(name string, values ...string) *Schema {
+	if _, err := s.RegisterEnum(name, values...); err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// RegisterEnum registers a PostgreSQL enum type of the given name and values (labels, in their sort order).
+// CreateSchema creates it and CheckSchema compares its values with the database ones.
+//
+// Columns refer to it by their `type` struct tag option, e.g. pg:"type=status",
+// so it must be registered before the tables which use it. A Go string type which has a Values method,
+// e.g. func (Status) Values() []Status, declares its enum type on Register without a RegisterEnum call.
+//
+// Registering the same enum twice is allowed as long as its values are the same.
+func (s *Schema) RegisterEnum(name string, values ...string) (*desc.Enum, error) {
+	e, err := desc.NewEnum(name, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = s.checkEnum(e); err != nil {
+		return nil, err
+	}
+
+	if idx := slices.IndexFunc(s.enums, func(registered *desc.Enum) bool { return registered.Name == name }); idx != -1 {
+		return s.enums[idx], nil
+	}
+
+	s.enums = append(s.enums, e)
+	return e, nil
+}
+
+// checkEnum reports an error if an enum of the same name but different values is already registered.
+// The caller must hold mu.
+func (s *Schema) checkEnum(e *desc.Enum) error {
+	for _, registered := range s.enums {
+		if registered.Name == e.Name && !registered.Equal(e) {
+			return fmt.Errorf("enum %s is already registered with different values: %v vs %v", e.Name, registered.Values, e.Values)
+		}
+	}
+
+	return nil
+}
+
+// Enums returns the registered enum types, in registration order.
+func (s *Schema) Enums() []*desc.Enum {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.enums)
+}
+
 // Last returns the last registered table definition.
 func (s *Schema) Last() *desc.Table {
 	s.mu.RLock()
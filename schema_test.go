@@ -1,10 +1,14 @@
 package pg
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
+
+	"github.com/kataras/pg/desc"
 )
 
 // TestSchemaConcurrent registers many distinct struct types concurrently while
@@ -147,3 +151,149 @@ func TestSchemaGetByTableNameFound(t *testing.T) {
 		t.Fatalf("expected table name %q, got %q", "customers", td.Name)
 	}
 }
+
+type schemaTestTicketStatus string
+
+func (schemaTestTicketStatus) Values() []schemaTestTicketStatus {
+	return []schemaTestTicketStatus{"open", "closed"}
+}
+
+// TestSchemaRegisterEnum verifies that enums registered through RegisterEnum and the ones
+// declared by the Go types of the registered tables are collected once, in order, and
+// created before the tables which use them.
+func TestSchemaRegisterEnum(t *testing.T) {
+	type ticket struct {
+		ID       int64                  `pg:"type=bigserial,primary"`
+		Status   schemaTestTicketStatus `pg:"type=ticket_status"`
+		Priority string                 `pg:"type=priority"`
+	}
+
+	schema := NewSchema()
+	schema.SetTimestampTriggerName = "" // no triggers, so the dump does not need a connection.
+	schema.MustRegisterEnum("priority", "low", "high").
+		MustRegisterEnum("priority", "low", "high"). // same values: no-op.
+		MustRegister("tickets", ticket{})
+
+	if _, err := schema.RegisterEnum("priority", "high", "low"); err == nil {
+		t.Fatal("expected an error for an enum registered with different values")
+	}
+
+	enums := schema.Enums()
+	if len(enums) != 2 || enums[0].Name != "priority" || enums[1].Name != "ticket_status" {
+		t.Fatalf("unexpected enums: %#v", enums)
+	}
+
+	db := &DB{schema: schema, searchPath: "public"}
+	dump, err := db.CreateSchemaDumpSQL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enumIndex := strings.Index(dump, "CREATE TYPE ticket_status AS ENUM ('open', 'closed')")
+	tableIndex := strings.Index(dump, `"status" ticket_status NOT NULL`)
+	if enumIndex == -1 || tableIndex == -1 || enumIndex > tableIndex {
+		t.Fatalf("expected the enum type to be created before the table:\n%s", dump)
+	}
+}
+
+func TestSchemaWithIndexes(t *testing.T) {
+	type event struct {
+		ID        int64  `pg:"type=bigserial,primary"`
+		TenantID  int64  `pg:"type=bigint"`
+		Name      string `pg:"type=text"`
+		Payload   any    `pg:"type=jsonb"`
+		CreatedAt string `pg:"type=timestamp"`
+	}
+
+	schema := NewSchema()
+	schema.SetTimestampTriggerName = "" // no triggers, so the dump does not need a connection.
+	schema.MustRegister("events", event{}, WithIndexes(
+		TableIndex{Columns: []string{"tenant_id", "created_at"}, Where: "name <> ''"},
+		TableIndex{Columns: []string{"payload"}, Method: desc.Gin},
+	))
+
+	if _, err := schema.Register("events_invalid", event{}, WithIndexes(TableIndex{Columns: []string{"missing"}})); err == nil {
+		t.Fatal("expected an error for an index of a missing column")
+	}
+
+	db := &DB{schema: schema, searchPath: "public"}
+	dump, err := db.CreateSchemaDumpSQL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`CREATE INDEX IF NOT EXISTS events_tenant_id_created_at_idx ON events USING btree ("tenant_id", "created_at") WHERE name <> '';`,
+		`CREATE INDEX IF NOT EXISTS events_payload_idx ON events USING gin ("payload");`,
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Fatalf("expected the dump to contain:\n%s\nbut got:\n%s", expected, dump)
+		}
+	}
+}
+
+func TestSchemaWithDescription(t *testing.T) {
+	type customer struct {
+		ID    int64  `pg:"type=bigserial,primary"`
+		Email string `pg:"type=text,desc=The email address, in lowercase"`
+	}
+
+	schema := NewSchema()
+	schema.SetTimestampTriggerName = "" // no triggers, so the dump does not need a connection.
+	schema.MustRegister("customers", customer{}, WithDescription("The customers of the shop"))
+
+	db := &DB{schema: schema, searchPath: "public"}
+	dump, err := db.CreateSchemaDumpSQL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`COMMENT ON TABLE customers IS 'The customers of the shop';`,
+		`COMMENT ON COLUMN customers."email" IS 'The email address, in lowercase';`,
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Fatalf("expected the dump to contain:\n%s\nbut got:\n%s", expected, dump)
+		}
+	}
+}
+
+func TestSchemaWithConstraints(t *testing.T) {
+	type booking struct {
+		ID       int64  `pg:"type=bigserial,primary"`
+		RoomID   int64  `pg:"type=bigint"`
+		During   string `pg:"type=tstzrange"`
+		StartsAt string `pg:"type=timestamp"`
+		EndsAt   string `pg:"type=timestamp"`
+	}
+
+	schema := NewSchema()
+	schema.SetTimestampTriggerName = "" // no triggers, so the dump does not need a connection.
+	schema.MustRegister("bookings", booking{}, WithConstraints(
+		TableConstraint{Name: "bookings_period_check", Check: "starts_at < ends_at"},
+		TableConstraint{
+			Name:    "bookings_no_overlap",
+			Exclude: []ExclusionElement{{Column: "room_id", Operator: "="}, {Column: "during", Operator: "&&"}},
+		},
+	))
+
+	if _, err := schema.Register("bookings_invalid", booking{}, WithConstraints(TableConstraint{Name: "bookings_invalid_check"})); err == nil {
+		t.Fatal("expected an error for a constraint without a check expression or exclusion elements")
+	}
+
+	db := &DB{schema: schema, searchPath: "public"}
+	dump, err := db.CreateSchemaDumpSQL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`CREATE EXTENSION IF NOT EXISTS btree_gist;`,
+		`CONSTRAINT bookings_period_check CHECK (starts_at < ends_at)`,
+		`CONSTRAINT bookings_no_overlap EXCLUDE USING gist ("room_id" WITH =, "during" WITH &&)`,
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Fatalf("expected the dump to contain:\n%s\nbut got:\n%s", expected, dump)
+		}
+	}
+}
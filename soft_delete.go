@@ -0,0 +1,392 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/kataras/pg/desc"
+)
+
+// This file adds soft-delete support to Repository. A table opts in by tagging a nullable time
+// column with `soft_delete`, e.g.
+//
+//	DeletedAt *time.Time `pg:"type=timestamp,soft_delete"`
+//
+// Once it has one, Repository.Delete/DeleteByID, DB.Delete, DB.DeleteByID and DB.DeleteBy set
+// that column to now() instead of removing the row, and Repository.Select, SelectSingle,
+// SelectByID, SelectIter, Exists, Count, SelectPaginated and SelectWithTotal (as well as
+// DB.SelectByID, DB.Exists, DB.ExistsBy and DB.CountBy) hide the soft-deleted rows. Repository.WithDeleted and
+// Repository.OnlyDeleted change what the reads of the returned repository see, Repository.Restore
+// brings a soft-deleted row back and Repository.HardDelete removes a row for good.
+//
+// Query, QueryRow, Exec and the other raw helpers are never rewritten.
+
+// WithDeleted returns a shallow copy of the repository whose read methods also see soft-deleted rows.
+// It makes no difference on tables without a soft_delete column.
+func (repo *Repository[T]) WithDeleted() *Repository[T] {
+	r := *repo
+	r.scope = desc.SoftDeleteScopeAll
+	return &r
+}
+
+// OnlyDeleted returns a shallow copy of the repository whose read methods see only soft-deleted rows.
+// It makes no difference on tables without a soft_delete column.
+func (repo *Repository[T]) OnlyDeleted() *Repository[T] {
+	r := *repo
+	r.scope = desc.SoftDeleteScopeDeleted
+	return &r
+}
+
+// Restore clears the soft-delete column of the row with the given primary key value
+// and reports whether a soft-deleted row was restored.
+// It returns an error if the table has no soft_delete column.
+func (repo *Repository[T]) Restore(ctx context.Context, id any) (bool, error) {
+	if repo.IsReadOnly() {
+		return false, ErrIsReadOnly
+	}
+
+	return repo.db.restoreByID(ctx, repo.td, id)
+}
+
+// HardDelete permanently removes the row with the given primary key value, even if the table
+// has a soft_delete column, and reports whether the row was removed.
+func (repo *Repository[T]) HardDelete(ctx context.Context, id any) (bool, error) {
+	if repo.IsReadOnly() {
+		return false, ErrIsReadOnly
+	}
+
+	return repo.db.hardDeleteByID(ctx, repo.td, id)
+}
+
+func (db *DB) restoreByID(ctx context.Context, td *desc.Table, id any) (bool, error) {
+	softDeleteCol, ok := td.SoftDeleteColumn()
+	if !ok {
+		return false, fmt.Errorf("no soft_delete column found in table definition: %s", td.Name)
+	}
+
+	where, args, err := primaryKeyWhere(td, id)
+	if err != nil {
+		return false, err
+	}
+
+	where, args, err = andTenant(ctx, td, where, args)
+	if err != nil {
+		return false, err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s.%s SET %s = NULL%s;`,
+		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), QuoteIdentifier(softDeleteCol.Name),
+		andCondition(where, td.SoftDeleteCondition(desc.SoftDeleteScopeDeleted)))
+	tag, err := db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (db *DB) hardDeleteByID(ctx context.Context, td *desc.Table, id any) (bool, error) {
+	where, args, err := primaryKeyWhere(td, id)
+	if err != nil {
+		return false, err
+	}
+
+	where, args, err = andTenant(ctx, td, where, args)
+	if err != nil {
+		return false, err
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s.%s%s;`,
+		QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), where)
+	tag, err := db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// scopeTable rewrites a caller-supplied query so that every unqualified reference to the
+// table resolves to its rows that match the given condition, e.g. the one of a soft-delete scope
+// and the tenant of the context. It does so by prepending a common table expression with the
+// same name as the table, which shadows the table for the rest of the statement:
+//
+//	WITH "users" AS (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL) SELECT * FROM users WHERE ...
+//
+// A query that already starts with WITH (or WITH RECURSIVE) gets the expression prepended to its
+// own list; it fails if that list already defines a query with the name of the table. The CTE is
+// referenced once in the common case, so PostgreSQL inlines it and the plan is the same as a
+// hand-written "deleted_at IS NULL" condition. Schema-qualified references (e.g. "public"."users")
+// are not rewritten.
+//
+// PostgreSQL rejects a locking clause (e.g. FOR UPDATE) on a CTE, so a query with one gets every
+// reference to the table after FROM or JOIN replaced by a subquery instead, under the same name
+// unless the query gives it an alias, and a locking clause locks the rows of its table:
+//
+//	SELECT * FROM (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL) AS "users" WHERE id = $1 FOR UPDATE
+//
+// It fails if such a query references the table anywhere else, e.g. in a comma-separated FROM list.
+//
+// The query is returned as it is when the condition is empty.
+func (db *DB) scopeTable(td *desc.Table, condition string, query string) (string, error) {
+	if condition == "" {
+		return query, nil
+	}
+
+	tokens := tokenizeQuery(query)
+	if definesQuery(tokens, td.Name) {
+		return "", fmt.Errorf("scope table: %s: the WITH query of the same name would be shadowed by the scope of the table, rename it", td.Name)
+	}
+
+	source := fmt.Sprintf(`SELECT * FROM %s.%s WHERE %s`, QuoteIdentifier(db.searchPath), QuoteIdentifier(td.Name), condition)
+
+	if hasLockingClause(tokens) {
+		return scopeTableReferences(td, "("+source+")", query, tokens)
+	}
+
+	cte := QuoteIdentifier(td.Name) + " AS (" + source + ")"
+
+	if rest, ok := cutKeyword(query, "WITH"); ok {
+		if recursiveRest, ok := cutKeyword(rest, "RECURSIVE"); ok {
+			return "WITH RECURSIVE " + cte + ", " + recursiveRest, nil
+		}
+
+		return "WITH " + cte + ", " + rest, nil
+	}
+
+	return "WITH " + cte + " " + query, nil
+}
+
+// scopeTableReferences replaces every reference to the table after FROM or JOIN with the subquery,
+// see scopeTable.
+func scopeTableReferences(td *desc.Table, subquery string, query string, tokens []queryToken) (string, error) {
+	var (
+		b    strings.Builder
+		last int
+	)
+	for i, token := range tokens {
+		if !token.isTable(td.Name) || (i > 0 && tokens[i-1].text == ".") || (i+1 < len(tokens) && tokens[i+1].text == ".") {
+			continue // a schema-qualified table or a column qualified by the table name.
+		}
+
+		if inLockedTables(tokens, i) {
+			continue // e.g. FOR UPDATE OF users, the name of the subquery.
+		}
+
+		if i == 0 || !tokens[i-1].isKeyword("from", "join") {
+			return "", fmt.Errorf("scope table: %s: a query with a locking clause must reference the table right after FROM or JOIN", td.Name)
+		}
+
+		b.WriteString(query[last:token.start])
+		b.WriteString(subquery)
+		if !hasAlias(tokens, i+1) {
+			b.WriteString(" AS " + QuoteIdentifier(td.Name))
+		}
+		last = token.end
+	}
+
+	b.WriteString(query[last:])
+	return b.String(), nil
+}
+
+// queryToken is a token of an SQL query, see tokenizeQuery.
+type queryToken struct {
+	text       string // a lowercased word, an unquoted identifier or a punctuation character.
+	word       bool   // an unquoted word, e.g. a keyword or an identifier.
+	quoted     bool   // a double-quoted identifier.
+	start, end int    // the position of the token in the query.
+}
+
+// isTable reports whether the token is a reference to the table name.
+func (t queryToken) isTable(name string) bool {
+	return (t.quoted && t.text == name) || (t.word && t.text == strings.ToLower(name))
+}
+
+// isKeyword reports whether the token is one of the given (lowercase) keywords.
+func (t queryToken) isKeyword(keywords ...string) bool {
+	return t.word && slices.Contains(keywords, t.text)
+}
+
+// tokenizeQuery splits a query into its words, double-quoted identifiers and punctuation,
+// skipping its string literals, dollar-quoted strings and comments.
+func tokenizeQuery(query string) []queryToken {
+	var tokens []queryToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '\'':
+			escapes := i > 0 && (query[i-1] == 'e' || query[i-1] == 'E') && (i == 1 || !isQueryWordChar(query[i-2])) // E'it\'s'.
+			i++
+			for i < len(query) {
+				if escapes && query[i] == '\\' {
+					i += 2
+					continue
+				}
+
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' { // an escaped quote.
+						i += 2
+						continue
+					}
+
+					break
+				}
+
+				i++
+			}
+			i++
+		case c == '"':
+			end := i + 1
+			var name strings.Builder
+			for end < len(query) {
+				if query[end] == '"' {
+					if end+1 < len(query) && query[end+1] == '"' {
+						name.WriteByte('"')
+						end += 2
+						continue
+					}
+
+					break
+				}
+
+				name.WriteByte(query[end])
+				end++
+			}
+			end = min(end+1, len(query))
+			tokens = append(tokens, queryToken{text: name.String(), quoted: true, start: i, end: end})
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end != -1 {
+				i += end + 1
+			} else {
+				i = len(query)
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end != -1 {
+				i += end + 4
+			} else {
+				i = len(query)
+			}
+		case c == '$' && dollarQuoteTag(query[i:]) != "":
+			tag := dollarQuoteTag(query[i:])
+			if end := strings.Index(query[i+len(tag):], tag); end != -1 {
+				i += len(tag) + end + len(tag)
+			} else {
+				i = len(query)
+			}
+		case isQueryWordChar(c):
+			end := i + 1
+			for end < len(query) && isQueryWordChar(query[end]) {
+				end++
+			}
+			tokens = append(tokens, queryToken{text: strings.ToLower(query[i:end]), word: true, start: i, end: end})
+			i = end
+		default:
+			tokens = append(tokens, queryToken{text: query[i : i+1], start: i, end: i + 1})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+func isQueryWordChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+// hasLockingClause reports whether the query has a FOR UPDATE, FOR NO KEY UPDATE, FOR SHARE
+// or FOR KEY SHARE clause, at any level.
+func hasLockingClause(tokens []queryToken) bool {
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].isKeyword("for") && tokens[i+1].isKeyword("update", "share", "no", "key") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// definesQuery reports whether the WITH list of the query defines a query with the given name.
+func definesQuery(tokens []queryToken, name string) bool {
+	if len(tokens) == 0 || !tokens[0].isKeyword("with") {
+		return false
+	}
+
+	depth := 0
+	for i, token := range tokens {
+		switch token.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+
+		if depth != 0 || i == 0 || i+1 == len(tokens) || !token.isTable(name) {
+			continue
+		}
+
+		if (tokens[i-1].isKeyword("with", "recursive") || tokens[i-1].text == ",") &&
+			(tokens[i+1].isKeyword("as") || tokens[i+1].text == "(") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inLockedTables reports whether the token at index i is in the table list of a locking clause,
+// e.g. FOR UPDATE OF orders, users.
+func inLockedTables(tokens []queryToken, i int) bool {
+	j := i - 1
+	for j >= 1 && tokens[j].text == "," {
+		j -= 2
+	}
+
+	return j >= 0 && tokens[j].isKeyword("of")
+}
+
+// tableAliasStopWords are the keywords which may follow a table reference without an alias.
+var tableAliasStopWords = []string{
+	"where", "join", "inner", "left", "right", "full", "cross", "natural", "on", "using", "group", "having",
+	"window", "order", "limit", "offset", "fetch", "for", "union", "intersect", "except", "tablesample",
+}
+
+// hasAlias reports whether the token at index i starts the alias of the table reference before it.
+func hasAlias(tokens []queryToken, i int) bool {
+	if i >= len(tokens) {
+		return false
+	}
+
+	token := tokens[i]
+	return token.quoted || (token.word && !slices.Contains(tableAliasStopWords, token.text))
+}
+
+// cutKeyword reports whether s, ignoring leading whitespace, starts with the given SQL keyword
+// (case-insensitively) followed by whitespace and, if so, returns the rest of s after it.
+func cutKeyword(s, keyword string) (string, bool) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if len(s) <= len(keyword) || !strings.EqualFold(s[:len(keyword)], keyword) || !unicode.IsSpace(rune(s[len(keyword)])) {
+		return "", false
+	}
+
+	return strings.TrimLeftFunc(s[len(keyword):], unicode.IsSpace), true
+}
+
+// andCondition appends condition to a (possibly empty) " WHERE ..." clause,
+// as returned by resolveWhere. An empty condition returns the clause as it is.
+func andCondition(where, condition string) string {
+	if condition == "" {
+		return where
+	}
+
+	if where == "" {
+		return " WHERE " + condition
+	}
+
+	return where + " AND " + condition
+}
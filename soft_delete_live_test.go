@@ -0,0 +1,181 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestRepositorySoftDelete' -v .
+
+type softDeleteItem struct {
+	ID        int64      `pg:"type=bigserial,primary"`
+	Name      string     `pg:"type=varchar(255)"`
+	DeletedAt *time.Time `pg:"type=timestamp,soft_delete"`
+}
+
+const softDeleteScratchTable = "test_soft_delete_items"
+
+func TestRepositorySoftDelete(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(softDeleteScratchTable, softDeleteItem{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, softDeleteScratchTable)
+	if _, err = db.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE %s (id BIGSERIAL PRIMARY KEY, name VARCHAR(255) NOT NULL, deleted_at TIMESTAMP NULL)",
+		softDeleteScratchTable)); err != nil {
+		t.Fatal(err)
+	}
+	defer dropTestTables(ctx, db, softDeleteScratchTable)
+
+	repo := NewRepository[softDeleteItem](db)
+	if err = repo.Insert(ctx, softDeleteItem{Name: "alpha"}, softDeleteItem{Name: "beta"}, softDeleteItem{Name: "gamma"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := repo.Select(ctx, "SELECT * FROM "+softDeleteScratchTable+" ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items but got: %d", len(items))
+	}
+
+	deleted, err := repo.DeleteByID(ctx, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted {
+		t.Fatal("expected the first item to be soft-deleted")
+	}
+
+	// a second soft-delete of the same row is a no-op.
+	if deleted, err = repo.DeleteByID(ctx, items[0].ID); err != nil || deleted {
+		t.Fatalf("expected no-op on an already soft-deleted row, got: %v, %v", deleted, err)
+	}
+
+	if _, err = db.DeleteBy(ctx, softDeleteScratchTable, "name", "beta"); err != nil {
+		t.Fatal(err)
+	}
+
+	total, err := repo.Count(ctx, "SELECT COUNT(*) FROM "+softDeleteScratchTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Fatalf("expected 1 visible row but got: %d", total)
+	}
+
+	if _, err = repo.SelectByID(ctx, items[0].ID); !IsErrNoRows(err) {
+		t.Fatalf("expected ErrNoRows for a soft-deleted row but got: %v", err)
+	}
+
+	exists, err := repo.Exists(ctx, softDeleteItem{ID: items[1].ID, Name: "beta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("expected a soft-deleted row to not exist")
+	}
+
+	page, pageTotal, err := repo.SelectPaginated(ctx, PageOptions{Limit: 10, OrderBy: `"id"`}, "SELECT * FROM "+softDeleteScratchTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageTotal != 1 || len(page) != 1 || page[0].Name != "gamma" {
+		t.Fatalf("expected only gamma on the page but got: %v (total %d)", page, pageTotal)
+	}
+
+	all, err := repo.WithDeleted().Select(ctx, "SELECT * FROM "+softDeleteScratchTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 rows with deleted but got: %d", len(all))
+	}
+
+	onlyDeleted, err := repo.OnlyDeleted().Select(ctx, "SELECT * FROM "+softDeleteScratchTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(onlyDeleted) != 2 {
+		t.Fatalf("expected 2 deleted rows but got: %d", len(onlyDeleted))
+	}
+	for _, item := range onlyDeleted {
+		if item.DeletedAt == nil {
+			t.Fatalf("expected deleted_at to be set on: %s", item.Name)
+		}
+	}
+
+	restored, err := repo.Restore(ctx, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored {
+		t.Fatal("expected the first item to be restored")
+	}
+
+	if _, err = repo.SelectByID(ctx, items[0].ID); err != nil {
+		t.Fatalf("expected the restored row to be visible but got: %v", err)
+	}
+
+	removed, err := repo.HardDelete(ctx, items[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !removed {
+		t.Fatal("expected the second item to be hard-deleted")
+	}
+
+	all, err = repo.WithDeleted().Select(ctx, "SELECT * FROM "+softDeleteScratchTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 rows after hard delete but got: %d", len(all))
+	}
+
+	// a locking read inside a transaction is scoped too, the read-then-update pattern.
+	if _, err = repo.DeleteByID(ctx, items[2].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	err = repo.InTransaction(ctx, func(repo *Repository[softDeleteItem]) error {
+		locked, err := repo.Select(ctx, "SELECT * FROM "+softDeleteScratchTable+" ORDER BY id FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		if len(locked) != 1 || locked[0].ID != items[0].ID {
+			return fmt.Errorf("expected only the restored row to be locked but got: %v", locked)
+		}
+
+		lockedDeleted, err := repo.OnlyDeleted().Select(ctx, "SELECT t.* FROM "+softDeleteScratchTable+" AS t WHERE t.id = $1 FOR SHARE OF t", items[2].ID)
+		if err != nil {
+			return err
+		}
+		if len(lockedDeleted) != 1 || lockedDeleted[0].Name != "gamma" {
+			return fmt.Errorf("expected the deleted gamma row to be locked but got: %v", lockedDeleted)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a WITH query named after the table cannot be scoped.
+	if _, err = repo.Select(ctx, "WITH "+softDeleteScratchTable+" AS (SELECT 1) SELECT * FROM "+softDeleteScratchTable); err == nil {
+		t.Fatal("expected an error for a WITH query named after the table")
+	}
+}
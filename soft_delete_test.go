@@ -0,0 +1,117 @@
+package pg
+
+import (
+	"testing"
+
+	"github.com/kataras/pg/desc"
+)
+
+func TestScopeSoftDeleted(t *testing.T) {
+	td := &desc.Table{Name: "users"}
+	td.AddColumns(
+		&desc.Column{Name: "id", Type: desc.BigInt, PrimaryKey: true},
+		&desc.Column{Name: "deleted_at", Type: desc.Timestamp, Nullable: true, SoftDelete: true},
+	)
+
+	db := &DB{searchPath: "public"}
+
+	tests := []struct {
+		scope    desc.SoftDeleteScope
+		query    string
+		expected string
+	}{
+		{
+			scope:    desc.SoftDeleteScopeActive,
+			query:    "SELECT * FROM users WHERE email = $1",
+			expected: `WITH "users" AS (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL) SELECT * FROM users WHERE email = $1`,
+		},
+		{
+			scope:    desc.SoftDeleteScopeDeleted,
+			query:    "SELECT COUNT(*) FROM users",
+			expected: `WITH "users" AS (SELECT * FROM "public"."users" WHERE "deleted_at" IS NOT NULL) SELECT COUNT(*) FROM users`,
+		},
+		{
+			scope:    desc.SoftDeleteScopeAll,
+			query:    "SELECT * FROM users",
+			expected: "SELECT * FROM users",
+		},
+		{
+			scope:    desc.SoftDeleteScopeActive,
+			query:    "  with recent AS (SELECT 1) SELECT * FROM users",
+			expected: `WITH "users" AS (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL), recent AS (SELECT 1) SELECT * FROM users`,
+		},
+		{
+			scope:    desc.SoftDeleteScopeActive,
+			query:    "WITH RECURSIVE tree AS (SELECT 1) SELECT * FROM users",
+			expected: `WITH RECURSIVE "users" AS (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL), tree AS (SELECT 1) SELECT * FROM users`,
+		},
+		{
+			scope:    desc.SoftDeleteScopeActive,
+			query:    "WITHOUT_PREFIX",
+			expected: `WITH "users" AS (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL) WITHOUT_PREFIX`,
+		},
+		{
+			scope:    desc.SoftDeleteScopeActive,
+			query:    "SELECT * FROM users WHERE id = $1 FOR UPDATE",
+			expected: `SELECT * FROM (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL) AS "users" WHERE id = $1 FOR UPDATE`,
+		},
+		{
+			scope:    desc.SoftDeleteScopeActive,
+			query:    "SELECT u.* FROM Users u JOIN orders o ON o.user_id = u.id WHERE o.note = 'from users' FOR NO KEY UPDATE OF u, users",
+			expected: `SELECT u.* FROM (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL) u JOIN orders o ON o.user_id = u.id WHERE o.note = 'from users' FOR NO KEY UPDATE OF u, users`,
+		},
+		{
+			scope:    desc.SoftDeleteScopeActive,
+			query:    `SELECT users.id FROM "users" JOIN public.users AS p ON p.id = users.id FOR SHARE`,
+			expected: `SELECT users.id FROM (SELECT * FROM "public"."users" WHERE "deleted_at" IS NULL) AS "users" JOIN public.users AS p ON p.id = users.id FOR SHARE`,
+		},
+	}
+
+	for i, tt := range tests {
+		got, err := db.scopeTable(td, td.SoftDeleteCondition(tt.scope), tt.query)
+		if err != nil {
+			t.Fatalf("[%d] %v", i, err)
+		}
+
+		if got != tt.expected {
+			t.Fatalf("[%d] expected:\n%s\nbut got:\n%s", i, tt.expected, got)
+		}
+	}
+
+	// Queries that cannot be scoped fail instead of reading unscoped rows.
+	invalid := []string{
+		"WITH users AS (SELECT 1) SELECT * FROM users",
+		`WITH recent AS (SELECT 1), "users"(id) AS (SELECT 1) SELECT * FROM users`,
+		"SELECT * FROM orders, users WHERE orders.user_id = users.id FOR UPDATE",
+	}
+
+	for i, query := range invalid {
+		if _, err := db.scopeTable(td, td.SoftDeleteCondition(desc.SoftDeleteScopeActive), query); err == nil {
+			t.Fatalf("[%d] expected an error for: %s", i, query)
+		}
+	}
+
+	// A table without a soft-delete column is never rewritten.
+	plain := &desc.Table{Name: "posts"}
+	plain.AddColumns(&desc.Column{Name: "id", Type: desc.BigInt, PrimaryKey: true})
+	if got, err := db.scopeTable(plain, plain.SoftDeleteCondition(desc.SoftDeleteScopeActive), "SELECT * FROM posts"); err != nil || got != "SELECT * FROM posts" {
+		t.Fatalf("expected the query to be unchanged but got: %s (%v)", got, err)
+	}
+}
+
+func TestAndCondition(t *testing.T) {
+	tests := []struct {
+		where, condition, expected string
+	}{
+		{"", "", ""},
+		{" WHERE a = $1", "", " WHERE a = $1"},
+		{"", `"deleted_at" IS NULL`, ` WHERE "deleted_at" IS NULL`},
+		{" WHERE a = $1", `"deleted_at" IS NULL`, ` WHERE a = $1 AND "deleted_at" IS NULL`},
+	}
+
+	for i, tt := range tests {
+		if got := andCondition(tt.where, tt.condition); got != tt.expected {
+			t.Fatalf("[%d] expected: %q but got: %q", i, tt.expected, got)
+		}
+	}
+}
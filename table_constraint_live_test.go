@@ -0,0 +1,111 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestTableConstraintsSchema' -v .
+
+type tableConstraintLiveBooking struct {
+	ID       string    `pg:"type=uuid,primary"`
+	RoomID   int64     `pg:"type=bigint"`
+	During   string    `pg:"type=tstzrange"`
+	StartsAt time.Time `pg:"type=timestamp"`
+	EndsAt   time.Time `pg:"type=timestamp"`
+}
+
+func (tableConstraintLiveBooking) TableConstraints() []TableConstraint {
+	return []TableConstraint{
+		{
+			Name:    "test_con_bookings_no_overlap",
+			Exclude: []ExclusionElement{{Column: "room_id", Operator: "="}, {Column: "during", Operator: "&&"}},
+		},
+	}
+}
+
+const tableConstraintScratchTable = "test_con_bookings"
+
+func TestTableConstraintsSchema(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(tableConstraintScratchTable, tableConstraintLiveBooking{}, WithConstraints(
+		TableConstraint{Name: "test_con_bookings_period_check", Check: "starts_at < ends_at"},
+	))
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, tableConstraintScratchTable)
+	defer dropTestTables(ctx, db, tableConstraintScratchTable)
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.CheckSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	startsAt := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	booking := tableConstraintLiveBooking{
+		RoomID:   1,
+		During:   "[2026-01-01 10:00:00+00,2026-01-01 12:00:00+00)",
+		StartsAt: startsAt,
+		EndsAt:   startsAt.Add(2 * time.Hour),
+	}
+
+	repo := NewRepository[tableConstraintLiveBooking](db)
+	if err = repo.InsertSingle(ctx, booking, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// an overlapping booking of the same room is rejected.
+	overlapping := booking
+	overlapping.During = "[2026-01-01 11:00:00+00,2026-01-01 13:00:00+00)"
+	if err = repo.InsertSingle(ctx, overlapping, nil); err == nil {
+		t.Fatal("expected an exclusion violation error")
+	}
+
+	// but not one of another room.
+	overlapping.RoomID = 2
+	if err = repo.InsertSingle(ctx, overlapping, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// an invalid period is rejected.
+	invalid := booking
+	invalid.RoomID, invalid.EndsAt = 3, invalid.StartsAt
+	if err = repo.InsertSingle(ctx, invalid, nil); err == nil {
+		t.Fatal("expected a check violation error")
+	}
+
+	// a changed definition is reported.
+	if _, err = db.Exec(ctx, `ALTER TABLE test_con_bookings DROP CONSTRAINT test_con_bookings_period_check;
+ALTER TABLE test_con_bookings ADD CONSTRAINT test_con_bookings_period_check CHECK (starts_at <= ends_at);`); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.CheckSchema(ctx)
+	if err == nil || !strings.Contains(err.Error(), `constraint "test_con_bookings_period_check" in table "test_con_bookings" has wrong definition`) {
+		t.Fatalf("expected a constraint definition mismatch error but got: %v", err)
+	}
+
+	// a missing constraint is reported.
+	if _, err = db.Exec(ctx, `ALTER TABLE test_con_bookings DROP CONSTRAINT test_con_bookings_period_check;`); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.CheckSchema(ctx)
+	if err == nil || !strings.Contains(err.Error(), `constraint "test_con_bookings_period_check" in table "test_con_bookings" not found in database`) {
+		t.Fatalf("expected a missing constraint error but got: %v", err)
+	}
+}
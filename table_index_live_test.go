@@ -0,0 +1,97 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kataras/pg/desc"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestTableIndexesSchema' -v .
+
+type tableIndexLiveCustomer struct {
+	ID        string     `pg:"type=uuid,primary"`
+	TenantID  int64      `pg:"type=bigint"`
+	Email     string     `pg:"type=varchar(255)"`
+	Tags      []string   `pg:"type=text[]"`
+	DeletedAt *time.Time `pg:"type=timestamp,nullable"`
+}
+
+func (tableIndexLiveCustomer) TableIndexes() []TableIndex {
+	return []TableIndex{
+		{Name: "test_idx_customers_email_lower_idx", Columns: []string{"lower(email)"}, Unique: true, Where: "deleted_at IS NULL"},
+		{Columns: []string{"tags"}, Method: desc.Gin},
+	}
+}
+
+const tableIndexScratchTable = "test_idx_customers"
+
+func TestTableIndexesSchema(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(tableIndexScratchTable, tableIndexLiveCustomer{}, WithIndexes(
+		TableIndex{Columns: []string{"tenant_id", "email DESC"}, Include: []string{"deleted_at"}},
+	))
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, tableIndexScratchTable)
+	defer dropTestTables(ctx, db, tableIndexScratchTable)
+
+	if err = db.CreateSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.CheckSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	indexes, err := db.ListIndexes(ctx, tableIndexScratchTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(indexes) != 3 {
+		t.Fatalf("expected 3 indexes but got: %d", len(indexes))
+	}
+
+	repo := NewRepository[tableIndexLiveCustomer](db)
+	if err = repo.InsertSingle(ctx, tableIndexLiveCustomer{TenantID: 1, Email: "Me@example.com"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the unique expression index is case-insensitive.
+	if err = repo.InsertSingle(ctx, tableIndexLiveCustomer{TenantID: 1, Email: "me@example.com"}, nil); err == nil {
+		t.Fatal("expected a unique violation error")
+	}
+
+	// a changed predicate is reported.
+	if _, err = db.Exec(ctx, `DROP INDEX test_idx_customers_email_lower_idx;
+CREATE UNIQUE INDEX test_idx_customers_email_lower_idx ON test_idx_customers (lower(email));`); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.CheckSchema(ctx)
+	if err == nil || !strings.Contains(err.Error(), `index "test_idx_customers_email_lower_idx" in table "test_idx_customers" has wrong definition`) {
+		t.Fatalf("expected an index definition mismatch error but got: %v", err)
+	}
+
+	// a missing index is reported.
+	if _, err = db.Exec(ctx, `DROP INDEX test_idx_customers_email_lower_idx;`); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.CheckSchema(ctx)
+	if err == nil || !strings.Contains(err.Error(), `index "test_idx_customers_email_lower_idx" in table "test_idx_customers" not found in database`) {
+		t.Fatalf("expected a missing index error but got: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kataras/pg/desc"
+)
+
+// This file adds row-level multitenancy: tables shared by all tenants whose rows belong to one
+// of them. A table opts in by tagging its tenant column with `tenant`, e.g.
+//
+//	TenantID int64 `pg:"type=bigint,index,tenant"`
+//
+// Once it has one, every query the package builds for that table requires a context carrying the
+// tenant ID (see WithTenant) and is scoped to that tenant:
+//   - Repository.Select, SelectSingle, SelectIter, Count, SelectPaginated, SelectWithTotal, SelectAfter
+//     and Find (including its Conditions) see only the tenant's rows, the same way soft-deleted rows
+//     are hidden (see scopeTable); so do the Preload queries of the related tables,
+//   - SelectByID, SelectByUsernameAndPassword, Exists, ExistsBy and CountBy filter by the tenant column,
+//   - the Insert, Upsert, InsertOnConflict, CopyFrom and Batch.Insert methods fill the tenant column,
+//   - the Update, Delete, DeleteByID, DeleteBy, Restore, HardDelete and Duplicate methods match the tenant's rows only.
+//
+// A context without a tenant ID makes them fail with ErrMissingTenant and a value of another tenant
+// with ErrTenantMismatch. Query, QueryRow, Exec and the other raw helpers are never rewritten:
+// Schema.TenantRowLevelSecurity adds PostgreSQL row-level security policies as a safety net for those.
+
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of the context which carries the given tenant ID, e.g. the tenant of
+// the authenticated user of an HTTP request. The queries of the tables with a tenant column see
+// and modify the rows of that tenant only. The ID must be assignable to the Go type of the tenant
+// column fields, typically a string or an integer.
+func WithTenant(ctx context.Context, id any) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, id)
+}
+
+// TenantFromContext returns the tenant ID of a context returned by WithTenant.
+func TenantFromContext(ctx context.Context) (any, bool) {
+	id := ctx.Value(tenantContextKey{})
+	return id, id != nil
+}
+
+var (
+	// ErrMissingTenant is returned by the queries of a table with a tenant column
+	// when their context carries no tenant ID, see WithTenant.
+	ErrMissingTenant = errors.New("pg: tenant: missing tenant ID in the context, see WithTenant")
+	// ErrTenantMismatch is returned when a value to insert or update belongs to
+	// another tenant than the one of the context.
+	ErrTenantMismatch = errors.New("pg: tenant: the value belongs to another tenant")
+)
+
+// tenantOf returns the tenant ID of the context for a table with a tenant column,
+// or false if the table has none.
+func tenantOf(ctx context.Context, td *desc.Table) (any, bool, error) {
+	if _, ok := td.TenantColumn(); !ok {
+		return nil, false, nil
+	}
+
+	id, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: table: %s", ErrMissingTenant, td.Name)
+	}
+
+	return id, true, nil
+}
+
+// tenantCondition returns the condition which restricts the rows of td to the tenant of the context,
+// with the tenant ID appended to args as its bind parameter. For a table without a tenant column
+// it returns an empty condition and args as they are.
+func tenantCondition(ctx context.Context, td *desc.Table, args []any) (string, []any, error) {
+	id, ok, err := tenantOf(ctx, td)
+	if !ok || err != nil {
+		return "", args, err
+	}
+
+	args = append(args[:len(args):len(args)], id) // never write to the caller's array.
+	return td.TenantCondition(len(args)), args, nil
+}
+
+// andTenant appends the tenant condition (see tenantCondition) to a (possibly empty) " WHERE ..." clause.
+func andTenant(ctx context.Context, td *desc.Table, where string, args []any) (string, []any, error) {
+	condition, args, err := tenantCondition(ctx, td, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return andCondition(where, condition), args, nil
+}
+
+// bindTenant returns the struct value with its tenant field set to the tenant of the context,
+// a copy of it if it is not addressable. It returns ErrTenantMismatch if the field is already
+// set to another tenant. Values of tables without a tenant column are returned as they are.
+func bindTenant(ctx context.Context, td *desc.Table, structValue reflect.Value) (reflect.Value, error) {
+	id, ok, err := tenantOf(ctx, td)
+	if !ok || err != nil {
+		return structValue, err
+	}
+
+	c, _ := td.TenantColumn()
+	field := structValue.FieldByIndex(c.FieldIndex)
+
+	idValue := reflect.ValueOf(id)
+	if !idValue.Type().ConvertibleTo(field.Type()) {
+		return structValue, fmt.Errorf("pg: tenant: table: %s: the tenant ID of type %s is not convertible to %s", td.Name, idValue.Type(), field.Type())
+	}
+	idValue = idValue.Convert(field.Type())
+
+	if !field.IsZero() {
+		if !field.Equal(idValue) {
+			return structValue, fmt.Errorf("%w: table: %s: %v", ErrTenantMismatch, td.Name, field.Interface())
+		}
+
+		return structValue, nil
+	}
+
+	if !field.CanSet() {
+		copied := reflect.New(structValue.Type()).Elem()
+		copied.Set(structValue)
+		structValue = copied
+		field = structValue.FieldByIndex(c.FieldIndex)
+	}
+
+	field.Set(idValue)
+	return structValue, nil
+}
+
+// bindTenantValue is bindTenant for a struct value or a pointer to it. The pointer is kept as it is,
+// so that the query results (e.g. a new version) can be written back to it.
+func bindTenantValue(ctx context.Context, td *desc.Table, value any) (any, error) {
+	if _, ok := td.TenantColumn(); !ok {
+		return value, nil
+	}
+
+	structValue, err := bindTenant(ctx, td, desc.IndirectValue(value))
+	if err != nil {
+		return nil, err
+	}
+
+	if structValue.CanAddr() {
+		return structValue.Addr().Interface(), nil
+	}
+
+	return structValue.Interface(), nil
+}
+
+// bindTenants returns the struct values of the given values, each one bound to the tenant of the context
+// (see bindTenant), for the bulk insert builders.
+func bindTenants[T any](ctx context.Context, td *desc.Table, values []T) ([]reflect.Value, error) {
+	structValues := make([]reflect.Value, len(values))
+	for i := range values {
+		structValue, err := bindTenant(ctx, td, desc.IndirectValue(values[i]))
+		if err != nil {
+			return nil, err
+		}
+
+		structValues[i] = structValue
+	}
+
+	return structValues, nil
+}
+
+// TenantSetting is the name of the run-time parameter which holds the tenant ID of the context
+// (see WithTenant) for the row-level security policies of Schema.TenantRowLevelSecurity.
+const TenantSetting = "app.tenant_id"
+
+// createTenantPoliciesDump creates the row-level security policies of the tables with a tenant
+// column, if Schema.TenantRowLevelSecurity is enabled. The policies are FORCEd, so they apply to the
+// owner of the tables too: a row is visible and writable only if its tenant column equals the
+// TenantSetting run-time parameter.
+func (db *DB) createTenantPoliciesDump(_ context.Context, b *strings.Builder) error {
+	if !db.schema.TenantRowLevelSecurity {
+		return nil
+	}
+
+	for _, td := range db.schema.Tables(desc.TableTypeBase) {
+		c, ok := td.TenantColumn()
+		if !ok || td.IsReadOnly() {
+			continue
+		}
+
+		tableName := QuoteIdentifier(td.Name)
+		condition := fmt.Sprintf(`%s = NULLIF(current_setting('%s', true), '')::%s`, QuoteIdentifier(c.Name), TenantSetting, c.Type.String())
+
+		fmt.Fprintf(b, `ALTER TABLE %s ENABLE ROW LEVEL SECURITY;`, tableName)
+		fmt.Fprintf(b, `ALTER TABLE %s FORCE ROW LEVEL SECURITY;`, tableName)
+		fmt.Fprintf(b, `DROP POLICY IF EXISTS tenant_isolation ON %s;`, tableName)
+		fmt.Fprintf(b, `CREATE POLICY tenant_isolation ON %s USING (%s) WITH CHECK (%s);`, tableName, condition, condition)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestRepositoryTenantScope' -v .
+
+type tenantScopeLiveOrder struct {
+	ID       int64  `pg:"type=bigserial,primary"`
+	TenantID string `pg:"type=varchar(64),index,tenant"`
+	Note     string `pg:"type=text"`
+}
+
+const tenantScopeScratchTable = "test_tenant_scope_orders"
+
+func TestRepositoryTenantScope(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(tenantScopeScratchTable, tenantScopeLiveOrder{})
+	schema.TenantRowLevelSecurity = true
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, tenantScopeScratchTable)
+	defer dropTestTables(ctx, db, tenantScopeScratchTable)
+
+	if err = db.CreateSchema(ctx); err != nil { // including the row-level security policies.
+		t.Fatal(err)
+	}
+
+	repo := NewRepository[tenantScopeLiveOrder](db)
+
+	if err = repo.InsertSingle(ctx, tenantScopeLiveOrder{Note: "none"}, nil); !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("expected ErrMissingTenant but got: %v", err)
+	}
+
+	acme, globex := WithTenant(ctx, "acme"), WithTenant(ctx, "globex")
+
+	acmeOrder := tenantScopeLiveOrder{Note: "acme"}
+	if err = repo.InsertSingle(acme, acmeOrder, &acmeOrder.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repo.Insert(globex, tenantScopeLiveOrder{Note: "globex 1"}, tenantScopeLiveOrder{Note: "globex 2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repo.InsertSingle(acme, tenantScopeLiveOrder{TenantID: "globex"}, nil); !errors.Is(err, ErrTenantMismatch) {
+		t.Fatalf("expected ErrTenantMismatch but got: %v", err)
+	}
+
+	for tenantCtx, expected := range map[context.Context]int64{acme: 1, globex: 2} {
+		count, err := repo.Count(tenantCtx, "SELECT COUNT(*) FROM "+tenantScopeScratchTable)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if count != expected {
+			t.Fatalf("expected %d rows but got: %d", expected, count)
+		}
+	}
+
+	orders, err := repo.Find().Where(Where("note LIKE $1", "%")).All(globex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != 2 {
+		t.Fatalf("expected the 2 rows of globex but got: %v", orders)
+	}
+
+	// the rows of another tenant can not be read, updated or deleted.
+	if _, err = repo.SelectByID(globex, acmeOrder.ID); !errors.Is(err, ErrNoRows) {
+		t.Fatalf("expected ErrNoRows but got: %v", err)
+	}
+
+	if rowsAffected, err := repo.Update(globex, tenantScopeLiveOrder{ID: acmeOrder.ID, Note: "stolen"}); err != nil || rowsAffected != 0 {
+		t.Fatalf("expected no updated rows but got: %d: %v", rowsAffected, err)
+	}
+
+	if deleted, err := db.DeleteByID(globex, tenantScopeScratchTable, acmeOrder.ID); err != nil || deleted {
+		t.Fatalf("expected no deleted row but got: %v: %v", deleted, err)
+	}
+
+	found, err := repo.SelectByID(acme, acmeOrder.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found.Note != "acme" || found.TenantID != "acme" {
+		t.Fatalf("expected the untouched acme row but got: %#v", found)
+	}
+
+	// the tenant setting of the policies is set on the connections and the transactions.
+	var setting string
+	if err = db.QueryRow(acme, "SELECT current_setting($1, true)", TenantSetting).Scan(&setting); err != nil {
+		t.Fatal(err)
+	}
+
+	if setting != "acme" {
+		t.Fatalf("expected the %s setting acme but got: %q", TenantSetting, setting)
+	}
+
+	err = db.InTransaction(globex, func(tx *DB) error {
+		return tx.QueryRow(globex, "SELECT current_setting($1, true)", TenantSetting).Scan(&setting)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if setting != "globex" {
+		t.Fatalf("expected the %s setting globex but got: %q", TenantSetting, setting)
+	}
+}
@@ -0,0 +1,145 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tenantOrder struct {
+	ID        int64      `pg:"type=bigserial,primary"`
+	TenantID  int64      `pg:"type=bigint,index,tenant"`
+	Note      string     `pg:"type=text"`
+	DeletedAt *time.Time `pg:"type=timestamp,soft_delete"`
+}
+
+func TestTenantScope(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("orders", tenantOrder{})
+	db := &DB{schema: schema, searchPath: "public"}
+	repo := NewRepository[tenantOrder](db)
+
+	ctx := context.Background()
+	if _, _, err := repo.scoped(ctx, "SELECT * FROM orders", nil); !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("expected ErrMissingTenant but got: %v", err)
+	}
+
+	ctx = WithTenant(ctx, 42)
+	if id, ok := TenantFromContext(ctx); !ok || id != 42 {
+		t.Fatalf("expected tenant 42 but got: %v", id)
+	}
+
+	args := []any{"note"}
+	query, scopedArgs, err := repo.scoped(ctx, "SELECT * FROM orders WHERE note = $1", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `WITH "orders" AS (SELECT * FROM "public"."orders" WHERE "deleted_at" IS NULL AND "tenant_id" = $2) SELECT * FROM orders WHERE note = $1`
+	if query != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, query)
+	}
+
+	if !reflect.DeepEqual(scopedArgs, []any{"note", 42}) || len(args) != 1 {
+		t.Fatalf("unexpected arguments: %v (caller's: %v)", scopedArgs, args)
+	}
+
+	query, scopedArgs, err = db.deleteByIDQuery(ctx, repo.td, int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected = `UPDATE "public"."orders" SET "deleted_at" = now() WHERE "id" = $1 AND "tenant_id" = $2 AND "deleted_at" IS NULL;`; query != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, query)
+	}
+
+	if !reflect.DeepEqual(scopedArgs, []any{int64(1), 42}) {
+		t.Fatalf("unexpected arguments: %v", scopedArgs)
+	}
+}
+
+func TestBindTenant(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("orders", tenantOrder{})
+	td, err := schema.GetByTableName("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithTenant(context.Background(), 42)
+
+	// a pointer is filled in place.
+	order := &tenantOrder{Note: "a"}
+	if _, err = bindTenantValue(ctx, td, order); err != nil {
+		t.Fatal(err)
+	}
+
+	if order.TenantID != 42 {
+		t.Fatalf("expected tenant 42 but got: %d", order.TenantID)
+	}
+
+	// a value is copied.
+	value := tenantOrder{Note: "b"}
+	bound, err := bindTenantValue(ctx, td, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := bound.(*tenantOrder).TenantID; got != 42 || value.TenantID != 0 {
+		t.Fatalf("expected a copy with tenant 42 but got: %d (original: %d)", got, value.TenantID)
+	}
+
+	structValues, err := bindTenants(ctx, td, []tenantOrder{{Note: "c"}, {TenantID: 42, Note: "d"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, structValue := range structValues {
+		if got := structValue.Interface().(tenantOrder).TenantID; got != 42 {
+			t.Fatalf("[%d] expected tenant 42 but got: %d", i, got)
+		}
+	}
+
+	if _, err = bindTenantValue(ctx, td, &tenantOrder{TenantID: 7}); !errors.Is(err, ErrTenantMismatch) {
+		t.Fatalf("expected ErrTenantMismatch but got: %v", err)
+	}
+
+	if _, err = bindTenantValue(WithTenant(ctx, "acme"), td, &tenantOrder{}); err == nil {
+		t.Fatal("expected an error for a tenant ID which is not convertible to the column type")
+	}
+
+	if _, err = bindTenantValue(context.Background(), td, &tenantOrder{}); !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("expected ErrMissingTenant but got: %v", err)
+	}
+}
+
+func TestCreateTenantPoliciesDump(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister("orders", tenantOrder{})
+	db := &DB{schema: schema, searchPath: "public"}
+
+	var b strings.Builder
+	if err := db.createTenantPoliciesDump(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Len() != 0 {
+		t.Fatalf("expected no policies without Schema.TenantRowLevelSecurity but got: %s", b.String())
+	}
+
+	schema.TenantRowLevelSecurity = true
+	if err := db.createTenantPoliciesDump(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `ALTER TABLE "orders" ENABLE ROW LEVEL SECURITY;` +
+		`ALTER TABLE "orders" FORCE ROW LEVEL SECURITY;` +
+		`DROP POLICY IF EXISTS tenant_isolation ON "orders";` +
+		`CREATE POLICY tenant_isolation ON "orders" USING ("tenant_id" = NULLIF(current_setting('app.tenant_id', true), '')::bigint) WITH CHECK ("tenant_id" = NULLIF(current_setting('app.tenant_id', true), '')::bigint);`
+	if got := b.String(); got != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, got)
+	}
+}
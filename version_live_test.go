@@ -0,0 +1,170 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// These tests require a live PostgreSQL server, see getTestConnString (db_example_test.go) and
+// the PG_CONNSTRING environment variable. Run with, e.g.:
+//
+//	PG_CONNSTRING="..." go test -run 'TestRepositoryUpdateVersion' -v .
+
+type versionedDocument struct {
+	ID      int64  `pg:"type=bigserial,primary"`
+	Title   string `pg:"type=varchar(255)"`
+	Version int    `pg:"type=int,version"`
+}
+
+const versionScratchTable = "test_versioned_documents"
+
+func TestRepositoryUpdateVersion(t *testing.T) {
+	schema := NewSchema()
+	schema.MustRegister(versionScratchTable, versionedDocument{})
+
+	db, err := Open(context.Background(), schema, getTestConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dropTestTables(ctx, db, versionScratchTable)
+	if _, err = db.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE %s (id BIGSERIAL PRIMARY KEY, title VARCHAR(255) NOT NULL, version INT NOT NULL DEFAULT 0)",
+		versionScratchTable)); err != nil {
+		t.Fatal(err)
+	}
+	defer dropTestTables(ctx, db, versionScratchTable)
+
+	repo := NewRepository[versionedDocument](db)
+
+	var id int64
+	if err = repo.InsertSingle(ctx, versionedDocument{Title: "draft"}, &id); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := repo.SelectByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := first // a concurrent editor read the same version.
+
+	docs := []versionedDocument{first}
+	docs[0].Title = "first edit"
+	if _, err = repo.Update(ctx, docs...); err != nil {
+		t.Fatal(err)
+	}
+
+	if docs[0].Version != first.Version+1 {
+		t.Fatalf("expected the new version %d to be written back but got: %d", first.Version+1, docs[0].Version)
+	}
+
+	second.Title = "second edit"
+	_, err = repo.Update(ctx, second)
+	if !errors.Is(err, ErrStaleEntity) {
+		t.Fatalf("expected ErrStaleEntity but got: %v", err)
+	}
+
+	staleErr, ok := errors.AsType[*StaleEntityError](err)
+	if !ok || staleErr.ID != id || staleErr.Version != second.Version {
+		t.Fatalf("unexpected stale entity error: %#v", staleErr)
+	}
+
+	// the written back version can be used for the next update.
+	docs[0].Title = "third edit"
+	if _, err = repo.UpdateOnlyColumns(ctx, []string{"title"}, docs...); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.SelectByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Title != "third edit" || got.Version != first.Version+2 {
+		t.Fatalf("unexpected document after updates: %#v", got)
+	}
+
+	// a stale value rolls back the whole update: the versions of the others are not written back.
+	var otherID int64
+	if err = repo.InsertSingle(ctx, versionedDocument{Title: "other"}, &otherID); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := repo.SelectByID(ctx, otherID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs = []versionedDocument{other, second}
+	if _, err = repo.Update(ctx, docs...); !errors.Is(err, ErrStaleEntity) {
+		t.Fatalf("expected ErrStaleEntity but got: %v", err)
+	}
+
+	if docs[0].Version != other.Version {
+		t.Fatalf("expected the version %d of the rolled back update to be kept but got: %d", other.Version, docs[0].Version)
+	}
+
+	// and the same values, with the stale one refreshed, update.
+	docs[1] = got
+	if _, err = repo.Update(ctx, docs...); err != nil {
+		t.Fatal(err)
+	}
+
+	// in a caller's transaction the new version is written back at once, so the value can be
+	// updated again, and the previous one is written back if the transaction rolls back.
+	committed := docs[0].Version
+	errRollback := errors.New("rollback")
+	err = repo.InTransaction(ctx, func(repo *Repository[versionedDocument]) error {
+		for range 2 {
+			if _, err := repo.Update(ctx, docs[:1]...); err != nil {
+				return err
+			}
+		}
+
+		if docs[0].Version != committed+2 {
+			return fmt.Errorf("expected the version %d in the transaction but got: %d", committed+2, docs[0].Version)
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected the rollback error but got: %v", err)
+	}
+
+	if docs[0].Version != committed {
+		t.Fatalf("expected the committed version %d after the rollback but got: %d", committed, docs[0].Version)
+	}
+
+	// a rolled back savepoint writes back the version of the enclosing transaction.
+	err = db.InTransaction(ctx, func(tx *DB) error {
+		if _, err := tx.Update(ctx, &docs[0]); err != nil {
+			return err
+		}
+
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, err = savepoint.Update(ctx, &docs[0]); err != nil {
+			return err
+		}
+
+		return savepoint.Rollback(ctx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if docs[0].Version != committed+1 {
+		t.Fatalf("expected the version %d of the committed transaction but got: %d", committed+1, docs[0].Version)
+	}
+
+	if _, err = repo.Update(ctx, docs[:1]...); err != nil {
+		t.Fatalf("expected the written back version to match the committed row: %v", err)
+	}
+}